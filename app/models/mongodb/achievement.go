@@ -55,6 +55,32 @@ type Achievement struct {
 	Attachments     []Attachment       `bson:"attachments" json:"attachments"`
 	Tags            []string           `bson:"tags" json:"tags"`
 	Points          int                `bson:"points" json:"points"`
+	Version         int                `bson:"version" json:"version"`
+	UpdatedBy       string             `bson:"updatedBy,omitempty" json:"updatedBy,omitempty"`
+	RevokedAt       *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	DeletedAt       *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+	DeletedBy       string             `bson:"deletedBy,omitempty" json:"deletedBy,omitempty"`
 	CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt       time.Time          `bson:"updatedAt" json:"updatedAt"`
-}
\ No newline at end of file
+}
+
+// AchievementVersion is the snapshot stored in achievement_versions each time
+// an achievement's content changes. AchievementID is the hex _id of the
+// achievement document; (achievementId, version) is unique.
+type AchievementVersion struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	AchievementID string             `bson:"achievementId" json:"-"`
+	Version       int                `bson:"version" json:"version"`
+	Snapshot      Achievement        `bson:"snapshot" json:"snapshot"`
+	CreatedBy     string             `bson:"createdBy,omitempty" json:"createdBy,omitempty"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// FieldChange is one entry of a diff between two versions; Field is the JSON
+// path, e.g. "details.rank". From or To is nil when the field was added or
+// removed.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+	"github.com/google/uuid"
+)
+
+type PersonalAccessToken struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UserID      uuid.UUID  `json:"userId" db:"user_id"`
+	Name        string     `json:"name" db:"name"`
+	TokenPrefix string     `json:"prefix" db:"token_prefix"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	Scopes      []string   `json:"scopes" db:"scopes"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	ExpiresAt   time.Time  `json:"expiresAt" db:"expires_at"`
+	LastUsedAt  *time.Time `json:"lastUsedAt" db:"last_used_at"`
+	LastUsedIP  *string    `json:"lastUsedIp" db:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+}
+
+// AccessTokenOwner is a valid token joined with the owner's current role.
+type AccessTokenOwner struct {
+	TokenID    uuid.UUID
+	UserID     uuid.UUID
+	RoleID     uuid.UUID
+	RoleName   string
+	Scopes     []string
+	LastUsedAt *time.Time
+}
+
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays"`
+}
+
+// CreateAccessTokenResp carries the plaintext token; it is shown only once.
+type CreateAccessTokenResp struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
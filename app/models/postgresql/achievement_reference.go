@@ -10,6 +10,9 @@ const (
 	StatusSubmitted = "submitted"
 	StatusVerified  = "verified"
 	StatusRejected  = "rejected"
+	StatusRevision  = "revision"
+	StatusRevoked   = "revoked"
+	StatusDeleted   = "deleted"
 )
 
 type AchievementReference struct {
@@ -22,6 +25,85 @@ type AchievementReference struct {
 	VerifiedAt         *time.Time `json:"verifiedAt" db:"verified_at"`
 	VerifiedBy         *uuid.UUID `json:"verifiedBy" db:"verified_by"`
 	RejectionNote      *string    `json:"rejectionNote" db:"rejection_note"`
+	RubricVersion      *int       `json:"rubricVersion" db:"rubric_version"`
+	SuggestedPoints    *int       `json:"suggestedPoints" db:"suggested_points"`
+	PointsOverridden   bool       `json:"pointsOverridden" db:"points_overridden"`
+	VerifiedVersion    *int       `json:"verifiedVersion" db:"verified_version"`
+	DeletedAt          *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	DeletedBy          *uuid.UUID `json:"deletedBy,omitempty" db:"deleted_by"`
 	CreatedAt          time.Time  `json:"createdAt" db:"created_at"`
 	UpdatedAt          time.Time  `json:"updatedAt" db:"updated_at"`
-}
\ No newline at end of file
+}
+
+// StatusChange describes who moves an achievement to a new status and why.
+// VerifiedBy is the lecturer profile recorded on verify and reject. Points,
+// when set, replaces the achievement's points; OldPoints is only recorded in
+// the history. On verify, Suggestion is the rubric score that was offered
+// and Version the details version that was reviewed. Outbox holds the Mongo
+// writes the change requires; they are stored in the same transaction.
+type StatusChange struct {
+	ActorID    uuid.UUID
+	VerifiedBy *uuid.UUID
+	Note       string
+	Points     *int
+	OldPoints  *int
+	Suggestion *SuggestedScore
+	Version    *int
+	Outbox     []OutboxEvent
+}
+
+// AchievementStatusHistory is one row of the append-only transition log.
+// FromStatus is nil for the row written when the draft is created.
+type AchievementStatusHistory struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	AchievementRefID uuid.UUID  `json:"achievementId" db:"achievement_ref_id"`
+	FromStatus       *string    `json:"fromStatus" db:"from_status"`
+	ToStatus         string     `json:"toStatus" db:"to_status"`
+	ActorID          *uuid.UUID `json:"actorId" db:"actor_id"`
+	ActorName        *string    `json:"actorName,omitempty" db:"-"`
+	Note             *string    `json:"note,omitempty" db:"note"`
+	OldPoints        *int       `json:"oldPoints,omitempty" db:"old_points"`
+	NewPoints        *int       `json:"newPoints,omitempty" db:"new_points"`
+	CreatedAt        time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// BulkVerifyItem is one entry of a bulk verification. Points and Version
+// mean the same as on the single verify endpoint.
+type BulkVerifyItem struct {
+	ID      uuid.UUID `json:"id"`
+	Points  int       `json:"points"`
+	Version int       `json:"version"`
+}
+
+// BulkRejectItem is one entry of a bulk rejection; an empty Note falls back
+// to the request's note.
+type BulkRejectItem struct {
+	ID   uuid.UUID `json:"id"`
+	Note string    `json:"note"`
+}
+
+type BulkVerifyRequest struct {
+	Items []BulkVerifyItem `json:"items"`
+}
+
+type BulkRejectRequest struct {
+	Note  string           `json:"note"`
+	Items []BulkRejectItem `json:"items"`
+}
+
+// BulkItemResult reports what happened to one item. Status is the HTTP
+// status the single endpoint would have answered.
+type BulkItemResult struct {
+	ID             uuid.UUID `json:"id"`
+	Success        bool      `json:"success"`
+	Status         int       `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	Points         *int      `json:"points,omitempty"`
+	CurrentVersion *int      `json:"currentVersion,omitempty"`
+}
+
+type BulkResult struct {
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
@@ -10,12 +10,14 @@ type JWTClaims struct {
 	UserID      uuid.UUID `json:"userId"`
 	RoleID      uuid.UUID `json:"roleId"`
 	RoleName    string    `json:"roleName"`
-	Permissions []string  `json:"permissions,omitempty"` 
+	SessionID   uuid.UUID `json:"sid"`
+	TokenVersion int      `json:"tv"`
 	jwt.RegisteredClaims
 }
 
 type RefreshClaims struct {
-	UserID string `json:"userId"`
+	UserID    string `json:"userId"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+	"github.com/google/uuid"
+)
+
+// AchievementComment is one message in an achievement's discussion. Field
+// names a details field such as "details.rank"; Attachment is the fileUrl
+// of one of the achievement's attachments. ParentID is set on a reply and
+// names the comment it answers.
+type AchievementComment struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	AchievementRefID uuid.UUID  `json:"achievementId" db:"achievement_ref_id"`
+	ParentID         *uuid.UUID `json:"parentId" db:"parent_id"`
+	AuthorID         *uuid.UUID `json:"authorId" db:"author_id"`
+	AuthorName       *string    `json:"authorName,omitempty" db:"-"`
+	AuthorRole       string     `json:"authorRole" db:"author_role"`
+	Body             string     `json:"body" db:"body"`
+	Field            *string    `json:"field,omitempty" db:"field"`
+	Attachment       *string    `json:"attachment,omitempty" db:"attachment_url"`
+	CreatedAt        time.Time  `json:"createdAt" db:"created_at"`
+	EditedAt         *time.Time `json:"editedAt,omitempty" db:"edited_at"`
+}
+
+type CommentRequest struct {
+	Body       string     `json:"body"`
+	Field      *string    `json:"field"`
+	Attachment *string    `json:"attachment"`
+	ParentID   *uuid.UUID `json:"parentId"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	LoginScopeUsername = "username"
+	LoginScopeIP       = "ip"
+)
+
+// LoginAttempt is the failed-login counter of one username or client IP.
+// LockedUntil is set both for the short exponential backoff and for the
+// longer lockout once Failures reaches the limit.
+type LoginAttempt struct {
+	Scope         string     `json:"scope" db:"scope"`
+	Subject       string     `json:"subject" db:"subject"`
+	Failures      int        `json:"failures" db:"failures"`
+	LastFailureAt time.Time  `json:"lastFailureAt" db:"last_failure_at"`
+	LockedUntil   *time.Time `json:"lockedUntil" db:"locked_until"`
+}
+
+type LoginLockResp struct {
+	Scope         string    `json:"scope"`
+	Subject       string    `json:"subject"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"lastFailureAt"`
+	LockedUntil   time.Time `json:"lockedUntil"`
+	LockedOut     bool      `json:"lockedOut"`
+}
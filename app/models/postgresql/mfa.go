@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+	"github.com/google/uuid"
+)
+
+type UserMFA struct {
+	UserID       uuid.UUID  `json:"userId" db:"user_id"`
+	Secret       string     `json:"-" db:"secret"`
+	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
+	EnabledAt    *time.Time `json:"enabledAt" db:"enabled_at"`
+	LastUsedStep int64      `json:"-" db:"last_used_step"`
+}
+
+func (m *UserMFA) Enabled() bool {
+	return m != nil && m.EnabledAt != nil
+}
+
+type MFAChallenge struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"userId" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	IPAddress string     `json:"ipAddress" db:"ip_address"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	ExpiresAt time.Time  `json:"expiresAt" db:"expires_at"`
+	Attempts  int        `json:"attempts" db:"attempts"`
+	UsedAt    *time.Time `json:"usedAt,omitempty" db:"used_at"`
+}
+
+type MFASetupResp struct {
+	Secret     string `json:"secret"`
+	OtpauthURL string `json:"otpauthUrl"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type MFADisableRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+type MFARecoveryCodesResp struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// MFAChallengeResp is returned by Login instead of tokens when a second
+// factor is needed. SetupRequired means the role demands 2FA but the user
+// has not enrolled yet.
+type MFAChallengeResp struct {
+	MFARequired    bool      `json:"mfaRequired"`
+	SetupRequired  bool      `json:"setupRequired"`
+	ChallengeToken string    `json:"challengeToken"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+}
+
+type MFAChallengeRequest struct {
+	ChallengeToken string `json:"challengeToken"`
+}
+
+type MFALoginRequest struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recoveryCode"`
+}
+
+// MFALoginResponse carries the recovery codes once, when the login also
+// completed a forced enrollment.
+type MFALoginResponse struct {
+	LoginResponse
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+	"github.com/google/uuid"
+)
+
+type OIDCLoginState struct {
+	ID           uuid.UUID  `db:"id"`
+	StateHash    string     `db:"state_hash"`
+	CodeVerifier string     `db:"code_verifier"`
+	Nonce        string     `db:"nonce"`
+	CreatedAt    time.Time  `db:"created_at"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+	UsedAt       *time.Time `db:"used_at"`
+}
+
+// UserIdentity links an identity provider subject to a local user.
+type UserIdentity struct {
+	Issuer      string     `json:"issuer" db:"issuer"`
+	Subject     string     `json:"subject" db:"subject"`
+	UserID      uuid.UUID  `json:"userId" db:"user_id"`
+	Email       string     `json:"email" db:"email"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	LastLoginAt *time.Time `json:"lastLoginAt" db:"last_login_at"`
+}
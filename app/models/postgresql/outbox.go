@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+	"github.com/google/uuid"
+)
+
+// Kinds of Mongo write carried by an outbox event.
+const (
+	OutboxCreateDetails  = "create_details"
+	OutboxSetPoints      = "set_points"
+	OutboxTrashDetails   = "trash_details"
+	OutboxRestoreDetails = "restore_details"
+	OutboxMarkRevoked    = "mark_revoked"
+)
+
+// OutboxEvent is a Mongo write owed after a Postgres change. It is stored in
+// the same transaction as the change and delivered until it succeeds.
+type OutboxEvent struct {
+	ID                 uuid.UUID       `json:"id" db:"id"`
+	AchievementRefID   uuid.UUID       `json:"achievementId" db:"achievement_ref_id"`
+	MongoAchievementID string          `json:"mongoAchievementId" db:"mongo_achievement_id"`
+	Kind               string          `json:"kind" db:"kind"`
+	Payload            json.RawMessage `json:"payload,omitempty" db:"payload"`
+	Attempts           int             `json:"attempts" db:"attempts"`
+	LastError          *string         `json:"lastError,omitempty" db:"last_error"`
+	NextAttemptAt      time.Time       `json:"nextAttemptAt" db:"next_attempt_at"`
+	CreatedAt          time.Time       `json:"createdAt" db:"created_at"`
+}
@@ -10,4 +10,9 @@ type Roles struct {
 	Name        string    `json:"name" db:"name"`
 	Description string    `json:"description" db:"description"`
 	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
-}
\ No newline at end of file
+}
+
+type RoleWithPermissions struct {
+	Roles
+	Permissions []Permission `json:"permissions"`
+}
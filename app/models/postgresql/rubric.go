@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+	"github.com/google/uuid"
+)
+
+// ScoringRubric is one version of the points rubric used to suggest a score
+// when an achievement is verified.
+type ScoringRubric struct {
+	ID          uuid.UUID    `json:"id" db:"id"`
+	Version     int          `json:"version" db:"version"`
+	Name        string       `json:"name" db:"name"`
+	Rules       []RubricRule `json:"rules" db:"rules"`
+	IsActive    bool         `json:"isActive" db:"is_active"`
+	CreatedBy   *uuid.UUID   `json:"createdBy" db:"created_by"`
+	CreatedAt   time.Time    `json:"createdAt" db:"created_at"`
+	ActivatedAt *time.Time   `json:"activatedAt" db:"activated_at"`
+}
+
+// RubricRule awards Points to achievements of AchievementType whose details
+// match every entry in When (detail field name -> value, case-insensitive),
+// e.g. {"competitionLevel": "national", "rank": "1"}.
+type RubricRule struct {
+	AchievementType string            `json:"achievementType"`
+	When            map[string]string `json:"when,omitempty"`
+	Points          int               `json:"points"`
+}
+
+type RubricRequest struct {
+	Name  string       `json:"name"`
+	Rules []RubricRule `json:"rules"`
+}
+
+// SuggestedScore is what the active rubric proposes for an achievement.
+type SuggestedScore struct {
+	Points        int        `json:"points"`
+	RubricVersion int        `json:"rubricVersion"`
+	Rule          RubricRule `json:"rule"`
+}
@@ -13,7 +13,87 @@ type User struct {
 	FullName     string    `json:"full_name" db:"full_name"`
 	RoleID       uuid.UUID `json:"role_id" db:"role_id"`
 	IsActive     bool      `json:"is_active" db:"is_active"`
+	TokenVersion int       `json:"-" db:"token_version"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
+
+// CreateUserRequest is the admin payload for POST /users. Exactly one of the
+// profiles is required, depending on the role (student or lecturer).
+type CreateUserRequest struct {
+	Username        string                  `json:"username"`
+	Email           string                  `json:"email"`
+	Password        string                  `json:"password"`
+	FullName        string                  `json:"fullName"`
+	RoleID          string                  `json:"roleId"`
+	IsActive        *bool                   `json:"isActive,omitempty"`
+	StudentProfile  *StudentProfileRequest  `json:"studentProfile,omitempty"`
+	LecturerProfile *LecturerProfileRequest `json:"lecturerProfile,omitempty"`
+}
+
+type StudentProfileRequest struct {
+	StudentID    string `json:"studentId"`
+	ProgramStudy string `json:"programStudy"`
+	AcademicYear string `json:"academicYear"`
+	AdvisorID    string `json:"advisorId,omitempty"`
+}
+
+type LecturerProfileRequest struct {
+	LecturerID string `json:"lecturerId"`
+	Department string `json:"department"`
+}
+
+// UpdateUserRequest is the admin payload for PUT /users/:id. Only fields that
+// are present are changed. Passwords cannot be set here.
+type UpdateUserRequest struct {
+	Username     *string `json:"username"`
+	Email        *string `json:"email"`
+	FullName     *string `json:"full_name"`
+	RoleID       *string `json:"role_id"`
+	IsActive     *bool   `json:"is_active"`
+	Password     *string `json:"password,omitempty" swaggerignore:"true"`
+	PasswordHash *string `json:"password_hash,omitempty" swaggerignore:"true"`
+}
+
+// UserAccountResp is what user-management endpoints return; it never carries
+// the password hash.
+type UserAccountResp struct {
+	ID        uuid.UUID     `json:"id"`
+	Username  string        `json:"username"`
+	Email     string        `json:"email"`
+	FullName  string        `json:"fullName"`
+	RoleID    uuid.UUID     `json:"roleId"`
+	RoleName  string        `json:"roleName"`
+	IsActive  bool          `json:"isActive"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Student   *StudentResp  `json:"student,omitempty"`
+	Lecturer  *LecturerResp `json:"lecturer,omitempty"`
+}
+
+// NewUserAccount groups the rows written for one created user.
+type NewUserAccount struct {
+	User     *User
+	Student  *Student
+	Lecturer *Lecturer
+}
+
+// UserImportRowResult reports the outcome of one data row of an import file.
+// Row is the 1-based line in the file, counting the header.
+type UserImportRowResult struct {
+	Row               int               `json:"row"`
+	Username          string            `json:"username"`
+	Valid             bool              `json:"valid"`
+	Errors            map[string]string `json:"errors,omitempty"`
+	UserID            *uuid.UUID        `json:"userId,omitempty"`
+	TemporaryPassword string            `json:"temporaryPassword,omitempty"`
+}
+
+type UserImportResult struct {
+	DryRun    bool                  `json:"dryRun"`
+	Committed bool                  `json:"committed"`
+	Total     int                   `json:"total"`
+	Valid     int                   `json:"valid"`
+	Invalid   int                   `json:"invalid"`
+	Rows      []UserImportRowResult `json:"rows"`
+}
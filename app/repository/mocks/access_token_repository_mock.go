@@ -0,0 +1,49 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+)
+
+type MockAccessTokenRepo struct {
+	mock.Mock
+}
+
+var _ repo.AccessTokenRepository = (*MockAccessTokenRepo)(nil)
+
+func (m *MockAccessTokenRepo) Create(ctx context.Context, t *models.PersonalAccessToken) error {
+	args := m.Called(ctx, t)
+	return args.Error(0)
+}
+
+func (m *MockAccessTokenRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockAccessTokenRepo) Revoke(ctx context.Context, id, userID uuid.UUID) error {
+	args := m.Called(ctx, id, userID)
+	return args.Error(0)
+}
+
+func (m *MockAccessTokenRepo) FindActive(ctx context.Context, tokenHash string) (*models.AccessTokenOwner, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccessTokenOwner), args.Error(1)
+}
+
+func (m *MockAccessTokenRepo) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time, ip string) error {
+	args := m.Called(ctx, id, at, ip)
+	return args.Error(0)
+}
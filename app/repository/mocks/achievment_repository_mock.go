@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -99,8 +100,8 @@ type MockAchievementPgRepo struct {
 // Compile-time check implementation
 var _ repoPg.AchievementRepoPostgres = (*MockAchievementPgRepo)(nil)
 
-func (m *MockAchievementPgRepo) Create(ctx context.Context, ref modelPg.AchievementReference) (uuid.UUID, error) {
-	args := m.Called(ctx, ref)
+func (m *MockAchievementPgRepo) Create(ctx context.Context, ref modelPg.AchievementReference, actorID uuid.UUID, events []modelPg.OutboxEvent) (uuid.UUID, error) {
+	args := m.Called(ctx, ref, actorID, events)
 	return args.Get(0).(uuid.UUID), args.Error(1)
 }
 
@@ -122,22 +123,103 @@ func (m *MockAchievementPgRepo) GetReferenceByID(ctx context.Context, id uuid.UU
 	return args.Get(0).(modelPg.AchievementReference), args.Error(1)
 }
 
-func (m *MockAchievementPgRepo) DeleteReference(ctx context.Context, id uuid.UUID) error {
-	args := m.Called(ctx, id)
+func (m *MockAchievementPgRepo) TransitionStatus(ctx context.Context, id uuid.UUID, from, to string, change modelPg.StatusChange) error {
+	args := m.Called(ctx, id, from, to, change)
 	return args.Error(0)
 }
 
-func (m *MockAchievementPgRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status string, verifiedBy *uuid.UUID, note string) error {
-	args := m.Called(ctx, id, status, verifiedBy, note)
+func (m *MockAchievementPgRepo) AdjustPoints(ctx context.Context, id uuid.UUID, status string, change modelPg.StatusChange) error {
+	args := m.Called(ctx, id, status, change)
 	return args.Error(0)
 }
 
-func (m *MockAchievementPgRepo) SubmitReference(ctx context.Context, id uuid.UUID) error {
+func (m *MockAchievementPgRepo) GetStatusHistory(ctx context.Context, id uuid.UUID) ([]modelPg.AchievementStatusHistory, error) {
 	args := m.Called(ctx, id)
-	return args.Error(0)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]modelPg.AchievementStatusHistory), args.Error(1)
 }
 
 func (m *MockAchievementMongoRepo) UpdatePoints( ctx context.Context, mongoID string, points int) error {
     args := m.Called(ctx, mongoID, points)
     return args.Error(0)
-}
\ No newline at end of file
+}
+
+func (m *MockAchievementMongoRepo) MarkRevoked(ctx context.Context, mongoID string, revokedAt time.Time) error {
+    args := m.Called(ctx, mongoID, revokedAt)
+    return args.Error(0)
+}
+
+func (m *MockAchievementMongoRepo) ListVersions(ctx context.Context, mongoID string) ([]modelMongo.AchievementVersion, error) {
+    args := m.Called(ctx, mongoID)
+    if args.Get(0) == nil {
+        return nil, args.Error(1)
+    }
+    return args.Get(0).([]modelMongo.AchievementVersion), args.Error(1)
+}
+
+func (m *MockAchievementMongoRepo) GetVersion(ctx context.Context, mongoID string, version int) (*modelMongo.AchievementVersion, error) {
+    args := m.Called(ctx, mongoID, version)
+    if args.Get(0) == nil {
+        return nil, args.Error(1)
+    }
+    return args.Get(0).(*modelMongo.AchievementVersion), args.Error(1)
+}
+
+func (m *MockAchievementMongoRepo) ListAll(ctx context.Context) ([]modelMongo.Achievement, error) {
+    args := m.Called(ctx)
+    if args.Get(0) == nil {
+        return nil, args.Error(1)
+    }
+    return args.Get(0).([]modelMongo.Achievement), args.Error(1)
+}
+
+func (m *MockAchievementPgRepo) ListReferences(ctx context.Context) ([]modelPg.AchievementReference, error) {
+    args := m.Called(ctx)
+    if args.Get(0) == nil {
+        return nil, args.Error(1)
+    }
+    return args.Get(0).([]modelPg.AchievementReference), args.Error(1)
+}
+
+func (m *MockAchievementMongoRepo) MarkDeleted(ctx context.Context, mongoID string, deletedBy string, deletedAt time.Time) error {
+    args := m.Called(ctx, mongoID, deletedBy, deletedAt)
+    return args.Error(0)
+}
+
+func (m *MockAchievementMongoRepo) ClearDeleted(ctx context.Context, mongoID string) error {
+    args := m.Called(ctx, mongoID)
+    return args.Error(0)
+}
+
+func (m *MockAchievementPgRepo) GetTrash(ctx context.Context, studentID uuid.UUID) ([]modelPg.AchievementReference, error) {
+    args := m.Called(ctx, studentID)
+    if args.Get(0) == nil {
+        return nil, args.Error(1)
+    }
+    return args.Get(0).([]modelPg.AchievementReference), args.Error(1)
+}
+
+func (m *MockAchievementPgRepo) GetDeletedReference(ctx context.Context, id uuid.UUID) (modelPg.AchievementReference, error) {
+    args := m.Called(ctx, id)
+    return args.Get(0).(modelPg.AchievementReference), args.Error(1)
+}
+
+func (m *MockAchievementPgRepo) ExpiredTrash(ctx context.Context, before time.Time, limit int) ([]modelPg.AchievementReference, error) {
+    args := m.Called(ctx, before, limit)
+    if args.Get(0) == nil {
+        return nil, args.Error(1)
+    }
+    return args.Get(0).([]modelPg.AchievementReference), args.Error(1)
+}
+
+// PurgeReference runs cleanup like the real repository unless the expected
+// call answers an error.
+func (m *MockAchievementPgRepo) PurgeReference(ctx context.Context, id uuid.UUID, before time.Time, cleanup func() error) error {
+    args := m.Called(ctx, id, before)
+    if err := args.Error(0); err != nil {
+        return err
+    }
+    return cleanup()
+}
@@ -75,6 +75,37 @@ func (m *MockAdminRepo) SetAdvisor(studentID, lecturerID uuid.UUID) error {
 	return args.Error(0)
 }
 
+func (m *MockAdminRepo) FindTakenCredentials(username, email string) (bool, bool, error) {
+	args := m.Called(username, email)
+	return args.Bool(0), args.Bool(1), args.Error(2)
+}
+
+func (m *MockAdminRepo) CreateUserWithProfile(user *models.User, student *models.Student, lecturer *models.Lecturer) error {
+	args := m.Called(user, student, lecturer)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepo) ListTakenCredentials(usernames, emails []string) (map[string]bool, map[string]bool, error) {
+	args := m.Called(usernames, emails)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(map[string]bool), args.Get(1).(map[string]bool), args.Error(2)
+}
+
+func (m *MockAdminRepo) ResolveLecturers(refs []string) (map[string]uuid.UUID, error) {
+	args := m.Called(refs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]uuid.UUID), args.Error(1)
+}
+
+func (m *MockAdminRepo) ImportUsers(accounts []models.NewUserAccount) error {
+	args := m.Called(accounts)
+	return args.Error(0)
+}
+
 // =========================================================
 // MOCK USER REPOSITORY
 // =========================================================
@@ -108,4 +139,9 @@ func (m *MockUserRepo) GetByID(id uuid.UUID) (*models.User, error) {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepo) GetTokenVersion(id uuid.UUID) (int, bool, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Bool(1), args.Error(2)
 }
\ No newline at end of file
@@ -0,0 +1,48 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+)
+
+type MockCommentRepo struct {
+	mock.Mock
+}
+
+var _ repo.CommentRepository = (*MockCommentRepo)(nil)
+
+func (m *MockCommentRepo) ListByAchievement(ctx context.Context, achievementID uuid.UUID) ([]models.AchievementComment, error) {
+	args := m.Called(ctx, achievementID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AchievementComment), args.Error(1)
+}
+
+func (m *MockCommentRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.AchievementComment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AchievementComment), args.Error(1)
+}
+
+func (m *MockCommentRepo) Create(ctx context.Context, comment *models.AchievementComment) error {
+	args := m.Called(ctx, comment)
+	return args.Error(0)
+}
+
+func (m *MockCommentRepo) Update(ctx context.Context, id uuid.UUID, body string) error {
+	args := m.Called(ctx, id, body)
+	return args.Error(0)
+}
+
+func (m *MockCommentRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
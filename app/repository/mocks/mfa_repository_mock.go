@@ -0,0 +1,73 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+)
+
+type MockMFARepo struct {
+	mock.Mock
+}
+
+var _ repo.MFARepository = (*MockMFARepo)(nil)
+
+func (m *MockMFARepo) Get(ctx context.Context, userID uuid.UUID) (*models.UserMFA, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserMFA), args.Error(1)
+}
+
+func (m *MockMFARepo) SavePendingSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *MockMFARepo) Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error {
+	args := m.Called(ctx, userID, step, recoveryHashes)
+	return args.Error(0)
+}
+
+func (m *MockMFARepo) Disable(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockMFARepo) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepo) CreateChallenge(ctx context.Context, ch *models.MFAChallenge) error {
+	args := m.Called(ctx, ch)
+	return args.Error(0)
+}
+
+func (m *MockMFARepo) GetChallenge(ctx context.Context, tokenHash string) (*models.MFAChallenge, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MFAChallenge), args.Error(1)
+}
+
+func (m *MockMFARepo) FailChallenge(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockMFARepo) ConsumeChallenge(ctx context.Context, id uuid.UUID, maxAttempts int) error {
+	args := m.Called(ctx, id, maxAttempts)
+	return args.Error(0)
+}
@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+)
+
+type MockOIDCRepo struct {
+	mock.Mock
+}
+
+var _ repo.OIDCRepository = (*MockOIDCRepo)(nil)
+
+func (m *MockOIDCRepo) SaveLoginState(ctx context.Context, s *models.OIDCLoginState) error {
+	args := m.Called(ctx, s)
+	return args.Error(0)
+}
+
+func (m *MockOIDCRepo) ConsumeLoginState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	args := m.Called(ctx, stateHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OIDCLoginState), args.Error(1)
+}
+
+func (m *MockOIDCRepo) FindUserByIdentity(ctx context.Context, issuer, subject string) (uuid.UUID, error) {
+	args := m.Called(ctx, issuer, subject)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockOIDCRepo) FindUserByEmail(ctx context.Context, email string) (uuid.UUID, error) {
+	args := m.Called(ctx, email)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockOIDCRepo) LinkIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *MockOIDCRepo) TouchIdentity(ctx context.Context, issuer, subject string) error {
+	args := m.Called(ctx, issuer, subject)
+	return args.Error(0)
+}
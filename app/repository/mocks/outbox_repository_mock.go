@@ -0,0 +1,49 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+)
+
+type MockOutboxRepo struct {
+	mock.Mock
+}
+
+var _ repo.OutboxRepository = (*MockOutboxRepo)(nil)
+
+func (m *MockOutboxRepo) Due(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.OutboxEvent), args.Error(1)
+}
+
+func (m *MockOutboxRepo) Pending(ctx context.Context) ([]models.OutboxEvent, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.OutboxEvent), args.Error(1)
+}
+
+func (m *MockOutboxRepo) Blocked(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOutboxRepo) MarkDelivered(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepo) MarkFailed(ctx context.Context, id uuid.UUID, lastError string, nextAttempt time.Time) error {
+	args := m.Called(ctx, id, lastError, nextAttempt)
+	return args.Error(0)
+}
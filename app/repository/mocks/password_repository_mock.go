@@ -0,0 +1,37 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+)
+
+type MockPasswordRepo struct {
+	mock.Mock
+}
+
+var _ repo.PasswordRepository = (*MockPasswordRepo)(nil)
+
+func (m *MockPasswordRepo) GetPasswordHash(ctx context.Context, userID uuid.UUID) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockPasswordRepo) UpdatePassword(ctx context.Context, userID uuid.UUID, hash string) error {
+	args := m.Called(ctx, userID, hash)
+	return args.Error(0)
+}
+
+func (m *MockPasswordRepo) CreateResetToken(ctx context.Context, t *models.PasswordResetToken) error {
+	args := m.Called(ctx, t)
+	return args.Error(0)
+}
+
+func (m *MockPasswordRepo) ResetPassword(ctx context.Context, tokenHash string, hash string) (uuid.UUID, error) {
+	args := m.Called(ctx, tokenHash, hash)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
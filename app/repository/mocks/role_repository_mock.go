@@ -0,0 +1,132 @@
+package mocks
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+)
+
+// =========================================================
+// MOCK ROLE REPOSITORY
+// =========================================================
+
+type MockRoleRepo struct {
+	mock.Mock
+}
+
+var _ repo.RoleRepository = (*MockRoleRepo)(nil)
+
+func (m *MockRoleRepo) GetRolePermissionMap() (map[uuid.UUID][]string, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[uuid.UUID][]string), args.Error(1)
+}
+
+func (m *MockRoleRepo) GetAllRoles() ([]models.Roles, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Roles), args.Error(1)
+}
+
+func (m *MockRoleRepo) GetRoleByID(id uuid.UUID) (*models.Roles, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Roles), args.Error(1)
+}
+
+func (m *MockRoleRepo) CreateRole(role *models.Roles) error {
+	args := m.Called(role)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepo) UpdateRole(role *models.Roles) error {
+	args := m.Called(role)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepo) DeleteRole(id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepo) CountUsersByRole(id uuid.UUID) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRoleRepo) GetPermissionsByRole(id uuid.UUID) ([]models.Permission, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Permission), args.Error(1)
+}
+
+func (m *MockRoleRepo) AttachPermission(roleID, permissionID uuid.UUID) error {
+	args := m.Called(roleID, permissionID)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepo) DetachPermission(roleID, permissionID uuid.UUID) error {
+	args := m.Called(roleID, permissionID)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepo) CountActiveUsersByRole(id uuid.UUID) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRoleRepo) CountAssignedRolesWithPermission(permissionName string, excludeRoleID uuid.UUID) (int, error) {
+	args := m.Called(permissionName, excludeRoleID)
+	return args.Int(0), args.Error(1)
+}
+
+// =========================================================
+// MOCK PERMISSION REPOSITORY
+// =========================================================
+
+type MockPermissionRepo struct {
+	mock.Mock
+}
+
+var _ repo.PermissionRepository = (*MockPermissionRepo)(nil)
+
+func (m *MockPermissionRepo) GetAllPermissions() ([]models.Permission, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Permission), args.Error(1)
+}
+
+func (m *MockPermissionRepo) GetPermissionByID(id uuid.UUID) (*models.Permission, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Permission), args.Error(1)
+}
+
+func (m *MockPermissionRepo) CreatePermission(p *models.Permission) error {
+	args := m.Called(p)
+	return args.Error(0)
+}
+
+func (m *MockPermissionRepo) UpdatePermission(p *models.Permission) error {
+	args := m.Called(p)
+	return args.Error(0)
+}
+
+func (m *MockPermissionRepo) DeletePermission(id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
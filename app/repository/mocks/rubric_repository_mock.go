@@ -0,0 +1,61 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+)
+
+type MockRubricRepo struct {
+	mock.Mock
+}
+
+var _ repo.RubricRepository = (*MockRubricRepo)(nil)
+
+func (m *MockRubricRepo) List(ctx context.Context) ([]models.ScoringRubric, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ScoringRubric), args.Error(1)
+}
+
+func (m *MockRubricRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.ScoringRubric, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScoringRubric), args.Error(1)
+}
+
+func (m *MockRubricRepo) GetActive(ctx context.Context) (*models.ScoringRubric, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScoringRubric), args.Error(1)
+}
+
+func (m *MockRubricRepo) Create(ctx context.Context, rubric *models.ScoringRubric) error {
+	args := m.Called(ctx, rubric)
+	return args.Error(0)
+}
+
+func (m *MockRubricRepo) Update(ctx context.Context, id uuid.UUID, name string, rules []models.RubricRule) error {
+	args := m.Called(ctx, id, name, rules)
+	return args.Error(0)
+}
+
+func (m *MockRubricRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRubricRepo) Activate(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
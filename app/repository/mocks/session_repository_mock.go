@@ -0,0 +1,59 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+)
+
+type MockSessionRepo struct {
+	mock.Mock
+}
+
+var _ repo.SessionRepository = (*MockSessionRepo)(nil)
+
+func (m *MockSessionRepo) Create(ctx context.Context, s *models.Session) error {
+	args := m.Called(ctx, s)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *MockSessionRepo) Rotate(ctx context.Context, id uuid.UUID, oldJTI, newJTI string, expiresAt time.Time) error {
+	args := m.Called(ctx, id, oldJTI, newJTI, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepo) Revoke(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepo) RevokeForUser(ctx context.Context, id, userID uuid.UUID) error {
+	args := m.Called(ctx, id, userID)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepo) RevokeAllByUser(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepo) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Session), args.Error(1)
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -134,4 +135,42 @@ func (m *MockAchievementRepo) GetStudentStats(ctx context.Context, studentID str
 func (m *MockAchievementRepo) UpdatePoints(ctx context.Context,mongoID string,points int) error {
 	args := m.Called(ctx, mongoID, points)
 	return args.Error(0)
-}
\ No newline at end of file
+}
+
+func (m *MockAchievementRepo) MarkRevoked(ctx context.Context, mongoID string, revokedAt time.Time) error {
+	args := m.Called(ctx, mongoID, revokedAt)
+	return args.Error(0)
+}
+func (m *MockAchievementRepo) ListVersions(ctx context.Context, mongoID string) ([]modelMongo.AchievementVersion, error) {
+	args := m.Called(ctx, mongoID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]modelMongo.AchievementVersion), args.Error(1)
+}
+
+func (m *MockAchievementRepo) GetVersion(ctx context.Context, mongoID string, version int) (*modelMongo.AchievementVersion, error) {
+	args := m.Called(ctx, mongoID, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*modelMongo.AchievementVersion), args.Error(1)
+}
+
+func (m *MockAchievementRepo) ListAll(ctx context.Context) ([]modelMongo.Achievement, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]modelMongo.Achievement), args.Error(1)
+}
+
+func (m *MockAchievementRepo) MarkDeleted(ctx context.Context, mongoID string, deletedBy string, deletedAt time.Time) error {
+	args := m.Called(ctx, mongoID, deletedBy, deletedAt)
+	return args.Error(0)
+}
+
+func (m *MockAchievementRepo) ClearDeleted(ctx context.Context, mongoID string) error {
+	args := m.Called(ctx, mongoID)
+	return args.Error(0)
+}
@@ -2,41 +2,74 @@ package repository
 
 import (
     "context"
+    "errors"
 	"time"
     models "student-performance-report/app/models/mongodb"
     "github.com/google/uuid"
     "go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/bson/primitive"
+    "go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var ErrVersionNotFound = errors.New("achievement version not found")
+
 type AchievementRepository interface {
     GetStudentAchievements(studentId uuid.UUID) ([]models.Achievement, error)
+    // InsertOne keeps a preset ID. Inserting an ID that is already stored
+    // succeeds without changing the document, so a retried outbox event is
+    // harmless.
     InsertOne(ctx context.Context, achievement models.Achievement) (string, error)
     FindAllDetails(ctx context.Context, mongoIDs []string) ([]models.Achievement, error)
 	FindOne(ctx context.Context, mongoID string) (*models.Achievement, error)
 	DeleteAchievement(ctx context.Context, mongoID string) error
+	// UpdateOne replaces the editable content; points are left alone, they
+	// only change through UpdatePoints and MarkRevoked.
 	UpdateOne(ctx context.Context, mongoID string, data models.Achievement) error
 	AddAttachment(ctx context.Context, mongoID string, attachment models.Attachment) error
     GetGlobalStats(ctx context.Context) (*models.GlobalStatistics, error) 
     GetStudentStats(ctx context.Context, studentID string) (*models.StudentStatistics, error) 
+    // UpdatePoints, MarkRevoked, MarkDeleted and ClearDeleted return
+    // mongo.ErrNoDocuments when the document does not exist, so an outbox
+    // event that runs ahead of its document is retried.
     UpdatePoints(ctx context.Context, mongoID string, points int) error
+    // MarkRevoked sets the points to 0 and stamps revokedAt; revoked
+    // achievements are left out of the statistics.
+    MarkRevoked(ctx context.Context, mongoID string, revokedAt time.Time) error
+    // InsertOne, UpdateOne and AddAttachment bump the document's version and
+    // store the result in achievement_versions.
+    ListVersions(ctx context.Context, mongoID string) ([]models.AchievementVersion, error)
+    GetVersion(ctx context.Context, mongoID string, version int) (*models.AchievementVersion, error)
+    // ListAll returns the ID, student, points, revokedAt, deletedAt and
+    // createdAt of every achievement document.
+    ListAll(ctx context.Context) ([]models.Achievement, error)
+    // MarkDeleted moves a document to the trash and ClearDeleted takes it
+    // back out; trashed documents are left out of the statistics and student
+    // listings. DeleteAchievement removes a document for good.
+    MarkDeleted(ctx context.Context, mongoID string, deletedBy string, deletedAt time.Time) error
+    ClearDeleted(ctx context.Context, mongoID string) error
 }
 
+// counted matches achievements that count towards the statistics: neither
+// revoked nor in the trash.
+var counted = bson.M{"$match": bson.M{"revokedAt": nil, "deletedAt": nil}}
+
 type achievementRepository struct {
     collection *mongo.Collection
+    versions   *mongo.Collection
 }
 
 func NewAchievementRepository(mongodb *mongo.Database) AchievementRepository {
     return &achievementRepository{
         collection: mongodb.Collection("achievements"),
+        versions:   mongodb.Collection("achievement_versions"),
     }
 }
 
 func (r *achievementRepository) GetStudentAchievements(studentId uuid.UUID) ([]models.Achievement, error) {
     ctx := context.Background()
 
-    filter := bson.M{"studentId": studentId.String()}
+    filter := bson.M{"studentId": studentId.String(), "deletedAt": nil}
     cursor, err := r.collection.Find(ctx, filter)
     if err != nil {
         return nil, err
@@ -49,11 +82,21 @@ func (r *achievementRepository) GetStudentAchievements(studentId uuid.UUID) ([]m
 
 func (r *achievementRepository) InsertOne(ctx context.Context, achievement models.Achievement) (string, error) {
 	collection := r.collection
+	achievement.Version = 1
 	result, err := collection.InsertOne(ctx, achievement)
 	if err != nil {
+		if !achievement.ID.IsZero() && mongo.IsDuplicateKeyError(err) {
+			return achievement.ID.Hex(), nil
+		}
+		return "", err
+	}
+
+	achievement.ID = result.InsertedID.(primitive.ObjectID)
+	if err := r.saveVersion(ctx, achievement); err != nil {
+		_, _ = collection.DeleteOne(ctx, bson.M{"_id": achievement.ID})
 		return "", err
 	}
-	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+	return achievement.ID.Hex(), nil
 }
 
 func (r *achievementRepository) FindAllDetails(ctx context.Context, mongoIDs []string) ([]models.Achievement, error) {
@@ -103,7 +146,10 @@ func (r *achievementRepository) DeleteAchievement(ctx context.Context, mongoID s
     }
 
     filter := bson.M{"_id": oid}
-    _, err = r.collection.DeleteOne(ctx, filter)
+    if _, err = r.collection.DeleteOne(ctx, filter); err != nil {
+        return err
+    }
+    _, err = r.versions.DeleteMany(ctx, bson.M{"achievementId": mongoID})
     return err
 }
 
@@ -119,14 +165,15 @@ func (r *achievementRepository) UpdateOne(ctx context.Context, mongoID string, d
             "description":     data.Description,
             "achievementType": data.AchievementType,
             "details":         data.Details,
+            "customFields":    data.CustomFields,
             "tags":            data.Tags,
-            "points":          data.Points,
+            "updatedBy":       data.UpdatedBy,
             "updatedAt":       time.Now(),
         },
+        "$inc": bson.M{"version": 1},
     }
 
-    _, err = r.collection.UpdateOne(ctx, bson.M{"_id": oid}, update)
-    return err
+    return r.updateVersioned(ctx, oid, update)
 }
 
 func (r *achievementRepository) AddAttachment(ctx context.Context, mongoID string, attachment models.Attachment) error {
@@ -138,12 +185,78 @@ func (r *achievementRepository) AddAttachment(ctx context.Context, mongoID strin
     update := bson.M{
         "$push": bson.M{"attachments": attachment},
         "$set":  bson.M{"updatedAt": time.Now()},
+        "$inc":  bson.M{"version": 1},
+    }
+
+    return r.updateVersioned(ctx, oid, update)
+}
+
+// updateVersioned applies a content change and stores the resulting document
+// as its next version. Documents created before versioning start at 1 on
+// their first change.
+func (r *achievementRepository) updateVersioned(ctx context.Context, oid primitive.ObjectID, update bson.M) error {
+    opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+    var updated models.Achievement
+    if err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": oid}, update, opts).Decode(&updated); err != nil {
+        return err
     }
+    return r.saveVersion(ctx, updated)
+}
 
-    _, err = r.collection.UpdateOne(ctx, bson.M{"_id": oid}, update)
+func (r *achievementRepository) saveVersion(ctx context.Context, a models.Achievement) error {
+    _, err := r.versions.InsertOne(ctx, models.AchievementVersion{
+        AchievementID: a.ID.Hex(),
+        Version:       a.Version,
+        Snapshot:      a,
+        CreatedBy:     a.UpdatedBy,
+        CreatedAt:     time.Now(),
+    })
     return err
 }
 
+func (r *achievementRepository) ListVersions(ctx context.Context, mongoID string) ([]models.AchievementVersion, error) {
+    opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+    cursor, err := r.versions.Find(ctx, bson.M{"achievementId": mongoID}, opts)
+    if err != nil {
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    var versions []models.AchievementVersion
+    if err = cursor.All(ctx, &versions); err != nil {
+        return nil, err
+    }
+    return versions, nil
+}
+
+func (r *achievementRepository) GetVersion(ctx context.Context, mongoID string, version int) (*models.AchievementVersion, error) {
+    var v models.AchievementVersion
+    err := r.versions.FindOne(ctx, bson.M{"achievementId": mongoID, "version": version}).Decode(&v)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return nil, ErrVersionNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &v, nil
+}
+
+func (r *achievementRepository) ListAll(ctx context.Context) ([]models.Achievement, error) {
+    opts := options.Find().SetProjection(bson.M{"studentId": 1, "points": 1, "revokedAt": 1, "deletedAt": 1, "createdAt": 1})
+    cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+    if err != nil {
+        return nil, err
+    }
+    defer cursor.Close(ctx)
+
+    var achievements []models.Achievement
+    if err = cursor.All(ctx, &achievements); err != nil {
+        return nil, err
+    }
+    return achievements, nil
+}
+
 func (r *achievementRepository) GetGlobalStats(ctx context.Context) (*models.GlobalStatistics, error) {
     stats := &models.GlobalStatistics{
         TypeDistribution:  make(map[string]int),
@@ -152,6 +265,7 @@ func (r *achievementRepository) GetGlobalStats(ctx context.Context) (*models.Glo
     }
 
     pipelineType := bson.A{
+        counted,
         bson.M{"$group": bson.M{"_id": "$achievementType", "count": bson.M{"$sum": 1}}},
     }
     cursor, _ := r.collection.Aggregate(ctx, pipelineType)
@@ -162,6 +276,7 @@ func (r *achievementRepository) GetGlobalStats(ctx context.Context) (*models.Glo
     }
 
     pipelineLevel := bson.A{
+        counted,
         bson.M{"$match": bson.M{"details.competitionLevel": bson.M{"$exists": true}}},
         bson.M{"$group": bson.M{"_id": "$details.competitionLevel", "count": bson.M{"$sum": 1}}},
     }
@@ -173,6 +288,7 @@ func (r *achievementRepository) GetGlobalStats(ctx context.Context) (*models.Glo
     }
 
     pipelineTop := bson.A{
+        counted,
         bson.M{"$group": bson.M{"_id": "$studentId", "totalPoints": bson.M{"$sum": "$points"}}},
         bson.M{"$sort": bson.M{"totalPoints": -1}},
         bson.M{"$limit": 5},
@@ -194,7 +310,7 @@ func (r *achievementRepository) GetGlobalStats(ctx context.Context) (*models.Glo
 func (r *achievementRepository) GetStudentStats(ctx context.Context, studentID string) (*models.StudentStatistics, error) {
     stats := &models.StudentStatistics{ByType: make(map[string]int)}
     pipeline := bson.A{
-        bson.M{"$match": bson.M{"studentId": studentID}},
+        bson.M{"$match": bson.M{"studentId": studentID, "revokedAt": nil, "deletedAt": nil}},
         bson.M{"$group": bson.M{
             "_id": "$achievementType",
             "count": bson.M{"$sum": 1},
@@ -231,7 +347,7 @@ func (r *achievementRepository) UpdatePoints(ctx context.Context,mongoID string,
         return err
     }
 
-    _, err = r.collection.UpdateOne(
+    result, err := r.collection.UpdateOne(
         ctx,
         bson.M{"_id": oid},
         bson.M{
@@ -242,5 +358,85 @@ func (r *achievementRepository) UpdatePoints(ctx context.Context,mongoID string,
         },
     )
 
-    return err
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
+}
+
+func (r *achievementRepository) MarkRevoked(ctx context.Context, mongoID string, revokedAt time.Time) error {
+    oid, err := primitive.ObjectIDFromHex(mongoID)
+    if err != nil {
+        return err
+    }
+
+    result, err := r.collection.UpdateOne(
+        ctx,
+        bson.M{"_id": oid},
+        bson.M{
+            "$set": bson.M{
+                "points":    0,
+                "revokedAt": revokedAt,
+                "updatedAt": time.Now(),
+            },
+        },
+    )
+
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
+}
+
+func (r *achievementRepository) MarkDeleted(ctx context.Context, mongoID string, deletedBy string, deletedAt time.Time) error {
+    oid, err := primitive.ObjectIDFromHex(mongoID)
+    if err != nil {
+        return err
+    }
+
+    result, err := r.collection.UpdateOne(
+        ctx,
+        bson.M{"_id": oid},
+        bson.M{
+            "$set": bson.M{
+                "deletedAt": deletedAt,
+                "deletedBy": deletedBy,
+            },
+        },
+    )
+
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
+}
+
+func (r *achievementRepository) ClearDeleted(ctx context.Context, mongoID string) error {
+    oid, err := primitive.ObjectIDFromHex(mongoID)
+    if err != nil {
+        return err
+    }
+
+    result, err := r.collection.UpdateOne(
+        ctx,
+        bson.M{"_id": oid},
+        bson.M{"$unset": bson.M{"deletedAt": "", "deletedBy": ""}},
+    )
+
+    if err != nil {
+        return err
+    }
+    if result.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
 }
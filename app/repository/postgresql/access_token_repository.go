@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	models "student-performance-report/app/models/postgresql"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrAccessTokenNotFound = errors.New("access token not found")
+
+type AccessTokenRepository interface {
+	Create(ctx context.Context, t *models.PersonalAccessToken) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error)
+	// Revoke only touches the owner's own, not yet revoked token.
+	Revoke(ctx context.Context, id, userID uuid.UUID) error
+	// FindActive returns ErrAccessTokenNotFound unless the token is unrevoked,
+	// unexpired and its owner is active.
+	FindActive(ctx context.Context, tokenHash string) (*models.AccessTokenOwner, error)
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time, ip string) error
+}
+
+type accessTokenRepository struct {
+	db *sql.DB
+}
+
+func NewAccessTokenRepository(db *sql.DB) AccessTokenRepository {
+	return &accessTokenRepository{db: db}
+}
+
+func (r *accessTokenRepository) Create(ctx context.Context, t *models.PersonalAccessToken) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO personal_access_tokens (id, user_id, name, token_prefix, token_hash, scopes, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, t.ID, t.UserID, t.Name, t.TokenPrefix, t.TokenHash, pq.Array(t.Scopes), t.CreatedAt, t.ExpiresAt)
+	return err
+}
+
+func (r *accessTokenRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, name, token_prefix, scopes, created_at, expires_at,
+		       last_used_at, last_used_ip, revoked_at
+		FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.PersonalAccessToken
+	for rows.Next() {
+		var t models.PersonalAccessToken
+		if err := rows.Scan(
+			&t.ID,
+			&t.UserID,
+			&t.Name,
+			&t.TokenPrefix,
+			pq.Array(&t.Scopes),
+			&t.CreatedAt,
+			&t.ExpiresAt,
+			&t.LastUsedAt,
+			&t.LastUsedIP,
+			&t.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+func (r *accessTokenRepository) Revoke(ctx context.Context, id, userID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE personal_access_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+func (r *accessTokenRepository) FindActive(ctx context.Context, tokenHash string) (*models.AccessTokenOwner, error) {
+	var o models.AccessTokenOwner
+	err := r.db.QueryRowContext(ctx, `
+		SELECT t.id, t.user_id, u.role_id, ro.name, t.scopes, t.last_used_at
+		FROM personal_access_tokens t
+		JOIN users u ON u.id = t.user_id
+		JOIN roles ro ON ro.id = u.role_id
+		WHERE t.token_hash = $1
+		  AND t.revoked_at IS NULL
+		  AND t.expires_at > NOW()
+		  AND u.is_active = TRUE
+	`, tokenHash).Scan(&o.TokenID, &o.UserID, &o.RoleID, &o.RoleName, pq.Array(&o.Scopes), &o.LastUsedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrAccessTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *accessTokenRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time, ip string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE personal_access_tokens SET last_used_at = $2, last_used_ip = $3
+		WHERE id = $1
+	`, id, at, ip)
+	return err
+}
@@ -3,6 +3,7 @@ package repository
 import (
     "context"
     "database/sql"
+    "errors"
     "fmt"
     "time"
     models "student-performance-report/app/models/postgresql"
@@ -10,14 +11,38 @@ import (
     "github.com/lib/pq"
 )
 
+// ErrStatusConflict means the achievement was no longer in the expected
+// status, usually because a concurrent request changed it first.
+var ErrStatusConflict = errors.New("achievement status was changed by another request")
+
 type AchievementRepoPostgres interface {
-    Create(ctx context.Context, ref models.AchievementReference) (uuid.UUID, error)
     GetStudentByUserID(ctx context.Context, userID uuid.UUID) (uuid.UUID, error)
     GetAllReferences(ctx context.Context, filter map[string]interface{}, limit, offset int, sort string) ([]models.AchievementReference, int64, error)
     GetReferenceByID(ctx context.Context, id uuid.UUID) (models.AchievementReference, error)
-    DeleteReference(ctx context.Context, id uuid.UUID) error
-    UpdateStatus(ctx context.Context, id uuid.UUID, status string, verifiedBy *uuid.UUID, note string) error
-    SubmitReference(ctx context.Context, id uuid.UUID) error
+    // Create and TransitionStatus also append a row to
+    // achievement_status_history in the same transaction, and store the
+    // outbox events (events, change.Outbox) for the Mongo writes that follow.
+    Create(ctx context.Context, ref models.AchievementReference, actorID uuid.UUID, events []models.OutboxEvent) (uuid.UUID, error)
+    TransitionStatus(ctx context.Context, id uuid.UUID, from, to string, change models.StatusChange) error
+    // AdjustPoints changes the points of an achievement that is still in
+    // status, records the correction in the history and stores change.Outbox.
+    AdjustPoints(ctx context.Context, id uuid.UUID, status string, change models.StatusChange) error
+    GetStatusHistory(ctx context.Context, id uuid.UUID) ([]models.AchievementStatusHistory, error)
+    // ListReferences returns every reference, deleted ones included, for
+    // checking them against the Mongo documents.
+    ListReferences(ctx context.Context) ([]models.AchievementReference, error)
+    // GetTrash returns a student's deleted achievements, most recently
+    // deleted first, and GetDeletedReference one of them.
+    GetTrash(ctx context.Context, studentID uuid.UUID) ([]models.AchievementReference, error)
+    GetDeletedReference(ctx context.Context, id uuid.UUID) (models.AchievementReference, error)
+    // ExpiredTrash returns up to limit references deleted before `before`.
+    // PurgeReference removes one of them with its history, comments and
+    // outbox events. It runs cleanup first, with the reference locked so it
+    // cannot be restored meanwhile, and keeps the reference when cleanup
+    // fails; it answers ErrStatusConflict when the reference was restored
+    // in the meantime.
+    ExpiredTrash(ctx context.Context, before time.Time, limit int) ([]models.AchievementReference, error)
+    PurgeReference(ctx context.Context, id uuid.UUID, before time.Time, cleanup func() error) error
 }
 
 type achievementRepoPostgres struct {
@@ -39,7 +64,13 @@ func (r *achievementRepoPostgres) GetStudentByUserID(ctx context.Context, userID
     return studentID, err
 }
 
-func (r *achievementRepoPostgres) Create(ctx context.Context, ref models.AchievementReference) (uuid.UUID, error) {
+func (r *achievementRepoPostgres) Create(ctx context.Context, ref models.AchievementReference, actorID uuid.UUID, events []models.OutboxEvent) (uuid.UUID, error) {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return uuid.Nil, err
+    }
+    defer tx.Rollback()
+
     query := `
         INSERT INTO achievement_references (
             student_id, mongo_achievement_id, status, created_at, updated_at
@@ -47,13 +78,24 @@ func (r *achievementRepoPostgres) Create(ctx context.Context, ref models.Achieve
         RETURNING id
     `
     var newID uuid.UUID
-    err := r.db.QueryRowContext(ctx, query, 
+    err = tx.QueryRowContext(ctx, query, 
         ref.StudentID, 
         ref.MongoAchievementID, 
         ref.Status, 
     ).Scan(&newID)
+    if err != nil {
+        return uuid.Nil, err
+    }
+
+    if err := recordStatusChange(ctx, tx, newID, nil, ref.Status, models.StatusChange{ActorID: actorID}); err != nil {
+        return uuid.Nil, err
+    }
 
-    return newID, err
+    if err := insertOutbox(ctx, tx, newID, events); err != nil {
+        return uuid.Nil, err
+    }
+
+    return newID, tx.Commit()
 }
 
 func (r *achievementRepoPostgres) GetAllReferences(ctx context.Context, filter map[string]interface{}, limit, offset int, sort string) ([]models.AchievementReference, int64, error) {
@@ -96,7 +138,7 @@ func (r *achievementRepoPostgres) GetAllReferences(ctx context.Context, filter m
     }
 
     query := `
-        SELECT id, student_id, mongo_achievement_id, status, submitted_at, verified_at, created_at 
+        SELECT id, student_id, mongo_achievement_id, status, points, submitted_at, verified_at, created_at 
         FROM achievement_references 
     ` + whereClause
 
@@ -125,6 +167,7 @@ func (r *achievementRepoPostgres) GetAllReferences(ctx context.Context, filter m
             &ref.StudentID, 
             &ref.MongoAchievementID, 
             &ref.Status, 
+            &ref.Points,
             &ref.SubmittedAt, 
             &ref.VerifiedAt,
             &ref.CreatedAt,
@@ -142,7 +185,8 @@ func (r *achievementRepoPostgres) GetReferenceByID(ctx context.Context, id uuid.
     query := `
         SELECT 
             id, student_id, mongo_achievement_id, status, rejection_note, 
-            created_at, submitted_at, verified_at, verified_by 
+            points, rubric_version, suggested_points, points_overridden,
+            verified_version, created_at, submitted_at, verified_at, verified_by 
         FROM achievement_references 
         WHERE status != 'deleted' AND id = $1
     `
@@ -156,6 +200,11 @@ func (r *achievementRepoPostgres) GetReferenceByID(ctx context.Context, id uuid.
         &ref.MongoAchievementID, 
         &ref.Status, 
         &rejectionNote,
+        &ref.Points,
+        &ref.RubricVersion,
+        &ref.SuggestedPoints,
+        &ref.PointsOverridden,
+        &ref.VerifiedVersion,
         &ref.CreatedAt,
         &ref.SubmittedAt, 
         &ref.VerifiedAt,  
@@ -170,36 +219,293 @@ func (r *achievementRepoPostgres) GetReferenceByID(ctx context.Context, id uuid.
     return ref, err
 }
 
-func (r *achievementRepoPostgres) DeleteReference(ctx context.Context, id uuid.UUID) error {
+// TransitionStatus moves the reference from `from` to `to` only if it is
+// still in `from` when the UPDATE runs, so of two concurrent transitions out
+// of the same status only one succeeds; the other gets ErrStatusConflict.
+// The history row is written in the same transaction.
+func (r *achievementRepoPostgres) TransitionStatus(ctx context.Context, id uuid.UUID, from, to string, change models.StatusChange) error {
+    set := "status = $3, updated_at = NOW()"
+    args := []interface{}{id, from, to}
+
+    switch to {
+    case models.StatusSubmitted:
+        set += ", submitted_at = NOW()"
+    case models.StatusDeleted:
+        var deletedBy *uuid.UUID
+        if change.ActorID != uuid.Nil {
+            deletedBy = &change.ActorID
+        }
+        set += ", deleted_at = NOW(), deleted_by = $4"
+        args = append(args, deletedBy)
+    case models.StatusVerified, models.StatusRejected:
+        var note *string
+        if to == models.StatusRejected {
+            note = &change.Note
+        }
+        set += ", verified_by = $4, verified_at = NOW(), rejection_note = $5"
+        args = append(args, change.VerifiedBy, note)
+    }
+    if from == models.StatusDeleted {
+        set += ", deleted_at = NULL, deleted_by = NULL"
+    }
+    if change.Points != nil {
+        args = append(args, *change.Points)
+        set += fmt.Sprintf(", points = $%d", len(args))
+    }
+    if to == models.StatusVerified {
+        var version, suggested *int
+        overridden := false
+        if sg := change.Suggestion; sg != nil {
+            version, suggested = &sg.RubricVersion, &sg.Points
+            overridden = change.Points != nil && *change.Points != sg.Points
+        }
+        args = append(args, version, suggested, overridden, change.Version)
+        n := len(args)
+        set += fmt.Sprintf(", rubric_version = $%d, suggested_points = $%d, points_overridden = $%d, verified_version = $%d", n-3, n-2, n-1, n)
+    }
+
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
     query := `
         UPDATE achievement_references 
-        SET status = 'deleted', updated_at = NOW() 
-        WHERE id = $1
+        SET ` + set + `
+        WHERE id = $1 AND status = $2
     `
-    _, err := r.db.ExecContext(ctx, query, id)
-    return err
+    result, err := tx.ExecContext(ctx, query, args...)
+    if err != nil {
+        return err
+    }
+    if rows, _ := result.RowsAffected(); rows == 0 {
+        return ErrStatusConflict
+    }
+
+    if err := recordStatusChange(ctx, tx, id, &from, to, change); err != nil {
+        return err
+    }
+
+    if err := insertOutbox(ctx, tx, id, change.Outbox); err != nil {
+        return err
+    }
+
+    return tx.Commit()
 }
 
+func (r *achievementRepoPostgres) AdjustPoints(ctx context.Context, id uuid.UUID, status string, change models.StatusChange) error {
+    if change.Points == nil {
+        return errors.New("new points are required")
+    }
 
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
 
-func (r *achievementRepoPostgres) UpdateStatus(ctx context.Context, id uuid.UUID, status string, verifiedBy *uuid.UUID, note string) error {
-    query := `
+    result, err := tx.ExecContext(ctx, `
         UPDATE achievement_references 
-        SET status = $1, verified_by = $2, verified_at = $3, rejection_note = $4, updated_at = NOW()
-        WHERE id = $5
-    `
-    _, err := r.db.ExecContext(ctx, query, status, verifiedBy, time.Now(), note, id)
+        SET points = $3, updated_at = NOW()
+        WHERE id = $1 AND status = $2
+    `, id, status, *change.Points)
+    if err != nil {
+        return err
+    }
+    if rows, _ := result.RowsAffected(); rows == 0 {
+        return ErrStatusConflict
+    }
+
+    if err := recordStatusChange(ctx, tx, id, &status, status, change); err != nil {
+        return err
+    }
+
+    if err := insertOutbox(ctx, tx, id, change.Outbox); err != nil {
+        return err
+    }
+
+    return tx.Commit()
+}
+
+func recordStatusChange(ctx context.Context, tx *sql.Tx, id uuid.UUID, from *string, to string, change models.StatusChange) error {
+    var actor *uuid.UUID
+    if change.ActorID != uuid.Nil {
+        actor = &change.ActorID
+    }
+    var note *string
+    if change.Note != "" {
+        note = &change.Note
+    }
+
+    _, err := tx.ExecContext(ctx, `
+        INSERT INTO achievement_status_history (id, achievement_ref_id, from_status, to_status, actor_id, note, old_points, new_points, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+    `, uuid.New(), id, from, to, actor, note, change.OldPoints, change.Points)
     return err
 }
 
-func (r *achievementRepoPostgres) SubmitReference(ctx context.Context, id uuid.UUID) error {
+// GetStatusHistory returns every recorded transition, oldest first.
+func (r *achievementRepoPostgres) GetStatusHistory(ctx context.Context, id uuid.UUID) ([]models.AchievementStatusHistory, error) {
     query := `
-        UPDATE achievement_references 
-        SET status = 'submitted', 
-            submitted_at = NOW(), 
-            updated_at = NOW()
-        WHERE id = $1
+        SELECT h.id, h.achievement_ref_id, h.from_status, h.to_status, h.actor_id, u.full_name, h.note,
+            h.old_points, h.new_points, h.created_at
+        FROM achievement_status_history h
+        LEFT JOIN users u ON u.id = h.actor_id
+        WHERE h.achievement_ref_id = $1
+        ORDER BY h.created_at ASC, h.id ASC
     `
-    _, err := r.db.ExecContext(ctx, query, id)
-    return err
-}
\ No newline at end of file
+    rows, err := r.db.QueryContext(ctx, query, id)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var history []models.AchievementStatusHistory
+    for rows.Next() {
+        var h models.AchievementStatusHistory
+        err := rows.Scan(
+            &h.ID,
+            &h.AchievementRefID,
+            &h.FromStatus,
+            &h.ToStatus,
+            &h.ActorID,
+            &h.ActorName,
+            &h.Note,
+            &h.OldPoints,
+            &h.NewPoints,
+            &h.CreatedAt,
+        )
+        if err != nil {
+            return nil, err
+        }
+        history = append(history, h)
+    }
+
+    return history, rows.Err()
+}
+
+func (r *achievementRepoPostgres) ListReferences(ctx context.Context) ([]models.AchievementReference, error) {
+    rows, err := r.db.QueryContext(ctx, `
+        SELECT id, student_id, mongo_achievement_id, status, points, deleted_at, deleted_by, created_at, updated_at
+        FROM achievement_references
+        ORDER BY created_at ASC
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var refs []models.AchievementReference
+    for rows.Next() {
+        var ref models.AchievementReference
+        err := rows.Scan(
+            &ref.ID,
+            &ref.StudentID,
+            &ref.MongoAchievementID,
+            &ref.Status,
+            &ref.Points,
+            &ref.DeletedAt,
+            &ref.DeletedBy,
+            &ref.CreatedAt,
+            &ref.UpdatedAt,
+        )
+        if err != nil {
+            return nil, err
+        }
+        refs = append(refs, ref)
+    }
+
+    return refs, rows.Err()
+}
+
+const trashSelect = `
+    SELECT id, student_id, mongo_achievement_id, status, deleted_at, deleted_by, created_at, updated_at
+    FROM achievement_references
+    WHERE status = 'deleted'
+`
+
+func (r *achievementRepoPostgres) GetTrash(ctx context.Context, studentID uuid.UUID) ([]models.AchievementReference, error) {
+    return r.listTrash(ctx, trashSelect+` AND student_id = $1 ORDER BY deleted_at DESC NULLS LAST`, studentID)
+}
+
+func (r *achievementRepoPostgres) GetDeletedReference(ctx context.Context, id uuid.UUID) (models.AchievementReference, error) {
+    var ref models.AchievementReference
+    err := r.db.QueryRowContext(ctx, trashSelect+` AND id = $1`, id).Scan(
+        &ref.ID,
+        &ref.StudentID,
+        &ref.MongoAchievementID,
+        &ref.Status,
+        &ref.DeletedAt,
+        &ref.DeletedBy,
+        &ref.CreatedAt,
+        &ref.UpdatedAt,
+    )
+    return ref, err
+}
+
+func (r *achievementRepoPostgres) ExpiredTrash(ctx context.Context, before time.Time, limit int) ([]models.AchievementReference, error) {
+    return r.listTrash(ctx, trashSelect+` AND deleted_at < $1 ORDER BY deleted_at ASC LIMIT $2`, before, limit)
+}
+
+func (r *achievementRepoPostgres) listTrash(ctx context.Context, query string, args ...interface{}) ([]models.AchievementReference, error) {
+    rows, err := r.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var refs []models.AchievementReference
+    for rows.Next() {
+        var ref models.AchievementReference
+        err := rows.Scan(
+            &ref.ID,
+            &ref.StudentID,
+            &ref.MongoAchievementID,
+            &ref.Status,
+            &ref.DeletedAt,
+            &ref.DeletedBy,
+            &ref.CreatedAt,
+            &ref.UpdatedAt,
+        )
+        if err != nil {
+            return nil, err
+        }
+        refs = append(refs, ref)
+    }
+
+    return refs, rows.Err()
+}
+
+// PurgeReference relies on ON DELETE CASCADE for the history, comments and
+// outbox rows.
+func (r *achievementRepoPostgres) PurgeReference(ctx context.Context, id uuid.UUID, before time.Time, cleanup func() error) error {
+    tx, err := r.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    var locked uuid.UUID
+    err = tx.QueryRowContext(ctx, `
+        SELECT id FROM achievement_references
+        WHERE id = $1 AND status = 'deleted' AND deleted_at < $2
+        FOR UPDATE
+    `, id, before).Scan(&locked)
+    if err == sql.ErrNoRows {
+        return ErrStatusConflict
+    }
+    if err != nil {
+        return err
+    }
+
+    if err := cleanup(); err != nil {
+        return err
+    }
+
+    if _, err := tx.ExecContext(ctx, `DELETE FROM achievement_references WHERE id = $1`, id); err != nil {
+        return err
+    }
+
+    return tx.Commit()
+}
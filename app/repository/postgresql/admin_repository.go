@@ -3,10 +3,20 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"strings"
 	models "student-performance-report/app/models/postgresql"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// ErrDuplicateUser is returned when a unique column (username, email,
+// student or lecturer number) is already taken.
+var ErrDuplicateUser = errors.New("username, email or profile number already exists")
+
+// ErrInvalidReference is returned when a foreign key (role or advisor) does
+// not point to an existing row.
+var ErrInvalidReference = errors.New("referenced role or advisor does not exist")
+
 type AdminRepository interface {
 	CreateUser(user *models.User) error
 	UpdateUser(user *models.User) error
@@ -17,6 +27,26 @@ type AdminRepository interface {
 	SetStudentProfile(profile *models.Student) error
 	SetLecturerProfile(profile *models.Lecturer) error
 	SetAdvisor(studentID, lecturerID uuid.UUID) error
+	FindTakenCredentials(username, email string) (usernameTaken bool, emailTaken bool, err error)
+	CreateUserWithProfile(user *models.User, student *models.Student, lecturer *models.Lecturer) error
+	ListTakenCredentials(usernames, emails []string) (takenUsernames map[string]bool, takenEmails map[string]bool, err error)
+	ResolveLecturers(refs []string) (map[string]uuid.UUID, error)
+	ImportUsers(accounts []models.NewUserAccount) error
+}
+
+// ImportRowError tells which account of an ImportUsers batch failed.
+type ImportRowError struct {
+	Index int
+	Err   error
+}
+
+func (e *ImportRowError) Error() string { return e.Err.Error() }
+func (e *ImportRowError) Unwrap() error { return e.Err }
+
+// execer is satisfied by both *sql.DB and *sql.Tx so the insert helpers can
+// run standalone or inside a transaction.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
 }
 
 type adminRepository struct {
@@ -28,11 +58,15 @@ func NewAdminRepository(db *sql.DB) AdminRepository {
 }
 
 func (r *adminRepository) CreateUser(user *models.User) error {
+	return mapConstraintError(insertUser(r.db, user))
+}
+
+func insertUser(ex execer, user *models.User) error {
 	query := `
 		INSERT INTO users (id, username, email, password_hash, full_name, role_id, is_active, created_at, updated_at)
 		VALUES ($1,$2,$3,$4,$5,$6,$7, NOW(), NOW())
 	`
-	_, err := r.db.Exec(query,
+	_, err := ex.Exec(query,
 		user.ID,
 		user.Username,
 		user.Email,
@@ -48,6 +82,7 @@ func (r *adminRepository) UpdateUser(user *models.User) error {
 	query := `
 		UPDATE users SET
 			username=$1, email=$2, full_name=$3,
+			token_version = token_version + CASE WHEN role_id <> $4 OR is_active <> $5 THEN 1 ELSE 0 END,
 			role_id=$4, is_active=$5, updated_at=NOW()
 		WHERE id=$6
 	`
@@ -60,13 +95,14 @@ func (r *adminRepository) UpdateUser(user *models.User) error {
 		user.IsActive,
 		user.ID,
 	)
-	return err
+	return mapConstraintError(err)
 }
 
 func (r *adminRepository) DeleteUser(id uuid.UUID) error {
 	query := `
         UPDATE users
         SET is_active = FALSE,
+            token_version = token_version + 1,
             updated_at = NOW()
         WHERE id = $1 AND is_active = TRUE
     `
@@ -150,18 +186,26 @@ func (r *adminRepository) GetAllUsers() ([]models.User, error) {
 }
 
 func (r *adminRepository) AssignRole(userID uuid.UUID, roleID uuid.UUID) error {
-	_, err := r.db.Exec(`UPDATE users SET role_id=$1 WHERE id=$2`, roleID, userID)
+	_, err := r.db.Exec(`
+		UPDATE users
+		SET role_id=$1, token_version = token_version + 1, updated_at=NOW()
+		WHERE id=$2
+	`, roleID, userID)
 	return err
 }
 
 func (r *adminRepository) SetStudentProfile(s *models.Student) error {
+	return mapConstraintError(upsertStudentProfile(r.db, s))
+}
+
+func upsertStudentProfile(ex execer, s *models.Student) error {
 	query := `
 		INSERT INTO students (id, user_id, student_id, program_study, academic_year, advisor_id, created_at)
 		VALUES ($1,$2,$3,$4,$5,$6, NOW())
 		ON CONFLICT (user_id) DO UPDATE SET
 			student_id=$3, program_study=$4, academic_year=$5, advisor_id=$6
 	`
-	_, err := r.db.Exec(query,
+	_, err := ex.Exec(query,
 		s.ID,
 		s.UserID,
 		s.StudentID,
@@ -173,13 +217,17 @@ func (r *adminRepository) SetStudentProfile(s *models.Student) error {
 }
 
 func (r *adminRepository) SetLecturerProfile(l *models.Lecturer) error {
+	return mapConstraintError(upsertLecturerProfile(r.db, l))
+}
+
+func upsertLecturerProfile(ex execer, l *models.Lecturer) error {
 	query := `
 		INSERT INTO lecturers (id, user_id, lecturer_id, department, created_at)
 		VALUES ($1,$2,$3,$4, NOW())
 		ON CONFLICT (user_id) DO UPDATE SET
 			lecturer_id=$3, department=$4
 	`
-	_, err := r.db.Exec(query,
+	_, err := ex.Exec(query,
 		l.ID,
 		l.UserID,
 		l.LecturerID,
@@ -189,7 +237,185 @@ func (r *adminRepository) SetLecturerProfile(l *models.Lecturer) error {
 }
 
 func (r *adminRepository) SetAdvisor(studentID, lecturerID uuid.UUID) error {
+	return setAdvisor(r.db, studentID, lecturerID)
+}
+
+// setAdvisor keys on the student's user_id, like SetAdvisor.
+func setAdvisor(ex execer, studentUserID, lecturerID uuid.UUID) error {
 	query := `UPDATE students SET advisor_id=$1 WHERE user_id=$2`
-	_, err := r.db.Exec(query, lecturerID, studentID)
+	_, err := ex.Exec(query, lecturerID, studentUserID)
+	return err
+}
+
+func (r *adminRepository) FindTakenCredentials(username, email string) (bool, bool, error) {
+	var usernameTaken, emailTaken bool
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM users WHERE LOWER(username) = LOWER($1)),
+			EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($2))
+	`
+	err := r.db.QueryRow(query, username, email).Scan(&usernameTaken, &emailTaken)
+	return usernameTaken, emailTaken, err
+}
+
+// CreateUserWithProfile inserts the user and its student or lecturer profile
+// in one transaction; nothing is written if any insert fails.
+func (r *adminRepository) CreateUserWithProfile(user *models.User, student *models.Student, lecturer *models.Lecturer) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertUser(tx, user); err != nil {
+		return mapConstraintError(err)
+	}
+
+	if student != nil {
+		if err := upsertStudentProfile(tx, student); err != nil {
+			return mapConstraintError(err)
+		}
+	}
+
+	if lecturer != nil {
+		if err := upsertLecturerProfile(tx, lecturer); err != nil {
+			return mapConstraintError(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// mapConstraintError turns Postgres unique and foreign-key violations into
+// ErrDuplicateUser and ErrInvalidReference.
+func mapConstraintError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return ErrDuplicateUser
+		case "23503":
+			return ErrInvalidReference
+		}
+	}
 	return err
 }
+
+// ListTakenCredentials returns, lower-cased, which of the given usernames and
+// emails already belong to a user.
+func (r *adminRepository) ListTakenCredentials(usernames, emails []string) (map[string]bool, map[string]bool, error) {
+	takenUsernames := map[string]bool{}
+	takenEmails := map[string]bool{}
+
+	query := `
+		SELECT LOWER(username), LOWER(email) FROM users
+		WHERE LOWER(username) = ANY($1) OR LOWER(email) = ANY($2)
+	`
+	rows, err := r.db.Query(query, pq.Array(lowerAll(usernames)), pq.Array(lowerAll(emails)))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	wantedUsernames := toSet(lowerAll(usernames))
+	wantedEmails := toSet(lowerAll(emails))
+	for rows.Next() {
+		var username, email string
+		if err := rows.Scan(&username, &email); err != nil {
+			return nil, nil, err
+		}
+		if wantedUsernames[username] {
+			takenUsernames[username] = true
+		}
+		if wantedEmails[email] {
+			takenEmails[email] = true
+		}
+	}
+	return takenUsernames, takenEmails, rows.Err()
+}
+
+// ResolveLecturers maps each reference, either a lecturers.id UUID or a
+// lecturer number (lecturer_id), to the lecturer's id. Unknown refs are left out.
+func (r *adminRepository) ResolveLecturers(refs []string) (map[string]uuid.UUID, error) {
+	result := map[string]uuid.UUID{}
+	if len(refs) == 0 {
+		return result, nil
+	}
+
+	query := `
+		SELECT id, lecturer_id FROM lecturers
+		WHERE id::text = ANY($1) OR lecturer_id = ANY($1)
+	`
+	rows, err := r.db.Query(query, pq.Array(refs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var number string
+		if err := rows.Scan(&id, &number); err != nil {
+			return nil, err
+		}
+		result[id.String()] = id
+		result[number] = id
+	}
+	return result, rows.Err()
+}
+
+// ImportUsers writes a whole import batch in one transaction. Users and
+// profiles are inserted first and advisors are linked afterwards with
+// setAdvisor, so a student may point at a lecturer created in the same batch.
+func (r *adminRepository) ImportUsers(accounts []models.NewUserAccount) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, acc := range accounts {
+		if err := insertUser(tx, acc.User); err != nil {
+			return &ImportRowError{Index: i, Err: mapConstraintError(err)}
+		}
+		if acc.Lecturer != nil {
+			if err := upsertLecturerProfile(tx, acc.Lecturer); err != nil {
+				return &ImportRowError{Index: i, Err: mapConstraintError(err)}
+			}
+		}
+		if acc.Student != nil {
+			profile := *acc.Student
+			profile.AdvisorID = nil
+			if err := upsertStudentProfile(tx, &profile); err != nil {
+				return &ImportRowError{Index: i, Err: mapConstraintError(err)}
+			}
+		}
+	}
+
+	for i, acc := range accounts {
+		if acc.Student == nil || acc.Student.AdvisorID == nil {
+			continue
+		}
+		if err := setAdvisor(tx, acc.User.ID, *acc.Student.AdvisorID); err != nil {
+			return &ImportRowError{Index: i, Err: mapConstraintError(err)}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func lowerAll(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToLower(v)
+	}
+	return out
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	models "student-performance-report/app/models/postgresql"
+	"github.com/google/uuid"
+)
+
+var ErrCommentNotFound = errors.New("comment not found")
+
+type CommentRepository interface {
+	// ListByAchievement returns the comments oldest first; replies carry
+	// the ParentID of the comment they answer.
+	ListByAchievement(ctx context.Context, achievementID uuid.UUID) ([]models.AchievementComment, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.AchievementComment, error)
+	Create(ctx context.Context, comment *models.AchievementComment) error
+	// Update replaces the body and stamps edited_at.
+	Update(ctx context.Context, id uuid.UUID, body string) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type commentRepository struct {
+	db *sql.DB
+}
+
+func NewCommentRepository(db *sql.DB) CommentRepository {
+	return &commentRepository{db: db}
+}
+
+const commentSelect = `
+	SELECT c.id, c.achievement_ref_id, c.parent_id, c.author_id, u.full_name, c.author_role,
+	       c.body, c.field, c.attachment_url, c.created_at, c.edited_at
+	FROM achievement_comments c
+	LEFT JOIN users u ON u.id = c.author_id
+`
+
+func scanComment(row rowScanner) (*models.AchievementComment, error) {
+	var c models.AchievementComment
+	var authorName sql.NullString
+	if err := row.Scan(
+		&c.ID,
+		&c.AchievementRefID,
+		&c.ParentID,
+		&c.AuthorID,
+		&authorName,
+		&c.AuthorRole,
+		&c.Body,
+		&c.Field,
+		&c.Attachment,
+		&c.CreatedAt,
+		&c.EditedAt,
+	); err != nil {
+		return nil, err
+	}
+	if authorName.Valid {
+		c.AuthorName = &authorName.String
+	}
+	return &c, nil
+}
+
+func (r *commentRepository) ListByAchievement(ctx context.Context, achievementID uuid.UUID) ([]models.AchievementComment, error) {
+	rows, err := r.db.QueryContext(ctx, commentSelect+` WHERE c.achievement_ref_id = $1 ORDER BY c.created_at, c.id`, achievementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.AchievementComment
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *c)
+	}
+	return list, rows.Err()
+}
+
+func (r *commentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.AchievementComment, error) {
+	c, err := scanComment(r.db.QueryRowContext(ctx, commentSelect+` WHERE c.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrCommentNotFound
+	}
+	return c, err
+}
+
+func (r *commentRepository) Create(ctx context.Context, c *models.AchievementComment) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO achievement_comments (id, achievement_ref_id, parent_id, author_id, author_role, body, field, attachment_url, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, c.ID, c.AchievementRefID, c.ParentID, c.AuthorID, c.AuthorRole, c.Body, c.Field, c.Attachment, c.CreatedAt)
+	return err
+}
+
+func (r *commentRepository) Update(ctx context.Context, id uuid.UUID, body string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE achievement_comments SET body = $2, edited_at = NOW() WHERE id = $1`, id, body)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+func (r *commentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM achievement_comments WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+	models "student-performance-report/app/models/postgresql"
+)
+
+type loginAttemptKey struct {
+	scope   string
+	subject string
+}
+
+type memoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[loginAttemptKey]models.LoginAttempt
+}
+
+// NewMemoryLoginAttemptRepository keeps the counters in process memory. Each
+// replica then counts on its own, so use the Postgres one behind a load
+// balancer.
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{attempts: make(map[loginAttemptKey]models.LoginAttempt)}
+}
+
+func (r *memoryLoginAttemptRepository) Get(ctx context.Context, scope, subject string) (*models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.attempts[loginAttemptKey{scope, subject}]
+	if !ok {
+		return nil, nil
+	}
+	return &a, nil
+}
+
+func (r *memoryLoginAttemptRepository) RecordFailure(ctx context.Context, scope, subject string, at time.Time, window time.Duration) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := loginAttemptKey{scope, subject}
+	a, ok := r.attempts[key]
+	if !ok || a.LastFailureAt.Before(at.Add(-window)) {
+		a = models.LoginAttempt{Scope: scope, Subject: subject, LockedUntil: a.LockedUntil}
+	}
+	a.Failures++
+	a.LastFailureAt = at
+	r.attempts[key] = a
+	return a.Failures, nil
+}
+
+func (r *memoryLoginAttemptRepository) Lock(ctx context.Context, scope, subject string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := loginAttemptKey{scope, subject}
+	a, ok := r.attempts[key]
+	if !ok {
+		return nil
+	}
+	if a.LockedUntil == nil || until.After(*a.LockedUntil) {
+		a.LockedUntil = &until
+	}
+	r.attempts[key] = a
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Clear(ctx context.Context, scope, subject string) error {
+	r.mu.Lock()
+	delete(r.attempts, loginAttemptKey{scope, subject})
+	r.mu.Unlock()
+	return nil
+}
+
+// ListLocked also drops counters that are neither locked nor recent, so the
+// map does not grow with every username ever tried.
+func (r *memoryLoginAttemptRepository) ListLocked(ctx context.Context, now time.Time) ([]models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var list []models.LoginAttempt
+	for key, a := range r.attempts {
+		if a.LockedUntil != nil && a.LockedUntil.After(now) {
+			list = append(list, a)
+			continue
+		}
+		if a.LastFailureAt.Before(now.Add(-24 * time.Hour)) {
+			delete(r.attempts, key)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LockedUntil.After(*list[j].LockedUntil) })
+	return list, nil
+}
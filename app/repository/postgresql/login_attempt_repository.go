@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	models "student-performance-report/app/models/postgresql"
+)
+
+// LoginAttemptRepository stores failed-login counters. The Postgres
+// implementation is shared by every replica; the in-memory one is meant for
+// single-instance deployments and tests.
+type LoginAttemptRepository interface {
+	Get(ctx context.Context, scope, subject string) (*models.LoginAttempt, error)
+	// RecordFailure bumps the counter and returns it. A counter whose last
+	// failure is older than window starts again from one.
+	RecordFailure(ctx context.Context, scope, subject string, at time.Time, window time.Duration) (int, error)
+	Lock(ctx context.Context, scope, subject string, until time.Time) error
+	Clear(ctx context.Context, scope, subject string) error
+	ListLocked(ctx context.Context, now time.Time) ([]models.LoginAttempt, error)
+}
+
+type loginAttemptRepository struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) Get(ctx context.Context, scope, subject string) (*models.LoginAttempt, error) {
+	query := `
+		SELECT scope, subject, failures, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE scope = $1 AND subject = $2
+	`
+	var a models.LoginAttempt
+	err := r.db.QueryRowContext(ctx, query, scope, subject).Scan(
+		&a.Scope,
+		&a.Subject,
+		&a.Failures,
+		&a.LastFailureAt,
+		&a.LockedUntil,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// RecordFailure is a single upsert, so concurrent failures from several
+// replicas are all counted.
+func (r *loginAttemptRepository) RecordFailure(ctx context.Context, scope, subject string, at time.Time, window time.Duration) (int, error) {
+	query := `
+		INSERT INTO login_attempts (scope, subject, failures, last_failure_at)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (scope, subject) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failure_at < $4 THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures
+	`
+	var failures int
+	err := r.db.QueryRowContext(ctx, query, scope, subject, at, at.Add(-window)).Scan(&failures)
+	return failures, err
+}
+
+func (r *loginAttemptRepository) Lock(ctx context.Context, scope, subject string, until time.Time) error {
+	query := `
+		UPDATE login_attempts
+		SET locked_until = GREATEST(COALESCE(locked_until, $3), $3)
+		WHERE scope = $1 AND subject = $2
+	`
+	_, err := r.db.ExecContext(ctx, query, scope, subject, until)
+	return err
+}
+
+func (r *loginAttemptRepository) Clear(ctx context.Context, scope, subject string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE scope = $1 AND subject = $2`, scope, subject)
+	return err
+}
+
+func (r *loginAttemptRepository) ListLocked(ctx context.Context, now time.Time) ([]models.LoginAttempt, error) {
+	query := `
+		SELECT scope, subject, failures, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE locked_until > $1
+		ORDER BY locked_until DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.LoginAttempt
+	for rows.Next() {
+		var a models.LoginAttempt
+		if err := rows.Scan(
+			&a.Scope,
+			&a.Subject,
+			&a.Failures,
+			&a.LastFailureAt,
+			&a.LockedUntil,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	models "student-performance-report/app/models/postgresql"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotPending       = errors.New("no two-factor enrollment in progress")
+	ErrMFAChallengeInvalid = errors.New("challenge is invalid, expired or already used")
+)
+
+type MFARepository interface {
+	Get(ctx context.Context, userID uuid.UUID) (*models.UserMFA, error)
+	SavePendingSecret(ctx context.Context, userID uuid.UUID, secret string) error
+	Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error
+	Disable(ctx context.Context, userID uuid.UUID) error
+	// UseStep records a TOTP step as spent; it returns false if that step or a
+	// later one was already used.
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+
+	CreateChallenge(ctx context.Context, ch *models.MFAChallenge) error
+	GetChallenge(ctx context.Context, tokenHash string) (*models.MFAChallenge, error)
+	FailChallenge(ctx context.Context, id uuid.UUID) error
+	ConsumeChallenge(ctx context.Context, id uuid.UUID, maxAttempts int) error
+}
+
+type mfaRepository struct {
+	db *sql.DB
+}
+
+func NewMFARepository(db *sql.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) Get(ctx context.Context, userID uuid.UUID) (*models.UserMFA, error) {
+	query := `
+		SELECT user_id, secret, created_at, enabled_at, last_used_step
+		FROM user_mfa
+		WHERE user_id = $1
+	`
+	var m models.UserMFA
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&m.UserID,
+		&m.Secret,
+		&m.CreatedAt,
+		&m.EnabledAt,
+		&m.LastUsedStep,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SavePendingSecret starts (or restarts) an enrollment. An enabled secret is
+// never replaced here; it has to be disabled first.
+func (r *mfaRepository) SavePendingSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	query := `
+		INSERT INTO user_mfa (user_id, secret, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, created_at = NOW(), last_used_step = 0
+		WHERE user_mfa.enabled_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+	return nil
+}
+
+// Enable confirms the pending secret and replaces the recovery codes in one
+// transaction.
+func (r *mfaRepository) Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE user_mfa SET enabled_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrMFANotPending
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryHashes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO user_mfa_recovery_codes (id, user_id, code_hash) VALUES ($1, $2, $3)
+		`, uuid.New(), userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *mfaRepository) Disable(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *mfaRepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE user_mfa SET last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NOT NULL AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE user_mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+func (r *mfaRepository) CreateChallenge(ctx context.Context, ch *models.MFAChallenge) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO mfa_challenges (id, user_id, token_hash, ip_address, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, ch.ID, ch.UserID, ch.TokenHash, ch.IPAddress, ch.CreatedAt, ch.ExpiresAt)
+	return err
+}
+
+func (r *mfaRepository) GetChallenge(ctx context.Context, tokenHash string) (*models.MFAChallenge, error) {
+	query := `
+		SELECT id, user_id, token_hash, COALESCE(ip_address, ''), created_at, expires_at, attempts, used_at
+		FROM mfa_challenges
+		WHERE token_hash = $1
+	`
+	var ch models.MFAChallenge
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&ch.ID,
+		&ch.UserID,
+		&ch.TokenHash,
+		&ch.IPAddress,
+		&ch.CreatedAt,
+		&ch.ExpiresAt,
+		&ch.Attempts,
+		&ch.UsedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+func (r *mfaRepository) FailChallenge(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id = $1`, id)
+	return err
+}
+
+// ConsumeChallenge spends the challenge; the conditional UPDATE makes it
+// usable exactly once even when two requests race.
+func (r *mfaRepository) ConsumeChallenge(ctx context.Context, id uuid.UUID, maxAttempts int) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE mfa_challenges SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL AND expires_at > NOW() AND attempts < $2
+	`, id, maxAttempts)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrMFAChallengeInvalid
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	models "student-performance-report/app/models/postgresql"
+	"github.com/google/uuid"
+)
+
+var ErrOIDCStateInvalid = errors.New("login state is invalid, expired or already used")
+
+type OIDCRepository interface {
+	SaveLoginState(ctx context.Context, s *models.OIDCLoginState) error
+	ConsumeLoginState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error)
+	// FindUserByIdentity returns uuid.Nil when the subject is not linked yet.
+	FindUserByIdentity(ctx context.Context, issuer, subject string) (uuid.UUID, error)
+	FindUserByEmail(ctx context.Context, email string) (uuid.UUID, error)
+	LinkIdentity(ctx context.Context, identity *models.UserIdentity) error
+	TouchIdentity(ctx context.Context, issuer, subject string) error
+}
+
+type oidcRepository struct {
+	db *sql.DB
+}
+
+func NewOIDCRepository(db *sql.DB) OIDCRepository {
+	return &oidcRepository{db: db}
+}
+
+func (r *oidcRepository) SaveLoginState(ctx context.Context, s *models.OIDCLoginState) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oidc_login_states (id, state_hash, code_verifier, nonce, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, s.ID, s.StateHash, s.CodeVerifier, s.Nonce, s.CreatedAt, s.ExpiresAt)
+	return err
+}
+
+// ConsumeLoginState spends the state in one conditional UPDATE, so a
+// replayed callback cannot be exchanged twice.
+func (r *oidcRepository) ConsumeLoginState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	var s models.OIDCLoginState
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE oidc_login_states SET used_at = NOW()
+		WHERE state_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, state_hash, code_verifier, nonce, created_at, expires_at, used_at
+	`, stateHash).Scan(
+		&s.ID,
+		&s.StateHash,
+		&s.CodeVerifier,
+		&s.Nonce,
+		&s.CreatedAt,
+		&s.ExpiresAt,
+		&s.UsedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrOIDCStateInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *oidcRepository) FindUserByIdentity(ctx context.Context, issuer, subject string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+		SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2
+	`, issuer, subject).Scan(&id)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, nil
+	}
+	return id, err
+}
+
+func (r *oidcRepository) FindUserByEmail(ctx context.Context, email string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `SELECT id FROM users WHERE LOWER(email) = LOWER($1)`, email).Scan(&id)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, nil
+	}
+	return id, err
+}
+
+func (r *oidcRepository) LinkIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_identities (issuer, subject, user_id, email, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+	`, identity.Issuer, identity.Subject, identity.UserID, identity.Email)
+	return err
+}
+
+func (r *oidcRepository) TouchIdentity(ctx context.Context, issuer, subject string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_identities SET last_login_at = NOW() WHERE issuer = $1 AND subject = $2
+	`, issuer, subject)
+	return err
+}
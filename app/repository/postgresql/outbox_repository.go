@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	models "student-performance-report/app/models/postgresql"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// OutboxRepository reads and settles the Mongo writes stored by Create,
+// TransitionStatus and AdjustPoints.
+type OutboxRepository interface {
+	// Due returns up to limit undelivered events whose next attempt is due,
+	// oldest first. An event is only due once every earlier event of its
+	// achievement is delivered.
+	Due(ctx context.Context, limit int) ([]models.OutboxEvent, error)
+	// Pending returns every undelivered event, oldest first.
+	Pending(ctx context.Context) ([]models.OutboxEvent, error)
+	// Blocked reports whether an earlier event of the same achievement is
+	// still undelivered.
+	Blocked(ctx context.Context, id uuid.UUID) (bool, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID) error
+	// MarkFailed counts the attempt, keeps the error and schedules the next
+	// attempt.
+	MarkFailed(ctx context.Context, id uuid.UUID, lastError string, nextAttempt time.Time) error
+}
+
+type outboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+const outboxSelect = `
+	SELECT o.id, o.achievement_ref_id, o.mongo_achievement_id, o.kind, o.payload,
+	       o.attempts, o.last_error, o.next_attempt_at, o.created_at
+	FROM achievement_outbox o
+	WHERE o.delivered_at IS NULL
+`
+
+// outboxEarlier matches the undelivered events stored before o for the same
+// achievement.
+const outboxEarlier = `
+	SELECT 1 FROM achievement_outbox e
+	WHERE e.achievement_ref_id = o.achievement_ref_id AND e.delivered_at IS NULL AND e.seq < o.seq
+`
+
+func (r *outboxRepository) Due(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	return r.list(ctx, outboxSelect+` AND o.next_attempt_at <= NOW() AND NOT EXISTS (`+outboxEarlier+`) ORDER BY o.seq ASC LIMIT $1`, limit)
+}
+
+func (r *outboxRepository) Pending(ctx context.Context) ([]models.OutboxEvent, error) {
+	return r.list(ctx, outboxSelect+` ORDER BY o.seq ASC`)
+}
+
+func (r *outboxRepository) Blocked(ctx context.Context, id uuid.UUID) (bool, error) {
+	var blocked bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (`+outboxEarlier+`) FROM achievement_outbox o WHERE o.id = $1`, id).Scan(&blocked)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return blocked, err
+}
+
+func (r *outboxRepository) list(ctx context.Context, query string, args ...interface{}) ([]models.OutboxEvent, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var e models.OutboxEvent
+		var payload []byte
+		if err := rows.Scan(
+			&e.ID,
+			&e.AchievementRefID,
+			&e.MongoAchievementID,
+			&e.Kind,
+			&payload,
+			&e.Attempts,
+			&e.LastError,
+			&e.NextAttemptAt,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		e.Payload = payload
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (r *outboxRepository) MarkDelivered(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE achievement_outbox SET delivered_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, lastError string, nextAttempt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE achievement_outbox
+		SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+		WHERE id = $1 AND delivered_at IS NULL
+	`, id, lastError, nextAttempt)
+	return err
+}
+
+// insertOutbox stores the events of one change inside its transaction. A new
+// event settles the undelivered events it makes obsolete, see supersedes, so
+// a late retry cannot write older points or undo a later trash or restore.
+func insertOutbox(ctx context.Context, tx *sql.Tx, refID uuid.UUID, events []models.OutboxEvent) error {
+	for i := range events {
+		e := &events[i]
+		e.AchievementRefID = refID
+		if e.ID == uuid.Nil {
+			e.ID = uuid.New()
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			UPDATE achievement_outbox
+			SET delivered_at = NOW(), last_error = 'superseded by ' || $2
+			WHERE achievement_ref_id = $1 AND delivered_at IS NULL AND kind = ANY($3)
+		`, refID, e.Kind, pq.Array(supersedes(e.Kind)))
+		if err != nil {
+			return err
+		}
+
+		var payload *string
+		if len(e.Payload) > 0 {
+			p := string(e.Payload)
+			payload = &p
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO achievement_outbox (id, achievement_ref_id, mongo_achievement_id, kind, payload, created_at, next_attempt_at)
+			VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		`, e.ID, refID, e.MongoAchievementID, e.Kind, payload)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// supersedes lists the kinds of undelivered event a new event of kind makes
+// obsolete: older writes of the same kind, for trash and restore each
+// other, since only the latest of the two decides whether the document is
+// trashed, and for a revoke any pending points, which it sets to 0.
+func supersedes(kind string) []string {
+	switch kind {
+	case models.OutboxTrashDetails, models.OutboxRestoreDetails:
+		return []string{models.OutboxTrashDetails, models.OutboxRestoreDetails}
+	case models.OutboxMarkRevoked:
+		return []string{models.OutboxMarkRevoked, models.OutboxSetPoints}
+	}
+	return []string{kind}
+}
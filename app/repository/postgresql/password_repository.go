@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	models "student-performance-report/app/models/postgresql"
+	"github.com/google/uuid"
+)
+
+var ErrResetTokenInvalid = errors.New("reset token is invalid, expired or already used")
+
+// PasswordRepository changes password hashes. Every change bumps the user's
+// token_version, revokes all refresh sessions and personal access tokens and
+// spends any pending reset token in the same transaction, so no old
+// credential survives it.
+type PasswordRepository interface {
+	GetPasswordHash(ctx context.Context, userID uuid.UUID) (string, error)
+	UpdatePassword(ctx context.Context, userID uuid.UUID, hash string) error
+	CreateResetToken(ctx context.Context, t *models.PasswordResetToken) error
+	ResetPassword(ctx context.Context, tokenHash string, hash string) (uuid.UUID, error)
+}
+
+type passwordRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordRepository(db *sql.DB) PasswordRepository {
+	return &passwordRepository{db: db}
+}
+
+func (r *passwordRepository) GetPasswordHash(ctx context.Context, userID uuid.UUID) (string, error) {
+	var hash string
+	err := r.db.QueryRowContext(ctx, `SELECT password_hash FROM users WHERE id = $1`, userID).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", errors.New("user not found")
+	}
+	return hash, err
+}
+
+func (r *passwordRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, hash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setPassword(ctx, tx, userID, hash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateResetToken stores a new token and spends the user's earlier ones, so
+// only the most recently issued token works.
+func (r *passwordRepository) CreateResetToken(ctx context.Context, t *models.PasswordResetToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, t.UserID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, t.ID, t.UserID, t.TokenHash, t.CreatedBy, t.CreatedAt, t.ExpiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ResetPassword spends the token and sets the new hash atomically. The
+// conditional UPDATE makes a token usable exactly once even under races.
+func (r *passwordRepository) ResetPassword(ctx context.Context, tokenHash string, hash string) (uuid.UUID, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`, tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, ErrResetTokenInvalid
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := setPassword(ctx, tx, userID, hash); err != nil {
+		return uuid.Nil, err
+	}
+	return userID, tx.Commit()
+}
+
+func setPassword(ctx context.Context, tx *sql.Tx, userID uuid.UUID, hash string) error {
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET password_hash = $1, token_version = token_version + 1, updated_at = NOW()
+		WHERE id = $2
+	`, hash, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errors.New("user not found")
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE personal_access_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID)
+	return err
+}
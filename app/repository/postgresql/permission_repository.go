@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	models "student-performance-report/app/models/postgresql"
+	"github.com/google/uuid"
+)
+
+type PermissionRepository interface {
+	GetAllPermissions() ([]models.Permission, error)
+	GetPermissionByID(id uuid.UUID) (*models.Permission, error)
+	CreatePermission(p *models.Permission) error
+	UpdatePermission(p *models.Permission) error
+	DeletePermission(id uuid.UUID) error
+}
+
+type permissionRepository struct {
+	db *sql.DB
+}
+
+func NewPermissionRepository(db *sql.DB) PermissionRepository {
+	return &permissionRepository{db: db}
+}
+
+func (r *permissionRepository) GetAllPermissions() ([]models.Permission, error) {
+	rows, err := r.db.Query(`
+		SELECT id, name, resource, action, COALESCE(description, '')
+		FROM permissions ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.Permission
+	for rows.Next() {
+		var p models.Permission
+		if err := rows.Scan(&p.ID, &p.Name, &p.Resource, &p.Action, &p.Description); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+func (r *permissionRepository) GetPermissionByID(id uuid.UUID) (*models.Permission, error) {
+	var p models.Permission
+	err := r.db.QueryRow(`
+		SELECT id, name, resource, action, COALESCE(description, '')
+		FROM permissions WHERE id = $1
+	`, id).Scan(&p.ID, &p.Name, &p.Resource, &p.Action, &p.Description)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("permission not found")
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *permissionRepository) CreatePermission(p *models.Permission) error {
+	_, err := r.db.Exec(`
+		INSERT INTO permissions (id, name, resource, action, description)
+		VALUES ($1, $2, $3, $4, $5)
+	`, p.ID, p.Name, p.Resource, p.Action, p.Description)
+	return err
+}
+
+func (r *permissionRepository) UpdatePermission(p *models.Permission) error {
+	result, err := r.db.Exec(`
+		UPDATE permissions SET name = $1, resource = $2, action = $3, description = $4
+		WHERE id = $5
+	`, p.Name, p.Resource, p.Action, p.Description, p.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("permission not found")
+	}
+	return nil
+}
+
+func (r *permissionRepository) DeletePermission(id uuid.UUID) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE permission_id = $1`, id); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM permissions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("permission not found")
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	models "student-performance-report/app/models/postgresql"
+	"github.com/google/uuid"
+)
+
+var ErrRoleNotFound = errors.New("role not found")
+
+type RoleRepository interface {
+	GetRolePermissionMap() (map[uuid.UUID][]string, error)
+	GetAllRoles() ([]models.Roles, error)
+	GetRoleByID(id uuid.UUID) (*models.Roles, error)
+	CreateRole(role *models.Roles) error
+	UpdateRole(role *models.Roles) error
+	DeleteRole(id uuid.UUID) error
+	CountUsersByRole(id uuid.UUID) (int, error)
+	CountActiveUsersByRole(id uuid.UUID) (int, error)
+	GetPermissionsByRole(id uuid.UUID) ([]models.Permission, error)
+	AttachPermission(roleID, permissionID uuid.UUID) error
+	DetachPermission(roleID, permissionID uuid.UUID) error
+	// CountAssignedRolesWithPermission counts the roles other than
+	// excludeRoleID that hold the permission and have at least one active
+	// user, i.e. the roles someone could still use it through.
+	CountAssignedRolesWithPermission(permissionName string, excludeRoleID uuid.UUID) (int, error)
+}
+
+type roleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) GetRolePermissionMap() (map[uuid.UUID][]string, error) {
+	query := `
+		SELECT r.id, p.name
+		FROM roles r
+		JOIN role_permissions rp ON rp.role_id = r.id
+		JOIN permissions p ON p.id = rp.permission_id
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[uuid.UUID][]string)
+	for rows.Next() {
+		var roleID uuid.UUID
+		var permName string
+		if err := rows.Scan(&roleID, &permName); err != nil {
+			return nil, err
+		}
+		result[roleID] = append(result[roleID], permName)
+	}
+
+	return result, nil
+}
+
+func (r *roleRepository) GetAllRoles() ([]models.Roles, error) {
+	rows, err := r.db.Query(`
+		SELECT id, name, COALESCE(description, ''), created_at
+		FROM roles ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.Roles
+	for rows.Next() {
+		var role models.Roles
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, role)
+	}
+	return list, nil
+}
+
+func (r *roleRepository) GetRoleByID(id uuid.UUID) (*models.Roles, error) {
+	var role models.Roles
+	err := r.db.QueryRow(`
+		SELECT id, name, COALESCE(description, ''), created_at
+		FROM roles WHERE id = $1
+	`, id).Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) CreateRole(role *models.Roles) error {
+	_, err := r.db.Exec(`
+		INSERT INTO roles (id, name, description, created_at)
+		VALUES ($1, $2, $3, NOW())
+	`, role.ID, role.Name, role.Description)
+	return err
+}
+
+func (r *roleRepository) UpdateRole(role *models.Roles) error {
+	result, err := r.db.Exec(`
+		UPDATE roles SET name = $1, description = $2
+		WHERE id = $3
+	`, role.Name, role.Description, role.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("role not found")
+	}
+	return nil
+}
+
+func (r *roleRepository) DeleteRole(id uuid.UUID) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_id = $1`, id); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM roles WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("role not found")
+	}
+
+	return tx.Commit()
+}
+
+func (r *roleRepository) CountUsersByRole(id uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM users WHERE role_id = $1`, id).Scan(&count)
+	return count, err
+}
+
+func (r *roleRepository) CountActiveUsersByRole(id uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM users WHERE role_id = $1 AND is_active`, id).Scan(&count)
+	return count, err
+}
+
+func (r *roleRepository) GetPermissionsByRole(id uuid.UUID) ([]models.Permission, error) {
+	rows, err := r.db.Query(`
+		SELECT p.id, p.name, p.resource, p.action, COALESCE(p.description, '')
+		FROM permissions p
+		JOIN role_permissions rp ON rp.permission_id = p.id
+		WHERE rp.role_id = $1
+		ORDER BY p.name
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Permission{}
+	for rows.Next() {
+		var p models.Permission
+		if err := rows.Scan(&p.ID, &p.Name, &p.Resource, &p.Action, &p.Description); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+func (r *roleRepository) AttachPermission(roleID, permissionID uuid.UUID) error {
+	_, err := r.db.Exec(`
+		INSERT INTO role_permissions (role_id, permission_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, roleID, permissionID)
+	return err
+}
+
+func (r *roleRepository) DetachPermission(roleID, permissionID uuid.UUID) error {
+	result, err := r.db.Exec(`
+		DELETE FROM role_permissions
+		WHERE role_id = $1 AND permission_id = $2
+	`, roleID, permissionID)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("permission is not attached to this role")
+	}
+	return nil
+}
+
+func (r *roleRepository) CountAssignedRolesWithPermission(permissionName string, excludeRoleID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(DISTINCT rp.role_id)
+		FROM role_permissions rp
+		JOIN permissions p ON p.id = rp.permission_id
+		JOIN users u ON u.role_id = rp.role_id AND u.is_active
+		WHERE p.name = $1 AND rp.role_id <> $2
+	`, permissionName, excludeRoleID).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	models "student-performance-report/app/models/postgresql"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRubricNotFound = errors.New("rubric not found")
+	ErrRubricFrozen   = errors.New("rubric has been active and can no longer be changed")
+)
+
+type RubricRepository interface {
+	List(ctx context.Context) ([]models.ScoringRubric, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.ScoringRubric, error)
+	// GetActive returns nil, nil when no version is active.
+	GetActive(ctx context.Context) (*models.ScoringRubric, error)
+	// Create stores a new version; the version number is assigned here.
+	Create(ctx context.Context, rubric *models.ScoringRubric) error
+	// Update and Delete return ErrRubricFrozen for a version that has ever
+	// been active.
+	Update(ctx context.Context, id uuid.UUID, name string, rules []models.RubricRule) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	Activate(ctx context.Context, id uuid.UUID) error
+}
+
+type rubricRepository struct {
+	db *sql.DB
+}
+
+func NewRubricRepository(db *sql.DB) RubricRepository {
+	return &rubricRepository{db: db}
+}
+
+const rubricColumns = `id, version, name, rules, is_active, created_by, created_at, activated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRubric(row rowScanner) (*models.ScoringRubric, error) {
+	var r models.ScoringRubric
+	var rules []byte
+	if err := row.Scan(
+		&r.ID,
+		&r.Version,
+		&r.Name,
+		&rules,
+		&r.IsActive,
+		&r.CreatedBy,
+		&r.CreatedAt,
+		&r.ActivatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(rules, &r.Rules); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *rubricRepository) List(ctx context.Context) ([]models.ScoringRubric, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+rubricColumns+` FROM scoring_rubrics ORDER BY version DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.ScoringRubric
+	for rows.Next() {
+		rubric, err := scanRubric(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *rubric)
+	}
+	return list, rows.Err()
+}
+
+func (r *rubricRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ScoringRubric, error) {
+	rubric, err := scanRubric(r.db.QueryRowContext(ctx, `SELECT `+rubricColumns+` FROM scoring_rubrics WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrRubricNotFound
+	}
+	return rubric, err
+}
+
+func (r *rubricRepository) GetActive(ctx context.Context) (*models.ScoringRubric, error) {
+	rubric, err := scanRubric(r.db.QueryRowContext(ctx, `SELECT `+rubricColumns+` FROM scoring_rubrics WHERE is_active`))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rubric, err
+}
+
+func (r *rubricRepository) Create(ctx context.Context, rubric *models.ScoringRubric) error {
+	rules, err := json.Marshal(rubric.Rules)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO scoring_rubrics (id, version, name, rules, created_by, created_at)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5 FROM scoring_rubrics
+		RETURNING version
+	`, rubric.ID, rubric.Name, rules, rubric.CreatedBy, rubric.CreatedAt).Scan(&rubric.Version)
+}
+
+func (r *rubricRepository) Update(ctx context.Context, id uuid.UUID, name string, rules []models.RubricRule) error {
+	raw, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE scoring_rubrics SET name = $2, rules = $3
+		WHERE id = $1 AND activated_at IS NULL
+	`, id, name, raw)
+	if err != nil {
+		return err
+	}
+	return r.frozenOrMissing(ctx, result, id)
+}
+
+func (r *rubricRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM scoring_rubrics WHERE id = $1 AND activated_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	return r.frozenOrMissing(ctx, result, id)
+}
+
+// frozenOrMissing explains an Update or Delete that touched no row.
+func (r *rubricRepository) frozenOrMissing(ctx context.Context, result sql.Result, id uuid.UUID) error {
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		return nil
+	}
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM scoring_rubrics WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrRubricFrozen
+	}
+	return ErrRubricNotFound
+}
+
+// Activate makes id the only active version.
+func (r *rubricRepository) Activate(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE scoring_rubrics SET is_active = FALSE WHERE is_active AND id <> $1`, id); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, `
+		UPDATE scoring_rubrics SET is_active = TRUE, activated_at = COALESCE(activated_at, NOW())
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrRubricNotFound
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	models "student-performance-report/app/models/postgresql"
+	"github.com/google/uuid"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionRepository interface {
+	Create(ctx context.Context, s *models.Session) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Session, error)
+	Rotate(ctx context.Context, id uuid.UUID, oldJTI, newJTI string, expiresAt time.Time) error
+	Revoke(ctx context.Context, id uuid.UUID) error
+	RevokeForUser(ctx context.Context, id, userID uuid.UUID) error
+	RevokeAllByUser(ctx context.Context, userID uuid.UUID) error
+	ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+}
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, s *models.Session) error {
+	query := `
+		INSERT INTO user_sessions (id, user_id, refresh_jti, user_agent, ip_address, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		s.ID,
+		s.UserID,
+		s.RefreshJTI,
+		s.UserAgent,
+		s.IPAddress,
+		s.IssuedAt,
+		s.ExpiresAt,
+	)
+	return err
+}
+
+func (r *sessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	query := `
+		SELECT id, user_id, refresh_jti, user_agent, ip_address,
+		       issued_at, expires_at, last_used_at, revoked_at
+		FROM user_sessions
+		WHERE id = $1
+	`
+	var s models.Session
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&s.ID,
+		&s.UserID,
+		&s.RefreshJTI,
+		&s.UserAgent,
+		&s.IPAddress,
+		&s.IssuedAt,
+		&s.ExpiresAt,
+		&s.LastUsedAt,
+		&s.RevokedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Rotate swaps the accepted refresh jti only if oldJTI is still the current one,
+// so two concurrent refreshes with the same token cannot both succeed.
+func (r *sessionRepository) Rotate(ctx context.Context, id uuid.UUID, oldJTI, newJTI string, expiresAt time.Time) error {
+	query := `
+		UPDATE user_sessions
+		SET refresh_jti = $1, expires_at = $2, last_used_at = NOW()
+		WHERE id = $3 AND refresh_jti = $4 AND revoked_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, newJTI, expiresAt, id, oldJTI)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *sessionRepository) RevokeForUser(ctx context.Context, id, userID uuid.UUID) error {
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeAllByUser(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
+func (r *sessionRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	query := `
+		SELECT id, user_id, user_agent, ip_address, issued_at, expires_at, last_used_at
+		FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY COALESCE(last_used_at, issued_at) DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.Session
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(
+			&s.ID,
+			&s.UserID,
+			&s.UserAgent,
+			&s.IPAddress,
+			&s.IssuedAt,
+			&s.ExpiresAt,
+			&s.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
@@ -9,6 +9,8 @@ import (
     "github.com/lib/pq"
 )
 
+var ErrStudentNotFound = errors.New("student not found")
+
 type StudentRepository interface {
     GetAllStudents(ctx context.Context) ([]models.Student, error)
     GetStudentByID(ctx context.Context, id uuid.UUID) (*models.Student, error)
@@ -80,7 +82,7 @@ func (r *studentRepository) GetStudentByID(ctx context.Context, id uuid.UUID) (*
     )
 
     if err == sql.ErrNoRows {
-        return nil, errors.New("student not found")
+        return nil, ErrStudentNotFound
     } else if err != nil {
         return nil, err
     }
@@ -7,10 +7,13 @@ import (
 	"github.com/google/uuid"
 )
 
+var ErrUserNotFound = errors.New("user not found")
+
 type UserRepository interface {
     GetByUsername(username string) (*models.User, string, error)
     GetPermissionsByRoleID(roleID uuid.UUID) ([]string, error)
 	GetByID(id uuid.UUID) (*models.User, error)
+	GetTokenVersion(id uuid.UUID) (int, bool, error)
 }
 
 type userRepository struct {
@@ -28,7 +31,7 @@ func (r *userRepository) GetByUsername(username string) (*models.User, string, e
 	query := `
 		SELECT 
 			u.id, u.username, u.email, u.password_hash, 
-			u.full_name, u.role_id, u.is_active, 
+			u.full_name, u.role_id, u.is_active, u.token_version,
 			r.name
 		FROM users u
 		JOIN roles r ON u.role_id = r.id
@@ -45,12 +48,13 @@ func (r *userRepository) GetByUsername(username string) (*models.User, string, e
 		&user.FullName,
 		&user.RoleID,
 		&user.IsActive,
+		&user.TokenVersion,
 		&roleName,    
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, "", errors.New("user not found")
+			return nil, "", ErrUserNotFound
 		}
 		return nil, "", err
 	}
@@ -88,7 +92,7 @@ func (r *userRepository) GetByID(id uuid.UUID) (*models.User, error) {
 	var user models.User
 
 	query := `
-		SELECT id, username, email, full_name, role_id, is_active
+		SELECT id, username, email, full_name, role_id, is_active, token_version
 		FROM users
 		WHERE id = $1
 	`
@@ -102,14 +106,30 @@ func (r *userRepository) GetByID(id uuid.UUID) (*models.User, error) {
 		&user.FullName,
 		&user.RoleID,
 		&user.IsActive,
+		&user.TokenVersion,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
 
 	return &user, nil
 }
+
+func (r *userRepository) GetTokenVersion(id uuid.UUID) (int, bool, error) {
+	var version int
+	var active bool
+
+	err := r.db.QueryRow(`SELECT token_version, is_active FROM users WHERE id = $1`, id).Scan(&version, &active)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, ErrUserNotFound
+		}
+		return 0, false, err
+	}
+
+	return version, active, nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+    "errors"
+    "fmt"
+    modelPg "student-performance-report/app/models/postgresql"
+    "github.com/gofiber/fiber/v2"
+    "github.com/google/uuid"
+)
+
+// maxBulkItems caps one bulk request so it finishes well within a request
+// timeout.
+const maxBulkItems = 100
+
+// BulkVerifyAchievements godoc
+// @Summary Verify Achievements in Bulk
+// @Description Verify several submitted achievements of your advisees at once. Each item is checked and applied on its own, exactly like the single verify endpoint; the report lists the outcome of every item.
+// @Tags Achievements
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body modelPg.BulkVerifyRequest true "Items with optional points and reviewed version"
+// @Success 200 {object} modelPg.BulkResult
+// @Failure 400,401,403 {object} map[string]interface{}
+// @Router /achievements/bulk/verify [post]
+func (s *AchievementService) BulkVerifyAchievements(c *fiber.Ctx) error {
+    actor, err := s.access.Actor(c)
+    if err != nil {
+        return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    lecturerID, ok := actor.LecturerID()
+    if !ok {
+        return c.Status(403).JSON(fiber.Map{"error": "User is not a lecturer"})
+    }
+
+    var req modelPg.BulkVerifyRequest
+    if err := c.BodyParser(&req); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+    }
+    if err := checkBulkSize(len(req.Items)); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    ids := make([]uuid.UUID, len(req.Items))
+    for i, item := range req.Items {
+        ids[i] = item.ID
+    }
+
+    return c.JSON(runBulk(ids, func(i int) (*int, error) {
+        result, err := s.verify(c.Context(), actor, lecturerID, req.Items[i])
+        if err != nil {
+            return nil, err
+        }
+        return &result.Points, nil
+    }))
+}
+
+// BulkRejectAchievements godoc
+// @Summary Reject Achievements in Bulk
+// @Description Reject several submitted achievements of your advisees at once. An item without a note uses the request's note. Each item is checked and applied on its own; the report lists the outcome of every item.
+// @Tags Achievements
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body modelPg.BulkRejectRequest true "Items with their rejection notes"
+// @Success 200 {object} modelPg.BulkResult
+// @Failure 400,401,403 {object} map[string]interface{}
+// @Router /achievements/bulk/reject [post]
+func (s *AchievementService) BulkRejectAchievements(c *fiber.Ctx) error {
+    actor, err := s.access.Actor(c)
+    if err != nil {
+        return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    lecturerID, ok := actor.LecturerID()
+    if !ok {
+        return c.Status(403).JSON(fiber.Map{"error": "User is not a lecturer"})
+    }
+
+    var req modelPg.BulkRejectRequest
+    if err := c.BodyParser(&req); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+    }
+    if err := checkBulkSize(len(req.Items)); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    ids := make([]uuid.UUID, len(req.Items))
+    for i, item := range req.Items {
+        ids[i] = item.ID
+    }
+
+    return c.JSON(runBulk(ids, func(i int) (*int, error) {
+        item := req.Items[i]
+        if item.Note == "" {
+            item.Note = req.Note
+        }
+        return nil, s.reject(c.Context(), actor, lecturerID, item)
+    }))
+}
+
+func checkBulkSize(n int) error {
+    if n == 0 {
+        return errors.New("At least one item is required")
+    }
+    if n > maxBulkItems {
+        return fmt.Errorf("At most %d items per request", maxBulkItems)
+    }
+    return nil
+}
+
+// runBulk applies apply to every item in order. A failed item does not stop
+// the others; an ID repeated in the same request is only applied once.
+func runBulk(ids []uuid.UUID, apply func(i int) (*int, error)) modelPg.BulkResult {
+    report := modelPg.BulkResult{Results: make([]modelPg.BulkItemResult, 0, len(ids))}
+    seen := make(map[uuid.UUID]bool, len(ids))
+
+    for i, id := range ids {
+        result := modelPg.BulkItemResult{ID: id}
+
+        var err error
+        if seen[id] {
+            err = fiber.NewError(400, "Duplicate item in this request")
+        } else {
+            seen[id] = true
+            result.Points, err = apply(i)
+        }
+
+        var changed *versionChangedError
+        var fe *fiber.Error
+        switch {
+        case err == nil:
+            result.Success, result.Status = true, 200
+        case errors.As(err, &changed):
+            result.Status, result.Error, result.CurrentVersion = 409, changed.Error(), &changed.current
+        case errors.As(err, &fe):
+            result.Status, result.Error = fe.Code, fe.Message
+        default:
+            result.Status, result.Error = 500, err.Error()
+        }
+
+        if result.Success {
+            report.Succeeded++
+        } else {
+            report.Failed++
+        }
+        report.Results = append(report.Results, result)
+    }
+    return report
+}
@@ -1,29 +1,99 @@
 package service
 
 import (
+    "context"
     "time"
     "errors"
+    "log"
     "os"
     "fmt"
     "path/filepath"
     "math"
+    "strings"
     modelMongo "student-performance-report/app/models/mongodb"
     modelPg "student-performance-report/app/models/postgresql"
     repoMongo "student-performance-report/app/repository/mongodb"
     repoPg "student-performance-report/app/repository/postgresql"
+    "student-performance-report/app/service/outbox"
+    "student-performance-report/app/service/policy"
+    "student-performance-report/app/service/schema"
+    "student-performance-report/app/service/scoring"
+    "student-performance-report/app/service/trash"
+    "student-performance-report/app/service/workflow"
     "github.com/gofiber/fiber/v2"
     "github.com/google/uuid"
-    "student-performance-report/middleware"
+    "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type AchievementService struct {
     mongoRepo repoMongo.AchievementRepository
     pgRepo    repoPg.AchievementRepoPostgres
     lecturer   repoPg.LecturerRepository
+    access    *policy.AccessPolicy
+    workflow  *workflow.AchievementWorkflow
+    rubrics   repoPg.RubricRepository
+    types     *schema.Registry
+    relay     *outbox.Relay
+    trash     *trash.Purger
 }
 
-func NewAchievementService(m repoMongo.AchievementRepository, p repoPg.AchievementRepoPostgres, l repoPg.LecturerRepository) *AchievementService {
-    return &AchievementService{mongoRepo: m, pgRepo: p, lecturer: l}
+func NewAchievementService(m repoMongo.AchievementRepository, p repoPg.AchievementRepoPostgres, l repoPg.LecturerRepository, a *policy.AccessPolicy, r repoPg.RubricRepository, relay *outbox.Relay, t *trash.Purger) *AchievementService {
+    return &AchievementService{mongoRepo: m, pgRepo: p, lecturer: l, access: a, workflow: workflow.NewAchievementWorkflow(p), rubrics: r, types: schema.Default(), relay: relay, trash: t}
+}
+
+// deliver applies the Mongo writes of a committed change right away. A
+// write that fails here stays in the outbox and is retried by the relay, so
+// the request itself still succeeds.
+func (s *AchievementService) deliver(ctx context.Context, events ...modelPg.OutboxEvent) {
+    if err := s.relay.Deliver(ctx, events...); err != nil {
+        log.Printf("achievement: %v (left for retry)", err)
+    }
+}
+
+// validationError answers 400 with one message per invalid field.
+func validationError(c *fiber.Ctx, errs schema.FieldErrors) error {
+    return c.Status(400).JSON(fiber.Map{"error": "Validation failed", "fields": errs})
+}
+
+// GetAchievementTypes godoc
+// @Summary List Achievement Types
+// @Description List the registered achievement types with their detail field rules and customFields JSON Schema
+// @Tags Achievements
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} schema.TypeSchema
+// @Failure 401 {object} map[string]interface{}
+// @Router /achievements/types [get]
+func (s *AchievementService) GetAchievementTypes(c *fiber.Ctx) error {
+    return c.JSON(s.types.Types())
+}
+
+// suggestScore applies the active rubric; it returns nil when no version is
+// active or no rule matches.
+func (s *AchievementService) suggestScore(ctx context.Context, detail *modelMongo.Achievement) (*modelPg.SuggestedScore, error) {
+    rubric, err := s.rubrics.GetActive(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return scoring.Suggest(rubric, detail), nil
+}
+
+// transitionError turns a failed workflow transition into the status to
+// answer with: 400 when the workflow forbids it, 409 when a concurrent
+// request changed the status first.
+func transitionError(err error, message string) *fiber.Error {
+    switch {
+    case errors.Is(err, workflow.ErrTransitionNotAllowed):
+        return fiber.NewError(400, err.Error())
+    case errors.Is(err, repoPg.ErrStatusConflict):
+        return fiber.NewError(409, err.Error())
+    }
+    return fiber.NewError(500, message)
+}
+
+// workflowError answers a failed status change.
+func workflowError(c *fiber.Ctx, err error, message string) error {
+    return fiberError(c, transitionError(err, message))
 }
 
 func getUserIDFromToken(c *fiber.Ctx) (uuid.UUID, error) {
@@ -58,9 +128,6 @@ func getUserIDFromToken(c *fiber.Ctx) (uuid.UUID, error) {
 // @Router /achievements [post]
 func (s *AchievementService) CreateAchievement(c *fiber.Ctx) error {
     ctx := c.Context()
-    if !middleware.HasPermission(c, "achievement:create") {
-    return fiber.ErrForbidden
-    }
 
     userID, err := getUserIDFromToken(c)
     if err != nil {
@@ -77,30 +144,36 @@ func (s *AchievementService) CreateAchievement(c *fiber.Ctx) error {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
     }
 
+    if errs := s.types.Validate(&req); errs != nil {
+        return validationError(c, errs)
+    }
+
+    req.ID = primitive.NewObjectID()
     req.Attachments = make([]modelMongo.Attachment, 0)
     req.StudentID = studentID.String()
+    req.UpdatedBy = userID.String()
     req.Points = 0 
     req.CreatedAt = time.Now()
     req.UpdatedAt = time.Now()
-    mongoID, err := s.mongoRepo.InsertOne(ctx, req)
 
+    event, err := outbox.CreateDetails(req)
     if err != nil {
         return c.Status(500).JSON(fiber.Map{"error": "Failed to save achievement details"})
     }
 
     ref := modelPg.AchievementReference{
         StudentID:          studentID,
-        MongoAchievementID: mongoID,
+        MongoAchievementID: req.ID.Hex(),
         Status:             "draft", 
         CreatedAt:          time.Now(),
     }
     
-    newID, err := s.pgRepo.Create(ctx, ref)
+    // The reference and the write of its details commit together.
+    newID, err := s.pgRepo.Create(ctx, ref, userID, []modelPg.OutboxEvent{event})
     if err != nil {
-        _ = s.mongoRepo.DeleteAchievement(ctx, mongoID)
-        
         return c.Status(500).JSON(fiber.Map{"error": "Failed to save achievement reference: " + err.Error()})
     }
+    s.deliver(ctx, event)
 
     return c.Status(201).JSON(fiber.Map{
         "message": "Achievement created successfully",
@@ -124,11 +197,8 @@ func (s *AchievementService) CreateAchievement(c *fiber.Ctx) error {
 // @Router /achievements [get]
 func (s *AchievementService) GetAllAchievements(c *fiber.Ctx) error {
     ctx := c.Context()
-    if !middleware.HasPermission(c, "achievement:read") {
-    return fiber.ErrForbidden
-    }
 
-    userID, err := getUserIDFromToken(c)
+    actor, err := s.access.Actor(c)
     if err != nil {
         return c.Status(401).JSON(fiber.Map{"error": err.Error()}) 
     }
@@ -146,46 +216,20 @@ func (s *AchievementService) GetAllAchievements(c *fiber.Ctx) error {
 
     offset := (query.Page - 1) * query.Limit
 
-    filters := make(map[string]interface{})
-
-    if studentID, err := s.pgRepo.GetStudentByUserID(ctx, userID); err == nil {
-        filters["student_id"] = studentID
-        if query.Status != "" {
-            filters["status"] = query.Status
-        }
-    }
-    
-    lecturerID, err := s.lecturer.GetLecturerByUserID(ctx, userID)
-    if  err == nil { 
-
-        advisees, err:= s.lecturer.GetAdvisees(lecturerID)
-        if err != nil {
+    filters, ok, err := s.access.AchievementScope(actor, query.Status)
+    if err != nil {
         return c.Status(500).JSON(fiber.Map{
             "error": "failed to fetch advisees",
         })
-        }
-
-        var studentIDs []uuid.UUID
-        for _, mhs := range advisees {
-            studentIDs = append(studentIDs, mhs.ID)
-        }
-        
-        if len(studentIDs) == 0 {
-            return c.JSON(modelPg.PaginatedResponse{
-                Data: []interface{}{},
-                Meta: modelPg.PaginationMeta{
-                    CurrentPage: query.Page, Limit: query.Limit, TotalData: 0, TotalPage: 0,
-                },
-            })
-        }
-
-        filters["student_ids"] = studentIDs
+    }
 
-        if query.Status != "" {
-            filters["status"] = query.Status
-        } else {
-            filters["status"] = []string{"submitted", "verified"} 
-        }
+    if !ok {
+        return c.JSON(modelPg.PaginatedResponse{
+            Data: []interface{}{},
+            Meta: modelPg.PaginationMeta{
+                CurrentPage: query.Page, Limit: query.Limit, TotalData: 0, TotalPage: 0,
+            },
+        })
     }
 
     refs, totalData, err := s.pgRepo.GetAllReferences(ctx, filters, query.Limit, offset, query.Sort)
@@ -203,29 +247,39 @@ func (s *AchievementService) GetAllAchievements(c *fiber.Ctx) error {
     }
 
     var mongoIDs []string
-    refMap := make(map[string]modelPg.AchievementReference)
     for _, r := range refs {
         mongoIDs = append(mongoIDs, r.MongoAchievementID)
-        refMap[r.MongoAchievementID] = r
     }
 
-    details, _ := s.mongoRepo.FindAllDetails(ctx, mongoIDs)
-
-    var data []interface{}
+    details, err := s.mongoRepo.FindAllDetails(ctx, mongoIDs)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch achievement details"})
+    }
+    detailMap := make(map[string]modelMongo.Achievement, len(details))
     for _, d := range details {
-        mongoIDHex := d.ID.Hex()
-        if ref, exists := refMap[mongoIDHex]; exists {
-            data = append(data, map[string]interface{}{
-                "id":             ref.ID,
-                "status":         ref.Status,
-                "submittedAt":    ref.SubmittedAt,
-                "title":          d.Title,
-                "type":           d.AchievementType,
-                "points":         d.Points,
-                "createdAt":      ref.CreatedAt,
-                "studentId":      ref.StudentID,
-            })
+        detailMap[d.ID.Hex()] = d
+    }
+
+    // Every reference is listed in the order Postgres returned. One whose
+    // details are not (yet) in Mongo is flagged instead of dropped.
+    data := make([]interface{}, 0, len(refs))
+    for _, ref := range refs {
+        item := map[string]interface{}{
+            "id":             ref.ID,
+            "status":         ref.Status,
+            "submittedAt":    ref.SubmittedAt,
+            "points":         ref.Points,
+            "createdAt":      ref.CreatedAt,
+            "studentId":      ref.StudentID,
         }
+        if d, ok := detailMap[ref.MongoAchievementID]; ok {
+            item["title"] = d.Title
+            item["type"] = d.AchievementType
+            item["points"] = d.Points
+        } else {
+            item["detailsMissing"] = true
+        }
+        data = append(data, item)
     }
 
     totalPages := int(math.Ceil(float64(totalData) / float64(query.Limit)))
@@ -243,7 +297,7 @@ func (s *AchievementService) GetAllAchievements(c *fiber.Ctx) error {
 
 // GetAchievementDetail godoc
 // @Summary Get Achievement Detail
-// @Description Get full details of a specific achievement including MongoDB data
+// @Description Get full details of a specific achievement including MongoDB data and the score suggested by the active rubric
 // @Tags Achievements
 // @Security BearerAuth
 // @Produce json
@@ -253,16 +307,13 @@ func (s *AchievementService) GetAllAchievements(c *fiber.Ctx) error {
 // @Router /achievements/{id} [get]
 func (s *AchievementService) GetAchievementDetail(c *fiber.Ctx) error {
     ctx := c.Context()
-    if !middleware.HasPermission(c, "achievement:read") {
-    return fiber.ErrForbidden
-    }
 
     achievementID, err := uuid.Parse(c.Params("id"))
     if err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid achievement ID"})
     }
 
-    userID, err := getUserIDFromToken(c)
+    actor, err := s.access.Actor(c)
     if err != nil {
         return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
     }
@@ -272,34 +323,12 @@ func (s *AchievementService) GetAchievementDetail(c *fiber.Ctx) error {
         return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"})
     }
 
-    currentStudentID, err := s.pgRepo.GetStudentByUserID(ctx, userID)
-    if err == nil {
-        if ref.StudentID != currentStudentID {
-            return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You cannot view this achievement"})
-        }
-    } 
-    lecturerID, err := s.lecturer.GetLecturerByUserID(ctx, userID)
-    if err ==nil{
-        advisees, err := s.lecturer.GetAdvisees(lecturerID)
-        if err != nil {
-            return c.Status(500).JSON(fiber.Map{"error": "Failed to check advisee relationship"})
-        }
-
-        isAdvisee := false
-        for _, mhs := range advisees {
-            if mhs.ID == ref.StudentID {
-                isAdvisee = true
-                break
-            }
-        }
-
-        if !isAdvisee {
-            return c.Status(403).JSON(fiber.Map{"error": "Forbidden: This student is not your advisee"})
-        }
-
-        if ref.Status == "draft" {
-            return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You cannot view draft achievements of your advisees"})
-        }
+    allowed, err := s.access.CanViewAchievement(actor, ref)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to check advisee relationship"})
+    }
+    if !allowed {
+        return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You cannot view this achievement"})
     }
 
     detail, err := s.mongoRepo.FindOne(ctx, ref.MongoAchievementID)
@@ -307,12 +336,20 @@ func (s *AchievementService) GetAchievementDetail(c *fiber.Ctx) error {
         return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch achievement details"})
     }
 
+    suggestion, err := s.suggestScore(ctx, detail)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to load scoring rubric"})
+    }
+
     response := map[string]interface{}{
-        "id":            ref.ID,
-        "status":        ref.Status,
-        "rejectionNote": ref.RejectionNote,
-        "details":       detail, 
-        "createdAt":     ref.CreatedAt,
+        "id":               ref.ID,
+        "status":           ref.Status,
+        "rejectionNote":    ref.RejectionNote,
+        "details":          detail, 
+        "createdAt":        ref.CreatedAt,
+        "suggestedScore":   suggestion,
+        "rubricVersion":    ref.RubricVersion,
+        "pointsOverridden": ref.PointsOverridden,
     }
 
     return c.JSON(response)
@@ -320,51 +357,39 @@ func (s *AchievementService) GetAchievementDetail(c *fiber.Ctx) error {
 
 // SubmitAchievement godoc
 // @Summary Submit Achievement
-// @Description Submit a draft achievement for verification (Student only)
+// @Description Submit a draft or revised achievement for verification (Student only)
 // @Tags Achievements
 // @Security BearerAuth
 // @Produce json
 // @Param id path string true "Achievement ID (UUID)"
 // @Success 200 {object} map[string]string
-// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Failure 400,401,403,404,409,500 {object} map[string]interface{}
 // @Router /achievements/{id}/submit [post]
 func (s *AchievementService) SubmitAchievement(c *fiber.Ctx) error {
     ctx := c.Context()
-    if !middleware.HasPermission(c, "achievement:create") {
-    return fiber.ErrForbidden
-    }
 
     achievementID, err := uuid.Parse(c.Params("id"))
     if err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"}) 
     }
 
-    userID, err := getUserIDFromToken(c)
+    actor, err := s.access.Actor(c)
     if err != nil {
         return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"}) 
     }
 
-    studentID, err := s.pgRepo.GetStudentByUserID(ctx, userID)
-    if err != nil {
-        return c.Status(404).JSON(fiber.Map{"error": "Student profile not found"}) 
-    }
-
     ref, err := s.pgRepo.GetReferenceByID(ctx, achievementID)
     if err != nil {
         return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"}) 
     }
 
-    if ref.StudentID != studentID {
+    if !s.access.CanModifyAchievement(actor, ref) {
         return c.Status(403).JSON(fiber.Map{"error": "Forbidden"})
     }
 
-    if ref.Status != "draft" {
-        return c.Status(400).JSON(fiber.Map{"error": "Only draft achievements can be submitted"})
-    }
-
-    err = s.pgRepo.SubmitReference(ctx, achievementID) 
+    err = s.workflow.Transition(ctx, ref, modelPg.StatusSubmitted, workflow.Owner, modelPg.StatusChange{ActorID: actor.UserID})
     if err != nil {
-        return c.Status(500).JSON(fiber.Map{"error": "Failed to submit achievement"+ err.Error(),})
+        return workflowError(c, err, "Failed to submit achievement")
     }
 
     return c.JSON(fiber.Map{"status": "success", "message": "Achievement submitted for verification"})
@@ -372,19 +397,16 @@ func (s *AchievementService) SubmitAchievement(c *fiber.Ctx) error {
 
 // DeleteAchievement godoc
 // @Summary Delete Achievement
-// @Description Delete an achievement (Only allowed if status is draft)
+// @Description Move a draft achievement to the trash. It can be restored until the retention window passes and is then purged with its attachments.
 // @Tags Achievements
 // @Security BearerAuth
 // @Produce json
 // @Param id path string true "Achievement ID (UUID)"
 // @Success 200 {object} map[string]string
-// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Failure 400,401,403,404,409,500 {object} map[string]interface{}
 // @Router /achievements/{id} [delete]
 func (s *AchievementService) DeleteAchievement(c *fiber.Ctx) error {
     ctx := c.Context()
-    if !middleware.HasPermission(c, "achievement:delete") {
-    return fiber.ErrForbidden
-    }
 
     
     achievementID, err := uuid.Parse(c.Params("id"))
@@ -392,115 +414,179 @@ func (s *AchievementService) DeleteAchievement(c *fiber.Ctx) error {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid achievement ID"})
     }
 
-    userID, err := getUserIDFromToken(c)
+    actor, err := s.access.Actor(c)
     if err != nil {
         return c.Status(401).JSON(fiber.Map{"error": err.Error()}) 
     }
 
-    studentID, err := s.pgRepo.GetStudentByUserID(ctx, userID)
-    if err != nil {
-        return c.Status(404).JSON(fiber.Map{"error": "Student profile not found"}) 
-    }
-
     ref, err := s.pgRepo.GetReferenceByID(ctx, achievementID)
     if err != nil {
         return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"})
     }
-    if ref.StudentID != studentID {
+    if !s.access.CanModifyAchievement(actor, ref) {
         return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You do not own this data"})
     }
 
-    if ref.Status != "draft" {
-        return c.Status(400).JSON(fiber.Map{"error": "Only draft achievements can be deleted"})
-    }
-
-    if err := s.pgRepo.DeleteReference(ctx, achievementID); err != nil {
-        return c.Status(500).JSON(fiber.Map{"error": "Failed to delete reference"})
+    trashed := outbox.TrashDetails(ref.MongoAchievementID, actor.UserID, time.Now())
+    change := modelPg.StatusChange{ActorID: actor.UserID, Outbox: []modelPg.OutboxEvent{trashed}}
+    err = s.workflow.Transition(ctx, ref, modelPg.StatusDeleted, workflow.Owner, change)
+    if err != nil {
+        return workflowError(c, err, "Failed to delete reference")
     }
+    s.deliver(ctx, change.Outbox...)
 
-    _ = s.mongoRepo.DeleteAchievement(ctx, ref.MongoAchievementID)
-
-    return c.JSON(fiber.Map{"message": "Achievement deleted successfully"})
+    return c.JSON(fiber.Map{"message": "Achievement moved to trash"})
 }
 
 // VerifyAchievement godoc
 // @Summary Verify Achievement
-// @Description Approve a submitted achievement (Lecturer/Dosen Wali only)
+// @Description Approve a submitted achievement (Lecturer/Dosen Wali only). Without points the active rubric's suggestion is awarded; the rubric version and any override are recorded. Passing the reviewed version answers 409 if the details changed since.
 // @Tags Achievements
 // @Security BearerAuth
+// @Accept json
 // @Produce json
 // @Param id path string true "Achievement ID (UUID)"
+// @Param request body object{points=int,version=int} false "Points (optional when the rubric suggests a score) and the reviewed version"
 // @Success 200 {object} map[string]string
-// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Failure 400,401,403,404,409,500 {object} map[string]interface{}
 // @Router /achievements/{id}/verify [post]
 func (s *AchievementService) VerifyAchievement(c *fiber.Ctx) error {
-    ctx := c.Context()
-
-    if !middleware.HasPermission(c, "achievement:verify") {
-        return fiber.ErrForbidden
-    }
-
     achievementID, err := uuid.Parse(c.Params("id"))
     if err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid achievement ID"})
     }
 
-    userID, err := getUserIDFromToken(c)
+    actor, err := s.access.Actor(c)
     if err != nil {
         return c.Status(401).JSON(fiber.Map{"error": err.Error()})
     }
 
-    lecturerID, err := s.lecturer.GetLecturerByUserID(ctx, userID)
-    if err != nil {
+    lecturerID, ok := actor.LecturerID()
+    if !ok {
         return c.Status(403).JSON(fiber.Map{"error": "User is not a lecturer"})
     }
 
     var req struct {
-        Points int `json:"points"`
+        Points  int `json:"points"`
+        Version int `json:"version"`
     }
 
     if err := c.BodyParser(&req); err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
     }
 
-    if req.Points <= 0 {
-        return c.Status(400).JSON(fiber.Map{
-            "error": "Points must be greater than 0",
-        })
+    result, err := s.verify(c.Context(), actor, lecturerID, modelPg.BulkVerifyItem{ID: achievementID, Points: req.Points, Version: req.Version})
+    if err != nil {
+        return reviewError(c, err)
     }
 
-    ref, err := s.pgRepo.GetReferenceByID(ctx, achievementID)
+    response := fiber.Map{
+        "status":     "success",
+        "message":    "Achievement verified",
+        "points":     result.Points,
+        "overridden": false,
+        "version":    result.Version,
+    }
+    if sg := result.Suggestion; sg != nil {
+        response["rubricVersion"] = sg.RubricVersion
+        response["suggestedPoints"] = sg.Points
+        response["overridden"] = result.Points != sg.Points
+    }
+    return c.JSON(response)
+}
+
+// verifiedResult is what one verification awarded.
+type verifiedResult struct {
+    Points     int
+    Version    *int
+    Suggestion *modelPg.SuggestedScore
+}
+
+// versionChangedError means the details moved past the version the lecturer
+// reviewed.
+type versionChangedError struct {
+    reviewed, current int
+}
+
+func (e *versionChangedError) Error() string {
+    return fmt.Sprintf("Achievement changed since version %d was reviewed", e.reviewed)
+}
+
+// reviewError answers a failed verify or reject.
+func reviewError(c *fiber.Ctx, err error) error {
+    var changed *versionChangedError
+    if errors.As(err, &changed) {
+        return c.Status(409).JSON(fiber.Map{"error": changed.Error(), "currentVersion": changed.current})
+    }
+    return fiberError(c, err)
+}
+
+// verify checks and applies one verification for the single and bulk
+// endpoints. Errors are *fiber.Error or *versionChangedError.
+func (s *AchievementService) verify(ctx context.Context, actor *policy.Actor, lecturerID uuid.UUID, item modelPg.BulkVerifyItem) (*verifiedResult, error) {
+    if item.Points < 0 {
+        return nil, fiber.NewError(400, "Points must be greater than 0")
+    }
+
+    ref, err := s.pgRepo.GetReferenceByID(ctx, item.ID)
     if err != nil {
-        return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"})
+        return nil, fiber.NewError(404, "Achievement not found")
     }
 
-    if ref.Status != "submitted" {
-        return c.Status(400).JSON(fiber.Map{
-            "error": "Achievement must be in 'submitted' status to be verified",
-        })
+    allowed, err := s.access.CanVerify(actor, ref)
+    if err != nil {
+        return nil, fiber.NewError(500, "Failed to check advisee relationship")
+    }
+    if !allowed {
+        return nil, fiber.NewError(403, "Forbidden: This student is not your advisee")
     }
 
-    
-    err = s.mongoRepo.UpdatePoints(ctx, ref.MongoAchievementID, req.Points)
+    detail, err := s.mongoRepo.FindOne(ctx, ref.MongoAchievementID)
     if err != nil {
-        return c.Status(500).JSON(fiber.Map{
-            "error": "Failed to update achievement points",
-        })
+        return nil, fiber.NewError(500, "Failed to fetch achievement details")
+    }
+    // The lecturer names the version they reviewed; anything else means the
+    // details changed under them.
+    if item.Version != 0 && item.Version != detail.Version {
+        return nil, &versionChangedError{reviewed: item.Version, current: detail.Version}
+    }
+    var version *int
+    if detail.Version > 0 {
+        version = &detail.Version
     }
 
-    // ✅ update status di Postgres
-    err = s.pgRepo.UpdateStatus(ctx, achievementID, "verified", &lecturerID, "")
+    suggestion, err := s.suggestScore(ctx, detail)
     if err != nil {
-        return c.Status(500).JSON(fiber.Map{
-            "error": "Failed to verify achievement",
-        })
+        return nil, fiber.NewError(500, "Failed to load scoring rubric")
     }
 
-    return c.JSON(fiber.Map{
-        "status":  "success",
-        "message": "Achievement verified",
-        "points":  req.Points,
-    })
+    // Without points the rubric's suggestion is awarded.
+    points := item.Points
+    if points == 0 {
+        if suggestion == nil {
+            return nil, fiber.NewError(400, "Points must be greater than 0 (no rubric rule matches this achievement)")
+        }
+        points = suggestion.Points
+    }
+
+    // The status is claimed first so that of two concurrent verify/reject
+    // requests only the winner writes points; the Mongo write is stored with
+    // it and retried by the relay if it fails here.
+    change := modelPg.StatusChange{
+        ActorID:    actor.UserID,
+        VerifiedBy: &lecturerID,
+        Points:     &points,
+        Suggestion: suggestion,
+        Version:    version,
+        Outbox:     []modelPg.OutboxEvent{outbox.SetPoints(ref.MongoAchievementID, points)},
+    }
+    err = s.workflow.Transition(ctx, ref, modelPg.StatusVerified, workflow.Advisor, change)
+    if err != nil {
+        return nil, transitionError(err, "Failed to verify achievement")
+    }
+    s.deliver(ctx, change.Outbox...)
+
+    return &verifiedResult{Points: points, Version: version, Suggestion: suggestion}, nil
 }
 
 
@@ -514,42 +600,260 @@ func (s *AchievementService) VerifyAchievement(c *fiber.Ctx) error {
 // @Param id path string true "Achievement ID (UUID)"
 // @Param request body object{note=string} true "Rejection Note"
 // @Success 200 {object} map[string]string
-// @Failure 400,401,403,500 {object} map[string]interface{}
+// @Failure 400,401,403,404,409,500 {object} map[string]interface{}
 // @Router /achievements/{id}/reject [post]
 func (s *AchievementService) RejectAchievement(c *fiber.Ctx) error {
-    ctx := c.Context()
-    if !middleware.HasPermission(c, "achievement:verify") {
-    return fiber.ErrForbidden
+    achievementID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid achievement ID"})
     }
 
-    achievementID, _ := uuid.Parse(c.Params("id"))
-
-    userID, err := getUserIDFromToken(c)
+    actor, err := s.access.Actor(c)
     if err != nil {
         return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"}) 
     }
 
-    _, err = s.lecturer.GetLecturerByUserID(ctx, userID)
-    if err != nil {
+    lecturerID, ok := actor.LecturerID()
+    if !ok {
         return c.Status(403).JSON(fiber.Map{"error": "User is not a lecturer"}) 
     }
 
     var req struct { Note string `json:"note"` }
-    if err := c.BodyParser(&req); err != nil || req.Note == "" {
+    if err := c.BodyParser(&req); err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "Rejection note is required"})
     }
 
-    err = s.pgRepo.UpdateStatus(ctx, achievementID, "rejected", &userID, req.Note)
-    if err != nil {
-        return c.Status(500).JSON(fiber.Map{"error": "Failed to reject"}) 
+    if err := s.reject(c.Context(), actor, lecturerID, modelPg.BulkRejectItem{ID: achievementID, Note: req.Note}); err != nil {
+        return reviewError(c, err)
     }
 
     return c.JSON(fiber.Map{"status": "success", "message": "Rejected"})
 }
 
+// reject checks and applies one rejection for the single and bulk endpoints.
+func (s *AchievementService) reject(ctx context.Context, actor *policy.Actor, lecturerID uuid.UUID, item modelPg.BulkRejectItem) error {
+    if strings.TrimSpace(item.Note) == "" {
+        return fiber.NewError(400, "Rejection note is required")
+    }
+
+    ref, err := s.pgRepo.GetReferenceByID(ctx, item.ID)
+    if err != nil {
+        return fiber.NewError(404, "Achievement not found")
+    }
+
+    allowed, err := s.access.CanVerify(actor, ref)
+    if err != nil {
+        return fiber.NewError(500, "Failed to check advisee relationship")
+    }
+    if !allowed {
+        return fiber.NewError(403, "Forbidden: This student is not your advisee")
+    }
+
+    change := modelPg.StatusChange{ActorID: actor.UserID, VerifiedBy: &lecturerID, Note: item.Note}
+    err = s.workflow.Transition(ctx, ref, modelPg.StatusRejected, workflow.Advisor, change)
+    if err != nil {
+        return transitionError(err, "Failed to reject")
+    }
+    return nil
+}
+
+// reviewerRole returns the workflow role of an actor that may correct a
+// verified achievement: an admin, or the student's advisor.
+func (s *AchievementService) reviewerRole(actor *policy.Actor, ref modelPg.AchievementReference) (workflow.Role, bool, error) {
+    if actor.IsAdmin {
+        return workflow.Admin, true, nil
+    }
+    allowed, err := s.access.CanVerify(actor, ref)
+    return workflow.Advisor, allowed, err
+}
+
+// RevokeAchievement godoc
+// @Summary Revoke Achievement
+// @Description Revoke a verified achievement, e.g. for a fraudulent certificate (advisor or admin). Its points drop to 0 and it no longer counts in reports.
+// @Tags Achievements
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Achievement ID (UUID)"
+// @Param request body object{reason=string} true "Reason"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400,401,403,404,409,500 {object} map[string]interface{}
+// @Router /achievements/{id}/revoke [post]
+func (s *AchievementService) RevokeAchievement(c *fiber.Ctx) error {
+    ctx := c.Context()
+
+    achievementID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid achievement ID"})
+    }
+
+    actor, err := s.access.Actor(c)
+    if err != nil {
+        return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+    }
+
+    var req struct { Reason string `json:"reason"` }
+    if err := c.BodyParser(&req); err != nil || req.Reason == "" {
+        return c.Status(400).JSON(fiber.Map{"error": "Reason is required"})
+    }
+
+    ref, err := s.pgRepo.GetReferenceByID(ctx, achievementID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"})
+    }
+
+    role, allowed, err := s.reviewerRole(actor, ref)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to check advisee relationship"})
+    }
+    if !allowed {
+        return c.Status(403).JSON(fiber.Map{"error": "Forbidden: Only the advisor or an admin can revoke this achievement"})
+    }
+
+    // Postgres holds the current points; the document may still wait for
+    // an undelivered write.
+    oldPoints, newPoints := ref.Points, 0
+    change := modelPg.StatusChange{
+        ActorID:   actor.UserID,
+        Note:      req.Reason,
+        Points:    &newPoints,
+        OldPoints: &oldPoints,
+        Outbox:    []modelPg.OutboxEvent{outbox.MarkRevoked(ref.MongoAchievementID, time.Now())},
+    }
+    err = s.workflow.Transition(ctx, ref, modelPg.StatusRevoked, role, change)
+    if err != nil {
+        return workflowError(c, err, "Failed to revoke achievement")
+    }
+    s.deliver(ctx, change.Outbox...)
+
+    return c.JSON(fiber.Map{
+        "status":    "success",
+        "message":   "Achievement revoked",
+        "oldPoints": oldPoints,
+        "newPoints": newPoints,
+    })
+}
+
+// AdjustAchievementPoints godoc
+// @Summary Adjust Achievement Points
+// @Description Correct the points of a verified achievement (advisor or admin). The old and new value, reason and actor are kept in the history.
+// @Tags Achievements
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Achievement ID (UUID)"
+// @Param request body object{points=int,reason=string} true "New points and reason"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400,401,403,404,409,500 {object} map[string]interface{}
+// @Router /achievements/{id}/adjust-points [post]
+func (s *AchievementService) AdjustAchievementPoints(c *fiber.Ctx) error {
+    ctx := c.Context()
+
+    achievementID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid achievement ID"})
+    }
+
+    actor, err := s.access.Actor(c)
+    if err != nil {
+        return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+    }
+
+    var req struct {
+        Points int    `json:"points"`
+        Reason string `json:"reason"`
+    }
+    if err := c.BodyParser(&req); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+    }
+    if req.Points <= 0 {
+        return c.Status(400).JSON(fiber.Map{"error": "Points must be greater than 0"})
+    }
+    if req.Reason == "" {
+        return c.Status(400).JSON(fiber.Map{"error": "Reason is required"})
+    }
+
+    ref, err := s.pgRepo.GetReferenceByID(ctx, achievementID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"})
+    }
+
+    role, allowed, err := s.reviewerRole(actor, ref)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to check advisee relationship"})
+    }
+    if !allowed {
+        return c.Status(403).JSON(fiber.Map{"error": "Forbidden: Only the advisor or an admin can adjust points"})
+    }
+
+    oldPoints := ref.Points
+    if oldPoints == req.Points {
+        return c.Status(400).JSON(fiber.Map{"error": "Points are unchanged"})
+    }
+
+    change := modelPg.StatusChange{
+        ActorID:   actor.UserID,
+        Note:      req.Reason,
+        Points:    &req.Points,
+        OldPoints: &oldPoints,
+        Outbox:    []modelPg.OutboxEvent{outbox.SetPoints(ref.MongoAchievementID, req.Points)},
+    }
+    if err := s.workflow.AdjustPoints(ctx, ref, role, change); err != nil {
+        return workflowError(c, err, "Failed to adjust points")
+    }
+    s.deliver(ctx, change.Outbox...)
+
+    return c.JSON(fiber.Map{
+        "status":    "success",
+        "message":   "Points adjusted",
+        "oldPoints": oldPoints,
+        "newPoints": req.Points,
+    })
+}
+
+// ReopenAchievement godoc
+// @Summary Reopen Rejected Achievement
+// @Description Move a rejected achievement back to draft (Student only). Editing a rejected achievement reopens it as a revision instead.
+// @Tags Achievements
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Achievement ID (UUID)"
+// @Success 200 {object} map[string]string
+// @Failure 400,401,403,404,409,500 {object} map[string]interface{}
+// @Router /achievements/{id}/reopen [post]
+func (s *AchievementService) ReopenAchievement(c *fiber.Ctx) error {
+    ctx := c.Context()
+
+    achievementID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+    }
+
+    actor, err := s.access.Actor(c)
+    if err != nil {
+        return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+    }
+
+    ref, err := s.pgRepo.GetReferenceByID(ctx, achievementID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"})
+    }
+
+    if !s.access.CanModifyAchievement(actor, ref) {
+        return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You do not own this data"})
+    }
+
+    err = s.workflow.Transition(ctx, ref, modelPg.StatusDraft, workflow.Owner, modelPg.StatusChange{ActorID: actor.UserID})
+    if err != nil {
+        return workflowError(c, err, "Failed to reopen achievement")
+    }
+
+    return c.JSON(fiber.Map{"status": "success", "message": "Achievement reopened as draft"})
+}
+
 // UpdateAchievement godoc
 // @Summary Update Achievement
-// @Description Update achievement data (Only allowed if status is draft)
+// @Description Update achievement data (draft, revision or rejected; a rejected achievement moves to revision)
 // @Tags Achievements
 // @Security BearerAuth
 // @Accept json
@@ -557,114 +861,99 @@ func (s *AchievementService) RejectAchievement(c *fiber.Ctx) error {
 // @Param id path string true "Achievement ID (UUID)"
 // @Param request body modelMongo.Achievement true "Updated Data"
 // @Success 200 {object} map[string]string
-// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Failure 400,401,403,404,409,500 {object} map[string]interface{}
 // @Router /achievements/{id} [put]
 func (s *AchievementService) UpdateAchievement(c *fiber.Ctx) error {
     ctx := c.Context()
-    if !middleware.HasPermission(c, "achievement:update") {
-    return fiber.ErrForbidden
-    }
 
     achievementID, err := uuid.Parse(c.Params("id"))
     if err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"}) 
     }
 
-    userID, err := getUserIDFromToken(c)
+    actor, err := s.access.Actor(c)
     if err != nil {
         return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"}) 
     }
 
-    studentID, err := s.pgRepo.GetStudentByUserID(ctx, userID)
-    if err != nil {
-        return c.Status(404).JSON(fiber.Map{"error": "Student profile not found"}) 
-    }
-
     ref, err := s.pgRepo.GetReferenceByID(ctx, achievementID)
     if err != nil {
         return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"}) 
     }
 
-    if ref.StudentID != studentID {
+    if !s.access.CanModifyAchievement(actor, ref) {
         return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You do not own this data"})
     }
 
-    if ref.Status != "draft" {
-        return c.Status(400).JSON(fiber.Map{"error": "Only draft achievements can be updated"})
-    }
-
     var req modelMongo.Achievement
     if err := c.BodyParser(&req); err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid body","details": err.Error(),})
     }
 
+    if errs := s.types.Validate(&req); errs != nil {
+        return validationError(c, errs)
+    }
+
+    status, err := workflow.EditStatus(ref)
+    if err != nil {
+        return workflowError(c, err, "Failed to update achievement")
+    }
+
+    req.UpdatedBy = actor.UserID.String()
     err = s.mongoRepo.UpdateOne(ctx, ref.MongoAchievementID, req)
     if err != nil {
         return c.Status(500).JSON(fiber.Map{"error": "Failed to update achievement"})
     }
 
-    return c.JSON(fiber.Map{"message": "Achievement updated successfully"})
+    if err := s.workflow.FinishEdit(ctx, ref, actor.UserID); err != nil {
+        return workflowError(c, err, "Failed to reopen achievement")
+    }
+
+    return c.JSON(fiber.Map{"message": "Achievement updated successfully", "status": status})
 }
 
 // GetAchievementHistory godoc
 // @Summary Get Achievement History
-// @Description Get status history log of an achievement
+// @Description Get every status transition of an achievement, oldest first, with actor and note
 // @Tags Achievements
 // @Security BearerAuth
 // @Produce json
 // @Param id path string true "Achievement ID (UUID)"
-// @Success 200 {array} map[string]interface{}
-// @Failure 400,401,404 {object} map[string]interface{}
+// @Success 200 {array} modelPg.AchievementStatusHistory
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
 // @Router /achievements/{id}/history [get]
 func (s *AchievementService) GetAchievementHistory(c *fiber.Ctx) error {
     ctx := c.Context()
-    if !middleware.HasPermission(c, "achievement:read") {
-    return fiber.ErrForbidden
-    }
 
     achievementID, err := uuid.Parse(c.Params("id"))
     if err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"}) 
     }
 
+    actor, err := s.access.Actor(c)
+    if err != nil {
+        return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+    }
+
     ref, err := s.pgRepo.GetReferenceByID(ctx, achievementID)
     if err != nil {
         return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"}) 
     }
 
-    var history []map[string]interface{}
-
-    history = append(history, map[string]interface{}{
-        "status":    "created",
-        "timestamp": ref.CreatedAt,
-        "note":      "Achievement draft created",
-    })
-
-    if ref.SubmittedAt != nil {
-        history = append(history, map[string]interface{}{
-            "status":    "submitted",
-            "timestamp": ref.SubmittedAt,
-            "note":      "Submitted for verification",
-        })
+    allowed, err := s.access.CanViewAchievement(actor, ref)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to check advisee relationship"})
+    }
+    if !allowed {
+        return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You cannot view this achievement"})
     }
 
-    if ref.VerifiedAt != nil {
-        action := "verified"
-        if ref.Status == "rejected" {
-            action = "rejected"
-        }
-        
-        item := map[string]interface{}{
-            "status":    action,
-            "timestamp": ref.VerifiedAt,
-            "by":        ref.VerifiedBy,
-        }
-
-        if ref.RejectionNote != nil && *ref.RejectionNote != "" {
-            item["note"] = *ref.RejectionNote
-        }
-
-        history = append(history, item)
+    history, err := s.pgRepo.GetStatusHistory(ctx, achievementID)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch achievement history"})
+    }
+    if history == nil {
+        history = []modelPg.AchievementStatusHistory{}
     }
 
     return c.JSON(history)
@@ -672,7 +961,7 @@ func (s *AchievementService) GetAchievementHistory(c *fiber.Ctx) error {
 
 // UploadAttachments godoc
 // @Summary Upload Attachment
-// @Description Upload a file attachment for an achievement (Draft only)
+// @Description Upload a file attachment for an achievement (draft, revision or rejected)
 // @Tags Achievements
 // @Security BearerAuth
 // @Accept multipart/form-data
@@ -680,46 +969,38 @@ func (s *AchievementService) GetAchievementHistory(c *fiber.Ctx) error {
 // @Param id path string true "Achievement ID (UUID)"
 // @Param file formData file true "File to upload"
 // @Success 200 {object} map[string]interface{}
-// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Failure 400,401,403,404,409,500 {object} map[string]interface{}
 // @Router /achievements/{id}/attachments [post]
 func (s *AchievementService) UploadAttachments(c *fiber.Ctx) error {
     ctx := c.Context()
-    if !middleware.HasPermission(c, "achievement:create") {
-    return fiber.ErrForbidden
-    }
 
     achievementID, err := uuid.Parse(c.Params("id"))
     if err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"}) 
     }
 
-    userID, err := getUserIDFromToken(c)
+    actor, err := s.access.Actor(c)
     if err != nil {
         return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"}) 
     }
 
-    studentID, err := s.pgRepo.GetStudentByUserID(ctx, userID)
-    if err != nil {
-        return c.Status(404).JSON(fiber.Map{"error": "Student profile not found"}) 
-    }
-
     ref, err := s.pgRepo.GetReferenceByID(ctx, achievementID)
     if err != nil {
         return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"}) 
     }
 
-    if ref.StudentID != studentID {
+    if !s.access.CanModifyAchievement(actor, ref) {
         return c.Status(403).JSON(fiber.Map{"error": "Forbidden"})
     }
-    if ref.Status != "draft" {
-        return c.Status(400).JSON(fiber.Map{"error": "Cannot upload files to submitted/verified achievements"})
-    }
-
     file, err := c.FormFile("file")
     if err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "No file uploaded"})
     }
 
+    if _, err := workflow.EditStatus(ref); err != nil {
+        return workflowError(c, err, "Failed to upload file")
+    }
+
     uploadDir := "./uploads"
     if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
         os.Mkdir(uploadDir, 0755)
@@ -743,9 +1024,14 @@ func (s *AchievementService) UploadAttachments(c *fiber.Ctx) error {
 
     err = s.mongoRepo.AddAttachment(ctx, ref.MongoAchievementID, attachment)
     if err != nil {
+        os.Remove(filePath)
         return c.Status(500).JSON(fiber.Map{"error": "Failed to update database info", "details": err.Error()})
     }
 
+    if err := s.workflow.FinishEdit(ctx, ref, actor.UserID); err != nil {
+        return workflowError(c, err, "Failed to reopen achievement")
+    }
+
     return c.JSON(fiber.Map{
         "message": "File uploaded successfully", 
         "data": attachment,
@@ -0,0 +1,145 @@
+package service
+
+import (
+    "errors"
+    "time"
+    modelMongo "student-performance-report/app/models/mongodb"
+    modelPg "student-performance-report/app/models/postgresql"
+    "student-performance-report/app/service/outbox"
+    "student-performance-report/app/service/workflow"
+    "github.com/gofiber/fiber/v2"
+    "github.com/google/uuid"
+    "go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetStudentTrash godoc
+// @Summary List Deleted Achievements
+// @Description List a student's deleted achievements, most recently deleted first, with when each is purged for good. Only the student and admins may see the trash.
+// @Tags Achievements
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Student ID (UUID)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400,401,403,500 {object} map[string]interface{}
+// @Router /students/{id}/trash [get]
+func (s *AchievementService) GetStudentTrash(c *fiber.Ctx) error {
+    ctx := c.Context()
+
+    studentID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid student ID"})
+    }
+
+    actor, err := s.access.Actor(c)
+    if err != nil {
+        return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+    }
+
+    if !s.access.CanAccessTrash(actor, studentID) {
+        return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You cannot view this trash"})
+    }
+
+    refs, err := s.pgRepo.GetTrash(ctx, studentID)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch deleted achievements"})
+    }
+
+    detailMap := make(map[string]modelMongo.Achievement, len(refs))
+    if len(refs) > 0 {
+        var mongoIDs []string
+        for _, r := range refs {
+            mongoIDs = append(mongoIDs, r.MongoAchievementID)
+        }
+        details, err := s.mongoRepo.FindAllDetails(ctx, mongoIDs)
+        if err != nil {
+            return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch achievement details"})
+        }
+        for _, d := range details {
+            detailMap[d.ID.Hex()] = d
+        }
+    }
+
+    now := time.Now()
+    data := make([]interface{}, 0, len(refs))
+    for _, ref := range refs {
+        item := map[string]interface{}{
+            "id":         ref.ID,
+            "createdAt":  ref.CreatedAt,
+            "deletedAt":  ref.DeletedAt,
+            "deletedBy":  ref.DeletedBy,
+            "restorable": false,
+        }
+        if ref.DeletedAt != nil {
+            purgeAt := s.trash.ExpiresAt(*ref.DeletedAt)
+            item["purgeAt"] = purgeAt
+            item["restorable"] = now.Before(purgeAt)
+        }
+        if d, ok := detailMap[ref.MongoAchievementID]; ok {
+            item["title"] = d.Title
+            item["type"] = d.AchievementType
+        } else {
+            item["detailsMissing"] = true
+            item["restorable"] = false
+        }
+        data = append(data, item)
+    }
+
+    return c.JSON(fiber.Map{"data": data})
+}
+
+// RestoreAchievement godoc
+// @Summary Restore Achievement
+// @Description Take a deleted achievement out of the trash as a draft. Allowed for the owning student or an admin until the retention window has passed.
+// @Tags Achievements
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Achievement ID (UUID)"
+// @Success 200 {object} map[string]string
+// @Failure 400,401,403,404,409,410,500 {object} map[string]interface{}
+// @Router /achievements/{id}/restore [post]
+func (s *AchievementService) RestoreAchievement(c *fiber.Ctx) error {
+    ctx := c.Context()
+
+    achievementID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+    }
+
+    actor, err := s.access.Actor(c)
+    if err != nil {
+        return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+    }
+
+    ref, err := s.pgRepo.GetDeletedReference(ctx, achievementID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "Achievement not found in trash"})
+    }
+
+    role := workflow.Owner
+    if !s.access.CanModifyAchievement(actor, ref) {
+        if !actor.IsAdmin {
+            return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You do not own this data"})
+        }
+        role = workflow.Admin
+    }
+
+    if ref.DeletedAt == nil || !time.Now().Before(s.trash.ExpiresAt(*ref.DeletedAt)) {
+        return c.Status(410).JSON(fiber.Map{"error": "The retention window for this achievement has passed"})
+    }
+
+    if _, err := s.mongoRepo.FindOne(ctx, ref.MongoAchievementID); err != nil {
+        if errors.Is(err, mongo.ErrNoDocuments) {
+            return c.Status(410).JSON(fiber.Map{"error": "Achievement details are no longer available"})
+        }
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch achievement details"})
+    }
+
+    change := modelPg.StatusChange{ActorID: actor.UserID, Outbox: []modelPg.OutboxEvent{outbox.RestoreDetails(ref.MongoAchievementID)}}
+    err = s.workflow.Transition(ctx, ref, modelPg.StatusDraft, role, change)
+    if err != nil {
+        return workflowError(c, err, "Failed to restore achievement")
+    }
+    s.deliver(ctx, change.Outbox...)
+
+    return c.JSON(fiber.Map{"status": "success", "message": "Achievement restored as draft"})
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "sort"
+    "strconv"
+    modelMongo "student-performance-report/app/models/mongodb"
+    repoMongo "student-performance-report/app/repository/mongodb"
+    "github.com/gofiber/fiber/v2"
+    "github.com/google/uuid"
+)
+
+// GetAchievementVersions godoc
+// @Summary List Achievement Versions
+// @Description List every stored snapshot of an achievement, oldest first. A new version is stored on create, update and attachment upload.
+// @Tags Achievements
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Achievement ID (UUID)"
+// @Success 200 {array} modelMongo.AchievementVersion
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /achievements/{id}/versions [get]
+func (s *AchievementService) GetAchievementVersions(c *fiber.Ctx) error {
+    ctx := c.Context()
+
+    achievementID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+    }
+
+    actor, err := s.access.Actor(c)
+    if err != nil {
+        return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+    }
+
+    ref, err := s.pgRepo.GetReferenceByID(ctx, achievementID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"})
+    }
+
+    allowed, err := s.access.CanViewAchievement(actor, ref)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to check advisee relationship"})
+    }
+    if !allowed {
+        return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You cannot view this achievement"})
+    }
+
+    versions, err := s.mongoRepo.ListVersions(ctx, ref.MongoAchievementID)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch achievement versions"})
+    }
+    if versions == nil {
+        versions = []modelMongo.AchievementVersion{}
+    }
+
+    return c.JSON(versions)
+}
+
+// GetAchievementVersion godoc
+// @Summary Get Achievement Version
+// @Description Get one stored snapshot of an achievement
+// @Tags Achievements
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Achievement ID (UUID)"
+// @Param v path int true "Version number"
+// @Success 200 {object} modelMongo.AchievementVersion
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /achievements/{id}/versions/{v} [get]
+func (s *AchievementService) GetAchievementVersion(c *fiber.Ctx) error {
+    ctx := c.Context()
+
+    achievementID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+    }
+
+    version, err := strconv.Atoi(c.Params("v"))
+    if err != nil || version <= 0 {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid version"})
+    }
+
+    actor, err := s.access.Actor(c)
+    if err != nil {
+        return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+    }
+
+    ref, err := s.pgRepo.GetReferenceByID(ctx, achievementID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"})
+    }
+
+    allowed, err := s.access.CanViewAchievement(actor, ref)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to check advisee relationship"})
+    }
+    if !allowed {
+        return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You cannot view this achievement"})
+    }
+
+    v, err := s.mongoRepo.GetVersion(ctx, ref.MongoAchievementID, version)
+    if errors.Is(err, repoMongo.ErrVersionNotFound) {
+        return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+    }
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch achievement version"})
+    }
+
+    return c.JSON(v)
+}
+
+// DiffAchievementVersions godoc
+// @Summary Compare Achievement Versions
+// @Description List the fields that differ between two versions, e.g. what a student changed after a rejection
+// @Tags Achievements
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Achievement ID (UUID)"
+// @Param from query int true "Older version"
+// @Param to query int true "Newer version"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /achievements/{id}/versions/diff [get]
+func (s *AchievementService) DiffAchievementVersions(c *fiber.Ctx) error {
+    ctx := c.Context()
+
+    achievementID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+    }
+
+    from, to := c.QueryInt("from"), c.QueryInt("to")
+    if from <= 0 || to <= 0 {
+        return c.Status(400).JSON(fiber.Map{"error": "Query parameters from and to must be version numbers"})
+    }
+
+    actor, err := s.access.Actor(c)
+    if err != nil {
+        return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+    }
+
+    ref, err := s.pgRepo.GetReferenceByID(ctx, achievementID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "Achievement not found"})
+    }
+
+    allowed, err := s.access.CanViewAchievement(actor, ref)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to check advisee relationship"})
+    }
+    if !allowed {
+        return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You cannot view this achievement"})
+    }
+
+    snapshots := make([]*modelMongo.AchievementVersion, 2)
+    for i, version := range []int{from, to} {
+        snapshots[i], err = s.mongoRepo.GetVersion(ctx, ref.MongoAchievementID, version)
+        if errors.Is(err, repoMongo.ErrVersionNotFound) {
+            return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf("Version %d not found", version)})
+        }
+        if err != nil {
+            return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch achievement version"})
+        }
+    }
+
+    return c.JSON(fiber.Map{
+        "from":    from,
+        "to":      to,
+        "changes": diffSnapshots(snapshots[0].Snapshot, snapshots[1].Snapshot),
+    })
+}
+
+// Bookkeeping fields that change with every version and are not content.
+var unversionedFields = map[string]bool{
+    "id": true, "version": true, "createdAt": true, "updatedAt": true, "updatedBy": true,
+}
+
+// diffSnapshots compares two snapshots field by field, by JSON path.
+func diffSnapshots(a, b modelMongo.Achievement) []modelMongo.FieldChange {
+    before, after := flattenSnapshot(a), flattenSnapshot(b)
+
+    fields := make(map[string]bool, len(before)+len(after))
+    for f := range before {
+        fields[f] = true
+    }
+    for f := range after {
+        fields[f] = true
+    }
+
+    changes := []modelMongo.FieldChange{}
+    for f := range fields {
+        if !reflect.DeepEqual(before[f], after[f]) {
+            changes = append(changes, modelMongo.FieldChange{Field: f, From: before[f], To: after[f]})
+        }
+    }
+    sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+    return changes
+}
+
+func flattenSnapshot(a modelMongo.Achievement) map[string]interface{} {
+    raw, _ := json.Marshal(a)
+    var doc map[string]interface{}
+    _ = json.Unmarshal(raw, &doc)
+
+    out := make(map[string]interface{})
+    for k, v := range doc {
+        if !unversionedFields[k] {
+            flatten(k, v, out)
+        }
+    }
+    return out
+}
+
+// zeroTime is how an unset date in details serialises.
+const zeroTime = "0001-01-01T00:00:00Z"
+
+func flatten(path string, v interface{}, out map[string]interface{}) {
+    switch val := v.(type) {
+    case map[string]interface{}:
+        for k, item := range val {
+            flatten(path+"."+k, item, out)
+        }
+    case []interface{}:
+        for i, item := range val {
+            flatten(fmt.Sprintf("%s[%d]", path, i), item, out)
+        }
+    case nil:
+    default:
+        if val != zeroTime {
+            out[path] = val
+        }
+    }
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+	modelMongo "student-performance-report/app/models/mongodb"
+	modelPg "student-performance-report/app/models/postgresql"
+	repoMongo "student-performance-report/app/repository/mongodb"
+	repoPg "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/policy"
+	"student-performance-report/app/service/schema"
+	"student-performance-report/app/service/workflow"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const maxCommentLength = 2000
+
+// CommentService handles the discussion on an achievement. Whoever may view
+// the achievement (its owner, the owner's advisor and admins) may read and
+// post comments.
+type CommentService struct {
+	comments     repoPg.CommentRepository
+	pgRepo       repoPg.AchievementRepoPostgres
+	mongoRepo    repoMongo.AchievementRepository
+	access       *policy.AccessPolicy
+	types        *schema.Registry
+	editWindow   time.Duration
+	deleteWindow time.Duration
+}
+
+func NewCommentService(cr repoPg.CommentRepository, p repoPg.AchievementRepoPostgres, m repoMongo.AchievementRepository, a *policy.AccessPolicy, editWindow, deleteWindow time.Duration) *CommentService {
+	return &CommentService{comments: cr, pgRepo: p, mongoRepo: m, access: a, types: schema.Default(), editWindow: editWindow, deleteWindow: deleteWindow}
+}
+
+// viewable loads the achievement of the request and checks the caller may
+// see it; on failure the returned error is a *fiber.Error to answer with.
+func (s *CommentService) viewable(c *fiber.Ctx) (*policy.Actor, modelPg.AchievementReference, error) {
+	var ref modelPg.AchievementReference
+
+	achievementID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, ref, fiber.NewError(400, "Invalid ID")
+	}
+
+	actor, err := s.access.Actor(c)
+	if err != nil {
+		return nil, ref, fiber.NewError(401, "Unauthorized")
+	}
+
+	ref, err = s.pgRepo.GetReferenceByID(c.Context(), achievementID)
+	if err != nil {
+		return nil, ref, fiber.NewError(404, "Achievement not found")
+	}
+
+	allowed, err := s.access.CanViewAchievement(actor, ref)
+	if err != nil {
+		return nil, ref, fiber.NewError(500, "Failed to check advisee relationship")
+	}
+	if !allowed {
+		return nil, ref, fiber.NewError(403, "Forbidden: You cannot view this achievement")
+	}
+	return actor, ref, nil
+}
+
+// ownComment loads a comment of the achievement and checks the caller wrote
+// it within window; admins skip both checks when adminOverride is set.
+func (s *CommentService) ownComment(c *fiber.Ctx, actor *policy.Actor, ref modelPg.AchievementReference, window time.Duration, adminOverride bool) (*modelPg.AchievementComment, error) {
+	commentID, err := uuid.Parse(c.Params("commentId"))
+	if err != nil {
+		return nil, fiber.NewError(400, "Invalid comment ID")
+	}
+
+	comment, err := s.comments.GetByID(c.Context(), commentID)
+	if errors.Is(err, repoPg.ErrCommentNotFound) || (err == nil && comment.AchievementRefID != ref.ID) {
+		return nil, fiber.NewError(404, "Comment not found")
+	}
+	if err != nil {
+		return nil, fiber.NewError(500, "Failed to fetch comment")
+	}
+
+	if adminOverride && actor.IsAdmin {
+		return comment, nil
+	}
+	if comment.AuthorID == nil || *comment.AuthorID != actor.UserID {
+		return nil, fiber.NewError(403, "Forbidden: You can only change your own comments")
+	}
+	if time.Since(comment.CreatedAt) > window {
+		return nil, fiber.NewError(403, "The time to change this comment has passed")
+	}
+	return comment, nil
+}
+
+func fiberError(c *fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+}
+
+// commentRole is the caller's relation to an achievement it may view.
+func commentRole(actor *policy.Actor, ref modelPg.AchievementReference) workflow.Role {
+	if actor.IsAdmin {
+		return workflow.Admin
+	}
+	if sid, ok := actor.StudentID(); ok && sid == ref.StudentID {
+		return workflow.Owner
+	}
+	return workflow.Advisor
+}
+
+// validCommentField accepts the top-level content fields, "details.<field>"
+// for a details field of the achievement's type and "customFields.<key>".
+func (s *CommentService) validCommentField(achievementType, field string) bool {
+	switch field {
+	case "title", "description", "tags":
+		return true
+	}
+	if name, ok := strings.CutPrefix(field, "details."); ok {
+		t, known := s.types.Get(achievementType)
+		_, listed := t.Fields[name]
+		return known && listed
+	}
+	key, ok := strings.CutPrefix(field, "customFields.")
+	return ok && key != ""
+}
+
+func parseCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", errors.New("Comment body is required")
+	}
+	if len([]rune(body)) > maxCommentLength {
+		return "", errors.New("Comment body is too long")
+	}
+	return body, nil
+}
+
+// GetComments godoc
+// @Summary List Achievement Comments
+// @Description List the discussion on an achievement, oldest first (owner, advisor or admin). Replies carry the parentId of the comment they answer, so the list can be shown as threads.
+// @Tags Comments
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Achievement ID (UUID)"
+// @Success 200 {array} modelPg.AchievementComment
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /achievements/{id}/comments [get]
+func (s *CommentService) GetComments(c *fiber.Ctx) error {
+	_, ref, err := s.viewable(c)
+	if err != nil {
+		return fiberError(c, err)
+	}
+
+	comments, err := s.comments.ListByAchievement(c.Context(), ref.ID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch comments"})
+	}
+	if comments == nil {
+		comments = []modelPg.AchievementComment{}
+	}
+	return c.JSON(comments)
+}
+
+// CreateComment godoc
+// @Summary Post Achievement Comment
+// @Description Comment on an achievement, optionally about one details field (e.g. "details.rank") or one attachment (its fileUrl). Set parentId to reply to a comment of the same achievement.
+// @Tags Comments
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Achievement ID (UUID)"
+// @Param request body modelPg.CommentRequest true "Comment"
+// @Success 201 {object} modelPg.AchievementComment
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /achievements/{id}/comments [post]
+func (s *CommentService) CreateComment(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	actor, ref, err := s.viewable(c)
+	if err != nil {
+		return fiberError(c, err)
+	}
+
+	var req modelPg.CommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	body, err := parseCommentBody(req.Body)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if req.ParentID != nil {
+		parent, err := s.comments.GetByID(ctx, *req.ParentID)
+		if errors.Is(err, repoPg.ErrCommentNotFound) || (err == nil && parent.AchievementRefID != ref.ID) {
+			return c.Status(400).JSON(fiber.Map{"error": "Parent comment not found on this achievement"})
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch parent comment"})
+		}
+	}
+	var detail *modelMongo.Achievement
+	if req.Field != nil || req.Attachment != nil {
+		detail, err = s.mongoRepo.FindOne(ctx, ref.MongoAchievementID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch achievement details"})
+		}
+	}
+	if req.Field != nil && !s.validCommentField(detail.AchievementType, *req.Field) {
+		return c.Status(400).JSON(fiber.Map{"error": "Unknown field: " + *req.Field})
+	}
+	if req.Attachment != nil {
+		found := false
+		for _, a := range detail.Attachments {
+			if a.FileURL == *req.Attachment {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return c.Status(400).JSON(fiber.Map{"error": "Attachment not found on this achievement"})
+		}
+	}
+
+	comment := modelPg.AchievementComment{
+		ID:               uuid.New(),
+		AchievementRefID: ref.ID,
+		ParentID:         req.ParentID,
+		AuthorID:         &actor.UserID,
+		AuthorRole:       commentRole(actor, ref).String(),
+		Body:             body,
+		Field:            req.Field,
+		Attachment:       req.Attachment,
+		CreatedAt:        time.Now(),
+	}
+	if err := s.comments.Create(ctx, &comment); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to save comment"})
+	}
+	return c.Status(201).JSON(comment)
+}
+
+// UpdateComment godoc
+// @Summary Edit Achievement Comment
+// @Description Edit the body of your own comment within the edit window
+// @Tags Comments
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Achievement ID (UUID)"
+// @Param commentId path string true "Comment ID (UUID)"
+// @Param request body object{body=string} true "New body"
+// @Success 200 {object} map[string]string
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /achievements/{id}/comments/{commentId} [put]
+func (s *CommentService) UpdateComment(c *fiber.Ctx) error {
+	actor, ref, err := s.viewable(c)
+	if err != nil {
+		return fiberError(c, err)
+	}
+
+	comment, err := s.ownComment(c, actor, ref, s.editWindow, false)
+	if err != nil {
+		return fiberError(c, err)
+	}
+
+	var req struct {
+		Body string `json:"body"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	body, err := parseCommentBody(req.Body)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := s.comments.Update(c.Context(), comment.ID, body); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to update comment"})
+	}
+	return c.JSON(fiber.Map{"message": "Comment updated"})
+}
+
+// DeleteComment godoc
+// @Summary Delete Achievement Comment
+// @Description Delete your own comment within the delete window; admins may delete any comment
+// @Tags Comments
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Achievement ID (UUID)"
+// @Param commentId path string true "Comment ID (UUID)"
+// @Success 200 {object} map[string]string
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /achievements/{id}/comments/{commentId} [delete]
+func (s *CommentService) DeleteComment(c *fiber.Ctx) error {
+	actor, ref, err := s.viewable(c)
+	if err != nil {
+		return fiberError(c, err)
+	}
+
+	comment, err := s.ownComment(c, actor, ref, s.deleteWindow, true)
+	if err != nil {
+		return fiberError(c, err)
+	}
+
+	if err := s.comments.Delete(c.Context(), comment.ID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete comment"})
+	}
+	return c.JSON(fiber.Map{"message": "Comment deleted"})
+}
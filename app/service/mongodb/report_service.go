@@ -5,16 +5,17 @@ import (
     "github.com/google/uuid"
     repoMongo "student-performance-report/app/repository/mongodb"
     repoPg "student-performance-report/app/repository/postgresql"
-    "student-performance-report/middleware"
+    "student-performance-report/app/service/policy"
 )
 
 type ReportService struct {
     mongoRepo   repoMongo.AchievementRepository
     studentRepo repoPg.StudentRepository
+    access      *policy.AccessPolicy
 }
 
-func NewReportService(m repoMongo.AchievementRepository, s repoPg.StudentRepository) *ReportService {
-    return &ReportService{mongoRepo: m, studentRepo: s}
+func NewReportService(m repoMongo.AchievementRepository, s repoPg.StudentRepository, p *policy.AccessPolicy) *ReportService {
+    return &ReportService{mongoRepo: m, studentRepo: s, access: p}
 }
 
 // GetStatistics godoc
@@ -28,9 +29,6 @@ func NewReportService(m repoMongo.AchievementRepository, s repoPg.StudentReposit
 // @Router /reports/statistics [get]
 func (s *ReportService) GetStatistics(c *fiber.Ctx) error {
     ctx := c.Context()
-    if !middleware.HasPermission(c, "report:students") {
-    return fiber.ErrForbidden
-    }
     stats, err := s.mongoRepo.GetGlobalStats(ctx)
     if err != nil {
         return c.Status(500).JSON(fiber.Map{"error": "Failed to generate stats"})
@@ -57,29 +55,39 @@ func (s *ReportService) GetStatistics(c *fiber.Ctx) error {
 
 // GetStudentReport godoc
 // @Summary Get Student Report
-// @Description Get specific statistics for a student
+// @Description Get specific statistics for a student (own report, advisee, or any for admin)
 // @Tags Reports
 // @Security BearerAuth
 // @Produce json
 // @Param id path string true "Student UUID"
 // @Success 200 {object} map[string]interface{}
-// @Failure 400,500 {object} map[string]interface{}
+// @Failure 400,401,403,500 {object} map[string]interface{}
 // @Router /reports/student/{id} [get]
 func (s *ReportService) GetStudentReport(c *fiber.Ctx) error {
     ctx := c.Context()
-    if !middleware.HasPermission(c, "report:students") {
-    return fiber.ErrForbidden
-    }
     targetStudentID := c.Params("id")
 
-    stats, err := s.mongoRepo.GetStudentStats(ctx, targetStudentID)
+    studentUUID, err := uuid.Parse(targetStudentID)
     if err != nil {
-        return c.Status(500).JSON(fiber.Map{"error": "Failed to get student stats"})
+         return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
     }
 
-    studentUUID, err := uuid.Parse(targetStudentID)
+    actor, err := s.access.Actor(c)
     if err != nil {
-         return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID"})
+        return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    allowed, err := s.access.CanAccessStudent(actor, studentUUID)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to check student access"})
+    }
+    if !allowed {
+        return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You cannot view this student's report"})
+    }
+
+    stats, err := s.mongoRepo.GetStudentStats(ctx, targetStudentID)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": "Failed to get student stats"})
     }
 
     studentProfile, err := s.studentRepo.GetStudentByID(ctx, studentUUID) 
@@ -0,0 +1,197 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+	modelMongo "student-performance-report/app/models/mongodb"
+	modelPg "student-performance-report/app/models/postgresql"
+	repoMongo "student-performance-report/app/repository/mongodb"
+	repoPg "student-performance-report/app/repository/postgresql"
+	"github.com/google/uuid"
+)
+
+// CreateDetails is the event that inserts the details document. The document
+// must carry its ID, since the reference stores it before the insert.
+func CreateDetails(a modelMongo.Achievement) (modelPg.OutboxEvent, error) {
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return modelPg.OutboxEvent{}, err
+	}
+	return modelPg.OutboxEvent{ID: uuid.New(), MongoAchievementID: a.ID.Hex(), Kind: modelPg.OutboxCreateDetails, Payload: payload}, nil
+}
+
+func SetPoints(mongoID string, points int) modelPg.OutboxEvent {
+	payload, _ := json.Marshal(pointsPayload{Points: points})
+	return modelPg.OutboxEvent{ID: uuid.New(), MongoAchievementID: mongoID, Kind: modelPg.OutboxSetPoints, Payload: payload}
+}
+
+// TrashDetails marks the details document deleted; it stays in Mongo until
+// the trash is purged.
+func TrashDetails(mongoID string, deletedBy uuid.UUID, deletedAt time.Time) modelPg.OutboxEvent {
+	payload, _ := json.Marshal(trashPayload{DeletedBy: deletedBy.String(), DeletedAt: deletedAt})
+	return modelPg.OutboxEvent{ID: uuid.New(), MongoAchievementID: mongoID, Kind: modelPg.OutboxTrashDetails, Payload: payload}
+}
+
+func RestoreDetails(mongoID string) modelPg.OutboxEvent {
+	return modelPg.OutboxEvent{ID: uuid.New(), MongoAchievementID: mongoID, Kind: modelPg.OutboxRestoreDetails}
+}
+
+// MarkRevoked sets the document's points to 0 and stamps when it was revoked.
+func MarkRevoked(mongoID string, revokedAt time.Time) modelPg.OutboxEvent {
+	payload, _ := json.Marshal(revokePayload{RevokedAt: revokedAt})
+	return modelPg.OutboxEvent{ID: uuid.New(), MongoAchievementID: mongoID, Kind: modelPg.OutboxMarkRevoked, Payload: payload}
+}
+
+type pointsPayload struct {
+	Points int `json:"points"`
+}
+
+type trashPayload struct {
+	DeletedBy string    `json:"deletedBy"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+type revokePayload struct {
+	RevokedAt time.Time `json:"revokedAt"`
+}
+
+// Relay applies outbox events to Mongo. Every write is idempotent, so an
+// event delivered twice, by the request that stored it and by the
+// background run, does no harm.
+type Relay struct {
+	outbox    repoPg.OutboxRepository
+	mongoRepo repoMongo.AchievementRepository
+	retryBase time.Duration
+	retryMax  time.Duration
+}
+
+func NewRelay(o repoPg.OutboxRepository, m repoMongo.AchievementRepository, retryBase, retryMax time.Duration) *Relay {
+	return &Relay{outbox: o, mongoRepo: m, retryBase: retryBase, retryMax: retryMax}
+}
+
+// Deliver applies the events in order and settles each one. A failed event
+// is scheduled for a retry, and an event whose achievement still has an
+// earlier undelivered event is left to the background run so it cannot
+// overtake it; the returned error is the first failure.
+func (r *Relay) Deliver(ctx context.Context, events ...modelPg.OutboxEvent) error {
+	var first error
+	for _, e := range events {
+		if err := r.deliverInOrder(ctx, e); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (r *Relay) deliverInOrder(ctx context.Context, e modelPg.OutboxEvent) error {
+	blocked, err := r.outbox.Blocked(ctx, e.ID)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", e.Kind, e.MongoAchievementID, err)
+	}
+	if blocked {
+		return fmt.Errorf("%s %s: waiting for an earlier event", e.Kind, e.MongoAchievementID)
+	}
+	return r.deliver(ctx, e)
+}
+
+func (r *Relay) deliver(ctx context.Context, e modelPg.OutboxEvent) error {
+	if err := r.apply(ctx, e); err != nil {
+		if markErr := r.outbox.MarkFailed(ctx, e.ID, err.Error(), time.Now().Add(r.backoff(e.Attempts))); markErr != nil {
+			log.Printf("outbox: recording failure of %s: %v", e.ID, markErr)
+		}
+		return fmt.Errorf("%s %s: %w", e.Kind, e.MongoAchievementID, err)
+	}
+	return r.outbox.MarkDelivered(ctx, e.ID)
+}
+
+func (r *Relay) apply(ctx context.Context, e modelPg.OutboxEvent) error {
+	switch e.Kind {
+	case modelPg.OutboxCreateDetails:
+		var a modelMongo.Achievement
+		if err := json.Unmarshal(e.Payload, &a); err != nil {
+			return err
+		}
+		_, err := r.mongoRepo.InsertOne(ctx, a)
+		return err
+	case modelPg.OutboxSetPoints:
+		var p pointsPayload
+		if err := json.Unmarshal(e.Payload, &p); err != nil {
+			return err
+		}
+		return r.mongoRepo.UpdatePoints(ctx, e.MongoAchievementID, p.Points)
+	case modelPg.OutboxTrashDetails:
+		// Events of the former delete_details kind carry no payload.
+		var p trashPayload
+		if len(e.Payload) > 0 {
+			if err := json.Unmarshal(e.Payload, &p); err != nil {
+				return err
+			}
+		}
+		if p.DeletedAt.IsZero() {
+			p.DeletedAt = e.CreatedAt
+		}
+		return r.mongoRepo.MarkDeleted(ctx, e.MongoAchievementID, p.DeletedBy, p.DeletedAt)
+	case modelPg.OutboxRestoreDetails:
+		return r.mongoRepo.ClearDeleted(ctx, e.MongoAchievementID)
+	case modelPg.OutboxMarkRevoked:
+		var p revokePayload
+		if err := json.Unmarshal(e.Payload, &p); err != nil {
+			return err
+		}
+		if p.RevokedAt.IsZero() {
+			p.RevokedAt = e.CreatedAt
+		}
+		return r.mongoRepo.MarkRevoked(ctx, e.MongoAchievementID, p.RevokedAt)
+	}
+	return fmt.Errorf("unknown outbox event kind %q", e.Kind)
+}
+
+// backoff doubles the wait after every failed attempt, up to retryMax.
+func (r *Relay) backoff(attempts int) time.Duration {
+	wait := r.retryBase
+	for i := 0; i < attempts && wait < r.retryMax; i++ {
+		wait *= 2
+	}
+	if wait > r.retryMax {
+		wait = r.retryMax
+	}
+	return wait
+}
+
+// RunOnce delivers up to batch due events and reports how many succeeded
+// and failed.
+func (r *Relay) RunOnce(ctx context.Context, batch int) (delivered, failed int, err error) {
+	events, err := r.outbox.Due(ctx, batch)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, e := range events {
+		if err := r.deliver(ctx, e); err != nil {
+			log.Printf("outbox: %v", err)
+			failed++
+			continue
+		}
+		delivered++
+	}
+	return delivered, failed, nil
+}
+
+// Run delivers due events every interval until ctx is done.
+func (r *Relay) Run(ctx context.Context, interval time.Duration, batch int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, _, err := r.RunOnce(ctx, batch); err != nil {
+			log.Printf("outbox: reading due events: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
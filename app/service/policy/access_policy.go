@@ -0,0 +1,220 @@
+package policy
+
+import (
+    "context"
+    "errors"
+    modelPg "student-performance-report/app/models/postgresql"
+    repoPg "student-performance-report/app/repository/postgresql"
+    "github.com/gofiber/fiber/v2"
+    "github.com/google/uuid"
+    "student-performance-report/middleware"
+)
+
+// AdminPermission marks an actor that may access every student's data.
+const AdminPermission = "manage:users"
+
+// Actor is the caller of a request. Its student and lecturer profiles are
+// looked up lazily, so a check only pays for the relationship it needs.
+type Actor struct {
+    UserID  uuid.UUID
+    IsAdmin bool
+
+    ctx    context.Context
+    policy *AccessPolicy
+
+    studentID       uuid.UUID
+    isStudent       bool
+    studentResolved bool
+
+    lecturerID       uuid.UUID
+    isLecturer       bool
+    lecturerResolved bool
+}
+
+// StudentID returns the caller's student profile ID, if it has one.
+func (a *Actor) StudentID() (uuid.UUID, bool) {
+    if !a.studentResolved {
+        id, err := a.policy.achRepo.GetStudentByUserID(a.ctx, a.UserID)
+        a.studentID, a.isStudent, a.studentResolved = id, err == nil, true
+    }
+    return a.studentID, a.isStudent
+}
+
+// LecturerID returns the caller's lecturer profile ID, if it has one.
+func (a *Actor) LecturerID() (uuid.UUID, bool) {
+    if !a.lecturerResolved {
+        id, err := a.policy.lecturerRepo.GetLecturerByUserID(a.ctx, a.UserID)
+        a.lecturerID, a.isLecturer, a.lecturerResolved = id, err == nil, true
+    }
+    return a.lecturerID, a.isLecturer
+}
+
+// AccessPolicy encodes the relationship rules shared by the achievement,
+// student, lecturer and report handlers:
+//   - a student owns its own achievements and student record,
+//   - a lecturer may see (and verify) the data of the students it advises,
+//     but never their drafts,
+//   - an admin may see everything.
+type AccessPolicy struct {
+    achRepo      repoPg.AchievementRepoPostgres
+    lecturerRepo repoPg.LecturerRepository
+    studentRepo  repoPg.StudentRepository
+}
+
+func NewAccessPolicy(a repoPg.AchievementRepoPostgres, l repoPg.LecturerRepository, s repoPg.StudentRepository) *AccessPolicy {
+    return &AccessPolicy{achRepo: a, lecturerRepo: l, studentRepo: s}
+}
+
+// Actor builds the actor for the authenticated user of the request.
+func (p *AccessPolicy) Actor(c *fiber.Ctx) (*Actor, error) {
+    var userID uuid.UUID
+    switch v := c.Locals("user_id").(type) {
+    case uuid.UUID:
+        userID = v
+    case string:
+        parsed, err := uuid.Parse(v)
+        if err != nil {
+            return nil, errors.New("server error: user_id format invalid")
+        }
+        userID = parsed
+    default:
+        return nil, errors.New("unauthorized: user_id missing in context")
+    }
+
+    return &Actor{
+        UserID:  userID,
+        IsAdmin: middleware.HasPermission(c, AdminPermission),
+        ctx:     c.Context(),
+        policy:  p,
+    }, nil
+}
+
+// Advises reports whether lecturerID is the advisor of studentID.
+func (p *AccessPolicy) Advises(ctx context.Context, lecturerID, studentID uuid.UUID) (bool, error) {
+    student, err := p.studentRepo.GetStudentByID(ctx, studentID)
+    if err != nil {
+        if errors.Is(err, repoPg.ErrStudentNotFound) {
+            return false, nil
+        }
+        return false, err
+    }
+    return student.AdvisorID != nil && *student.AdvisorID == lecturerID, nil
+}
+
+// CanAccessStudent covers student records, their achievement lists and reports.
+func (p *AccessPolicy) CanAccessStudent(actor *Actor, studentID uuid.UUID) (bool, error) {
+    if actor.IsAdmin {
+        return true, nil
+    }
+    if sid, ok := actor.StudentID(); ok {
+        return sid == studentID, nil
+    }
+    if lid, ok := actor.LecturerID(); ok {
+        return p.Advises(actor.ctx, lid, studentID)
+    }
+    return false, nil
+}
+
+// CanAccessLecturer covers a lecturer's own data such as its advisee list.
+func (p *AccessPolicy) CanAccessLecturer(actor *Actor, lecturerID uuid.UUID) bool {
+    if actor.IsAdmin {
+        return true
+    }
+    lid, ok := actor.LecturerID()
+    return ok && lid == lecturerID
+}
+
+// CanViewAchievement covers the detail and history of one achievement.
+func (p *AccessPolicy) CanViewAchievement(actor *Actor, ref modelPg.AchievementReference) (bool, error) {
+    if actor.IsAdmin {
+        return true, nil
+    }
+    if sid, ok := actor.StudentID(); ok {
+        return sid == ref.StudentID, nil
+    }
+    if lid, ok := actor.LecturerID(); ok {
+        if ref.Status == modelPg.StatusDraft {
+            return false, nil
+        }
+        return p.Advises(actor.ctx, lid, ref.StudentID)
+    }
+    return false, nil
+}
+
+// CanModifyAchievement covers editing, submitting, deleting and uploading
+// attachments: only the owning student may do that.
+func (p *AccessPolicy) CanModifyAchievement(actor *Actor, ref modelPg.AchievementReference) bool {
+    sid, ok := actor.StudentID()
+    return ok && sid == ref.StudentID
+}
+
+// CanAccessTrash covers a student's deleted achievements. Only drafts can be
+// deleted, so like drafts the trash is not shown to the advisor.
+func (p *AccessPolicy) CanAccessTrash(actor *Actor, studentID uuid.UUID) bool {
+    if actor.IsAdmin {
+        return true
+    }
+    sid, ok := actor.StudentID()
+    return ok && sid == studentID
+}
+
+// CanVerify covers verifying and rejecting: only the student's advisor may.
+func (p *AccessPolicy) CanVerify(actor *Actor, ref modelPg.AchievementReference) (bool, error) {
+    lid, ok := actor.LecturerID()
+    if !ok {
+        return false, nil
+    }
+    return p.Advises(actor.ctx, lid, ref.StudentID)
+}
+
+// AchievementScope returns the GetAllReferences filter limiting a listing to
+// what the actor may see. ok is false when the actor may see nothing.
+func (p *AccessPolicy) AchievementScope(actor *Actor, status string) (map[string]interface{}, bool, error) {
+    filters := make(map[string]interface{})
+    if status != "" {
+        filters["status"] = status
+    }
+
+    if actor.IsAdmin {
+        return filters, true, nil
+    }
+
+    if sid, ok := actor.StudentID(); ok {
+        filters["student_id"] = sid
+        return filters, true, nil
+    }
+
+    if lid, ok := actor.LecturerID(); ok {
+        advisees, err := p.lecturerRepo.GetAdvisees(lid)
+        if err != nil {
+            return nil, false, err
+        }
+        if len(advisees) == 0 {
+            return nil, false, nil
+        }
+
+        var studentIDs []uuid.UUID
+        for _, mhs := range advisees {
+            studentIDs = append(studentIDs, mhs.ID)
+        }
+        filters["student_ids"] = studentIDs
+
+        // Same rule as CanViewAchievement: everything but drafts, and the
+        // deleted drafts in the trash.
+        switch status {
+        case "":
+            filters["status"] = []string{
+                modelPg.StatusSubmitted,
+                modelPg.StatusVerified,
+                modelPg.StatusRejected,
+                modelPg.StatusRevision,
+                modelPg.StatusRevoked,
+            }
+        case modelPg.StatusDraft, modelPg.StatusDeleted:
+            return nil, false, nil
+        }
+        return filters, true, nil
+    }
+
+    return nil, false, nil
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/middleware"
+	"student-performance-report/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// accessTokenPrefixLen characters of the token are stored in clear to
+	// identify it in listings: "spr_pat_" plus four random characters.
+	accessTokenPrefixLen = len(middleware.AccessTokenPrefix) + 4
+
+	// accessTokenTouchInterval limits last_used_at writes to one per minute
+	// per token, so a busy script does not write on every request.
+	accessTokenTouchInterval = time.Minute
+)
+
+type AccessTokenService struct {
+	tokenRepo  repo.AccessTokenRepository
+	userRepo   repo.UserRepository
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+}
+
+func NewAccessTokenService(tokenRepo repo.AccessTokenRepository, userRepo repo.UserRepository, defaultTTL, maxTTL time.Duration) *AccessTokenService {
+	return &AccessTokenService{tokenRepo: tokenRepo, userRepo: userRepo, defaultTTL: defaultTTL, maxTTL: maxTTL}
+}
+
+// CreateAccessToken godoc
+// @Summary Create Personal Access Token
+// @Description Create a long-lived token for scripts. Scopes must be permissions the caller holds; the token is shown only once. Send it as "Authorization: Bearer spr_pat_...".
+// @Tags Authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.CreateAccessTokenRequest true "Name, scopes and lifetime in days"
+// @Success 201 {object} models.CreateAccessTokenResp
+// @Failure 400,403,500 {object} map[string]interface{}
+// @Router /auth/tokens [post]
+func (s *AccessTokenService) CreateAccessToken(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req models.CreateAccessTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		return c.Status(400).JSON(fiber.Map{"error": "name is required (at most 100 characters)"})
+	}
+
+	ttl := s.defaultTTL
+	if req.ExpiresInDays != 0 {
+		ttl = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+	if ttl <= 0 || ttl > s.maxTTL {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("expiresInDays must be between 1 and %d", int(s.maxTTL.Hours()/24))})
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+	owned, err := s.userRepo.GetPermissionsByRoleID(user.RoleID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	scopes, err := scopeSubset(req.Scopes, owned)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	token, hash, err := utils.GenerateOpaqueToken(middleware.AccessTokenPrefix)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	now := time.Now()
+	pat := models.PersonalAccessToken{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Name:        req.Name,
+		TokenPrefix: token[:accessTokenPrefixLen],
+		TokenHash:   hash,
+		Scopes:      scopes,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	if err := s.tokenRepo.Create(c.Context(), &pat); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(models.CreateAccessTokenResp{PersonalAccessToken: pat, Token: token})
+}
+
+// scopeSubset checks that every requested scope is one of the owner's
+// permissions and returns them sorted and without duplicates.
+func scopeSubset(requested, owned []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	has := make(map[string]bool, len(owned))
+	for _, p := range owned {
+		has[p] = true
+	}
+
+	seen := make(map[string]bool, len(requested))
+	var scopes, missing []string
+	for _, sc := range requested {
+		sc = strings.TrimSpace(sc)
+		if seen[sc] {
+			continue
+		}
+		seen[sc] = true
+		if !has[sc] {
+			missing = append(missing, sc)
+			continue
+		}
+		scopes = append(scopes, sc)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("scopes exceed your permissions: %s", strings.Join(missing, ", "))
+	}
+	sort.Strings(scopes)
+	return scopes, nil
+}
+
+// GetAccessTokens godoc
+// @Summary List Personal Access Tokens
+// @Description List the current user's tokens, including expired and revoked ones
+// @Tags Authentication
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.PersonalAccessToken
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/tokens [get]
+func (s *AccessTokenService) GetAccessTokens(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	tokens, err := s.tokenRepo.ListByUser(c.Context(), userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if tokens == nil {
+		tokens = []models.PersonalAccessToken{}
+	}
+	return c.JSON(tokens)
+}
+
+// RevokeAccessToken godoc
+// @Summary Revoke Personal Access Token
+// @Description Revoke one of the current user's tokens
+// @Tags Authentication
+// @Security BearerAuth
+// @Param id path string true "Token UUID"
+// @Success 200 {object} map[string]string
+// @Failure 400,404,500 {object} map[string]interface{}
+// @Router /auth/tokens/{id} [delete]
+func (s *AccessTokenService) RevokeAccessToken(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid token id"})
+	}
+
+	if err := s.tokenRepo.Revoke(c.Context(), id, userID); err != nil {
+		if err == repo.ErrAccessTokenNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "token revoked"})
+}
+
+// Authenticate is the middleware.AccessTokenLoader for AuthRequired.
+func (s *AccessTokenService) Authenticate(ctx context.Context, token, ip string) (*middleware.AccessTokenIdentity, error) {
+	owner, err := s.tokenRepo.FindActive(ctx, utils.HashOpaqueToken(token))
+	if err == repo.ErrAccessTokenNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if owner.LastUsedAt == nil || now.Sub(*owner.LastUsedAt) >= accessTokenTouchInterval {
+		// Usage tracking must not fail the request.
+		_ = s.tokenRepo.TouchLastUsed(ctx, owner.TokenID, now, ip)
+	}
+
+	return &middleware.AccessTokenIdentity{
+		TokenID:  owner.TokenID,
+		UserID:   owner.UserID,
+		RoleID:   owner.RoleID,
+		RoleName: owner.RoleName,
+		Scopes:   owner.Scopes,
+	}, nil
+}
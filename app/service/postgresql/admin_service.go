@@ -1,22 +1,27 @@
 package service
 
 import (
+    "errors"
+    "net/mail"
+    "regexp"
+    "strings"
     "time"
-    "golang.org/x/crypto/bcrypt"
     models "student-performance-report/app/models/postgresql"
     repo "student-performance-report/app/repository/postgresql"
     "github.com/gofiber/fiber/v2"
     "github.com/google/uuid"
     "student-performance-report/middleware"
+    "student-performance-report/utils"
 )
 
 type AdminService struct {
     adminRepo repo.AdminRepository
     userRepo  repo.UserRepository
+    roleRepo  repo.RoleRepository
 }
 
-func NewAdminService(adminRepo repo.AdminRepository, userRepo repo.UserRepository) *AdminService {
-    return &AdminService{adminRepo: adminRepo, userRepo: userRepo}
+func NewAdminService(adminRepo repo.AdminRepository, userRepo repo.UserRepository, roleRepo repo.RoleRepository) *AdminService {
+    return &AdminService{adminRepo: adminRepo, userRepo: userRepo, roleRepo: roleRepo}
 }
 
 // GetAllUsers godoc
@@ -29,9 +34,6 @@ func NewAdminService(adminRepo repo.AdminRepository, userRepo repo.UserRepositor
 // @Failure 403,500 {object} map[string]interface{}
 // @Router /users [get]
 func (s *AdminService) GetAllUsers(c *fiber.Ctx) error {
-    if !middleware.HasPermission(c, "manage:users") {
-        return fiber.ErrForbidden
-    }
 
     users, err := s.adminRepo.GetAllUsers()
     if err != nil {
@@ -43,7 +45,7 @@ func (s *AdminService) GetAllUsers(c *fiber.Ctx) error {
 
 // GetUserByID godoc
 // @Summary Get User by ID
-// @Description Get user details by ID
+// @Description Get user details by ID (own account, or any account with manage:users)
 // @Tags Users
 // @Security BearerAuth
 // @Produce json
@@ -59,7 +61,9 @@ func (s *AdminService) GetUserByID(c *fiber.Ctx) error {
         return c.Status(400).JSON(fiber.Map{"error": "invalid user id"})
     }
 
-    if !middleware.HasPermission(c, "manage:users") {
+    // Users may always read their own record; anyone else needs manage:users.
+    callerID, _ := c.Locals("user_id").(uuid.UUID)
+    if callerID != paramID && !middleware.HasPermission(c, "manage:users") {
         return fiber.ErrForbidden
     }
 
@@ -73,50 +77,341 @@ func (s *AdminService) GetUserByID(c *fiber.Ctx) error {
 
 // CreateUser godoc
 // @Summary Create New User
-// @Description Create a new user (Admin only)
+// @Description Create a new user together with its student or lecturer profile (Admin only)
 // @Tags Users
 // @Security BearerAuth
 // @Accept json
 // @Produce json
-// @Param request body models.User true "User Data"
-// @Success 200 {object} models.User
-// @Failure 400,403,500 {object} map[string]interface{}
+// @Param request body models.CreateUserRequest true "User Data"
+// @Success 201 {object} models.UserAccountResp
+// @Failure 400,403,409,500 {object} map[string]interface{}
 // @Router /users [post]
 func (s *AdminService) CreateUser(c *fiber.Ctx) error {
-    if !middleware.HasPermission(c, "manage:users") {
-        return fiber.ErrForbidden
-    }
 
-    var req models.User
+    var req models.CreateUserRequest
     if err := c.BodyParser(&req); err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
     }
 
-    req.ID = uuid.New()
-    req.CreatedAt = time.Now()
-    req.UpdatedAt = time.Now()
+    account, fieldErrors, err := s.prepareNewUser(&req, s.lookupRole)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+    if len(fieldErrors) > 0 {
+        return c.Status(400).JSON(fiber.Map{"error": "validation failed", "details": fieldErrors})
+    }
+
+    usernameTaken, emailTaken, err := s.adminRepo.FindTakenCredentials(account.user.Username, account.user.Email)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+    if usernameTaken || emailTaken {
+        conflicts := map[string]string{}
+        if usernameTaken {
+            conflicts["username"] = "already taken"
+        }
+        if emailTaken {
+            conflicts["email"] = "already taken"
+        }
+        return c.Status(409).JSON(fiber.Map{"error": "user already exists", "details": conflicts})
+    }
 
-    hashed, _ := bcrypt.GenerateFromPassword([]byte(req.PasswordHash), bcrypt.DefaultCost)
-    req.PasswordHash = string(hashed)
+    if err := account.hashPassword(); err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
 
-    if err := s.adminRepo.CreateUser(&req); err != nil {
+    if err := s.adminRepo.CreateUserWithProfile(account.user, account.student, account.lecturer); err != nil {
+        switch {
+        case errors.Is(err, repo.ErrDuplicateUser):
+            return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+        case errors.Is(err, repo.ErrInvalidReference):
+            return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+        }
         return c.Status(500).JSON(fiber.Map{"error": err.Error()})
     }
 
-    return c.JSON(req)
+    return c.Status(201).JSON(account.response())
+}
+
+const (
+    profileNone     = ""
+    profileStudent  = "student"
+    profileLecturer = "lecturer"
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{3,50}$`)
+
+// newAccount is a validated user with its profile. The password is only
+// hashed by hashPassword, so validating (e.g. an import dry-run) stays cheap.
+type newAccount struct {
+    user     *models.User
+    password string
+    roleName string
+    student  *models.Student
+    lecturer *models.Lecturer
+}
+
+func (a *newAccount) hashPassword() error {
+    hashed, err := utils.HashPassword(a.password)
+    if err != nil {
+        return err
+    }
+    a.user.PasswordHash = hashed
+    return nil
+}
+
+// roleInfo is a role together with the profile kind it requires.
+type roleInfo struct {
+    role *models.Roles
+    kind string
+}
+
+// lookupRole returns nil (without error) when the role does not exist.
+func (s *AdminService) lookupRole(id uuid.UUID) (*roleInfo, error) {
+    role, err := s.roleRepo.GetRoleByID(id)
+    if errors.Is(err, repo.ErrRoleNotFound) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    perms, err := s.roleRepo.GetPermissionsByRole(id)
+    if err != nil {
+        return nil, err
+    }
+    return &roleInfo{role: role, kind: roleProfileKind(perms)}, nil
+}
+
+// roleManagesUsers reports whether the role holds manage:users.
+func (s *AdminService) roleManagesUsers(roleID uuid.UUID) (bool, error) {
+    perms, err := s.roleRepo.GetPermissionsByRole(roleID)
+    if err != nil {
+        return false, err
+    }
+    for _, p := range perms {
+        if p.Name == permManageUsers {
+            return true, nil
+        }
+    }
+    return false, nil
+}
+
+// removesLastAdmin reports whether giving user the role newRoleID and the
+// active flag active would leave no active user able to manage users. Like
+// RoleService.isLastAdminRole, other roles only count when they have active
+// users.
+func (s *AdminService) removesLastAdmin(user *models.User, newRoleID uuid.UUID, active bool) (bool, error) {
+    if !user.IsActive || (active && newRoleID == user.RoleID) {
+        return false, nil
+    }
+
+    holds, err := s.roleManagesUsers(user.RoleID)
+    if err != nil || !holds {
+        return false, err
+    }
+    if active {
+        if keeps, err := s.roleManagesUsers(newRoleID); err != nil || keeps {
+            return false, err
+        }
+    }
+
+    others, err := s.roleRepo.CountAssignedRolesWithPermission(permManageUsers, user.RoleID)
+    if err != nil || others > 0 {
+        return false, err
+    }
+    peers, err := s.roleRepo.CountActiveUsersByRole(user.RoleID)
+    if err != nil {
+        return false, err
+    }
+    return peers <= 1, nil
+}
+
+func (a *newAccount) response() models.UserAccountResp {
+    resp := models.UserAccountResp{
+        ID:        a.user.ID,
+        Username:  a.user.Username,
+        Email:     a.user.Email,
+        FullName:  a.user.FullName,
+        RoleID:    a.user.RoleID,
+        RoleName:  a.roleName,
+        IsActive:  a.user.IsActive,
+        CreatedAt: a.user.CreatedAt,
+    }
+    if a.student != nil {
+        resp.Student = &models.StudentResp{
+            ID:           a.student.ID,
+            StudentID:    a.student.StudentID,
+            FullName:     a.user.FullName,
+            ProgramStudy: a.student.ProgramStudy,
+            AcademicYear: a.student.AcademicYear,
+            AdvisorID:    a.student.AdvisorID,
+        }
+    }
+    if a.lecturer != nil {
+        resp.Lecturer = &models.LecturerResp{
+            ID:         a.lecturer.ID,
+            LecturerID: a.lecturer.LecturerID,
+            FullName:   a.user.FullName,
+            Department: a.lecturer.Department,
+        }
+    }
+    return resp
+}
+
+// roleProfileKind tells which profile a role needs: admins (manage:users)
+// need none, verifiers are lecturers, achievement creators are students.
+func roleProfileKind(perms []models.Permission) string {
+    has := make(map[string]bool, len(perms))
+    for _, p := range perms {
+        has[p.Name] = true
+    }
+    switch {
+    case has[permManageUsers]:
+        return profileNone
+    case has["achievement:verify"]:
+        return profileLecturer
+    case has["achievement:create"]:
+        return profileStudent
+    }
+    return profileNone
+}
+
+// prepareNewUser validates the request and builds the user and profile rows.
+// Validation problems are returned per field; err is only set for lookups
+// that failed for reasons other than bad input.
+func (s *AdminService) prepareNewUser(req *models.CreateUserRequest, lookupRole func(uuid.UUID) (*roleInfo, error)) (*newAccount, map[string]string, error) {
+    fieldErrors := map[string]string{}
+
+    req.Username = strings.TrimSpace(req.Username)
+    req.Email = strings.TrimSpace(req.Email)
+    req.FullName = strings.TrimSpace(req.FullName)
+
+    if !usernamePattern.MatchString(req.Username) {
+        fieldErrors["username"] = "must be 3-50 characters of letters, digits, '.', '_' or '-'"
+    }
+    if addr, err := mail.ParseAddress(req.Email); err != nil || addr.Address != req.Email {
+        fieldErrors["email"] = "must be a valid email address"
+    }
+    if req.FullName == "" {
+        fieldErrors["fullName"] = "is required"
+    }
+    if err := utils.ValidatePassword(req.Password); err != nil {
+        fieldErrors["password"] = err.Error()
+    }
+
+    account := &newAccount{}
+    kind := profileNone
+
+    roleID, err := uuid.Parse(req.RoleID)
+    if err != nil {
+        fieldErrors["roleId"] = "must be a valid role id"
+    } else if info, err := lookupRole(roleID); err != nil {
+        return nil, nil, err
+    } else if info == nil {
+        fieldErrors["roleId"] = "role not found"
+    } else {
+        account.roleName = info.role.Name
+        kind = info.kind
+    }
+
+    userID := uuid.New()
+    now := time.Now()
+
+    switch kind {
+    case profileStudent:
+        p := req.StudentProfile
+        if p == nil {
+            fieldErrors["studentProfile"] = "is required for this role"
+            break
+        }
+        if strings.TrimSpace(p.StudentID) == "" {
+            fieldErrors["studentProfile.studentId"] = "is required"
+        }
+        if strings.TrimSpace(p.ProgramStudy) == "" {
+            fieldErrors["studentProfile.programStudy"] = "is required"
+        }
+        if strings.TrimSpace(p.AcademicYear) == "" {
+            fieldErrors["studentProfile.academicYear"] = "is required"
+        }
+        account.student = &models.Student{
+            ID:           uuid.New(),
+            UserID:       userID,
+            StudentID:    strings.TrimSpace(p.StudentID),
+            ProgramStudy: strings.TrimSpace(p.ProgramStudy),
+            AcademicYear: strings.TrimSpace(p.AcademicYear),
+        }
+        if p.AdvisorID != "" {
+            advisorID, err := uuid.Parse(p.AdvisorID)
+            if err != nil {
+                fieldErrors["studentProfile.advisorId"] = "must be a valid lecturer id"
+            } else {
+                account.student.AdvisorID = &advisorID
+            }
+        }
+        if req.LecturerProfile != nil {
+            fieldErrors["lecturerProfile"] = "not allowed for this role"
+        }
+    case profileLecturer:
+        p := req.LecturerProfile
+        if p == nil {
+            fieldErrors["lecturerProfile"] = "is required for this role"
+            break
+        }
+        if strings.TrimSpace(p.LecturerID) == "" {
+            fieldErrors["lecturerProfile.lecturerId"] = "is required"
+        }
+        if strings.TrimSpace(p.Department) == "" {
+            fieldErrors["lecturerProfile.department"] = "is required"
+        }
+        account.lecturer = &models.Lecturer{
+            ID:         uuid.New(),
+            UserID:     userID,
+            LecturerID: strings.TrimSpace(p.LecturerID),
+            Department: strings.TrimSpace(p.Department),
+        }
+        if req.StudentProfile != nil {
+            fieldErrors["studentProfile"] = "not allowed for this role"
+        }
+    default:
+        if _, ok := fieldErrors["roleId"]; !ok && (req.StudentProfile != nil || req.LecturerProfile != nil) {
+            fieldErrors["roleId"] = "role does not take a student or lecturer profile"
+        }
+    }
+
+    if len(fieldErrors) > 0 {
+        return nil, fieldErrors, nil
+    }
+
+    isActive := true
+    if req.IsActive != nil {
+        isActive = *req.IsActive
+    }
+
+    account.password = req.Password
+    account.user = &models.User{
+        ID:           userID,
+        Username:     req.Username,
+        Email:        req.Email,
+        FullName:     req.FullName,
+        RoleID:       roleID,
+        IsActive:     isActive,
+        CreatedAt:    now,
+        UpdatedAt:    now,
+    }
+    return account, nil, nil
 }
 
 // UpdateUser godoc
 // @Summary Update User
-// @Description Update user data
+// @Description Update user data. Only the fields present in the body are changed; passwords are changed through the reset-password flow.
 // @Tags Users
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path string true "User UUID"
-// @Param request body models.User true "User Data"
-// @Success 200 {object} models.User
-// @Failure 400,403,500 {object} map[string]interface{}
+// @Param request body models.UpdateUserRequest true "User Data"
+// @Success 200 {object} models.UserAccountResp
+// @Failure 400,403,404,409,500 {object} map[string]interface{}
 // @Router /users/{id} [put]
 func (s *AdminService) UpdateUser(c *fiber.Ctx) error {
     paramID := c.Params("id")
@@ -126,22 +421,111 @@ func (s *AdminService) UpdateUser(c *fiber.Ctx) error {
         return c.Status(400).JSON(fiber.Map{"error": "invalid user id"})
     }
 
-    if !middleware.HasPermission(c, "manage:users") {
-        return fiber.ErrForbidden
-    }
 
-    var req models.User
+    var req models.UpdateUserRequest
     if err := c.BodyParser(&req); err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
     }
 
-    req.ID = targetID
+    if req.Password != nil || req.PasswordHash != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "passwords cannot be changed here, use POST /users/:id/reset-password"})
+    }
+
+    user, err := s.adminRepo.GetUserByID(targetID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+    }
+
+    current := *user
+    fieldErrors := map[string]string{}
+    var changedUsername, changedEmail string
+
+    if req.Username != nil {
+        username := strings.TrimSpace(*req.Username)
+        if !usernamePattern.MatchString(username) {
+            fieldErrors["username"] = "must be 3-50 characters of letters, digits, '.', '_' or '-'"
+        } else if !strings.EqualFold(username, user.Username) {
+            changedUsername = username
+        }
+        user.Username = username
+    }
+    if req.Email != nil {
+        email := strings.TrimSpace(*req.Email)
+        if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+            fieldErrors["email"] = "must be a valid email address"
+        } else if !strings.EqualFold(email, user.Email) {
+            changedEmail = email
+        }
+        user.Email = email
+    }
+    if req.FullName != nil {
+        user.FullName = strings.TrimSpace(*req.FullName)
+        if user.FullName == "" {
+            fieldErrors["full_name"] = "must not be empty"
+        }
+    }
+    if req.RoleID != nil {
+        roleID, err := uuid.Parse(*req.RoleID)
+        if err != nil {
+            fieldErrors["role_id"] = "must be a valid role id"
+        } else if _, err := s.roleRepo.GetRoleByID(roleID); err != nil {
+            fieldErrors["role_id"] = "role not found"
+        } else {
+            user.RoleID = roleID
+        }
+    }
+    if req.IsActive != nil {
+        user.IsActive = *req.IsActive
+    }
+
+    if len(fieldErrors) > 0 {
+        return c.Status(400).JSON(fiber.Map{"error": "validation failed", "details": fieldErrors})
+    }
 
-    if err := s.adminRepo.UpdateUser(&req); err != nil {
+    if last, err := s.removesLastAdmin(&current, user.RoleID, user.IsActive); err != nil {
         return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    } else if last {
+        return c.Status(409).JSON(fiber.Map{"error": "cannot remove the last active user holding '" + permManageUsers + "'"})
+    }
+
+    if changedUsername != "" || changedEmail != "" {
+        usernameTaken, emailTaken, err := s.adminRepo.FindTakenCredentials(changedUsername, changedEmail)
+        if err != nil {
+            return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+        }
+        conflicts := map[string]string{}
+        if changedUsername != "" && usernameTaken {
+            conflicts["username"] = "already taken"
+        }
+        if changedEmail != "" && emailTaken {
+            conflicts["email"] = "already taken"
+        }
+        if len(conflicts) > 0 {
+            return c.Status(409).JSON(fiber.Map{"error": "user already exists", "details": conflicts})
+        }
     }
 
-    return c.JSON(req)
+    if err := s.adminRepo.UpdateUser(user); err != nil {
+        if errors.Is(err, repo.ErrDuplicateUser) {
+            return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+        }
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+    middleware.InvalidateTokenVersion(targetID)
+
+    resp := models.UserAccountResp{
+        ID:        user.ID,
+        Username:  user.Username,
+        Email:     user.Email,
+        FullName:  user.FullName,
+        RoleID:    user.RoleID,
+        IsActive:  user.IsActive,
+        CreatedAt: user.CreatedAt,
+    }
+    if role, err := s.roleRepo.GetRoleByID(user.RoleID); err == nil {
+        resp.RoleName = role.Name
+    }
+    return c.JSON(resp)
 }
 
 // DeleteUser godoc
@@ -151,7 +535,7 @@ func (s *AdminService) UpdateUser(c *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param id path string true "User UUID"
 // @Success 200 {object} map[string]string
-// @Failure 400,403,500 {object} map[string]interface{}
+// @Failure 400,403,404,409,500 {object} map[string]interface{}
 // @Router /users/{id} [delete]
 func (s *AdminService) DeleteUser(c *fiber.Ctx) error {
 	paramID := c.Params("id")
@@ -160,13 +544,21 @@ func (s *AdminService) DeleteUser(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid user id"})
 	}
 
-	if !middleware.HasPermission(c, "manage:users") {
-        return fiber.ErrForbidden
-    }
+	user, err := s.adminRepo.GetUserByID(targetID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+
+	if last, err := s.removesLastAdmin(user, user.RoleID, false); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	} else if last {
+		return c.Status(409).JSON(fiber.Map{"error": "cannot delete the last active user holding '" + permManageUsers + "'"})
+	}
 
 	if err := s.adminRepo.DeleteUser(targetID); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	middleware.InvalidateTokenVersion(targetID)
 
 	return c.JSON(fiber.Map{"message": "user deactivated (soft deleted)"})
 }
@@ -180,12 +572,9 @@ func (s *AdminService) DeleteUser(c *fiber.Ctx) error {
 // @Param id path string true "User UUID"
 // @Param request body object{roleId=string} true "Role ID"
 // @Success 200 {object} map[string]string
-// @Failure 400,403,500 {object} map[string]interface{}
+// @Failure 400,403,404,409,500 {object} map[string]interface{}
 // @Router /users/{id}/role [put]
 func (s *AdminService) AssignRole(c *fiber.Ctx) error {
-    if !middleware.HasPermission(c, "manage:users") {
-        return fiber.ErrForbidden
-    }
 
     var req struct {
         RoleID string `json:"roleId"`
@@ -194,12 +583,35 @@ func (s *AdminService) AssignRole(c *fiber.Ctx) error {
         return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
     }
 
-    userID, _ := uuid.Parse(c.Params("id"))
-    roleID, _ := uuid.Parse(req.RoleID)
+    userID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid user id"})
+    }
+
+    roleID, err := uuid.Parse(req.RoleID)
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid role id"})
+    }
+
+    if _, err := s.roleRepo.GetRoleByID(roleID); err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "role not found"})
+    }
+
+    user, err := s.adminRepo.GetUserByID(userID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+    }
+
+    if last, err := s.removesLastAdmin(user, roleID, user.IsActive); err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    } else if last {
+        return c.Status(409).JSON(fiber.Map{"error": "cannot move the last active user holding '" + permManageUsers + "' to another role"})
+    }
 
     if err := s.adminRepo.AssignRole(userID, roleID); err != nil {
         return c.Status(500).JSON(fiber.Map{"error": err.Error()})
     }
+    middleware.InvalidateTokenVersion(userID)
 
     return c.JSON(fiber.Map{"message": "role assigned"})
 }
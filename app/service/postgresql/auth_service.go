@@ -1,32 +1,93 @@
 package service
 
 import (
+	"errors"
+	"time"
 	models "student-performance-report/app/models/postgresql"
 	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/middleware"
 	"student-performance-report/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 type AuthService struct {
-	userRepo repo.UserRepository
+	userRepo    repo.UserRepository
+	sessionRepo repo.SessionRepository
+	authn       *Authenticators
+	throttle    *LoginThrottle
+	mfa         *MFAService
 }
 
-func NewAuthService(userRepo repo.UserRepository) *AuthService {
-	return &AuthService{userRepo: userRepo}
+func NewAuthService(userRepo repo.UserRepository, sessionRepo repo.SessionRepository, authn *Authenticators, throttle *LoginThrottle, mfa *MFAService) *AuthService {
+	return &AuthService{userRepo: userRepo, sessionRepo: sessionRepo, authn: authn, throttle: throttle, mfa: mfa}
+}
+
+// dummyPasswordHash is compared against when the username does not exist, so
+// an unknown username costs the same bcrypt work as a wrong password.
+var dummyPasswordHash, _ = utils.HashPassword(uuid.NewString())
+
+func (s *AuthService) startSession(c *fiber.Ctx, user *models.User) (*models.Session, error) {
+	now := time.Now()
+	session := &models.Session{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		RefreshJTI: uuid.New().String(),
+		UserAgent:  c.Get("User-Agent"),
+		IPAddress:  c.IP(),
+		IssuedAt:   now,
+		ExpiresAt:  now.Add(utils.RefreshTokenTTL),
+	}
+
+	if err := s.sessionRepo.Create(c.Context(), session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// issueLogin opens a session and signs its access and refresh tokens.
+func (s *AuthService) issueLogin(c *fiber.Ctx, user *models.User, roleName string, permissions []string) (*models.LoginResponse, error) {
+	session, err := s.startSession(c, user)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenString, err := utils.GenerateToken(user, roleName, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := utils.GenerateRefreshToken(user, session.ID, session.RefreshJTI)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LoginResponse{
+		Token:        tokenString,
+		RefreshToken: refresh,
+		User: models.UserResp{
+			ID:          user.ID,
+			Username:    user.Username,
+			FullName:    user.FullName,
+			Role:        roleName,
+			Permissions: permissions,
+		},
+	}, nil
 }
 
 // Login godoc
 // @Summary User Login
-// @Description Authenticate user and return token
+// @Description Authenticate user and return tokens. When 2FA is enabled or required for the role, an MFAChallengeResp is returned instead; finish with /auth/2fa/login.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param request body object{username=string,password=string} true "Login Credentials"
 // @Success 200 {object} models.LoginResponse
-// @Failure 400,401,403 {object} map[string]interface{}
+// @Failure 400,401,403,429,503 {object} map[string]interface{}
 // @Router /auth/login [post]
 func (s *AuthService) Login(c *fiber.Ctx) error {
+	ctx := c.Context()
+
 	var req struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
@@ -36,14 +97,40 @@ func (s *AuthService) Login(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
 	}
 
-	user, roleName, err := s.userRepo.GetByUsername(req.Username)
+	wait, err := s.throttle.Check(ctx, req.Username, c.IP())
 	if err != nil {
-		return c.Status(401).JSON(fiber.Map{"error": "invalid username or password"})
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, retryAfterSeconds(wait))
+		return c.Status(429).JSON(fiber.Map{"error": "too many failed login attempts, try again later"})
+	}
+
+	user, roleName, err := s.userRepo.GetByUsername(req.Username)
+	if err == nil {
+		roleName, err = s.authn.Authenticate(ctx, user, roleName, req.Password)
+	} else {
+		utils.CheckPasswordHash(req.Password, dummyPasswordHash)
+		err = ErrInvalidCredentials
 	}
 
-	if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
+	if errors.Is(err, ErrInvalidCredentials) {
+		if err := s.throttle.RecordFailure(ctx, req.Username, c.IP()); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(401).JSON(fiber.Map{"error": "invalid username or password"})
 	}
+	if err != nil {
+		return c.Status(503).JSON(fiber.Map{"error": "could not verify credentials: " + err.Error()})
+	}
+
+	return s.completeLogin(c, user, roleName)
+}
+
+// completeLogin runs once the first factor (password or single sign-on) is
+// accepted: it either answers with a 2FA challenge or issues the tokens.
+func (s *AuthService) completeLogin(c *fiber.Ctx, user *models.User, roleName string) error {
+	ctx := c.Context()
 
 	if !user.IsActive {
 		return c.Status(403).JSON(fiber.Map{"error": "account is inactive"})
@@ -54,40 +141,141 @@ func (s *AuthService) Login(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	tokenString, err := utils.GenerateToken(user, roleName, permissions)
+	// With 2FA the first factor only buys a challenge; the failure counter
+	// is reset once the second factor is accepted too.
+	mfa, err := s.mfa.mfaRepo.Get(ctx, user.ID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	if mfa.Enabled() || s.mfa.requiredFor(permissions) {
+		challenge, err := s.mfa.startChallenge(ctx, user.ID, c.IP())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		challenge.SetupRequired = !mfa.Enabled()
+		return c.JSON(challenge)
+	}
 
-	refresh, err := utils.GenerateRefreshToken(user)
+	if err := s.throttle.RecordSuccess(ctx, user.Username); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	resp, err := s.issueLogin(c, user, roleName, permissions)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(resp)
+}
 
-	return c.JSON(models.LoginResponse{
-		Token:        tokenString,
-		RefreshToken: refresh,
-		User: models.UserResp{
-			ID:          user.ID,
-			Username:    user.Username,
-			FullName:    user.FullName,
-			Role:        roleName,
-			Permissions: permissions,
-		},
-	})
+// LoginMFA godoc
+// @Summary Complete 2FA Login
+// @Description Second login step: exchange the challenge token and a TOTP code (or a recovery code) for tokens. For a forced enrollment started with /auth/2fa/login/setup, the code also activates 2FA and the recovery codes are returned once.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.MFALoginRequest true "Challenge token and second factor"
+// @Success 200 {object} models.MFALoginResponse
+// @Failure 400,401,403,429 {object} map[string]interface{}
+// @Router /auth/2fa/login [post]
+func (s *AuthService) LoginMFA(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	var req models.MFALoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+	}
+
+	challenge, err := s.mfa.resolveChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		if err == repo.ErrMFAChallengeInvalid {
+			return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	user, err := s.userRepo.GetByID(challenge.UserID)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": repo.ErrMFAChallengeInvalid.Error()})
+	}
+	if !user.IsActive {
+		return c.Status(403).JSON(fiber.Map{"error": "account is inactive"})
+	}
+
+	wait, err := s.throttle.Check(ctx, user.Username, c.IP())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if wait > 0 {
+		c.Set(fiber.HeaderRetryAfter, retryAfterSeconds(wait))
+		return c.Status(429).JSON(fiber.Map{"error": "too many failed login attempts, try again later"})
+	}
+
+	mfa, err := s.mfa.mfaRepo.Get(ctx, user.ID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var ok bool
+	var recoveryCodes []string
+	switch {
+	case mfa.Enabled():
+		ok, err = s.mfa.verifySecondFactor(ctx, mfa, req.Code, req.RecoveryCode)
+	case mfa != nil:
+		recoveryCodes, ok, err = s.mfa.enroll(ctx, mfa, req.Code)
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "two-factor enrollment required, start it with /auth/2fa/login/setup"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if !ok {
+		if err := s.mfa.mfaRepo.FailChallenge(ctx, challenge.ID); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err := s.throttle.RecordFailure(ctx, user.Username, c.IP()); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(401).JSON(fiber.Map{"error": "invalid code"})
+	}
+
+	if err := s.mfa.mfaRepo.ConsumeChallenge(ctx, challenge.ID, mfaChallengeAttempts); err != nil {
+		if err == repo.ErrMFAChallengeInvalid {
+			return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := s.throttle.RecordSuccess(ctx, user.Username); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	_, roleName, _ := s.userRepo.GetByUsername(user.Username)
+	permissions, err := s.userRepo.GetPermissionsByRoleID(user.RoleID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	resp, err := s.issueLogin(c, user, roleName, permissions)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(models.MFALoginResponse{LoginResponse: *resp, RecoveryCodes: recoveryCodes})
 }
 
 // Refresh godoc
 // @Summary Refresh Access Token
-// @Description Get new access token using refresh token
+// @Description Rotate the refresh token and get a new access token. Reusing an already rotated refresh token revokes the whole session.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param request body object{refreshToken=string} true "Refresh Token"
 // @Success 200 {object} map[string]string
-// @Failure 400,401 {object} map[string]interface{}
+// @Failure 400,401,403 {object} map[string]interface{}
 // @Router /auth/refresh [post]
 func (s *AuthService) Refresh(c *fiber.Ctx) error {
+	ctx := c.Context()
+
 	var req struct {
 		RefreshToken string `json:"refreshToken"`
 	}
@@ -106,35 +294,148 @@ func (s *AuthService) Refresh(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid user id"})
 	}
 
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "invalid refresh token"})
+	}
+
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil || session.UserID != userID {
+		return c.Status(401).JSON(fiber.Map{"error": "invalid refresh token"})
+	}
+
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return c.Status(401).JSON(fiber.Map{"error": "session expired or revoked"})
+	}
+
+	// A signed token for this session whose jti is no longer current has already
+	// been rotated once, so someone else holds a copy. Kill the whole session.
+	if session.RefreshJTI != claims.ID {
+		_ = s.sessionRepo.Revoke(ctx, session.ID)
+		middleware.InvalidateSession(session.ID)
+		return c.Status(401).JSON(fiber.Map{"error": "refresh token reuse detected, session revoked"})
+	}
+
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
 	}
 
-	permissions, _ := s.userRepo.GetPermissionsByRoleID(user.RoleID)
+	if !user.IsActive {
+		_ = s.sessionRepo.Revoke(ctx, session.ID)
+		middleware.InvalidateSession(session.ID)
+		return c.Status(403).JSON(fiber.Map{"error": "account is inactive"})
+	}
+
+	newJTI := uuid.New().String()
+	if err := s.sessionRepo.Rotate(ctx, session.ID, claims.ID, newJTI, time.Now().Add(utils.RefreshTokenTTL)); err != nil {
+		if err == repo.ErrSessionNotFound {
+			_ = s.sessionRepo.Revoke(ctx, session.ID)
+			middleware.InvalidateSession(session.ID)
+			return c.Status(401).JSON(fiber.Map{"error": "refresh token reuse detected, session revoked"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	_, roleName, _ := s.userRepo.GetByUsername(user.Username)
 
-	newToken, err := utils.GenerateToken(user, roleName, permissions)
+	newToken, err := utils.GenerateToken(user, roleName, session.ID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	newRefresh, err := utils.GenerateRefreshToken(user, session.ID, newJTI)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.JSON(fiber.Map{"token": newToken})
+	return c.JSON(fiber.Map{"token": newToken, "refreshToken": newRefresh})
 }
 
 // Logout godoc
 // @Summary User Logout
-// @Description Logout user (Client side should clear token)
+// @Description Revoke the current session so its refresh token can no longer be used
 // @Tags Authentication
 // @Security BearerAuth
 // @Success 200 {object} map[string]string
+// @Failure 500 {object} map[string]interface{}
 // @Router /auth/logout [post]
 func (s *AuthService) Logout(c *fiber.Ctx) error {
+	sessionID, ok := c.Locals("session_id").(uuid.UUID)
+	if ok && sessionID != uuid.Nil {
+		if err := s.sessionRepo.Revoke(c.Context(), sessionID); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		middleware.InvalidateSession(sessionID)
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "logout successful",
 	})
 }
 
+// GetSessions godoc
+// @Summary List Active Sessions
+// @Description List the current user's active sessions (one per logged-in device)
+// @Tags Authentication
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.SessionResp
+// @Failure 500 {object} map[string]interface{}
+// @Router /auth/sessions [get]
+func (s *AuthService) GetSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+	currentID, _ := c.Locals("session_id").(uuid.UUID)
+
+	sessions, err := s.sessionRepo.ListActiveByUser(c.Context(), userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	resp := make([]models.SessionResp, 0, len(sessions))
+	for _, ses := range sessions {
+		resp = append(resp, models.SessionResp{
+			ID:         ses.ID,
+			UserAgent:  ses.UserAgent,
+			IPAddress:  ses.IPAddress,
+			IssuedAt:   ses.IssuedAt,
+			ExpiresAt:  ses.ExpiresAt,
+			LastUsedAt: ses.LastUsedAt,
+			Current:    ses.ID == currentID,
+		})
+	}
+
+	return c.JSON(resp)
+}
+
+// RevokeSession godoc
+// @Summary Revoke Session
+// @Description Revoke one of the current user's sessions
+// @Tags Authentication
+// @Security BearerAuth
+// @Param id path string true "Session UUID"
+// @Success 200 {object} map[string]string
+// @Failure 400,404,500 {object} map[string]interface{}
+// @Router /auth/sessions/{id} [delete]
+func (s *AuthService) RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	sessionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid session id"})
+	}
+
+	if err := s.sessionRepo.RevokeForUser(c.Context(), sessionID, userID); err != nil {
+		if err == repo.ErrSessionNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": "session not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	middleware.InvalidateSession(sessionID)
+
+	return c.JSON(fiber.Map{"message": "session revoked"})
+}
+
 // Profile godoc
 // @Summary Get User Profile
 // @Description Get currently logged in user profile
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/middleware"
+	"student-performance-report/utils"
+)
+
+// ErrInvalidCredentials is returned by an Authenticator for a wrong password.
+// Any other error means the check itself could not be done.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// Authenticator checks the password of an existing local user.
+type Authenticator interface {
+	Authenticate(ctx context.Context, user *models.User, password string) (*AuthResult, error)
+}
+
+// AuthResult carries what the authenticator learned about the user. A
+// non-empty RoleName replaces the user's local role.
+type AuthResult struct {
+	RoleName string
+}
+
+// PasswordAuthenticator checks the bcrypt hash in users.password_hash.
+type PasswordAuthenticator struct{}
+
+func (PasswordAuthenticator) Authenticate(ctx context.Context, user *models.User, password string) (*AuthResult, error) {
+	if !utils.CheckPasswordHash(password, user.PasswordHash) {
+		return nil, ErrInvalidCredentials
+	}
+	return &AuthResult{}, nil
+}
+
+// Directory verifies credentials against an external directory and returns
+// the groups the user belongs to. utils.LDAPDirectory implements it.
+type Directory interface {
+	Authenticate(ctx context.Context, username, password string) ([]string, error)
+}
+
+// GroupRole maps a directory group (by cn, case-insensitive) to a local role.
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+// LDAPAuthenticator binds to the directory as the user. The first GroupRole
+// whose group the user is in decides the role; without a match the local
+// role is kept.
+type LDAPAuthenticator struct {
+	dir        Directory
+	groupRoles []GroupRole
+}
+
+func NewLDAPAuthenticator(dir Directory, groupRoles []GroupRole) *LDAPAuthenticator {
+	return &LDAPAuthenticator{dir: dir, groupRoles: groupRoles}
+}
+
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, user *models.User, password string) (*AuthResult, error) {
+	groups, err := a.dir.Authenticate(ctx, user.Username, password)
+	if errors.Is(err, utils.ErrLDAPInvalidCredentials) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, gr := range a.groupRoles {
+		for _, g := range groups {
+			if strings.EqualFold(g, gr.Group) {
+				return &AuthResult{RoleName: gr.Role}, nil
+			}
+		}
+	}
+	return &AuthResult{}, nil
+}
+
+// AuthenticatorRule sends users with one of Roles, or whose username matches
+// UsernamePattern, to Authenticator.
+type AuthenticatorRule struct {
+	Roles           []string
+	UsernamePattern *regexp.Regexp
+	Authenticator   Authenticator
+}
+
+func (r AuthenticatorRule) matches(user *models.User, roleName string) bool {
+	for _, role := range r.Roles {
+		if strings.EqualFold(role, roleName) {
+			return true
+		}
+	}
+	return r.UsernamePattern != nil && r.UsernamePattern.MatchString(user.Username)
+}
+
+// Authenticators picks the authenticator for a user (first matching rule,
+// else the fallback) and syncs the local role when the authenticator
+// reports a different one.
+type Authenticators struct {
+	fallback  Authenticator
+	rules     []AuthenticatorRule
+	roleRepo  repo.RoleRepository
+	adminRepo repo.AdminRepository
+}
+
+func NewAuthenticators(roleRepo repo.RoleRepository, adminRepo repo.AdminRepository, fallback Authenticator, rules ...AuthenticatorRule) *Authenticators {
+	return &Authenticators{fallback: fallback, rules: rules, roleRepo: roleRepo, adminRepo: adminRepo}
+}
+
+func (a *Authenticators) pick(user *models.User, roleName string) Authenticator {
+	for _, r := range a.rules {
+		if r.matches(user, roleName) {
+			return r.Authenticator
+		}
+	}
+	return a.fallback
+}
+
+// Authenticate checks the password and returns the user's role name after
+// any sync; user.RoleID and user.TokenVersion are updated in place.
+func (a *Authenticators) Authenticate(ctx context.Context, user *models.User, roleName, password string) (string, error) {
+	result, err := a.pick(user, roleName).Authenticate(ctx, user, password)
+	if err != nil {
+		return "", err
+	}
+	if result.RoleName == "" || strings.EqualFold(result.RoleName, roleName) {
+		return roleName, nil
+	}
+
+	roles, err := a.roleRepo.GetAllRoles()
+	if err != nil {
+		return "", err
+	}
+	for _, role := range roles {
+		if !strings.EqualFold(role.Name, result.RoleName) {
+			continue
+		}
+		// AssignRole bumps token_version, so tokens issued under the old
+		// role stop working.
+		if err := a.adminRepo.AssignRole(user.ID, role.ID); err != nil {
+			return "", err
+		}
+		middleware.InvalidateTokenVersion(user.ID)
+		user.RoleID = role.ID
+		user.TokenVersion++
+		return role.Name, nil
+	}
+	return "", fmt.Errorf("role %q mapped from the directory does not exist", result.RoleName)
+}
@@ -2,17 +2,18 @@ package service
 
 import (
 	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/policy"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"student-performance-report/middleware"
 )
 
 type LecturerService struct {
 	lecturerRepo repo.LecturerRepository
+	access       *policy.AccessPolicy
 }
 
-func NewLecturerService(r repo.LecturerRepository) *LecturerService {
-	return &LecturerService{lecturerRepo: r}
+func NewLecturerService(r repo.LecturerRepository, p *policy.AccessPolicy) *LecturerService {
+	return &LecturerService{lecturerRepo: r, access: p}
 }
 
 // GetAllLecturers godoc
@@ -24,9 +25,6 @@ func NewLecturerService(r repo.LecturerRepository) *LecturerService {
 // @Success 200 {array} models.Lecturer
 // @Router /lecturers [get]
 func (s *LecturerService) GetAllLecturers(c *fiber.Ctx) error {
-	if !middleware.HasPermission(c, "manage:lecturers") {
-		return fiber.ErrForbidden
-	}
 	data, err := s.lecturerRepo.GetAllLecturers()
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
@@ -35,9 +33,6 @@ func (s *LecturerService) GetAllLecturers(c *fiber.Ctx) error {
 }
 
 func (s *LecturerService) GetLecturerByID(c *fiber.Ctx) error {
-	if !middleware.HasPermission(c, "manage:lecturers") {
-		return fiber.ErrForbidden
-	}
 
 	id, _ := uuid.Parse(c.Params("id"))
 
@@ -51,18 +46,27 @@ func (s *LecturerService) GetLecturerByID(c *fiber.Ctx) error {
 
 // GetAdvisees godoc
 // @Summary Get Lecturer Advisees
-// @Description Get list of students advised by this lecturer
+// @Description Get list of students advised by this lecturer (the lecturer itself or admin)
 // @Tags Students & Lecturers
 // @Security BearerAuth
 // @Param id path string true "Lecturer UUID"
 // @Produce json
 // @Success 200 {array} models.Student
+// @Failure 400,401,403,500 {object} map[string]interface{}
 // @Router /lecturers/{id}/advisees [get]
 func (s *LecturerService) GetAdvisees(c *fiber.Ctx) error {
-	if !middleware.HasPermission(c, "manage:students") {
-		return fiber.ErrForbidden
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID format"})
+	}
+
+	actor, err := s.access.Actor(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !s.access.CanAccessLecturer(actor, id) {
+		return c.Status(403).JSON(fiber.Map{"error": "Forbidden: You can only view your own advisees"})
 	}
-	id, _ := uuid.Parse(c.Params("id"))
 
 	students, err := s.lecturerRepo.GetAdvisees(id)
 	if err != nil {
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LockoutPolicy describes how one scope (username or IP) is throttled. The
+// first FreeFailures failures cost nothing, every further one doubles the
+// wait starting at BackoffBase (capped at BackoffMax), and reaching
+// MaxFailures locks the subject for Lockout. Failures older than Window are
+// forgotten.
+type LockoutPolicy struct {
+	FreeFailures int
+	MaxFailures  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	Lockout      time.Duration
+	Window       time.Duration
+}
+
+// Delay returns how long a subject is blocked after its n-th failure.
+func (p LockoutPolicy) Delay(failures int) time.Duration {
+	if failures >= p.MaxFailures {
+		return p.Lockout
+	}
+	if failures <= p.FreeFailures {
+		return 0
+	}
+	delay := float64(p.BackoffBase) * math.Pow(2, float64(failures-p.FreeFailures-1))
+	if delay > float64(p.BackoffMax) {
+		return p.BackoffMax
+	}
+	return time.Duration(delay)
+}
+
+// LoginThrottle counts failed logins per username and per client IP and
+// blocks further attempts according to each scope's LockoutPolicy.
+type LoginThrottle struct {
+	attempts repo.LoginAttemptRepository
+	policies map[string]LockoutPolicy
+}
+
+func NewLoginThrottle(attempts repo.LoginAttemptRepository, username, ip LockoutPolicy) *LoginThrottle {
+	return &LoginThrottle{
+		attempts: attempts,
+		policies: map[string]LockoutPolicy{
+			models.LoginScopeUsername: username,
+			models.LoginScopeIP:       ip,
+		},
+	}
+}
+
+// loginSubjects returns the counters a login attempt touches. Usernames are
+// compared case-insensitively so "Budi" and "budi" share one counter.
+func loginSubjects(username, ip string) [][2]string {
+	var keys [][2]string
+	if u := strings.ToLower(strings.TrimSpace(username)); u != "" {
+		keys = append(keys, [2]string{models.LoginScopeUsername, u})
+	}
+	if ip != "" {
+		keys = append(keys, [2]string{models.LoginScopeIP, ip})
+	}
+	return keys
+}
+
+// Check returns how long the caller still has to wait, or zero if the
+// attempt may go ahead.
+func (t *LoginThrottle) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range loginSubjects(username, ip) {
+		a, err := t.attempts.Get(ctx, key[0], key[1])
+		if err != nil {
+			return 0, err
+		}
+		if a != nil && a.LockedUntil != nil && a.LockedUntil.After(now) {
+			if left := a.LockedUntil.Sub(now); left > wait {
+				wait = left
+			}
+		}
+	}
+	return wait, nil
+}
+
+func (t *LoginThrottle) RecordFailure(ctx context.Context, username, ip string) error {
+	now := time.Now()
+	for _, key := range loginSubjects(username, ip) {
+		policy := t.policies[key[0]]
+		failures, err := t.attempts.RecordFailure(ctx, key[0], key[1], now, policy.Window)
+		if err != nil {
+			return err
+		}
+		if delay := policy.Delay(failures); delay > 0 {
+			if err := t.attempts.Lock(ctx, key[0], key[1], now.Add(delay)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RecordSuccess resets the username counter. The IP counter is kept so one
+// valid account cannot be used to reset an address that is guessing others.
+func (t *LoginThrottle) RecordSuccess(ctx context.Context, username string) error {
+	return t.attempts.Clear(ctx, models.LoginScopeUsername, strings.ToLower(strings.TrimSpace(username)))
+}
+
+// GetLoginLocks godoc
+// @Summary List Login Locks
+// @Description List usernames and client IPs that are currently blocked after failed logins
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.LoginLockResp
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/login-locks [get]
+func (t *LoginThrottle) GetLoginLocks(c *fiber.Ctx) error {
+	locked, err := t.attempts.ListLocked(c.Context(), time.Now())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	resp := make([]models.LoginLockResp, 0, len(locked))
+	for _, a := range locked {
+		resp = append(resp, models.LoginLockResp{
+			Scope:         a.Scope,
+			Subject:       a.Subject,
+			Failures:      a.Failures,
+			LastFailureAt: a.LastFailureAt,
+			LockedUntil:   *a.LockedUntil,
+			LockedOut:     a.Failures >= t.policies[a.Scope].MaxFailures,
+		})
+	}
+	return c.JSON(resp)
+}
+
+// ClearLoginLock godoc
+// @Summary Clear Login Lock
+// @Description Reset the failed-login counter of a username or client IP
+// @Tags Admin
+// @Security BearerAuth
+// @Param scope query string true "username or ip"
+// @Param subject query string true "Username or IP address"
+// @Success 200 {object} map[string]string
+// @Failure 400,404,500 {object} map[string]interface{}
+// @Router /admin/login-locks [delete]
+func (t *LoginThrottle) ClearLoginLock(c *fiber.Ctx) error {
+	scope := c.Query("scope")
+	subject := strings.TrimSpace(c.Query("subject"))
+	if _, ok := t.policies[scope]; !ok || subject == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "scope must be username or ip and subject is required"})
+	}
+	if scope == models.LoginScopeUsername {
+		subject = strings.ToLower(subject)
+	}
+
+	a, err := t.attempts.Get(c.Context(), scope, subject)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if a == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "no failed logins recorded for " + scope + " " + subject})
+	}
+
+	if err := t.attempts.Clear(c.Context(), scope, subject); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "login lock cleared"})
+}
+
+func retryAfterSeconds(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
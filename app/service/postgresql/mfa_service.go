@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"time"
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	mfaChallengePrefix   = "mfa_"
+	mfaChallengeAttempts = 5
+	mfaRecoveryCodeCount = 10
+)
+
+type MFAService struct {
+	mfaRepo             repo.MFARepository
+	userRepo            repo.UserRepository
+	passwordRepo        repo.PasswordRepository
+	issuer              string
+	challengeTTL        time.Duration
+	requiredPermissions []string
+}
+
+func NewMFAService(mfaRepo repo.MFARepository, userRepo repo.UserRepository, passwordRepo repo.PasswordRepository, issuer string, challengeTTL time.Duration, requiredPermissions []string) *MFAService {
+	return &MFAService{
+		mfaRepo:             mfaRepo,
+		userRepo:            userRepo,
+		passwordRepo:        passwordRepo,
+		issuer:              issuer,
+		challengeTTL:        challengeTTL,
+		requiredPermissions: requiredPermissions,
+	}
+}
+
+// requiredFor reports whether a role with these permissions must use 2FA.
+func (s *MFAService) requiredFor(permissions []string) bool {
+	for _, p := range permissions {
+		for _, required := range s.requiredPermissions {
+			if p == required {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *MFAService) startChallenge(ctx context.Context, userID uuid.UUID, ip string) (*models.MFAChallengeResp, error) {
+	token, hash, err := utils.GenerateOpaqueToken(mfaChallengePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ch := &models.MFAChallenge{
+		ID:        uuid.New(),
+		UserID:    userID,
+		TokenHash: hash,
+		IPAddress: ip,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.challengeTTL),
+	}
+	if err := s.mfaRepo.CreateChallenge(ctx, ch); err != nil {
+		return nil, err
+	}
+	return &models.MFAChallengeResp{MFARequired: true, ChallengeToken: token, ExpiresAt: ch.ExpiresAt}, nil
+}
+
+func (s *MFAService) resolveChallenge(ctx context.Context, token string) (*models.MFAChallenge, error) {
+	if token == "" {
+		return nil, repo.ErrMFAChallengeInvalid
+	}
+	ch, err := s.mfaRepo.GetChallenge(ctx, utils.HashOpaqueToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if ch.UsedAt != nil || time.Now().After(ch.ExpiresAt) || ch.Attempts >= mfaChallengeAttempts {
+		return nil, repo.ErrMFAChallengeInvalid
+	}
+	return ch, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code.
+func (s *MFAService) verifySecondFactor(ctx context.Context, m *models.UserMFA, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return s.mfaRepo.UseRecoveryCode(ctx, m.UserID, utils.HashOpaqueToken(utils.NormalizeRecoveryCode(recoveryCode)))
+	}
+
+	step, ok := utils.ValidateTOTP(m.Secret, code, time.Now())
+	if !ok || step <= m.LastUsedStep {
+		return false, nil
+	}
+	return s.mfaRepo.UseStep(ctx, m.UserID, step)
+}
+
+// enroll confirms a pending secret with a valid code and returns the new
+// recovery codes in plain text; only their hashes are stored.
+func (s *MFAService) enroll(ctx context.Context, m *models.UserMFA, code string) ([]string, bool, error) {
+	step, ok := utils.ValidateTOTP(m.Secret, code, time.Now())
+	if !ok {
+		return nil, false, nil
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(mfaRecoveryCodeCount)
+	if err != nil {
+		return nil, false, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashOpaqueToken(code)
+	}
+
+	if err := s.mfaRepo.Enable(ctx, m.UserID, step, hashes); err != nil {
+		return nil, false, err
+	}
+	return codes, true, nil
+}
+
+func (s *MFAService) beginEnrollment(c *fiber.Ctx, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := s.mfaRepo.SavePendingSecret(c.Context(), userID, secret); err != nil {
+		if err == repo.ErrMFAAlreadyEnabled {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(models.MFASetupResp{
+		Secret:     secret,
+		OtpauthURL: utils.TOTPAuthURL(s.issuer, user.Username, secret),
+	})
+}
+
+// Setup godoc
+// @Summary Start 2FA Enrollment
+// @Description Generate a new TOTP secret for the current user. 2FA is not active until the secret is confirmed with /auth/2fa/confirm.
+// @Tags Authentication
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.MFASetupResp
+// @Failure 404,409,500 {object} map[string]interface{}
+// @Router /auth/2fa/setup [post]
+func (s *MFAService) Setup(c *fiber.Ctx) error {
+	return s.beginEnrollment(c, c.Locals("user_id").(uuid.UUID))
+}
+
+// Confirm godoc
+// @Summary Confirm 2FA Enrollment
+// @Description Activate 2FA with a code from the authenticator app. The recovery codes are returned only once.
+// @Tags Authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.MFACodeRequest true "TOTP code"
+// @Success 200 {object} models.MFARecoveryCodesResp
+// @Failure 400,409,500 {object} map[string]interface{}
+// @Router /auth/2fa/confirm [post]
+func (s *MFAService) Confirm(c *fiber.Ctx) error {
+	ctx := c.Context()
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req models.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+	}
+
+	m, err := s.mfaRepo.Get(ctx, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if m == nil {
+		return c.Status(400).JSON(fiber.Map{"error": repo.ErrMFANotPending.Error()})
+	}
+	if m.Enabled() {
+		return c.Status(409).JSON(fiber.Map{"error": repo.ErrMFAAlreadyEnabled.Error()})
+	}
+
+	codes, ok, err := s.enroll(ctx, m, req.Code)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid code"})
+	}
+
+	return c.JSON(models.MFARecoveryCodesResp{RecoveryCodes: codes})
+}
+
+// Disable godoc
+// @Summary Disable 2FA
+// @Description Turn 2FA off for the current user. Requires the password and a TOTP or recovery code, and is refused when the user's role requires 2FA.
+// @Tags Authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.MFADisableRequest true "Password and second factor"
+// @Success 200 {object} map[string]string
+// @Failure 400,403,500 {object} map[string]interface{}
+// @Router /auth/2fa/disable [post]
+func (s *MFAService) Disable(c *fiber.Ctx) error {
+	ctx := c.Context()
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req models.MFADisableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+	}
+
+	m, err := s.mfaRepo.Get(ctx, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !m.Enabled() {
+		return c.Status(400).JSON(fiber.Map{"error": "two-factor authentication is not enabled"})
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+	permissions, err := s.userRepo.GetPermissionsByRoleID(user.RoleID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if s.requiredFor(permissions) {
+		return c.Status(403).JSON(fiber.Map{"error": "two-factor authentication is mandatory for your role"})
+	}
+
+	hash, err := s.passwordRepo.GetPasswordHash(ctx, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !utils.CheckPasswordHash(req.Password, hash) {
+		return c.Status(400).JSON(fiber.Map{"error": "password is incorrect"})
+	}
+
+	ok, err := s.verifySecondFactor(ctx, m, req.Code, req.RecoveryCode)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid code"})
+	}
+
+	if err := s.mfaRepo.Disable(ctx, userID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "two-factor authentication disabled"})
+}
+
+// LoginSetup godoc
+// @Summary Start Required 2FA Enrollment During Login
+// @Description For users whose role requires 2FA but who have not enrolled yet. Exchange the login challenge for a TOTP secret, then finish with /auth/2fa/login.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.MFAChallengeRequest true "Challenge token from /auth/login"
+// @Success 200 {object} models.MFASetupResp
+// @Failure 400,401,409,500 {object} map[string]interface{}
+// @Router /auth/2fa/login/setup [post]
+func (s *MFAService) LoginSetup(c *fiber.Ctx) error {
+	var req models.MFAChallengeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+	}
+
+	ch, err := s.resolveChallenge(c.Context(), req.ChallengeToken)
+	if err != nil {
+		if err == repo.ErrMFAChallengeInvalid {
+			return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return s.beginEnrollment(c, ch.UserID)
+}
+
+// ResetUserMFA godoc
+// @Summary Reset User 2FA
+// @Description Remove a user's 2FA enrollment and recovery codes, e.g. after a lost phone. Users whose role requires 2FA must enroll again at their next login.
+// @Tags Users
+// @Security BearerAuth
+// @Param id path string true "User UUID"
+// @Success 200 {object} map[string]string
+// @Failure 400,404,500 {object} map[string]interface{}
+// @Router /users/{id}/2fa [delete]
+func (s *MFAService) ResetUserMFA(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	m, err := s.mfaRepo.Get(ctx, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if m == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user has no two-factor enrollment"})
+	}
+
+	if err := s.mfaRepo.Disable(ctx, userID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "two-factor authentication reset"})
+}
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// OIDCProvisioning controls whether unknown single sign-on users get a
+// student account, and which ID token claims fill the student profile.
+type OIDCProvisioning struct {
+	Enabled           bool
+	RoleName          string
+	StudentIDClaim    string
+	ProgramStudyClaim string
+	AcademicYearClaim string
+}
+
+type OIDCService struct {
+	provider     *utils.OIDCProvider
+	oidcRepo     repo.OIDCRepository
+	admin        *AdminService
+	auth         *AuthService
+	stateTTL     time.Duration
+	provisioning OIDCProvisioning
+}
+
+// NewOIDCService takes a nil provider when single sign-on is not configured;
+// the endpoints then answer 404.
+func NewOIDCService(provider *utils.OIDCProvider, oidcRepo repo.OIDCRepository, admin *AdminService, auth *AuthService, stateTTL time.Duration, provisioning OIDCProvisioning) *OIDCService {
+	return &OIDCService{
+		provider:     provider,
+		oidcRepo:     oidcRepo,
+		admin:        admin,
+		auth:         auth,
+		stateTTL:     stateTTL,
+		provisioning: provisioning,
+	}
+}
+
+func randomURLToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// OIDCLogin godoc
+// @Summary Start Single Sign-On
+// @Description Redirect to the university identity provider (authorization code flow with PKCE)
+// @Tags Authentication
+// @Success 302
+// @Failure 404,502 {object} map[string]interface{}
+// @Router /auth/oidc/login [get]
+func (s *OIDCService) OIDCLogin(c *fiber.Ctx) error {
+	if s.provider == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "single sign-on is not configured"})
+	}
+
+	state, err := randomURLToken()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	nonce, err := randomURLToken()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	verifier, challenge, err := utils.NewPKCEVerifier()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	authURL, err := s.provider.AuthCodeURL(c.Context(), state, nonce, challenge)
+	if err != nil {
+		return c.Status(502).JSON(fiber.Map{"error": "identity provider unavailable: " + err.Error()})
+	}
+
+	now := time.Now()
+	if err := s.oidcRepo.SaveLoginState(c.Context(), &models.OIDCLoginState{
+		ID:           uuid.New(),
+		StateHash:    utils.HashOpaqueToken(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(s.stateTTL),
+	}); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback godoc
+// @Summary Finish Single Sign-On
+// @Description Exchange the authorization code, verify the ID token and log in the linked user (or a newly provisioned student). Returns the same body as /auth/login.
+// @Tags Authentication
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from /auth/oidc/login"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400,401,403,404,409,502 {object} map[string]interface{}
+// @Router /auth/oidc/callback [get]
+func (s *OIDCService) OIDCCallback(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	if s.provider == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "single sign-on is not configured"})
+	}
+	if e := c.Query("error"); e != "" {
+		return c.Status(401).JSON(fiber.Map{"error": "identity provider refused the login: " + e})
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "code and state are required"})
+	}
+
+	login, err := s.oidcRepo.ConsumeLoginState(ctx, utils.HashOpaqueToken(state))
+	if err != nil {
+		if err == repo.ErrOIDCStateInvalid {
+			return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	rawIDToken, err := s.provider.Exchange(ctx, code, login.CodeVerifier)
+	if err != nil {
+		return c.Status(502).JSON(fiber.Map{"error": "code exchange failed: " + err.Error()})
+	}
+
+	claims, err := s.provider.VerifyIDToken(ctx, rawIDToken, login.Nonce)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "invalid ID token: " + err.Error()})
+	}
+
+	userID, status, err := s.resolveUser(c, claims)
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	user, err := s.admin.userRepo.GetByID(userID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+	_, roleName, _ := s.admin.userRepo.GetByUsername(user.Username)
+
+	return s.auth.completeLogin(c, user, roleName)
+}
+
+// resolveUser finds the local user for the ID token: an existing link, then
+// a user with the same verified email (which gets linked), then, if enabled,
+// a newly provisioned student. The int is the HTTP status for err.
+func (s *OIDCService) resolveUser(c *fiber.Ctx, claims jwt.MapClaims) (uuid.UUID, int, error) {
+	ctx := c.Context()
+	issuer := s.provider.Issuer()
+	subject := claimString(claims, "sub")
+	email := strings.TrimSpace(claimString(claims, "email"))
+
+	userID, err := s.oidcRepo.FindUserByIdentity(ctx, issuer, subject)
+	if err != nil {
+		return uuid.Nil, 500, err
+	}
+	if userID != uuid.Nil {
+		if err := s.oidcRepo.TouchIdentity(ctx, issuer, subject); err != nil {
+			return uuid.Nil, 500, err
+		}
+		return userID, 0, nil
+	}
+
+	// Emails the provider has not verified are never trusted for matching.
+	if email == "" || !claimBool(claims, "email_verified") {
+		return uuid.Nil, 403, fmt.Errorf("no account is linked to this identity and the provider did not supply a verified email")
+	}
+
+	userID, err = s.oidcRepo.FindUserByEmail(ctx, email)
+	if err != nil {
+		return uuid.Nil, 500, err
+	}
+	if userID == uuid.Nil {
+		if !s.provisioning.Enabled {
+			return uuid.Nil, 403, fmt.Errorf("no account exists for %s", email)
+		}
+		var status int
+		userID, status, err = s.provision(claims, email)
+		if err != nil {
+			return uuid.Nil, status, err
+		}
+	}
+
+	// A failed provisioning or link is retried on the next login through the
+	// email match above.
+	if err := s.oidcRepo.LinkIdentity(ctx, &models.UserIdentity{
+		Issuer:  issuer,
+		Subject: subject,
+		UserID:  userID,
+		Email:   email,
+	}); err != nil {
+		return uuid.Nil, 500, err
+	}
+	return userID, 0, nil
+}
+
+// provision creates a student from the ID token claims through the same
+// validation as POST /users. The account gets a random password; the
+// student can ask an admin for a reset token to log in locally.
+func (s *OIDCService) provision(claims jwt.MapClaims, email string) (uuid.UUID, int, error) {
+	roles, err := s.admin.roleRepo.GetAllRoles()
+	if err != nil {
+		return uuid.Nil, 500, err
+	}
+	var roleID string
+	for _, r := range roles {
+		if strings.EqualFold(r.Name, s.provisioning.RoleName) {
+			roleID = r.ID.String()
+		}
+	}
+	if roleID == "" {
+		return uuid.Nil, 500, fmt.Errorf("provisioning role %q does not exist", s.provisioning.RoleName)
+	}
+
+	password, err := utils.GenerateTemporaryPassword()
+	if err != nil {
+		return uuid.Nil, 500, err
+	}
+
+	username := provisionUsername(claims, email)
+	takenUsername, takenEmail, err := s.admin.adminRepo.FindTakenCredentials(username, email)
+	if err != nil {
+		return uuid.Nil, 500, err
+	}
+	if takenEmail {
+		return uuid.Nil, 409, fmt.Errorf("email %s is already used by another account", email)
+	}
+	if takenUsername {
+		username = fmt.Sprintf("%s.%s", username, uuid.NewString()[:4])
+	}
+
+	req := models.CreateUserRequest{
+		Username: username,
+		Email:    email,
+		Password: password,
+		FullName: claimString(claims, "name"),
+		RoleID:   roleID,
+		StudentProfile: &models.StudentProfileRequest{
+			StudentID:    claimString(claims, s.provisioning.StudentIDClaim),
+			ProgramStudy: claimString(claims, s.provisioning.ProgramStudyClaim),
+			AcademicYear: claimString(claims, s.provisioning.AcademicYearClaim),
+		},
+	}
+	account, fieldErrors, err := s.admin.prepareNewUser(&req, s.admin.lookupRole)
+	if err != nil {
+		return uuid.Nil, 500, err
+	}
+	if len(fieldErrors) > 0 {
+		missing := make([]string, 0, len(fieldErrors))
+		for field, msg := range fieldErrors {
+			missing = append(missing, field+" "+msg)
+		}
+		sort.Strings(missing)
+		return uuid.Nil, 403, fmt.Errorf("cannot create an account from the identity provider claims: %s", strings.Join(missing, "; "))
+	}
+
+	if err := account.hashPassword(); err != nil {
+		return uuid.Nil, 500, err
+	}
+	if err := s.admin.adminRepo.CreateUserWithProfile(account.user, account.student, account.lecturer); err != nil {
+		if errors.Is(err, repo.ErrDuplicateUser) {
+			return uuid.Nil, 409, err
+		}
+		return uuid.Nil, 500, err
+	}
+	return account.user.ID, 0, nil
+}
+
+// provisionUsername prefers preferred_username and falls back to the local
+// part of the email, keeping only characters usernames may contain.
+func provisionUsername(claims jwt.MapClaims, email string) string {
+	candidate := claimString(claims, "preferred_username")
+	if !usernamePattern.MatchString(candidate) {
+		candidate = strings.Split(email, "@")[0]
+	}
+	candidate = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return -1
+	}, candidate)
+	if len(candidate) < 3 {
+		candidate = "sso." + candidate
+	}
+	if len(candidate) > 45 {
+		candidate = candidate[:45]
+	}
+	return candidate
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
+
+// claimBool accepts true and "true"; some providers send booleans as strings.
+func claimBool(claims jwt.MapClaims, name string) bool {
+	switch v := claims[name].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+    "time"
+    models "student-performance-report/app/models/postgresql"
+    repo "student-performance-report/app/repository/postgresql"
+    "github.com/gofiber/fiber/v2"
+    "github.com/google/uuid"
+    "student-performance-report/middleware"
+    "student-performance-report/utils"
+)
+
+const resetTokenPrefix = "prt_"
+
+type PasswordService struct {
+    passwordRepo repo.PasswordRepository
+    adminRepo    repo.AdminRepository
+    resetTTL     time.Duration
+}
+
+func NewPasswordService(passwordRepo repo.PasswordRepository, adminRepo repo.AdminRepository, resetTTL time.Duration) *PasswordService {
+    return &PasswordService{passwordRepo: passwordRepo, adminRepo: adminRepo, resetTTL: resetTTL}
+}
+
+// ChangePassword godoc
+// @Summary Change Password
+// @Description Change the current user's password. All sessions, including the current one, are revoked and the user must log in again.
+// @Tags Authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400,401,500 {object} map[string]interface{}
+// @Router /auth/password [put]
+func (s *PasswordService) ChangePassword(c *fiber.Ctx) error {
+    ctx := c.Context()
+    userID := c.Locals("user_id").(uuid.UUID)
+
+    var req models.ChangePasswordRequest
+    if err := c.BodyParser(&req); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+    }
+
+    if req.CurrentPassword == "" || req.NewPassword == "" {
+        return c.Status(400).JSON(fiber.Map{"error": "currentPassword and newPassword are required"})
+    }
+
+    currentHash, err := s.passwordRepo.GetPasswordHash(ctx, userID)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    if !utils.CheckPasswordHash(req.CurrentPassword, currentHash) {
+        return c.Status(400).JSON(fiber.Map{"error": "current password is incorrect"})
+    }
+
+    if req.NewPassword == req.CurrentPassword {
+        return c.Status(400).JSON(fiber.Map{"error": "new password must differ from the current one"})
+    }
+
+    if err := utils.ValidatePassword(req.NewPassword); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    hashed, err := utils.HashPassword(req.NewPassword)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    if err := s.passwordRepo.UpdatePassword(ctx, userID, hashed); err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+    middleware.InvalidateTokenVersion(userID)
+
+    return c.JSON(fiber.Map{"message": "password changed, please log in again"})
+}
+
+// IssueResetToken godoc
+// @Summary Issue Password Reset Token
+// @Description Issue a one-time password reset token for a user (Admin only). The token is shown only in this response; issuing a new one voids the previous one.
+// @Tags Users
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User UUID"
+// @Success 201 {object} models.PasswordResetTokenResp
+// @Failure 400,403,404,500 {object} map[string]interface{}
+// @Router /users/{id}/reset-password [post]
+func (s *PasswordService) IssueResetToken(c *fiber.Ctx) error {
+    targetID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid user id"})
+    }
+
+    user, err := s.adminRepo.GetUserByID(targetID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+    }
+    if !user.IsActive {
+        return c.Status(400).JSON(fiber.Map{"error": "user is inactive"})
+    }
+
+    token, tokenHash, err := utils.GenerateOpaqueToken(resetTokenPrefix)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    now := time.Now()
+    reset := &models.PasswordResetToken{
+        ID:        uuid.New(),
+        UserID:    user.ID,
+        TokenHash: tokenHash,
+        CreatedAt: now,
+        ExpiresAt: now.Add(s.resetTTL),
+    }
+    if adminID, ok := c.Locals("user_id").(uuid.UUID); ok {
+        reset.CreatedBy = &adminID
+    }
+
+    if err := s.passwordRepo.CreateResetToken(c.Context(), reset); err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    return c.Status(201).JSON(models.PasswordResetTokenResp{
+        Token:     token,
+        ExpiresAt: reset.ExpiresAt,
+    })
+}
+
+// ResetPassword godoc
+// @Summary Reset Password
+// @Description Set a new password with a one-time reset token issued by an admin. All existing sessions of the user are revoked.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400,500 {object} map[string]interface{}
+// @Router /auth/reset-password [post]
+func (s *PasswordService) ResetPassword(c *fiber.Ctx) error {
+    var req models.ResetPasswordRequest
+    if err := c.BodyParser(&req); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+    }
+
+    if req.Token == "" {
+        return c.Status(400).JSON(fiber.Map{"error": "token is required"})
+    }
+
+    if err := utils.ValidatePassword(req.NewPassword); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    hashed, err := utils.HashPassword(req.NewPassword)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    userID, err := s.passwordRepo.ResetPassword(c.Context(), utils.HashOpaqueToken(req.Token), hashed)
+    if err != nil {
+        if err == repo.ErrResetTokenInvalid {
+            return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+        }
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+    middleware.InvalidateTokenVersion(userID)
+
+    return c.JSON(fiber.Map{"message": "password has been reset, please log in"})
+}
@@ -0,0 +1,491 @@
+package service
+
+import (
+    "errors"
+    "strings"
+    models "student-performance-report/app/models/postgresql"
+    repo "student-performance-report/app/repository/postgresql"
+    "github.com/gofiber/fiber/v2"
+    "github.com/google/uuid"
+    "student-performance-report/middleware"
+)
+
+// permManageUsers is the permission that makes a role an admin role. The API
+// refuses changes that would leave no role holding it.
+const permManageUsers = "manage:users"
+
+type RoleService struct {
+    roleRepo repo.RoleRepository
+    permRepo repo.PermissionRepository
+}
+
+func NewRoleService(roleRepo repo.RoleRepository, permRepo repo.PermissionRepository) *RoleService {
+    return &RoleService{roleRepo: roleRepo, permRepo: permRepo}
+}
+
+type roleRequest struct {
+    Name        string `json:"name"`
+    Description string `json:"description"`
+}
+
+type permissionRequest struct {
+    Name        string `json:"name"`
+    Resource    string `json:"resource"`
+    Action      string `json:"action"`
+    Description string `json:"description"`
+}
+
+// GetAllRoles godoc
+// @Summary Get All Roles
+// @Description Get list of all roles (Admin only)
+// @Tags Roles & Permissions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Roles
+// @Failure 403,500 {object} map[string]interface{}
+// @Router /roles [get]
+func (s *RoleService) GetAllRoles(c *fiber.Ctx) error {
+
+    roles, err := s.roleRepo.GetAllRoles()
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    return c.JSON(roles)
+}
+
+// GetRoleByID godoc
+// @Summary Get Role by ID
+// @Description Get a role together with its permissions (Admin only)
+// @Tags Roles & Permissions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Role UUID"
+// @Success 200 {object} models.RoleWithPermissions
+// @Failure 400,403,404,500 {object} map[string]interface{}
+// @Router /roles/{id} [get]
+func (s *RoleService) GetRoleByID(c *fiber.Ctx) error {
+
+    roleID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid role id"})
+    }
+
+    role, err := s.roleRepo.GetRoleByID(roleID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "role not found"})
+    }
+
+    perms, err := s.roleRepo.GetPermissionsByRole(roleID)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    return c.JSON(models.RoleWithPermissions{Roles: *role, Permissions: perms})
+}
+
+// CreateRole godoc
+// @Summary Create Role
+// @Description Create a new role (Admin only)
+// @Tags Roles & Permissions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body object{name=string,description=string} true "Role Data"
+// @Success 201 {object} models.Roles
+// @Failure 400,403,500 {object} map[string]interface{}
+// @Router /roles [post]
+func (s *RoleService) CreateRole(c *fiber.Ctx) error {
+
+    var req roleRequest
+    if err := c.BodyParser(&req); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+    }
+
+    req.Name = strings.TrimSpace(req.Name)
+    if req.Name == "" {
+        return c.Status(400).JSON(fiber.Map{"error": "role name is required"})
+    }
+
+    role := models.Roles{ID: uuid.New(), Name: req.Name, Description: req.Description}
+    if err := s.roleRepo.CreateRole(&role); err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    return c.Status(201).JSON(role)
+}
+
+// UpdateRole godoc
+// @Summary Update Role
+// @Description Update role name and description (Admin only)
+// @Tags Roles & Permissions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Role UUID"
+// @Param request body object{name=string,description=string} true "Role Data"
+// @Success 200 {object} models.Roles
+// @Failure 400,403,404 {object} map[string]interface{}
+// @Router /roles/{id} [put]
+func (s *RoleService) UpdateRole(c *fiber.Ctx) error {
+
+    roleID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid role id"})
+    }
+
+    var req roleRequest
+    if err := c.BodyParser(&req); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+    }
+
+    req.Name = strings.TrimSpace(req.Name)
+    if req.Name == "" {
+        return c.Status(400).JSON(fiber.Map{"error": "role name is required"})
+    }
+
+    role := models.Roles{ID: roleID, Name: req.Name, Description: req.Description}
+    if err := s.roleRepo.UpdateRole(&role); err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    return c.JSON(role)
+}
+
+// DeleteRole godoc
+// @Summary Delete Role
+// @Description Delete a role that has no users (Admin only)
+// @Tags Roles & Permissions
+// @Security BearerAuth
+// @Param id path string true "Role UUID"
+// @Success 200 {object} map[string]string
+// @Failure 400,403,404,409,500 {object} map[string]interface{}
+// @Router /roles/{id} [delete]
+func (s *RoleService) DeleteRole(c *fiber.Ctx) error {
+
+    roleID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid role id"})
+    }
+
+    if _, err := s.roleRepo.GetRoleByID(roleID); err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "role not found"})
+    }
+
+    users, err := s.roleRepo.CountUsersByRole(roleID)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+    if users > 0 {
+        return c.Status(409).JSON(fiber.Map{"error": "role still has users assigned"})
+    }
+
+    if last, err := s.isLastAdminRole(roleID); err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    } else if last {
+        return c.Status(409).JSON(fiber.Map{"error": "cannot delete the last role holding '" + permManageUsers + "'"})
+    }
+
+    if err := s.roleRepo.DeleteRole(roleID); err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+    middleware.InvalidatePermissions()
+
+    return c.JSON(fiber.Map{"message": "role deleted"})
+}
+
+// AttachPermission godoc
+// @Summary Attach Permission to Role
+// @Description Grant a permission to a role (Admin only)
+// @Tags Roles & Permissions
+// @Security BearerAuth
+// @Accept json
+// @Param id path string true "Role UUID"
+// @Param request body object{permissionId=string} true "Permission UUID"
+// @Success 200 {object} map[string]string
+// @Failure 400,403,404,500 {object} map[string]interface{}
+// @Router /roles/{id}/permissions [post]
+func (s *RoleService) AttachPermission(c *fiber.Ctx) error {
+
+    roleID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid role id"})
+    }
+
+    var req struct {
+        PermissionID string `json:"permissionId"`
+    }
+    if err := c.BodyParser(&req); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+    }
+
+    permID, err := uuid.Parse(req.PermissionID)
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid permission id"})
+    }
+
+    if _, err := s.roleRepo.GetRoleByID(roleID); err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "role not found"})
+    }
+    if _, err := s.permRepo.GetPermissionByID(permID); err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "permission not found"})
+    }
+
+    if err := s.roleRepo.AttachPermission(roleID, permID); err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+    middleware.InvalidatePermissions()
+
+    return c.JSON(fiber.Map{"message": "permission attached"})
+}
+
+// DetachPermission godoc
+// @Summary Detach Permission from Role
+// @Description Revoke a permission from a role (Admin only)
+// @Tags Roles & Permissions
+// @Security BearerAuth
+// @Param id path string true "Role UUID"
+// @Param permissionId path string true "Permission UUID"
+// @Success 200 {object} map[string]string
+// @Failure 400,403,404,409,500 {object} map[string]interface{}
+// @Router /roles/{id}/permissions/{permissionId} [delete]
+func (s *RoleService) DetachPermission(c *fiber.Ctx) error {
+
+    roleID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid role id"})
+    }
+
+    permID, err := uuid.Parse(c.Params("permissionId"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid permission id"})
+    }
+
+    perm, err := s.permRepo.GetPermissionByID(permID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "permission not found"})
+    }
+
+    if perm.Name == permManageUsers {
+        if last, err := s.isLastAdminRole(roleID); err != nil {
+            return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+        } else if last {
+            return c.Status(409).JSON(fiber.Map{"error": "cannot remove '" + permManageUsers + "' from the last admin role"})
+        }
+    }
+
+    if err := s.roleRepo.DetachPermission(roleID, permID); err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+    }
+    middleware.InvalidatePermissions()
+
+    return c.JSON(fiber.Map{"message": "permission detached"})
+}
+
+// isLastAdminRole reports whether roleID holds manage:users and no other role
+// with active users does, so removing it would leave nobody able to manage
+// users. Roles without active users do not count, since they give nobody
+// access.
+func (s *RoleService) isLastAdminRole(roleID uuid.UUID) (bool, error) {
+    perms, err := s.roleRepo.GetPermissionsByRole(roleID)
+    if err != nil {
+        return false, err
+    }
+
+    holds := false
+    for _, p := range perms {
+        if p.Name == permManageUsers {
+            holds = true
+            break
+        }
+    }
+    if !holds {
+        return false, nil
+    }
+
+    others, err := s.roleRepo.CountAssignedRolesWithPermission(permManageUsers, roleID)
+    if err != nil {
+        return false, err
+    }
+    return others == 0, nil
+}
+
+// GetAllPermissions godoc
+// @Summary Get All Permissions
+// @Description Get list of all permissions (Admin only)
+// @Tags Roles & Permissions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Permission
+// @Failure 403,500 {object} map[string]interface{}
+// @Router /permissions [get]
+func (s *RoleService) GetAllPermissions(c *fiber.Ctx) error {
+
+    perms, err := s.permRepo.GetAllPermissions()
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    return c.JSON(perms)
+}
+
+// GetPermissionByID godoc
+// @Summary Get Permission by ID
+// @Description Get permission details (Admin only)
+// @Tags Roles & Permissions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Permission UUID"
+// @Success 200 {object} models.Permission
+// @Failure 400,403,404 {object} map[string]interface{}
+// @Router /permissions/{id} [get]
+func (s *RoleService) GetPermissionByID(c *fiber.Ctx) error {
+
+    permID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid permission id"})
+    }
+
+    perm, err := s.permRepo.GetPermissionByID(permID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "permission not found"})
+    }
+
+    return c.JSON(perm)
+}
+
+// CreatePermission godoc
+// @Summary Create Permission
+// @Description Create a new permission, e.g. name "achievement:verify" (Admin only)
+// @Tags Roles & Permissions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body object{name=string,resource=string,action=string,description=string} true "Permission Data"
+// @Success 201 {object} models.Permission
+// @Failure 400,403,500 {object} map[string]interface{}
+// @Router /permissions [post]
+func (s *RoleService) CreatePermission(c *fiber.Ctx) error {
+
+    var req permissionRequest
+    if err := c.BodyParser(&req); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+    }
+
+    perm, err := buildPermission(uuid.New(), req)
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    if err := s.permRepo.CreatePermission(&perm); err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    return c.Status(201).JSON(perm)
+}
+
+// UpdatePermission godoc
+// @Summary Update Permission
+// @Description Update a permission (Admin only)
+// @Tags Roles & Permissions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Permission UUID"
+// @Param request body object{name=string,resource=string,action=string,description=string} true "Permission Data"
+// @Success 200 {object} models.Permission
+// @Failure 400,403,404,409 {object} map[string]interface{}
+// @Router /permissions/{id} [put]
+func (s *RoleService) UpdatePermission(c *fiber.Ctx) error {
+
+    permID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid permission id"})
+    }
+
+    var req permissionRequest
+    if err := c.BodyParser(&req); err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+    }
+
+    existing, err := s.permRepo.GetPermissionByID(permID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "permission not found"})
+    }
+
+    perm, err := buildPermission(permID, req)
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    if existing.Name == permManageUsers && perm.Name != permManageUsers {
+        return c.Status(409).JSON(fiber.Map{"error": "'" + permManageUsers + "' cannot be renamed"})
+    }
+
+    if err := s.permRepo.UpdatePermission(&perm); err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+    middleware.InvalidatePermissions()
+
+    return c.JSON(perm)
+}
+
+// DeletePermission godoc
+// @Summary Delete Permission
+// @Description Delete a permission and detach it from all roles (Admin only)
+// @Tags Roles & Permissions
+// @Security BearerAuth
+// @Param id path string true "Permission UUID"
+// @Success 200 {object} map[string]string
+// @Failure 400,403,404,409,500 {object} map[string]interface{}
+// @Router /permissions/{id} [delete]
+func (s *RoleService) DeletePermission(c *fiber.Ctx) error {
+
+    permID, err := uuid.Parse(c.Params("id"))
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "invalid permission id"})
+    }
+
+    perm, err := s.permRepo.GetPermissionByID(permID)
+    if err != nil {
+        return c.Status(404).JSON(fiber.Map{"error": "permission not found"})
+    }
+
+    if perm.Name == permManageUsers {
+        return c.Status(409).JSON(fiber.Map{"error": "'" + permManageUsers + "' cannot be deleted"})
+    }
+
+    if err := s.permRepo.DeletePermission(permID); err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+    middleware.InvalidatePermissions()
+
+    return c.JSON(fiber.Map{"message": "permission deleted"})
+}
+
+// buildPermission validates the request and fills resource/action from a
+// "resource:action" name when they are not given explicitly.
+func buildPermission(id uuid.UUID, req permissionRequest) (models.Permission, error) {
+    name := strings.TrimSpace(req.Name)
+    if name == "" {
+        return models.Permission{}, errors.New("permission name is required")
+    }
+
+    resource, action := req.Resource, req.Action
+    if parts := strings.SplitN(name, ":", 2); len(parts) == 2 {
+        if resource == "" {
+            resource = parts[0]
+        }
+        if action == "" {
+            action = parts[1]
+        }
+    }
+
+    return models.Permission{
+        ID:          id,
+        Name:        name,
+        Resource:    resource,
+        Action:      action,
+        Description: req.Description,
+    }, nil
+}
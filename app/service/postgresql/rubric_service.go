@@ -0,0 +1,194 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+	models "student-performance-report/app/models/postgresql"
+	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/scoring"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type RubricService struct {
+	rubricRepo repo.RubricRepository
+}
+
+func NewRubricService(rubricRepo repo.RubricRepository) *RubricService {
+	return &RubricService{rubricRepo: rubricRepo}
+}
+
+// rubricError maps repository errors shared by the rubric handlers.
+func rubricError(c *fiber.Ctx, err error) error {
+	switch err {
+	case repo.ErrRubricNotFound:
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case repo.ErrRubricFrozen:
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+}
+
+// parseRubricRequest reads and validates the body of create and update; any
+// error is a 400.
+func parseRubricRequest(c *fiber.Ctx) (*models.RubricRequest, error) {
+	var req models.RubricRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, errors.New("invalid JSON")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		return nil, errors.New("name is required (at most 100 characters)")
+	}
+	if err := scoring.Validate(req.Rules); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// GetRubrics godoc
+// @Summary List Scoring Rubrics
+// @Description List every rubric version, newest first (Admin only)
+// @Tags Scoring Rubrics
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.ScoringRubric
+// @Failure 403,500 {object} map[string]interface{}
+// @Router /rubrics [get]
+func (s *RubricService) GetRubrics(c *fiber.Ctx) error {
+	list, err := s.rubricRepo.List(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if list == nil {
+		list = []models.ScoringRubric{}
+	}
+	return c.JSON(list)
+}
+
+// GetRubric godoc
+// @Summary Get Scoring Rubric
+// @Description Get one rubric version with its rules (Admin only)
+// @Tags Scoring Rubrics
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Rubric UUID"
+// @Success 200 {object} models.ScoringRubric
+// @Failure 400,403,404,500 {object} map[string]interface{}
+// @Router /rubrics/{id} [get]
+func (s *RubricService) GetRubric(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid rubric id"})
+	}
+
+	rubric, err := s.rubricRepo.GetByID(c.Context(), id)
+	if err != nil {
+		return rubricError(c, err)
+	}
+	return c.JSON(rubric)
+}
+
+// CreateRubric godoc
+// @Summary Create Scoring Rubric Version
+// @Description Store a new, inactive rubric version. Each rule awards points to an achievement type whose details match all "when" conditions; the most specific matching rule wins (Admin only).
+// @Tags Scoring Rubrics
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.RubricRequest true "Name and rules"
+// @Success 201 {object} models.ScoringRubric
+// @Failure 400,403,500 {object} map[string]interface{}
+// @Router /rubrics [post]
+func (s *RubricService) CreateRubric(c *fiber.Ctx) error {
+	req, err := parseRubricRequest(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+	rubric := models.ScoringRubric{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		Rules:     req.Rules,
+		CreatedBy: &userID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.rubricRepo.Create(c.Context(), &rubric); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(rubric)
+}
+
+// UpdateRubric godoc
+// @Summary Update Scoring Rubric Version
+// @Description Change a rubric version that has never been active; active and retired versions are frozen (Admin only)
+// @Tags Scoring Rubrics
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Rubric UUID"
+// @Param request body models.RubricRequest true "Name and rules"
+// @Success 200 {object} map[string]string
+// @Failure 400,403,404,409,500 {object} map[string]interface{}
+// @Router /rubrics/{id} [put]
+func (s *RubricService) UpdateRubric(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid rubric id"})
+	}
+
+	req, err := parseRubricRequest(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := s.rubricRepo.Update(c.Context(), id, req.Name, req.Rules); err != nil {
+		return rubricError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "rubric updated"})
+}
+
+// DeleteRubric godoc
+// @Summary Delete Scoring Rubric Version
+// @Description Delete a rubric version that has never been active (Admin only)
+// @Tags Scoring Rubrics
+// @Security BearerAuth
+// @Param id path string true "Rubric UUID"
+// @Success 200 {object} map[string]string
+// @Failure 400,403,404,409,500 {object} map[string]interface{}
+// @Router /rubrics/{id} [delete]
+func (s *RubricService) DeleteRubric(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid rubric id"})
+	}
+
+	if err := s.rubricRepo.Delete(c.Context(), id); err != nil {
+		return rubricError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "rubric deleted"})
+}
+
+// ActivateRubric godoc
+// @Summary Activate Scoring Rubric Version
+// @Description Make this version the one used for suggestions; the previously active version is retired (Admin only)
+// @Tags Scoring Rubrics
+// @Security BearerAuth
+// @Param id path string true "Rubric UUID"
+// @Success 200 {object} map[string]string
+// @Failure 400,403,404,500 {object} map[string]interface{}
+// @Router /rubrics/{id}/activate [post]
+func (s *RubricService) ActivateRubric(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid rubric id"})
+	}
+
+	if err := s.rubricRepo.Activate(c.Context(), id); err != nil {
+		return rubricError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "rubric activated"})
+}
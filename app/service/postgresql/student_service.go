@@ -3,18 +3,37 @@ package service
 import (
     repo "student-performance-report/app/repository/postgresql"
     mongoRepo "student-performance-report/app/repository/mongodb"
+    "student-performance-report/app/service/policy"
     "github.com/gofiber/fiber/v2"
     "github.com/google/uuid"
-    "student-performance-report/middleware"
 )
 
 type StudentService struct {
     studentRepo     repo.StudentRepository
     achievementRepo mongoRepo.AchievementRepository
+    access          *policy.AccessPolicy
 }
 
-func NewStudentService(r repo.StudentRepository, a mongoRepo.AchievementRepository) *StudentService {
-    return &StudentService{studentRepo: r, achievementRepo: a}
+func NewStudentService(r repo.StudentRepository, a mongoRepo.AchievementRepository, p *policy.AccessPolicy) *StudentService {
+    return &StudentService{studentRepo: r, achievementRepo: a, access: p}
+}
+
+// authorizeStudent writes a 401/403/500 response and returns false when the
+// caller may not access the given student's data.
+func (s *StudentService) authorizeStudent(c *fiber.Ctx, studentID uuid.UUID) (bool, error) {
+    actor, err := s.access.Actor(c)
+    if err != nil {
+        return false, c.Status(401).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    allowed, err := s.access.CanAccessStudent(actor, studentID)
+    if err != nil {
+        return false, c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+    if !allowed {
+        return false, c.Status(403).JSON(fiber.Map{"error": "Forbidden: You cannot access this student's data"})
+    }
+    return true, nil
 }
 
 // GetAllStudents godoc
@@ -26,9 +45,6 @@ func NewStudentService(r repo.StudentRepository, a mongoRepo.AchievementReposito
 // @Success 200 {array} models.Student
 // @Router /students [get]
 func (s *StudentService) GetAllStudents(c *fiber.Ctx) error {
-    if !middleware.HasPermission(c, "manage:students") {
-		return fiber.ErrForbidden
-	}
     data, err := s.studentRepo.GetAllStudents(c.Context())
     if err != nil {
         return c.Status(500).JSON(fiber.Map{"error": err.Error()})
@@ -38,23 +54,24 @@ func (s *StudentService) GetAllStudents(c *fiber.Ctx) error {
 
 // GetStudentByID godoc
 // @Summary Get Student by ID
-// @Description Get specific student details
+// @Description Get specific student details (own record, advisee, or any for admin)
 // @Tags Students & Lecturers
 // @Security BearerAuth
 // @Produce json
 // @Param id path string true "Student UUID"
 // @Success 200 {object} models.Student
-// @Failure 404 {object} map[string]interface{}
+// @Failure 400,401,403,404 {object} map[string]interface{}
 // @Router /students/{id} [get]
 func (s *StudentService) GetStudentByID(c *fiber.Ctx) error {
-    if !middleware.HasPermission(c, "manage:students") {
-		return fiber.ErrForbidden
-	}
     id, err := uuid.Parse(c.Params("id"))
     if err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID format"})
     }
 
+    if ok, err := s.authorizeStudent(c, id); !ok {
+        return err
+    }
+
     student, err := s.studentRepo.GetStudentByID(c.Context(), id)
     if err != nil {
         return c.Status(404).JSON(fiber.Map{"error": "student not found"})
@@ -65,21 +82,24 @@ func (s *StudentService) GetStudentByID(c *fiber.Ctx) error {
 
 // GetStudentAchievements godoc
 // @Summary Get Student Achievements
-// @Description Get achievements list for a specific student
+// @Description Get achievements list for a specific student (own record, advisee, or any for admin)
 // @Tags Students & Lecturers
 // @Security BearerAuth
 // @Produce json
 // @Param id path string true "Student UUID"
 // @Success 200 {array} models.Achievement
+// @Failure 400,401,403,500 {object} map[string]interface{}
 // @Router /students/{id}/achievements [get]
 func (s *StudentService) GetStudentAchievements(c *fiber.Ctx) error {
-    if !middleware.HasPermission(c, "manage:students") {
-		return fiber.ErrForbidden
-	}
     id, err := uuid.Parse(c.Params("id"))
     if err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "Invalid UUID format"})
     }
+
+    if ok, err := s.authorizeStudent(c, id); !ok {
+        return err
+    }
+
     achievements, err := s.achievementRepo.GetStudentAchievements(id)
     if err != nil {
         return c.Status(500).JSON(fiber.Map{"error": err.Error()})
@@ -103,9 +123,6 @@ func (s *StudentService) UpdateAdvisor(c *fiber.Ctx) error {
     var body struct {
         LecturerID string `json:"lecturerId"`
     }
-    if !middleware.HasPermission(c, "manage:lecturers") {
-		return fiber.ErrForbidden
-	}
     if err := c.BodyParser(&body); err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
     }
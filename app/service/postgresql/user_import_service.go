@@ -0,0 +1,436 @@
+package service
+
+import (
+    "bytes"
+    "errors"
+    "io"
+    "path/filepath"
+    "strconv"
+    "strings"
+    models "student-performance-report/app/models/postgresql"
+    repo "student-performance-report/app/repository/postgresql"
+    "github.com/gofiber/fiber/v2"
+    "github.com/google/uuid"
+    "student-performance-report/utils"
+)
+
+const maxImportRows = 2000
+
+// importColumnAliases maps accepted header spellings to canonical columns.
+var importColumnAliases = map[string]string{
+    "username":            "username",
+    "email":               "email",
+    "full_name":           "full_name",
+    "fullname":            "full_name",
+    "name":                "full_name",
+    "role":                "role",
+    "role_id":             "role",
+    "password":            "password",
+    "student_id":          "student_id",
+    "nim":                 "student_id",
+    "program_study":       "program_study",
+    "academic_year":       "academic_year",
+    "advisor_id":          "advisor_id",
+    "advisor":             "advisor_id",
+    "advisor_lecturer_id": "advisor_id",
+    "lecturer_id":         "lecturer_id",
+    "nip":                 "lecturer_id",
+    "department":          "department",
+}
+
+var requiredImportColumns = []string{"username", "email", "full_name", "role"}
+
+// importFieldNames maps CreateUserRequest validation keys to file columns.
+var importFieldNames = map[string]string{
+    "fullName":                    "full_name",
+    "roleId":                      "role",
+    "studentProfile":              "student_id",
+    "studentProfile.studentId":    "student_id",
+    "studentProfile.programStudy": "program_study",
+    "studentProfile.academicYear": "academic_year",
+    "lecturerProfile":             "lecturer_id",
+    "lecturerProfile.lecturerId":  "lecturer_id",
+    "lecturerProfile.department":  "department",
+}
+
+// importRow is one data row on its way through validation.
+type importRow struct {
+    result            models.UserImportRowResult
+    account           *newAccount
+    advisorRef        string
+    generatedPassword bool
+}
+
+func (r *importRow) fail(field, msg string) {
+    if r.result.Errors == nil {
+        r.result.Errors = map[string]string{}
+    }
+    if _, exists := r.result.Errors[field]; !exists {
+        r.result.Errors[field] = msg
+    }
+}
+
+// ImportUsers godoc
+// @Summary Import Users
+// @Description Bulk-create users with their student/lecturer profiles and advisors from a CSV or XLSX file (Admin only).
+// @Description Columns: username, email, full_name, role (name or id), password (optional, generated when empty), student_id (NIM), program_study, academic_year, advisor_id (lecturer id or lecturer number), lecturer_id, department.
+// @Description With dryRun=true only the per-row validation report is returned. Otherwise all rows are written in one transaction, or none if any row is invalid.
+// @Tags Users
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX file"
+// @Param dryRun query bool false "Validate only"
+// @Success 200 {object} models.UserImportResult "Dry-run report"
+// @Success 201 {object} models.UserImportResult "Committed"
+// @Failure 400,403,409,422,500 {object} map[string]interface{}
+// @Router /users/import [post]
+func (s *AdminService) ImportUsers(c *fiber.Ctx) error {
+    dryRun := c.QueryBool("dryRun", false)
+
+    file, err := c.FormFile("file")
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "No file uploaded"})
+    }
+
+    f, err := file.Open()
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Failed to read uploaded file"})
+    }
+    defer f.Close()
+
+    data, err := io.ReadAll(f)
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": "Failed to read uploaded file"})
+    }
+
+    var records [][]string
+    switch strings.ToLower(filepath.Ext(file.Filename)) {
+    case ".csv":
+        records, err = utils.ReadCSVRows(bytes.NewReader(data))
+    case ".xlsx":
+        records, err = utils.ReadXLSXRows(bytes.NewReader(data), int64(len(data)))
+    default:
+        return c.Status(400).JSON(fiber.Map{"error": "unsupported file type, use .csv or .xlsx"})
+    }
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    if len(records) < 2 {
+        return c.Status(400).JSON(fiber.Map{"error": "file has no data rows"})
+    }
+    if len(records)-1 > maxImportRows {
+        return c.Status(400).JSON(fiber.Map{"error": "too many rows", "limit": maxImportRows})
+    }
+
+    columns, missing := mapImportHeader(records[0])
+    if len(missing) > 0 {
+        return c.Status(400).JSON(fiber.Map{"error": "missing required columns", "columns": missing})
+    }
+
+    rows, err := s.validateImportRows(records, columns)
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+    if len(rows) == 0 {
+        return c.Status(400).JSON(fiber.Map{"error": "file has no data rows"})
+    }
+
+    result := models.UserImportResult{DryRun: dryRun, Total: len(rows)}
+    for _, row := range rows {
+        row.result.Valid = len(row.result.Errors) == 0
+        if row.result.Valid {
+            result.Valid++
+        } else {
+            result.Invalid++
+        }
+    }
+
+    if dryRun || result.Invalid > 0 {
+        result.Rows = importResults(rows, false)
+        if dryRun {
+            return c.JSON(result)
+        }
+        return c.Status(422).JSON(result)
+    }
+
+    accounts := make([]models.NewUserAccount, len(rows))
+    for i, row := range rows {
+        if err := row.account.hashPassword(); err != nil {
+            return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+        }
+        accounts[i] = models.NewUserAccount{
+            User:     row.account.user,
+            Student:  row.account.student,
+            Lecturer: row.account.lecturer,
+        }
+    }
+
+    if err := s.adminRepo.ImportUsers(accounts); err != nil {
+        var rowErr *repo.ImportRowError
+        if !errors.As(err, &rowErr) {
+            return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+        }
+
+        status := 500
+        switch {
+        case errors.Is(err, repo.ErrDuplicateUser):
+            status = 409
+        case errors.Is(err, repo.ErrInvalidReference):
+            status = 422
+        }
+        failed := rows[rowErr.Index]
+        failed.fail("row", rowErr.Error())
+        failed.result.Valid = false
+        result.Valid--
+        result.Invalid++
+        result.Rows = importResults(rows, false)
+        return c.Status(status).JSON(result)
+    }
+
+    result.Committed = true
+    result.Rows = importResults(rows, true)
+    return c.Status(201).JSON(result)
+}
+
+// mapImportHeader returns canonical column name -> index, plus the required
+// columns that are missing.
+func mapImportHeader(header []string) (map[string]int, []string) {
+    columns := map[string]int{}
+    for i, h := range header {
+        key := strings.ToLower(strings.TrimSpace(h))
+        key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+        if canonical, ok := importColumnAliases[key]; ok {
+            if _, dup := columns[canonical]; !dup {
+                columns[canonical] = i
+            }
+        }
+    }
+
+    var missing []string
+    for _, col := range requiredImportColumns {
+        if _, ok := columns[col]; !ok {
+            missing = append(missing, col)
+        }
+    }
+    return columns, missing
+}
+
+// validateImportRows runs every data row through prepareNewUser and then the
+// checks that need the whole file: duplicates within the file, usernames and
+// emails already in the database, and advisor references.
+func (s *AdminService) validateImportRows(records [][]string, columns map[string]int) ([]*importRow, error) {
+    roles, err := s.roleRepo.GetAllRoles()
+    if err != nil {
+        return nil, err
+    }
+    roleByName := map[string]uuid.UUID{}
+    for _, r := range roles {
+        roleByName[strings.ToLower(r.Name)] = r.ID
+    }
+
+    roleCache := map[uuid.UUID]*roleInfo{}
+    cachedLookup := func(id uuid.UUID) (*roleInfo, error) {
+        if info, ok := roleCache[id]; ok {
+            return info, nil
+        }
+        info, err := s.lookupRole(id)
+        if err != nil {
+            return nil, err
+        }
+        roleCache[id] = info
+        return info, nil
+    }
+
+    cell := func(record []string, col string) string {
+        idx, ok := columns[col]
+        if !ok || idx >= len(record) {
+            return ""
+        }
+        return strings.TrimSpace(record[idx])
+    }
+
+    var rows []*importRow
+    seen := map[string]int{}
+
+    for i, record := range records[1:] {
+        if strings.TrimSpace(strings.Join(record, "")) == "" {
+            continue
+        }
+
+        row := &importRow{result: models.UserImportRowResult{Row: i + 2, Username: cell(record, "username")}}
+        rows = append(rows, row)
+
+        // Roles are given by name; a role id is accepted as well.
+        roleRef := cell(record, "role")
+        roleID := roleRef
+        if id, ok := roleByName[strings.ToLower(roleRef)]; ok {
+            roleID = id.String()
+        }
+
+        password := cell(record, "password")
+        if password == "" {
+            password, err = utils.GenerateTemporaryPassword()
+            if err != nil {
+                return nil, err
+            }
+            row.generatedPassword = true
+        }
+
+        req := models.CreateUserRequest{
+            Username: cell(record, "username"),
+            Email:    cell(record, "email"),
+            Password: password,
+            FullName: cell(record, "full_name"),
+            RoleID:   roleID,
+        }
+        if cell(record, "student_id") != "" || cell(record, "program_study") != "" || cell(record, "academic_year") != "" {
+            req.StudentProfile = &models.StudentProfileRequest{
+                StudentID:    cell(record, "student_id"),
+                ProgramStudy: cell(record, "program_study"),
+                AcademicYear: cell(record, "academic_year"),
+            }
+        }
+        if cell(record, "lecturer_id") != "" || cell(record, "department") != "" {
+            req.LecturerProfile = &models.LecturerProfileRequest{
+                LecturerID: cell(record, "lecturer_id"),
+                Department: cell(record, "department"),
+            }
+        }
+
+        account, fieldErrors, err := s.prepareNewUser(&req, cachedLookup)
+        if err != nil {
+            return nil, err
+        }
+        for field, msg := range fieldErrors {
+            if col, ok := importFieldNames[field]; ok {
+                field = col
+            }
+            if field == "role" && roleRef != "" {
+                msg = "unknown role"
+            }
+            row.fail(field, msg)
+        }
+        if account == nil {
+            continue
+        }
+        row.account = account
+
+        row.advisorRef = cell(record, "advisor_id")
+        if row.advisorRef != "" && account.student == nil {
+            row.fail("advisor_id", "only students can have an advisor")
+        }
+
+        keys := map[string]string{
+            "username": "username:" + strings.ToLower(account.user.Username),
+            "email":    "email:" + strings.ToLower(account.user.Email),
+        }
+        if account.student != nil {
+            keys["student_id"] = "student_id:" + account.student.StudentID
+        }
+        if account.lecturer != nil {
+            keys["lecturer_id"] = "lecturer_id:" + account.lecturer.LecturerID
+        }
+        for field, key := range keys {
+            if first, dup := seen[key]; dup {
+                row.fail(field, "duplicate of row "+strconv.Itoa(first))
+            } else {
+                seen[key] = row.result.Row
+            }
+        }
+    }
+
+    var usernames, emails []string
+    for _, row := range rows {
+        if row.account != nil {
+            usernames = append(usernames, row.account.user.Username)
+            emails = append(emails, row.account.user.Email)
+        }
+    }
+    if len(usernames) > 0 {
+        takenUsernames, takenEmails, err := s.adminRepo.ListTakenCredentials(usernames, emails)
+        if err != nil {
+            return nil, err
+        }
+        for _, row := range rows {
+            if row.account == nil {
+                continue
+            }
+            if takenUsernames[strings.ToLower(row.account.user.Username)] {
+                row.fail("username", "already taken")
+            }
+            if takenEmails[strings.ToLower(row.account.user.Email)] {
+                row.fail("email", "already taken")
+            }
+        }
+    }
+
+    if err := s.resolveImportAdvisors(rows); err != nil {
+        return nil, err
+    }
+    return rows, nil
+}
+
+// resolveImportAdvisors points each student at its advisor, looking first at
+// lecturers created in the same file (by lecturer number) and then in the
+// database (by lecturer id or lecturer number).
+func (s *AdminService) resolveImportAdvisors(rows []*importRow) error {
+    inFile := map[string]uuid.UUID{}
+    for _, row := range rows {
+        if row.account != nil && row.account.lecturer != nil {
+            inFile[row.account.lecturer.LecturerID] = row.account.lecturer.ID
+        }
+    }
+
+    var lookup []string
+    for _, row := range rows {
+        if row.account == nil || row.account.student == nil || row.advisorRef == "" {
+            continue
+        }
+        if _, ok := inFile[row.advisorRef]; !ok {
+            lookup = append(lookup, row.advisorRef)
+        }
+    }
+
+    known := map[string]uuid.UUID{}
+    if len(lookup) > 0 {
+        var err error
+        known, err = s.adminRepo.ResolveLecturers(lookup)
+        if err != nil {
+            return err
+        }
+    }
+
+    for _, row := range rows {
+        if row.account == nil || row.account.student == nil || row.advisorRef == "" {
+            continue
+        }
+        id, ok := inFile[row.advisorRef]
+        if !ok {
+            id, ok = known[row.advisorRef]
+        }
+        if !ok {
+            row.fail("advisor_id", "lecturer not found")
+            continue
+        }
+        row.account.student.AdvisorID = &id
+    }
+    return nil
+}
+
+// importResults only exposes user ids and generated passwords once committed.
+func importResults(rows []*importRow, committed bool) []models.UserImportRowResult {
+    results := make([]models.UserImportRowResult, len(rows))
+    for i, row := range rows {
+        results[i] = row.result
+        if committed {
+            id := row.account.user.ID
+            results[i].UserID = &id
+            if row.generatedPassword {
+                results[i].TemporaryPassword = row.account.password
+            }
+        }
+    }
+    return results
+}
@@ -0,0 +1,217 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"time"
+	modelMongo "student-performance-report/app/models/mongodb"
+	modelPg "student-performance-report/app/models/postgresql"
+	repoMongo "student-performance-report/app/repository/mongodb"
+	repoPg "student-performance-report/app/repository/postgresql"
+	"github.com/google/uuid"
+)
+
+// Kinds of issue found between the two stores.
+const (
+	// MissingDetails is a live reference whose Mongo document is gone.
+	MissingDetails = "missing_details"
+	// OrphanDetails is a Mongo document without any reference.
+	OrphanDetails = "orphan_details"
+	// PointsMismatch is a document whose points differ from the reference.
+	PointsMismatch = "points_mismatch"
+	// TrashMismatch is a document whose trash state differs from the
+	// reference: deleted in one store but not in the other.
+	TrashMismatch = "trash_mismatch"
+	// StuckEvent is an outbox event that is still undelivered after the
+	// grace period.
+	StuckEvent = "stuck_event"
+)
+
+type Issue struct {
+	Kind          string     `json:"kind"`
+	AchievementID *uuid.UUID `json:"achievementId,omitempty"`
+	MongoID       string     `json:"mongoAchievementId"`
+	Detail        string     `json:"detail"`
+	Repaired      bool       `json:"repaired"`
+	RepairError   string     `json:"repairError,omitempty"`
+}
+
+type Report struct {
+	References int     `json:"references"`
+	Documents  int     `json:"documents"`
+	Issues     []Issue `json:"issues"`
+}
+
+// Unresolved counts the issues that are still open after the run.
+func (r *Report) Unresolved() int {
+	n := 0
+	for _, i := range r.Issues {
+		if !i.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+// Reconciler checks the Postgres references against the Mongo documents.
+// Postgres is the source of truth: repairs change Mongo to match it, except
+// that a reference whose details are gone is marked deleted.
+type Reconciler struct {
+	pgRepo    repoPg.AchievementRepoPostgres
+	mongoRepo repoMongo.AchievementRepository
+	outbox    repoPg.OutboxRepository
+	// Records younger than grace may still be in the middle of a write and
+	// are not reported.
+	grace time.Duration
+}
+
+func New(p repoPg.AchievementRepoPostgres, m repoMongo.AchievementRepository, o repoPg.OutboxRepository, grace time.Duration) *Reconciler {
+	return &Reconciler{pgRepo: p, mongoRepo: m, outbox: o, grace: grace}
+}
+
+// Run compares both stores and, when repair is set, fixes what it can.
+// Achievements with an undelivered outbox event are left to the relay.
+func (r *Reconciler) Run(ctx context.Context, repair bool) (*Report, error) {
+	refs, err := r.pgRepo.ListReferences(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing references: %w", err)
+	}
+	docs, err := r.mongoRepo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing achievement documents: %w", err)
+	}
+	pending, err := r.outbox.Pending(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing outbox events: %w", err)
+	}
+
+	report := &Report{References: len(refs), Documents: len(docs), Issues: []Issue{}}
+	cutoff := time.Now().Add(-r.grace)
+
+	inFlight := make(map[string]bool, len(pending))
+	for _, e := range pending {
+		inFlight[e.MongoAchievementID] = true
+		if e.CreatedAt.Before(cutoff) {
+			id := e.AchievementRefID
+			detail := fmt.Sprintf("%s undelivered after %d attempts", e.Kind, e.Attempts)
+			if e.LastError != nil {
+				detail += ": " + *e.LastError
+			}
+			report.Issues = append(report.Issues, Issue{Kind: StuckEvent, AchievementID: &id, MongoID: e.MongoAchievementID, Detail: detail})
+		}
+	}
+
+	docByID := make(map[string]modelMongo.Achievement, len(docs))
+	for _, d := range docs {
+		docByID[d.ID.Hex()] = d
+	}
+
+	referenced := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		referenced[ref.MongoAchievementID] = true
+		if inFlight[ref.MongoAchievementID] {
+			continue
+		}
+
+		doc, found := docByID[ref.MongoAchievementID]
+		switch {
+		case ref.Status == modelPg.StatusDeleted:
+			// References deleted before the trash existed have lost their
+			// document already; the purge job removes them in time.
+			if found && doc.DeletedAt == nil {
+				r.add(report, Issue{Kind: TrashMismatch, Detail: "reference is deleted, document is not in the trash"}, ref, repair, func() error {
+					deletedBy, deletedAt := "", ref.UpdatedAt
+					if ref.DeletedBy != nil {
+						deletedBy = ref.DeletedBy.String()
+					}
+					if ref.DeletedAt != nil {
+						deletedAt = *ref.DeletedAt
+					}
+					return r.mongoRepo.MarkDeleted(ctx, ref.MongoAchievementID, deletedBy, deletedAt)
+				})
+			}
+		case !found:
+			if ref.CreatedAt.After(cutoff) {
+				continue
+			}
+			r.add(report, Issue{Kind: MissingDetails, Detail: "no Mongo document for " + ref.Status + " reference"}, ref, repair, func() error {
+				return r.pgRepo.TransitionStatus(ctx, ref.ID, ref.Status, modelPg.StatusDeleted, modelPg.StatusChange{
+					Note: "details missing in Mongo; removed by reconciliation",
+				})
+			})
+		default:
+			if doc.DeletedAt != nil {
+				r.add(report, Issue{Kind: TrashMismatch, Detail: "document is in the trash, reference is " + ref.Status}, ref, repair, func() error {
+					return r.mongoRepo.ClearDeleted(ctx, ref.MongoAchievementID)
+				})
+			}
+			r.checkPoints(ctx, report, ref, doc, repair)
+		}
+	}
+
+	for _, d := range docs {
+		id := d.ID.Hex()
+		if referenced[id] || inFlight[id] || d.CreatedAt.After(cutoff) {
+			continue
+		}
+		issue := Issue{Kind: OrphanDetails, MongoID: id, Detail: "no reference for this document"}
+		if repair {
+			if err := r.mongoRepo.DeleteAchievement(ctx, id); err != nil {
+				issue.RepairError = err.Error()
+			} else {
+				issue.Repaired = true
+			}
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	return report, nil
+}
+
+// checkPoints compares the document's points with the reference: verified
+// achievements carry the reference's points, everything else 0, and a
+// revoked one must be marked revoked in Mongo.
+func (r *Reconciler) checkPoints(ctx context.Context, report *Report, ref modelPg.AchievementReference, doc modelMongo.Achievement, repair bool) {
+	expected := 0
+	if ref.Status == modelPg.StatusVerified {
+		expected = ref.Points
+	}
+	revokedMissing := ref.Status == modelPg.StatusRevoked && doc.RevokedAt == nil
+	if doc.Points == expected && !revokedMissing {
+		return
+	}
+
+	issue := Issue{Kind: PointsMismatch, Detail: fmt.Sprintf("%s reference has %d points, document has %d", ref.Status, expected, doc.Points)}
+	if revokedMissing {
+		issue.Detail = "revoked reference, document is not marked revoked"
+	}
+
+	// Verifications from before Postgres kept the points have 0 there;
+	// which side is right has to be decided by hand.
+	if ref.Status == modelPg.StatusVerified && expected == 0 {
+		issue.Detail += " (Postgres has no points recorded; not repaired)"
+		r.add(report, issue, ref, false, nil)
+		return
+	}
+
+	r.add(report, issue, ref, repair, func() error {
+		if ref.Status == modelPg.StatusRevoked {
+			return r.mongoRepo.MarkRevoked(ctx, ref.MongoAchievementID, time.Now())
+		}
+		return r.mongoRepo.UpdatePoints(ctx, ref.MongoAchievementID, expected)
+	})
+}
+
+func (r *Reconciler) add(report *Report, issue Issue, ref modelPg.AchievementReference, repair bool, fix func() error) {
+	id := ref.ID
+	issue.AchievementID = &id
+	issue.MongoID = ref.MongoAchievementID
+	if repair && fix != nil {
+		if err := fix(); err != nil {
+			issue.RepairError = err.Error()
+		} else {
+			issue.Repaired = true
+		}
+	}
+	report.Issues = append(report.Issues, issue)
+}
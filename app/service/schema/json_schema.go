@@ -0,0 +1,333 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONSchema is a compiled subset of JSON Schema used for customFields:
+// type, enum, required, properties, additionalProperties, items, minimum,
+// maximum, minLength, maxLength, minItems, maxItems, pattern and format
+// (date, date-time, email). Annotations such as title and description are
+// accepted and ignored; any other keyword is rejected when compiling so a
+// schema never silently validates less than its author expects.
+type JSONSchema struct {
+	Types                []string
+	Enum                 []interface{}
+	Required             []string
+	Properties           map[string]*JSONSchema
+	AdditionalProperties *JSONSchema
+	NoAdditional         bool
+	Items                *JSONSchema
+	Minimum              *float64
+	Maximum              *float64
+	MinLength            *int
+	MaxLength            *int
+	MinItems             *int
+	MaxItems             *int
+	Pattern              *regexp.Regexp
+	Format               string
+}
+
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true,
+	"title": true, "description": true, "default": true, "examples": true,
+}
+
+var jsonTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// CompileJSONSchema parses a schema document.
+func CompileJSONSchema(raw []byte) (*JSONSchema, error) {
+	return compile(raw, "")
+}
+
+func compile(raw []byte, at string) (*JSONSchema, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("%sschema must be an object: %w", prefix(at), err)
+	}
+
+	s := &JSONSchema{}
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		val := doc[key]
+		var err error
+		switch key {
+		case "type":
+			err = s.compileType(val)
+		case "enum":
+			err = json.Unmarshal(val, &s.Enum)
+		case "required":
+			err = json.Unmarshal(val, &s.Required)
+		case "properties":
+			var props map[string]json.RawMessage
+			if err = json.Unmarshal(val, &props); err != nil {
+				break
+			}
+			s.Properties = make(map[string]*JSONSchema, len(props))
+			for name, sub := range props {
+				if s.Properties[name], err = compile(sub, join(at, name)); err != nil {
+					return nil, err
+				}
+			}
+		case "additionalProperties":
+			var allowed bool
+			if json.Unmarshal(val, &allowed) == nil {
+				s.NoAdditional = !allowed
+				break
+			}
+			s.AdditionalProperties, err = compile(val, join(at, "*"))
+		case "items":
+			s.Items, err = compile(val, at+"[]")
+		case "minimum":
+			err = json.Unmarshal(val, &s.Minimum)
+		case "maximum":
+			err = json.Unmarshal(val, &s.Maximum)
+		case "minLength":
+			err = json.Unmarshal(val, &s.MinLength)
+		case "maxLength":
+			err = json.Unmarshal(val, &s.MaxLength)
+		case "minItems":
+			err = json.Unmarshal(val, &s.MinItems)
+		case "maxItems":
+			err = json.Unmarshal(val, &s.MaxItems)
+		case "pattern":
+			var p string
+			if err = json.Unmarshal(val, &p); err == nil {
+				s.Pattern, err = regexp.Compile(p)
+			}
+		case "format":
+			if err = json.Unmarshal(val, &s.Format); err == nil && formats[s.Format] == nil {
+				err = fmt.Errorf("unsupported format %q", s.Format)
+			}
+		default:
+			if !annotations[key] {
+				err = fmt.Errorf("unsupported keyword %q", key)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s%s: %w", prefix(at), key, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *JSONSchema) compileType(raw json.RawMessage) error {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		s.Types = []string{one}
+	} else if err := json.Unmarshal(raw, &s.Types); err != nil {
+		return err
+	}
+	for _, t := range s.Types {
+		if !jsonTypes[t] {
+			return fmt.Errorf("unknown type %q", t)
+		}
+	}
+	return nil
+}
+
+// Validate checks a decoded JSON value and records every violation in errs,
+// keyed by path below root (e.g. "customFields.team[0]").
+func (s *JSONSchema) Validate(value interface{}, path string, errs FieldErrors) {
+	if len(s.Types) > 0 && !s.hasType(value) {
+		errs.Add(path, "must be of type "+strings.Join(s.Types, " or "))
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(value, s.Enum) {
+		errs.Add(path, "must be one of "+enumList(s.Enum))
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		s.validateObject(v, path, errs)
+	case []interface{}:
+		s.validateArray(v, path, errs)
+	case string:
+		s.validateString(v, path, errs)
+	default:
+		if n, ok := number(v); ok {
+			if s.Minimum != nil && n < *s.Minimum {
+				errs.Add(path, "must be at least "+formatNumber(*s.Minimum))
+			}
+			if s.Maximum != nil && n > *s.Maximum {
+				errs.Add(path, "must be at most "+formatNumber(*s.Maximum))
+			}
+		}
+	}
+}
+
+func (s *JSONSchema) validateObject(v map[string]interface{}, path string, errs FieldErrors) {
+	for _, name := range s.Required {
+		if _, ok := v[name]; !ok {
+			errs.Add(join(path, name), "is required")
+		}
+	}
+	for name, item := range v {
+		if prop, ok := s.Properties[name]; ok {
+			prop.Validate(item, join(path, name), errs)
+		} else if s.NoAdditional {
+			errs.Add(join(path, name), "is not allowed")
+		} else if s.AdditionalProperties != nil {
+			s.AdditionalProperties.Validate(item, join(path, name), errs)
+		}
+	}
+}
+
+func (s *JSONSchema) validateArray(v []interface{}, path string, errs FieldErrors) {
+	if s.MinItems != nil && len(v) < *s.MinItems {
+		errs.Add(path, fmt.Sprintf("must have at least %d items", *s.MinItems))
+	}
+	if s.MaxItems != nil && len(v) > *s.MaxItems {
+		errs.Add(path, fmt.Sprintf("must have at most %d items", *s.MaxItems))
+	}
+	if s.Items != nil {
+		for i, item := range v {
+			s.Items.Validate(item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func (s *JSONSchema) validateString(v string, path string, errs FieldErrors) {
+	n := len([]rune(v))
+	if s.MinLength != nil && n < *s.MinLength {
+		errs.Add(path, fmt.Sprintf("must be at least %d characters", *s.MinLength))
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		errs.Add(path, fmt.Sprintf("must be at most %d characters", *s.MaxLength))
+	}
+	if s.Pattern != nil && !s.Pattern.MatchString(v) {
+		errs.Add(path, "must match pattern "+s.Pattern.String())
+	}
+	if s.Format != "" && !formats[s.Format](v) {
+		errs.Add(path, "must be a valid "+s.Format)
+	}
+}
+
+func (s *JSONSchema) hasType(value interface{}) bool {
+	for _, t := range s.Types {
+		switch t {
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		case "number":
+			if _, ok := number(value); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := number(value); ok && n == math.Trunc(n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var formats = map[string]func(string) bool{
+	"date": func(v string) bool {
+		_, err := time.Parse("2006-01-02", v)
+		return err == nil
+	},
+	"date-time": func(v string) bool {
+		_, err := time.Parse(time.RFC3339, v)
+		return err == nil
+	},
+	"email": func(v string) bool {
+		addr, err := mail.ParseAddress(v)
+		return err == nil && addr.Address == v
+	},
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func inEnum(value interface{}, enum []interface{}) bool {
+	for _, e := range enum {
+		if en, ok := number(e); ok {
+			if vn, ok := number(value); ok && vn == en {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(e, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []interface{}) string {
+	parts := make([]string, len(enum))
+	for i, e := range enum {
+		parts[i] = fmt.Sprint(e)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func prefix(at string) string {
+	if at == "" {
+		return ""
+	}
+	return at + ": "
+}
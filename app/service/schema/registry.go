@@ -0,0 +1,222 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	modelMongo "student-performance-report/app/models/mongodb"
+)
+
+// FieldErrors maps a field path (e.g. "details.competitionLevel") to what is
+// wrong with it.
+type FieldErrors map[string]string
+
+// Add keeps the first message recorded for a path.
+func (e FieldErrors) Add(path, msg string) {
+	if _, ok := e[path]; !ok {
+		e[path] = msg
+	}
+}
+
+func (e FieldErrors) Error() string {
+	paths := make([]string, 0, len(e))
+	for p := range e {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	parts := make([]string, len(paths))
+	for i, p := range paths {
+		parts[i] = p + " " + e[p]
+	}
+	return strings.Join(parts, "; ")
+}
+
+// FieldRule constrains one detail field of an achievement type. Enum applies
+// to text fields and Min to numeric ones.
+type FieldRule struct {
+	Required bool     `json:"required"`
+	Enum     []string `json:"enum,omitempty"`
+	Min      *float64 `json:"min,omitempty"`
+}
+
+// TypeSchema describes one achievement type. Only the detail fields listed
+// in Fields may be set; CustomFields, when given, is the JSON Schema the
+// customFields object must satisfy. Check runs after the field rules for
+// constraints spanning several fields.
+type TypeSchema struct {
+	Type         string                                                  `json:"type"`
+	Fields       map[string]FieldRule                                    `json:"fields"`
+	CustomFields json.RawMessage                                         `json:"customFields,omitempty"`
+	Check        func(d modelMongo.AchievementDetails, errs FieldErrors) `json:"-"`
+
+	compiled *JSONSchema
+}
+
+// Registry holds the achievement types a student may create.
+type Registry struct {
+	types map[string]TypeSchema
+}
+
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[string]TypeSchema)}
+}
+
+// Register adds or replaces a type after checking its field names and
+// compiling its customFields schema.
+func (r *Registry) Register(t TypeSchema) error {
+	if strings.TrimSpace(t.Type) == "" {
+		return fmt.Errorf("type name is required")
+	}
+	for name, rule := range t.Fields {
+		kind, ok := detailKinds[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown detail field %q", t.Type, name)
+		}
+		if len(rule.Enum) > 0 && kind != reflect.String {
+			return fmt.Errorf("%s: enum on non-text field %q", t.Type, name)
+		}
+		if rule.Min != nil && !isNumeric(kind) {
+			return fmt.Errorf("%s: min on non-numeric field %q", t.Type, name)
+		}
+	}
+	if len(t.CustomFields) > 0 {
+		compiled, err := CompileJSONSchema(t.CustomFields)
+		if err != nil {
+			return fmt.Errorf("%s: customFields schema: %w", t.Type, err)
+		}
+		t.compiled = compiled
+	}
+	r.types[t.Type] = t
+	return nil
+}
+
+// Get returns the schema of a type.
+func (r *Registry) Get(name string) (TypeSchema, bool) {
+	t, ok := r.types[name]
+	return t, ok
+}
+
+// Types lists the registered types by name.
+func (r *Registry) Types() []TypeSchema {
+	out := make([]TypeSchema, 0, len(r.types))
+	for _, t := range r.types {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	return out
+}
+
+func (r *Registry) names() string {
+	names := make([]string, 0, len(r.types))
+	for name := range r.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Validate checks an achievement submitted for create or update and returns
+// nil when it is valid.
+func (r *Registry) Validate(a *modelMongo.Achievement) FieldErrors {
+	errs := FieldErrors{}
+	if strings.TrimSpace(a.Title) == "" {
+		errs.Add("title", "is required")
+	}
+
+	t, ok := r.types[a.AchievementType]
+	if !ok {
+		if a.AchievementType == "" {
+			errs.Add("achievementType", "is required")
+		} else {
+			errs.Add("achievementType", "must be one of "+r.names())
+		}
+		return errs
+	}
+
+	v := reflect.ValueOf(a.Details)
+	for i := 0; i < v.NumField(); i++ {
+		name := jsonName(v.Type().Field(i))
+		field := v.Field(i)
+		path := "details." + name
+		rule, allowed := t.Fields[name]
+
+		if field.IsZero() || (field.Kind() == reflect.Slice && field.Len() == 0) {
+			if allowed && rule.Required {
+				errs.Add(path, "is required")
+			}
+			continue
+		}
+		if !allowed {
+			errs.Add(path, "is not allowed for achievementType "+t.Type)
+			continue
+		}
+		if field.Kind() == reflect.String && len(rule.Enum) > 0 && !contains(rule.Enum, field.String()) {
+			errs.Add(path, "must be one of "+strings.Join(rule.Enum, ", "))
+		}
+		if rule.Min != nil && numeric(field) < *rule.Min {
+			errs.Add(path, "must be at least "+formatNumber(*rule.Min))
+		}
+	}
+	if t.Check != nil {
+		t.Check(a.Details, errs)
+	}
+
+	if len(a.CustomFields) > 0 || t.compiled != nil {
+		if t.compiled == nil {
+			errs.Add("customFields", "are not allowed for achievementType "+t.Type)
+		} else {
+			custom := make(map[string]interface{}, len(a.CustomFields))
+			for k, val := range a.CustomFields {
+				custom[k] = val
+			}
+			t.compiled.Validate(custom, "customFields", errs)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+var detailKinds = func() map[string]reflect.Kind {
+	kinds := make(map[string]reflect.Kind)
+	t := reflect.TypeOf(modelMongo.AchievementDetails{})
+	for i := 0; i < t.NumField(); i++ {
+		kinds[jsonName(t.Field(i))] = t.Field(i).Type.Kind()
+	}
+	return kinds
+}()
+
+func jsonName(f reflect.StructField) string {
+	return strings.Split(f.Tag.Get("json"), ",")[0]
+}
+
+func isNumeric(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func numeric(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return 0
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
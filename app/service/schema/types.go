@@ -0,0 +1,128 @@
+package schema
+
+import (
+	"encoding/json"
+	"regexp"
+	modelMongo "student-performance-report/app/models/mongodb"
+)
+
+var (
+	zero = 0.0
+	one  = 1.0
+
+	issnPattern = regexp.MustCompile(`^\d{4}-\d{3}[\dX]$`)
+)
+
+// builtinTypes are the achievement types every deployment starts with.
+var builtinTypes = []TypeSchema{
+	{
+		Type: "competition",
+		Fields: map[string]FieldRule{
+			"competitionName":  {Required: true},
+			"competitionLevel": {Required: true, Enum: []string{"international", "national", "regional", "local"}},
+			"rank":             {Min: &one},
+			"medalType":        {Enum: []string{"gold", "silver", "bronze"}},
+			"eventDate":        {},
+			"location":         {},
+			"organizer":        {},
+			"score":            {Min: &zero},
+		},
+		CustomFields: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"teamName": {"type": "string", "maxLength": 100},
+				"teamMembers": {"type": "array", "items": {"type": "string", "minLength": 1}},
+				"category": {"type": "string"}
+			}
+		}`),
+	},
+	{
+		Type: "publication",
+		Fields: map[string]FieldRule{
+			"publicationType":  {Required: true, Enum: []string{"journal", "conference", "book"}},
+			"publicationTitle": {Required: true},
+			"authors":          {Required: true},
+			"publisher":        {Required: true},
+			"issn":             {},
+			"eventDate":        {},
+		},
+		Check: func(d modelMongo.AchievementDetails, errs FieldErrors) {
+			if d.ISSN != "" && !issnPattern.MatchString(d.ISSN) {
+				errs.Add("details.issn", "must look like 1234-567X")
+			}
+		},
+		CustomFields: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"doi": {"type": "string", "pattern": "^10\\.\\d{4,9}/\\S+$"},
+				"volume": {"type": "string"},
+				"pages": {"type": "string"},
+				"indexedBy": {"type": "array", "items": {"type": "string"}}
+			}
+		}`),
+	},
+	{
+		Type: "organization",
+		Fields: map[string]FieldRule{
+			"organizationName": {Required: true},
+			"position":         {Required: true},
+			"startDate":        {Required: true},
+			"endDate":          {},
+			"location":         {},
+		},
+		Check: func(d modelMongo.AchievementDetails, errs FieldErrors) {
+			if !d.EndDate.IsZero() && d.EndDate.Before(d.StartDate) {
+				errs.Add("details.endDate", "must not be before startDate")
+			}
+		},
+		CustomFields: json.RawMessage(`{"type": "object"}`),
+	},
+	{
+		Type: "certification",
+		Fields: map[string]FieldRule{
+			"certificationName":   {Required: true},
+			"issuedBy":            {Required: true},
+			"certificationNumber": {},
+			"validUntil":          {},
+			"eventDate":           {},
+		},
+		CustomFields: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"credentialUrl": {"type": "string", "pattern": "^https?://"},
+				"level": {"type": "string"}
+			}
+		}`),
+	},
+	{
+		Type: "academic",
+		Fields: map[string]FieldRule{
+			"eventDate": {},
+			"location":  {},
+			"organizer": {},
+			"score":     {Min: &zero},
+		},
+		CustomFields: json.RawMessage(`{"type": "object"}`),
+	},
+	{
+		Type: "other",
+		Fields: map[string]FieldRule{
+			"eventDate": {},
+			"location":  {},
+			"organizer": {},
+			"score":     {Min: &zero},
+		},
+		CustomFields: json.RawMessage(`{"type": "object"}`),
+	},
+}
+
+// Default returns a registry holding the built-in types.
+func Default() *Registry {
+	r := NewRegistry()
+	for _, t := range builtinTypes {
+		if err := r.Register(t); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
@@ -0,0 +1,122 @@
+package scoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	modelMongo "student-performance-report/app/models/mongodb"
+	modelPg "student-performance-report/app/models/postgresql"
+)
+
+// Fields are the achievement detail fields a rule may test, by JSON name.
+var Fields = detailFields()
+
+func detailFields() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(modelMongo.AchievementDetails{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// Validate checks a rubric's rules before it is stored.
+func Validate(rules []modelPg.RubricRule) error {
+	if len(rules) == 0 {
+		return fmt.Errorf("at least one rule is required")
+	}
+	for i, r := range rules {
+		if strings.TrimSpace(r.AchievementType) == "" {
+			return fmt.Errorf("rule %d: achievementType is required", i+1)
+		}
+		if r.Points <= 0 {
+			return fmt.Errorf("rule %d: points must be greater than 0", i+1)
+		}
+		for field := range r.When {
+			if !Fields[field] {
+				return fmt.Errorf("rule %d: unknown detail field %q", i+1, field)
+			}
+		}
+	}
+	return nil
+}
+
+// Suggest returns the score the rubric proposes for an achievement, or nil
+// when no rule matches. Of the matching rules the most specific one (most
+// conditions in When) wins; on a tie the earlier rule wins.
+func Suggest(rubric *modelPg.ScoringRubric, a *modelMongo.Achievement) *modelPg.SuggestedScore {
+	if rubric == nil || a == nil {
+		return nil
+	}
+
+	values := detailValues(a.Details)
+	best := -1
+	for i, r := range rubric.Rules {
+		if !strings.EqualFold(r.AchievementType, a.AchievementType) || !matches(r.When, values) {
+			continue
+		}
+		if best < 0 || len(r.When) > len(rubric.Rules[best].When) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+
+	rule := rubric.Rules[best]
+	return &modelPg.SuggestedScore{Points: rule.Points, RubricVersion: rubric.Version, Rule: rule}
+}
+
+func matches(when map[string]string, values map[string][]string) bool {
+	for field, want := range when {
+		found := false
+		for _, v := range values[field] {
+			if strings.EqualFold(v, strings.TrimSpace(want)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// detailValues flattens the details into field -> values; list fields such
+// as authors match on any element.
+func detailValues(d modelMongo.AchievementDetails) map[string][]string {
+	raw, _ := json.Marshal(d)
+	var m map[string]interface{}
+	_ = json.Unmarshal(raw, &m)
+
+	values := make(map[string][]string, len(m))
+	for field, v := range m {
+		switch val := v.(type) {
+		case []interface{}:
+			for _, item := range val {
+				values[field] = append(values[field], scalar(item))
+			}
+		default:
+			values[field] = []string{scalar(val)}
+		}
+	}
+	return values
+}
+
+func scalar(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,151 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	modelPg "student-performance-report/app/models/postgresql"
+	repoMongo "student-performance-report/app/repository/mongodb"
+	repoPg "student-performance-report/app/repository/postgresql"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// uploadURLPrefix is where UploadAttachments serves the files it stores in
+// the upload directory.
+const uploadURLPrefix = "/uploads/"
+
+// Purger permanently removes achievements that have been in the trash for
+// longer than the retention window: the Postgres reference with its history,
+// comments and outbox events, the Mongo document with its versions, and the
+// uploaded attachment files.
+type Purger struct {
+	pgRepo    repoPg.AchievementRepoPostgres
+	mongoRepo repoMongo.AchievementRepository
+	retention time.Duration
+	uploadDir string
+}
+
+func NewPurger(p repoPg.AchievementRepoPostgres, m repoMongo.AchievementRepository, retention time.Duration, uploadDir string) *Purger {
+	return &Purger{pgRepo: p, mongoRepo: m, retention: retention, uploadDir: uploadDir}
+}
+
+// ExpiresAt is when an achievement deleted at deletedAt is purged; until
+// then it can be restored.
+func (p *Purger) ExpiresAt(deletedAt time.Time) time.Time {
+	return deletedAt.Add(p.retention)
+}
+
+// RunOnce purges up to batch expired achievements and reports how many were
+// removed and how many failed. An achievement restored while it was being
+// purged is left alone and counted in neither.
+func (p *Purger) RunOnce(ctx context.Context, batch int) (purged, failed int, err error) {
+	cutoff := time.Now().Add(-p.retention)
+	refs, err := p.pgRepo.ExpiredTrash(ctx, cutoff, batch)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, ref := range refs {
+		ok, err := p.purge(ctx, ref, cutoff)
+		if err != nil {
+			log.Printf("trash: purging %s: %v", ref.ID, err)
+			failed++
+			continue
+		}
+		if ok {
+			purged++
+		}
+	}
+	return purged, failed, nil
+}
+
+// purge removes the attachment files and the document before the
+// reference, all while the reference is locked against a restore. Any
+// failure keeps the reference, so the next run tries the whole purge again;
+// removing a file or document that is already gone is not an error.
+func (p *Purger) purge(ctx context.Context, ref modelPg.AchievementReference, cutoff time.Time) (bool, error) {
+	err := p.pgRepo.PurgeReference(ctx, ref.ID, cutoff, func() error {
+		files, err := p.attachmentFiles(ctx, ref.MongoAchievementID)
+		if err != nil {
+			return fmt.Errorf("reading attachments: %w", err)
+		}
+		for _, f := range files {
+			if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("removing %s: %w", f, err)
+			}
+		}
+		if err := p.mongoRepo.DeleteAchievement(ctx, ref.MongoAchievementID); err != nil {
+			return fmt.Errorf("removing details %s: %w", ref.MongoAchievementID, err)
+		}
+		return nil
+	})
+	if errors.Is(err, repoPg.ErrStatusConflict) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// attachmentFiles returns the local paths of every file the document or any
+// of its versions points at. A document that is already gone has none.
+func (p *Purger) attachmentFiles(ctx context.Context, mongoID string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(url string) {
+		if !strings.HasPrefix(url, uploadURLPrefix) {
+			return
+		}
+		path := filepath.Join(p.uploadDir, filepath.Base(url))
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	doc, err := p.mongoRepo.FindOne(ctx, mongoID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range doc.Attachments {
+		add(a.FileURL)
+	}
+
+	versions, err := p.mongoRepo.ListVersions(ctx, mongoID)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		for _, a := range v.Snapshot.Attachments {
+			add(a.FileURL)
+		}
+	}
+	return files, nil
+}
+
+// Run purges expired achievements every interval until ctx is done.
+func (p *Purger) Run(ctx context.Context, interval time.Duration, batch int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if purged, _, err := p.RunOnce(ctx, batch); err != nil {
+			log.Printf("trash: reading expired achievements: %v", err)
+		} else if purged > 0 {
+			log.Printf("trash: purged %d achievements", purged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
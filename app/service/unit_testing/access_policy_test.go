@@ -0,0 +1,273 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	models "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/policy"
+)
+
+// --- SETUP HELPERS ---
+
+// policyFixture: satu mahasiswa (ownStudentID) dengan dosen wali advisorID,
+// dan satu mahasiswa lain (otherStudentID) yang bukan bimbingannya.
+type policyFixture struct {
+	ownStudentID   uuid.UUID
+	otherStudentID uuid.UUID
+	advisorID      uuid.UUID
+	otherLecturer  uuid.UUID
+}
+
+type policyActor struct {
+	name       string
+	admin      bool
+	studentID  *uuid.UUID
+	lecturerID *uuid.UUID
+}
+
+func newPolicyFixture() policyFixture {
+	return policyFixture{
+		ownStudentID:   uuid.New(),
+		otherStudentID: uuid.New(),
+		advisorID:      uuid.New(),
+		otherLecturer:  uuid.New(),
+	}
+}
+
+func (f policyFixture) actors() []policyActor {
+	return []policyActor{
+		{name: "admin", admin: true},
+		{name: "owning student", studentID: &f.ownStudentID},
+		{name: "other student", studentID: &f.otherStudentID},
+		{name: "advisor lecturer", lecturerID: &f.advisorID},
+		{name: "other lecturer", lecturerID: &f.otherLecturer},
+		{name: "no profile"},
+	}
+}
+
+// runWithActor membangun AccessPolicy di atas mock untuk actor tertentu lalu
+// menjalankan check di dalam request Fiber agar Actor() bisa membaca locals.
+func runWithActor(t *testing.T, f policyFixture, a policyActor, check func(p *policy.AccessPolicy, actor *policy.Actor)) {
+	mockPg := new(mocks.MockAchievementPgRepo)
+	mockLecturer := new(mocks.MockLecturerRepo)
+	mockStudent := new(mocks.MockStudentRepo)
+
+	if a.studentID != nil {
+		mockPg.On("GetStudentByUserID", mock.Anything, mock.Anything).Return(*a.studentID, nil)
+	} else {
+		mockPg.On("GetStudentByUserID", mock.Anything, mock.Anything).Return(uuid.Nil, sql.ErrNoRows)
+	}
+	if a.lecturerID != nil {
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, mock.Anything).Return(*a.lecturerID, nil)
+	} else {
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, mock.Anything).Return(uuid.Nil, sql.ErrNoRows)
+	}
+	mockLecturer.On("GetAdvisees", f.advisorID).Return([]models.Student{{ID: f.ownStudentID, AdvisorID: &f.advisorID}}, nil)
+	mockStudent.On("GetStudentByID", mock.Anything, f.ownStudentID).Return(&models.Student{ID: f.ownStudentID, AdvisorID: &f.advisorID}, nil)
+	mockStudent.On("GetStudentByID", mock.Anything, f.otherStudentID).Return(&models.Student{ID: f.otherStudentID}, nil)
+	mockStudent.On("GetStudentByID", mock.Anything, mock.Anything).Return(nil, repo.ErrStudentNotFound)
+
+	p := policy.NewAccessPolicy(mockPg, mockLecturer, mockStudent)
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Locals("user_id", uuid.New())
+		if a.admin {
+			c.Locals("permissions", []string{policy.AdminPermission})
+		} else {
+			c.Locals("permissions", []string{"achievement:read"})
+		}
+
+		actor, err := p.Actor(c)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		check(p, actor)
+		return nil
+	})
+
+	_, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	assert.NoError(t, err)
+}
+
+// --- TEST CASES ---
+
+func TestAccessPolicyCanAccessStudent(t *testing.T) {
+	f := newPolicyFixture()
+	expected := map[string]bool{
+		"admin":            true,
+		"owning student":   true,
+		"other student":    false,
+		"advisor lecturer": true,
+		"other lecturer":   false,
+		"no profile":       false,
+	}
+
+	for _, a := range f.actors() {
+		t.Run(a.name, func(t *testing.T) {
+			runWithActor(t, f, a, func(p *policy.AccessPolicy, actor *policy.Actor) {
+				allowed, err := p.CanAccessStudent(actor, f.ownStudentID)
+				assert.NoError(t, err)
+				assert.Equal(t, expected[a.name], allowed)
+			})
+		})
+	}
+}
+
+func TestAccessPolicyAdvisesUnknownStudent(t *testing.T) {
+	f := newPolicyFixture()
+	a := policyActor{name: "advisor lecturer", lecturerID: &f.advisorID}
+
+	// Mahasiswa yang tidak ada bukan error, hanya bukan bimbingan siapa pun
+	runWithActor(t, f, a, func(p *policy.AccessPolicy, actor *policy.Actor) {
+		advises, err := p.Advises(context.Background(), f.advisorID, uuid.New())
+		assert.NoError(t, err)
+		assert.False(t, advises)
+	})
+}
+
+func TestAccessPolicyCanViewAchievement(t *testing.T) {
+	f := newPolicyFixture()
+
+	tests := []struct {
+		status   string
+		expected map[string]bool
+	}{
+		{
+			status: models.StatusDraft,
+			expected: map[string]bool{
+				"admin": true, "owning student": true, "other student": false,
+				"advisor lecturer": false, "other lecturer": false, "no profile": false,
+			},
+		},
+		{
+			status: models.StatusSubmitted,
+			expected: map[string]bool{
+				"admin": true, "owning student": true, "other student": false,
+				"advisor lecturer": true, "other lecturer": false, "no profile": false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		ref := models.AchievementReference{ID: uuid.New(), StudentID: f.ownStudentID, Status: tt.status}
+		for _, a := range f.actors() {
+			t.Run(tt.status+"/"+a.name, func(t *testing.T) {
+				runWithActor(t, f, a, func(p *policy.AccessPolicy, actor *policy.Actor) {
+					allowed, err := p.CanViewAchievement(actor, ref)
+					assert.NoError(t, err)
+					assert.Equal(t, tt.expected[a.name], allowed)
+				})
+			})
+		}
+	}
+}
+
+func TestAccessPolicyCanModifyAndVerify(t *testing.T) {
+	f := newPolicyFixture()
+	ref := models.AchievementReference{ID: uuid.New(), StudentID: f.ownStudentID, Status: models.StatusSubmitted}
+
+	tests := map[string]struct {
+		modify bool
+		verify bool
+	}{
+		"admin":            {modify: false, verify: false},
+		"owning student":   {modify: true, verify: false},
+		"other student":    {modify: false, verify: false},
+		"advisor lecturer": {modify: false, verify: true},
+		"other lecturer":   {modify: false, verify: false},
+		"no profile":       {modify: false, verify: false},
+	}
+
+	for _, a := range f.actors() {
+		t.Run(a.name, func(t *testing.T) {
+			runWithActor(t, f, a, func(p *policy.AccessPolicy, actor *policy.Actor) {
+				assert.Equal(t, tests[a.name].modify, p.CanModifyAchievement(actor, ref))
+
+				allowed, err := p.CanVerify(actor, ref)
+				assert.NoError(t, err)
+				assert.Equal(t, tests[a.name].verify, allowed)
+			})
+		})
+	}
+}
+
+func TestAccessPolicyCanAccessLecturer(t *testing.T) {
+	f := newPolicyFixture()
+	expected := map[string]bool{
+		"admin":            true,
+		"owning student":   false,
+		"other student":    false,
+		"advisor lecturer": true,
+		"other lecturer":   false,
+		"no profile":       false,
+	}
+
+	for _, a := range f.actors() {
+		t.Run(a.name, func(t *testing.T) {
+			runWithActor(t, f, a, func(p *policy.AccessPolicy, actor *policy.Actor) {
+				assert.Equal(t, expected[a.name], p.CanAccessLecturer(actor, f.advisorID))
+			})
+		})
+	}
+}
+
+func TestAccessPolicyAchievementScope(t *testing.T) {
+	f := newPolicyFixture()
+
+	t.Run("student: own records only", func(t *testing.T) {
+		a := policyActor{name: "owning student", studentID: &f.ownStudentID}
+		runWithActor(t, f, a, func(p *policy.AccessPolicy, actor *policy.Actor) {
+			filters, ok, err := p.AchievementScope(actor, "")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, f.ownStudentID, filters["student_id"])
+		})
+	})
+
+	t.Run("admin: no restriction", func(t *testing.T) {
+		a := policyActor{name: "admin", admin: true}
+		runWithActor(t, f, a, func(p *policy.AccessPolicy, actor *policy.Actor) {
+			filters, ok, err := p.AchievementScope(actor, models.StatusVerified)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, map[string]interface{}{"status": models.StatusVerified}, filters)
+		})
+	})
+
+	t.Run("advisor: every status but draft and deleted", func(t *testing.T) {
+		a := policyActor{name: "advisor lecturer", lecturerID: &f.advisorID}
+		runWithActor(t, f, a, func(p *policy.AccessPolicy, actor *policy.Actor) {
+			filters, ok, err := p.AchievementScope(actor, "")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.ElementsMatch(t, []string{
+				models.StatusSubmitted, models.StatusVerified, models.StatusRejected,
+				models.StatusRevision, models.StatusRevoked,
+			}, filters["status"])
+
+			for _, hidden := range []string{models.StatusDraft, models.StatusDeleted} {
+				_, ok, err := p.AchievementScope(actor, hidden)
+				assert.NoError(t, err)
+				assert.False(t, ok, hidden)
+			}
+		})
+	})
+
+	t.Run("no profile: nothing visible", func(t *testing.T) {
+		a := policyActor{name: "no profile"}
+		runWithActor(t, f, a, func(p *policy.AccessPolicy, actor *policy.Actor) {
+			_, ok, err := p.AchievementScope(actor, "")
+			assert.NoError(t, err)
+			assert.False(t, ok)
+		})
+	})
+}
@@ -0,0 +1,185 @@
+package service_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	models "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/postgresql"
+	"student-performance-report/middleware"
+	"student-performance-report/utils"
+)
+
+// --- SETUP HELPERS ---
+
+func setupAccessTokenServiceTest() (*service.AccessTokenService, *mocks.MockAccessTokenRepo, *mocks.MockUserRepo) {
+	mockTokenRepo := new(mocks.MockAccessTokenRepo)
+	mockUserRepo := new(mocks.MockUserRepo)
+	svc := service.NewAccessTokenService(mockTokenRepo, mockUserRepo, 90*24*time.Hour, 365*24*time.Hour)
+	return svc, mockTokenRepo, mockUserRepo
+}
+
+// setupAccessTokenApp: route laporan (report:students), route prestasi
+// (achievement:read) dan route akun yang menolak token akses.
+func setupAccessTokenApp() *fiber.App {
+	app := fiber.New()
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(200) }
+	app.Get("/reports", middleware.AuthRequired(), middleware.PermissionRequired("report:students"), ok)
+	app.Get("/achievements", middleware.AuthRequired(), middleware.PermissionRequired("achievement:read"), ok)
+	app.Get("/sessions", middleware.AuthRequired(), middleware.SessionRequired(), ok)
+	return app
+}
+
+func callWithBearer(app *fiber.App, path, token string) int {
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, _ := app.Test(req, -1)
+	return resp.StatusCode
+}
+
+// --- TEST CASES ---
+
+func TestCreateAccessToken(t *testing.T) {
+	t.Run("Success: Token is returned once, only hash and prefix are stored", func(t *testing.T) {
+		svc, mockTokenRepo, mockUserRepo := setupAccessTokenServiceTest()
+		userID, roleID := uuid.New(), uuid.New()
+		app := setupApp("dosen", userID)
+		app.Post("/auth/tokens", svc.CreateAccessToken)
+
+		mockUserRepo.On("GetByID", userID).Return(&models.User{ID: userID, RoleID: roleID}, nil)
+		mockUserRepo.On("GetPermissionsByRoleID", roleID).Return([]string{"achievement:read", "report:students"}, nil)
+
+		var stored *models.PersonalAccessToken
+		mockTokenRepo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.PersonalAccessToken)
+		}).Return(nil)
+
+		status, body := sendJSON(app, "POST", "/auth/tokens", models.CreateAccessTokenRequest{
+			Name:          "nightly statistics",
+			Scopes:        []string{"report:students", "report:students"},
+			ExpiresInDays: 30,
+		})
+
+		require.Equal(t, 201, status)
+		token, _ := body["token"].(string)
+		assert.True(t, strings.HasPrefix(token, middleware.AccessTokenPrefix))
+		assert.Equal(t, utils.HashOpaqueToken(token), stored.TokenHash)
+		assert.Equal(t, token[:12], stored.TokenPrefix)
+		assert.Equal(t, []string{"report:students"}, stored.Scopes)
+		assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), stored.ExpiresAt, time.Minute)
+		assert.NotContains(t, body, "tokenHash")
+	})
+
+	t.Run("Error: Scope outside the owner's permissions", func(t *testing.T) {
+		svc, mockTokenRepo, mockUserRepo := setupAccessTokenServiceTest()
+		userID, roleID := uuid.New(), uuid.New()
+		app := setupApp("dosen", userID)
+		app.Post("/auth/tokens", svc.CreateAccessToken)
+
+		mockUserRepo.On("GetByID", userID).Return(&models.User{ID: userID, RoleID: roleID}, nil)
+		mockUserRepo.On("GetPermissionsByRoleID", roleID).Return([]string{"report:students"}, nil)
+
+		status, body := sendJSON(app, "POST", "/auth/tokens", models.CreateAccessTokenRequest{
+			Name:   "escalate",
+			Scopes: []string{"report:students", "manage:users"},
+		})
+
+		assert.Equal(t, 400, status)
+		assert.Contains(t, body["error"], "manage:users")
+		mockTokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Lifetime above the maximum", func(t *testing.T) {
+		svc, mockTokenRepo, _ := setupAccessTokenServiceTest()
+		app := setupApp("dosen", uuid.New())
+		app.Post("/auth/tokens", svc.CreateAccessToken)
+
+		status, _ := sendJSON(app, "POST", "/auth/tokens", models.CreateAccessTokenRequest{
+			Name:          "forever",
+			Scopes:        []string{"report:students"},
+			ExpiresInDays: 1000,
+		})
+
+		assert.Equal(t, 400, status)
+		mockTokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	t.Run("Error: Token of another user is not found", func(t *testing.T) {
+		svc, mockTokenRepo, _ := setupAccessTokenServiceTest()
+		userID, tokenID := uuid.New(), uuid.New()
+		app := setupApp("dosen", userID)
+		app.Delete("/auth/tokens/:id", svc.RevokeAccessToken)
+
+		mockTokenRepo.On("Revoke", mock.Anything, tokenID, userID).Return(repo.ErrAccessTokenNotFound)
+
+		status, _ := sendJSON(app, "DELETE", "/auth/tokens/"+tokenID.String(), nil)
+
+		assert.Equal(t, 404, status)
+	})
+}
+
+func TestAuthRequiredAccessToken(t *testing.T) {
+	defer middleware.UseAccessTokens(nil)
+	defer middleware.UsePermissionResolver(nil)
+
+	roleID := uuid.New()
+	middleware.UsePermissionResolver(middleware.NewPermissionResolver(func() (map[uuid.UUID][]string, error) {
+		return map[uuid.UUID][]string{roleID: {"achievement:read", "report:students"}}, nil
+	}, time.Minute))
+
+	token := middleware.AccessTokenPrefix + "valid-token"
+	owner := &models.AccessTokenOwner{TokenID: uuid.New(), UserID: uuid.New(), RoleID: roleID, RoleName: "Dosen Wali", Scopes: []string{"report:students"}}
+
+	t.Run("Success: Scoped token reaches its route and records usage", func(t *testing.T) {
+		svc, mockTokenRepo, _ := setupAccessTokenServiceTest()
+		middleware.UseAccessTokens(svc.Authenticate)
+
+		mockTokenRepo.On("FindActive", mock.Anything, utils.HashOpaqueToken(token)).Return(owner, nil)
+		mockTokenRepo.On("TouchLastUsed", mock.Anything, owner.TokenID, mock.Anything, mock.Anything).Return(nil)
+
+		assert.Equal(t, 200, callWithBearer(setupAccessTokenApp(), "/reports", token))
+		mockTokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error: Permission of the owner outside the token scopes", func(t *testing.T) {
+		svc, mockTokenRepo, _ := setupAccessTokenServiceTest()
+		middleware.UseAccessTokens(svc.Authenticate)
+
+		recent := time.Now()
+		used := *owner
+		used.LastUsedAt = &recent
+		mockTokenRepo.On("FindActive", mock.Anything, mock.Anything).Return(&used, nil)
+
+		assert.Equal(t, 403, callWithBearer(setupAccessTokenApp(), "/achievements", token))
+		mockTokenRepo.AssertNotCalled(t, "TouchLastUsed", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Account endpoints need a login session", func(t *testing.T) {
+		svc, mockTokenRepo, _ := setupAccessTokenServiceTest()
+		middleware.UseAccessTokens(svc.Authenticate)
+
+		mockTokenRepo.On("FindActive", mock.Anything, mock.Anything).Return(owner, nil)
+		mockTokenRepo.On("TouchLastUsed", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		assert.Equal(t, 403, callWithBearer(setupAccessTokenApp(), "/sessions", token))
+	})
+
+	t.Run("Error: Revoked or expired token", func(t *testing.T) {
+		svc, mockTokenRepo, _ := setupAccessTokenServiceTest()
+		middleware.UseAccessTokens(svc.Authenticate)
+
+		mockTokenRepo.On("FindActive", mock.Anything, mock.Anything).Return(nil, repo.ErrAccessTokenNotFound)
+
+		assert.Equal(t, 401, callWithBearer(setupAccessTokenApp(), "/reports", token))
+	})
+}
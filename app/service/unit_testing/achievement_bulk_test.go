@@ -0,0 +1,121 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	modelPg "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+)
+
+func TestBulkReview(t *testing.T) {
+	// Tiga prestasi: milik mahasiswa bimbingan (submitted), milik mahasiswa
+	// lain, dan milik mahasiswa bimbingan yang sudah diverifikasi.
+	setup := func() (*mocks.MockAchievementMongoRepo, *mocks.MockAchievementPgRepo, *fiber.App, [3]uuid.UUID) {
+		svc, mockMongo, mockPg, mockLecturer, mockStudent := setupAchievementServiceWithStudents()
+		lecturerUserID, lecturerID := uuid.New(), uuid.New()
+		advisee, other := uuid.New(), uuid.New()
+		ids := [3]uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, lecturerUserID).Return(lecturerID, nil)
+		mockStudent.On("GetStudentByID", mock.Anything, advisee).Return(&modelPg.Student{ID: advisee, AdvisorID: &lecturerID}, nil)
+		mockStudent.On("GetStudentByID", mock.Anything, other).Return(&modelPg.Student{ID: other}, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, ids[0]).Return(modelPg.AchievementReference{ID: ids[0], StudentID: advisee, MongoAchievementID: "m0", Status: "submitted"}, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, ids[1]).Return(modelPg.AchievementReference{ID: ids[1], StudentID: other, MongoAchievementID: "m1", Status: "submitted"}, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, ids[2]).Return(modelPg.AchievementReference{ID: ids[2], StudentID: advisee, MongoAchievementID: "m2", Status: "verified"}, nil)
+		mockMongo.On("FindOne", mock.Anything, mock.Anything).Return(&versionedCompetition(3, 1).Snapshot, nil)
+
+		app := setupAchievementApp("dosen_wali", lecturerUserID)
+		app.Post("/achievements/bulk/verify", svc.BulkVerifyAchievements)
+		app.Post("/achievements/bulk/reject", svc.BulkRejectAchievements)
+		return mockMongo, mockPg, app, ids
+	}
+
+	t.Run("Success: Partial success with a per-item report", func(t *testing.T) {
+		mockMongo, mockPg, app, ids := setup()
+		mockPg.On("TransitionStatus", mock.Anything, ids[0], "submitted", "verified", mock.Anything).Return(nil)
+		mockMongo.On("UpdatePoints", mock.Anything, "m0", 80).Return(nil)
+
+		status, body := sendJSON(app, "POST", "/achievements/bulk/verify", modelPg.BulkVerifyRequest{Items: []modelPg.BulkVerifyItem{
+			{ID: ids[0], Points: 80},
+			{ID: ids[1], Points: 80},
+			{ID: ids[2], Points: 80},
+			{ID: ids[0], Points: 90},
+		}})
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(1), body["succeeded"])
+		assert.Equal(t, float64(3), body["failed"])
+
+		results := body["results"].([]interface{})
+		codes := make([]float64, len(results))
+		for i, r := range results {
+			codes[i] = r.(map[string]interface{})["status"].(float64)
+		}
+		assert.Equal(t, []float64{200, 403, 400, 400}, codes)
+		assert.Equal(t, float64(80), results[0].(map[string]interface{})["points"])
+		mockPg.AssertNotCalled(t, "TransitionStatus", mock.Anything, ids[1], mock.Anything, mock.Anything, mock.Anything)
+		mockMongo.AssertNumberOfCalls(t, "UpdatePoints", 1)
+	})
+
+	t.Run("Error: Item reviewed at an older version", func(t *testing.T) {
+		mockMongo, mockPg, app, ids := setup()
+
+		status, body := sendJSON(app, "POST", "/achievements/bulk/verify", modelPg.BulkVerifyRequest{Items: []modelPg.BulkVerifyItem{{ID: ids[0], Points: 80, Version: 2}}})
+
+		assert.Equal(t, 200, status)
+		result := body["results"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, float64(409), result["status"])
+		assert.Equal(t, float64(3), result["currentVersion"])
+		mockPg.AssertNotCalled(t, "TransitionStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockMongo.AssertNotCalled(t, "UpdatePoints", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success: A failed points write is left to the outbox relay", func(t *testing.T) {
+		mockMongo, mockPg, app, ids := setup()
+		mockPg.On("TransitionStatus", mock.Anything, ids[0], "submitted", "verified", mock.Anything).Return(nil)
+		mockMongo.On("UpdatePoints", mock.Anything, "m0", 80).Return(errors.New("mongo down"))
+
+		status, body := sendJSON(app, "POST", "/achievements/bulk/verify", modelPg.BulkVerifyRequest{Items: []modelPg.BulkVerifyItem{{ID: ids[0], Points: 80}}})
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(1), body["succeeded"])
+		mockPg.AssertNotCalled(t, "TransitionStatus", mock.Anything, ids[0], "verified", "submitted", mock.Anything)
+	})
+
+	t.Run("Success: Bulk reject falls back to the request note", func(t *testing.T) {
+		_, mockPg, app, ids := setup()
+		mockPg.On("TransitionStatus", mock.Anything, ids[0], "submitted", "rejected", mock.MatchedBy(func(ch modelPg.StatusChange) bool {
+			return ch.Note == "Lampirkan sertifikat"
+		})).Return(nil)
+
+		status, body := sendJSON(app, "POST", "/achievements/bulk/reject", modelPg.BulkRejectRequest{
+			Note:  "Lampirkan sertifikat",
+			Items: []modelPg.BulkRejectItem{{ID: ids[0]}},
+		})
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(1), body["succeeded"])
+		mockPg.AssertCalled(t, "TransitionStatus", mock.Anything, ids[0], "submitted", "rejected", mock.Anything)
+	})
+
+	t.Run("Error: Empty request or not a lecturer", func(t *testing.T) {
+		_, _, app, ids := setup()
+
+		status, _ := sendJSON(app, "POST", "/achievements/bulk/verify", modelPg.BulkVerifyRequest{})
+		assert.Equal(t, 400, status)
+
+		svc, _, _, mockLecturer := setupAchievementServiceTest()
+		userID := uuid.New()
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, userID).Return(uuid.Nil, errors.New("not a lecturer"))
+		student := setupAchievementApp("mahasiswa", userID)
+		student.Post("/achievements/bulk/reject", svc.BulkRejectAchievements)
+
+		status, _ = sendJSON(student, "POST", "/achievements/bulk/reject", modelPg.BulkRejectRequest{Note: "x", Items: []modelPg.BulkRejectItem{{ID: ids[0]}}})
+		assert.Equal(t, 403, status)
+	})
+}
@@ -0,0 +1,224 @@
+package service_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	modelMongo "student-performance-report/app/models/mongodb"
+	modelPg "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	"student-performance-report/app/service/outbox"
+	"student-performance-report/app/service/reconcile"
+)
+
+func TestOutboxRelay(t *testing.T) {
+	t.Run("Success: Due events are applied to Mongo and marked delivered", func(t *testing.T) {
+		mockMongo, mockOutbox := new(mocks.MockAchievementMongoRepo), new(mocks.MockOutboxRepo)
+		relay := outbox.NewRelay(mockOutbox, mockMongo, time.Second, time.Minute)
+
+		detail := validCompetition("Gemastik 2025")
+		detail.ID = primitive.NewObjectID()
+		create, _ := outbox.CreateDetails(detail)
+		points := outbox.SetPoints("m1", 120)
+		deletedBy, deletedAt := uuid.New(), time.Now().Add(-time.Minute).Truncate(time.Second)
+		del := outbox.TrashDetails("m2", deletedBy, deletedAt)
+		revoke := outbox.MarkRevoked("m3", deletedAt)
+
+		mockOutbox.On("Due", mock.Anything, 10).Return([]modelPg.OutboxEvent{create, points, del, revoke}, nil)
+		mockOutbox.On("MarkDelivered", mock.Anything, mock.Anything).Return(nil)
+		mockMongo.On("InsertOne", mock.Anything, mock.MatchedBy(func(a modelMongo.Achievement) bool {
+			return a.ID == detail.ID && a.Title == "Gemastik 2025"
+		})).Return(detail.ID.Hex(), nil)
+		mockMongo.On("UpdatePoints", mock.Anything, "m1", 120).Return(nil)
+		mockMongo.On("MarkDeleted", mock.Anything, "m2", deletedBy.String(), mock.MatchedBy(deletedAt.Equal)).Return(nil)
+		mockMongo.On("MarkRevoked", mock.Anything, "m3", mock.MatchedBy(deletedAt.Equal)).Return(nil)
+
+		delivered, failed, err := relay.RunOnce(t.Context(), 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, delivered)
+		assert.Equal(t, 0, failed)
+		mockOutbox.AssertNumberOfCalls(t, "MarkDelivered", 4)
+		mockMongo.AssertExpectations(t)
+	})
+
+	t.Run("Error: A failed write is rescheduled with a growing wait", func(t *testing.T) {
+		mockMongo, mockOutbox := new(mocks.MockAchievementMongoRepo), new(mocks.MockOutboxRepo)
+		relay := outbox.NewRelay(mockOutbox, mockMongo, time.Second, time.Minute)
+
+		event := outbox.SetPoints("m1", 120)
+		event.Attempts = 3
+		mockOutbox.On("Due", mock.Anything, 10).Return([]modelPg.OutboxEvent{event}, nil)
+		mockMongo.On("UpdatePoints", mock.Anything, "m1", 120).Return(errors.New("mongo down"))
+
+		var next time.Time
+		mockOutbox.On("MarkFailed", mock.Anything, event.ID, "mongo down", mock.Anything).Run(func(args mock.Arguments) {
+			next = args.Get(3).(time.Time)
+		}).Return(nil)
+
+		delivered, failed, err := relay.RunOnce(t.Context(), 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, delivered)
+		assert.Equal(t, 1, failed)
+		// 1s digandakan tiga kali
+		assert.WithinDuration(t, time.Now().Add(8*time.Second), next, time.Second)
+		mockOutbox.AssertNotCalled(t, "MarkDelivered", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Deliver does not overtake an earlier undelivered event", func(t *testing.T) {
+		mockMongo, mockOutbox := new(mocks.MockAchievementMongoRepo), new(mocks.MockOutboxRepo)
+		relay := outbox.NewRelay(mockOutbox, mockMongo, time.Second, time.Minute)
+
+		// create_details masih menunggu retry; penandaan sampah harus menunggu.
+		event := outbox.TrashDetails("m1", uuid.New(), time.Now())
+		mockOutbox.On("Blocked", mock.Anything, event.ID).Return(true, nil)
+
+		err := relay.Deliver(t.Context(), event)
+
+		assert.Error(t, err)
+		mockMongo.AssertNotCalled(t, "MarkDeleted", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockOutbox.AssertNotCalled(t, "MarkDelivered", mock.Anything, mock.Anything)
+		mockOutbox.AssertNotCalled(t, "MarkFailed", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAchievementOutboxWrites(t *testing.T) {
+	t.Run("Success: Delete stores and delivers the move to the trash", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		userID, studentID, achievementID := uuid.New(), uuid.New(), uuid.New()
+		app := setupAchievementApp("mahasiswa", userID)
+		app.Delete("/achievements/:id", svc.DeleteAchievement)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, MongoAchievementID: "mongo_obj_id_123", Status: "draft"}
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockPg.On("TransitionStatus", mock.Anything, achievementID, "draft", "deleted", mock.MatchedBy(func(ch modelPg.StatusChange) bool {
+			return len(ch.Outbox) == 1 && ch.Outbox[0].Kind == modelPg.OutboxTrashDetails && ch.Outbox[0].MongoAchievementID == "mongo_obj_id_123"
+		})).Return(nil)
+		mockMongo.On("MarkDeleted", mock.Anything, "mongo_obj_id_123", userID.String(), mock.Anything).Return(errors.New("mongo down"))
+
+		resp, _ := app.Test(httptest.NewRequest("DELETE", "/achievements/"+achievementID.String(), nil))
+
+		// Referensi sudah di tong sampah; penandaan di Mongo diulang oleh relay.
+		assert.Equal(t, 200, resp.StatusCode)
+		mockPg.AssertExpectations(t)
+		mockMongo.AssertNotCalled(t, "DeleteAchievement", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success: List keeps references whose details are missing", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		app := setupApp("admin", uuid.New())
+		app.Get("/achievements", svc.GetAllAchievements)
+
+		found := validCompetition("Gemastik 2025")
+		found.ID = primitive.NewObjectID()
+		refs := []modelPg.AchievementReference{
+			{ID: uuid.New(), MongoAchievementID: found.ID.Hex(), Status: "draft"},
+			{ID: uuid.New(), MongoAchievementID: primitive.NewObjectID().Hex(), Status: "verified", Points: 80},
+		}
+		mockPg.On("GetAllReferences", mock.Anything, mock.Anything, 10, 0, "").Return(refs, int64(2), nil)
+		mockMongo.On("FindAllDetails", mock.Anything, mock.Anything).Return([]modelMongo.Achievement{found}, nil)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/achievements", nil))
+
+		assert.Equal(t, 200, resp.StatusCode)
+		var body struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		assert.Len(t, body.Data, 2)
+		assert.Equal(t, "Gemastik 2025", body.Data[0]["title"])
+		assert.Nil(t, body.Data[0]["detailsMissing"])
+		assert.Equal(t, true, body.Data[1]["detailsMissing"])
+		assert.Equal(t, float64(80), body.Data[1]["points"])
+	})
+}
+
+func TestReconcile(t *testing.T) {
+	old := time.Now().Add(-2 * time.Hour)
+	doc := func(points int) modelMongo.Achievement {
+		return modelMongo.Achievement{ID: primitive.NewObjectID(), Points: points, CreatedAt: old}
+	}
+
+	// Satu contoh untuk setiap jenis masalah, ditambah data yang konsisten
+	// dan data yang masih ditangani outbox.
+	setup := func() (*reconcile.Reconciler, *mocks.MockAchievementMongoRepo, *mocks.MockAchievementPgRepo, map[string]modelPg.AchievementReference) {
+		mockMongo, mockPg, mockOutbox := new(mocks.MockAchievementMongoRepo), new(mocks.MockAchievementPgRepo), new(mocks.MockOutboxRepo)
+
+		consistent, mismatch, orphan, ofDeleted, inFlight := doc(100), doc(50), doc(0), doc(0), doc(0)
+		deletedBy := uuid.New()
+		refs := map[string]modelPg.AchievementReference{
+			"consistent": {ID: uuid.New(), MongoAchievementID: consistent.ID.Hex(), Status: "verified", Points: 100, CreatedAt: old},
+			"mismatch":   {ID: uuid.New(), MongoAchievementID: mismatch.ID.Hex(), Status: "verified", Points: 120, CreatedAt: old},
+			"missing":    {ID: uuid.New(), MongoAchievementID: primitive.NewObjectID().Hex(), Status: "submitted", CreatedAt: old},
+			"fresh":      {ID: uuid.New(), MongoAchievementID: primitive.NewObjectID().Hex(), Status: "draft", CreatedAt: time.Now()},
+			"deleted":    {ID: uuid.New(), MongoAchievementID: ofDeleted.ID.Hex(), Status: "deleted", CreatedAt: old, DeletedAt: &old, DeletedBy: &deletedBy},
+			"inFlight":   {ID: uuid.New(), MongoAchievementID: inFlight.ID.Hex(), Status: "verified", Points: 70, CreatedAt: old},
+		}
+		var list []modelPg.AchievementReference
+		for _, r := range refs {
+			list = append(list, r)
+		}
+
+		mockPg.On("ListReferences", mock.Anything).Return(list, nil)
+		mockMongo.On("ListAll", mock.Anything).Return([]modelMongo.Achievement{consistent, mismatch, orphan, ofDeleted, inFlight}, nil)
+		mockOutbox.On("Pending", mock.Anything).Return([]modelPg.OutboxEvent{
+			{AchievementRefID: refs["inFlight"].ID, MongoAchievementID: inFlight.ID.Hex(), Kind: modelPg.OutboxSetPoints, CreatedAt: time.Now()},
+		}, nil)
+
+		refs["orphan"] = modelPg.AchievementReference{MongoAchievementID: orphan.ID.Hex()}
+		refs["deletedBy"] = modelPg.AchievementReference{ID: deletedBy}
+		return reconcile.New(mockPg, mockMongo, mockOutbox, time.Hour), mockMongo, mockPg, refs
+	}
+
+	kinds := func(report *reconcile.Report) map[string]string {
+		byMongoID := map[string]string{}
+		for _, i := range report.Issues {
+			byMongoID[i.MongoID] = i.Kind
+		}
+		return byMongoID
+	}
+
+	t.Run("Success: Report only", func(t *testing.T) {
+		r, mockMongo, mockPg, refs := setup()
+
+		report, err := r.Run(t.Context(), false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			refs["mismatch"].MongoAchievementID: reconcile.PointsMismatch,
+			refs["missing"].MongoAchievementID:  reconcile.MissingDetails,
+			refs["deleted"].MongoAchievementID:  reconcile.TrashMismatch,
+			refs["orphan"].MongoAchievementID:   reconcile.OrphanDetails,
+		}, kinds(report))
+		assert.Equal(t, 4, report.Unresolved())
+		mockMongo.AssertNotCalled(t, "UpdatePoints", mock.Anything, mock.Anything, mock.Anything)
+		mockMongo.AssertNotCalled(t, "DeleteAchievement", mock.Anything, mock.Anything)
+		mockMongo.AssertNotCalled(t, "MarkDeleted", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockPg.AssertNotCalled(t, "TransitionStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success: Repair makes Mongo follow Postgres", func(t *testing.T) {
+		r, mockMongo, mockPg, refs := setup()
+		mockMongo.On("UpdatePoints", mock.Anything, refs["mismatch"].MongoAchievementID, 120).Return(nil)
+		mockMongo.On("MarkDeleted", mock.Anything, refs["deleted"].MongoAchievementID, refs["deletedBy"].ID.String(), old).Return(nil)
+		mockMongo.On("DeleteAchievement", mock.Anything, refs["orphan"].MongoAchievementID).Return(errors.New("mongo down"))
+		mockPg.On("TransitionStatus", mock.Anything, refs["missing"].ID, "submitted", "deleted", mock.Anything).Return(nil)
+
+		report, err := r.Run(t.Context(), true)
+
+		assert.NoError(t, err)
+		assert.Len(t, report.Issues, 4)
+		assert.Equal(t, 1, report.Unresolved())
+		mockMongo.AssertExpectations(t)
+		mockPg.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,179 @@
+package service_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	modelMongo "student-performance-report/app/models/mongodb"
+	modelPg "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/service/schema"
+)
+
+func TestAchievementTypeRegistry(t *testing.T) {
+	registry := schema.Default()
+
+	t.Run("Success: Valid competition", func(t *testing.T) {
+		a := validCompetition("Gemastik 2025")
+		a.Details.Rank = 1
+		a.Details.MedalType = "gold"
+		a.CustomFields = map[string]interface{}{"teamName": "Tim A", "teamMembers": []interface{}{"Budi", "Sari"}}
+
+		assert.Nil(t, registry.Validate(&a))
+	})
+
+	t.Run("Error: Competition with ISSN and no competition name", func(t *testing.T) {
+		a := modelMongo.Achievement{
+			Title:           "Lomba",
+			AchievementType: "competition",
+			Details:         modelMongo.AchievementDetails{CompetitionLevel: "national", ISSN: "1234-5678"},
+		}
+
+		errs := registry.Validate(&a)
+		assert.Equal(t, "is required", errs["details.competitionName"])
+		assert.Equal(t, "is not allowed for achievementType competition", errs["details.issn"])
+	})
+
+	t.Run("Error: Value outside enumeration", func(t *testing.T) {
+		a := validCompetition("Lomba")
+		a.Details.CompetitionLevel = "kampus"
+		a.Details.MedalType = "platinum"
+		a.Details.Rank = -2
+
+		errs := registry.Validate(&a)
+		assert.Equal(t, "must be one of international, national, regional, local", errs["details.competitionLevel"])
+		assert.Equal(t, "must be one of gold, silver, bronze", errs["details.medalType"])
+		assert.Equal(t, "must be at least 1", errs["details.rank"])
+	})
+
+	t.Run("Error: Unknown or missing type", func(t *testing.T) {
+		errs := registry.Validate(&modelMongo.Achievement{Title: "x", AchievementType: "hobby"})
+		assert.Contains(t, errs["achievementType"], "must be one of")
+
+		errs = registry.Validate(&modelMongo.Achievement{})
+		assert.Equal(t, "is required", errs["achievementType"])
+		assert.Equal(t, "is required", errs["title"])
+	})
+
+	t.Run("Error: Cross-field checks", func(t *testing.T) {
+		start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		org := modelMongo.Achievement{
+			Title:           "BEM",
+			AchievementType: "organization",
+			Details:         modelMongo.AchievementDetails{OrganizationName: "BEM", Position: "Ketua", StartDate: start, EndDate: start.AddDate(0, -1, 0)},
+		}
+		assert.Equal(t, "must not be before startDate", registry.Validate(&org)["details.endDate"])
+
+		pub := modelMongo.Achievement{
+			Title:           "Jurnal",
+			AchievementType: "publication",
+			Details:         modelMongo.AchievementDetails{PublicationType: "journal", PublicationTitle: "X", Authors: []string{"Budi"}, Publisher: "IEEE", ISSN: "12345678"},
+		}
+		assert.Equal(t, "must look like 1234-567X", registry.Validate(&pub)["details.issn"])
+	})
+
+	t.Run("Error: Custom fields violate the type's JSON Schema", func(t *testing.T) {
+		a := validCompetition("Lomba")
+		a.CustomFields = map[string]interface{}{"teamName": 7, "teamMembers": []interface{}{"Budi", ""}}
+
+		errs := registry.Validate(&a)
+		assert.Equal(t, "must be of type string", errs["customFields.teamName"])
+		assert.Equal(t, "must be at least 1 characters", errs["customFields.teamMembers[1]"])
+	})
+
+	t.Run("Error: Register rejects unknown fields and bad schemas", func(t *testing.T) {
+		r := schema.NewRegistry()
+		assert.Error(t, r.Register(schema.TypeSchema{Type: "x", Fields: map[string]schema.FieldRule{"level": {}}}))
+		assert.Error(t, r.Register(schema.TypeSchema{Type: "x", Fields: map[string]schema.FieldRule{"rank": {Enum: []string{"1"}}}}))
+		assert.Error(t, r.Register(schema.TypeSchema{Type: "x", CustomFields: json.RawMessage(`{"type": "object", "oneOf": []}`)}))
+	})
+}
+
+func TestJSONSchema(t *testing.T) {
+	s, err := schema.CompileJSONSchema([]byte(`{
+		"type": "object",
+		"required": ["sks"],
+		"additionalProperties": false,
+		"properties": {
+			"sks": {"type": "integer", "minimum": 1, "maximum": 24},
+			"grade": {"enum": ["A", "B", "C"]},
+			"date": {"type": "string", "format": "date"}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	errs := schema.FieldErrors{}
+	s.Validate(map[string]interface{}{"sks": 2.5, "grade": "E", "date": "17-10-2026", "extra": true}, "customFields", errs)
+	assert.Equal(t, schema.FieldErrors{
+		"customFields.sks":   "must be of type integer",
+		"customFields.grade": "must be one of A, B, C",
+		"customFields.date":  "must be a valid date",
+		"customFields.extra": "is not allowed",
+	}, errs)
+
+	errs = schema.FieldErrors{}
+	s.Validate(map[string]interface{}{}, "customFields", errs)
+	assert.Equal(t, "is required", errs["customFields.sks"])
+
+	errs = schema.FieldErrors{}
+	s.Validate(map[string]interface{}{"sks": float64(30)}, "customFields", errs)
+	assert.Equal(t, "must be at most 24", errs["customFields.sks"])
+}
+
+func TestAchievementValidationHandlers(t *testing.T) {
+	t.Run("Error: Create returns field-level errors", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		userID := uuid.New()
+		app := setupAchievementApp("mahasiswa", userID)
+
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(uuid.New(), nil)
+		app.Post("/achievements", svc.CreateAchievement)
+
+		body := modelMongo.Achievement{Title: "Lomba", AchievementType: "competition", Details: modelMongo.AchievementDetails{ISSN: "1234-5678"}}
+		status, resp := sendJSON(app, "POST", "/achievements", body)
+
+		assert.Equal(t, 400, status)
+		fields := resp["fields"].(map[string]interface{})
+		assert.Equal(t, "is required", fields["details.competitionName"])
+		assert.Equal(t, "is not allowed for achievementType competition", fields["details.issn"])
+		mockMongo.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Invalid update leaves a rejected achievement untouched", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		userID, studentID, achievementID := uuid.New(), uuid.New(), uuid.New()
+		app := setupAchievementApp("mahasiswa", userID)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, MongoAchievementID: "mongo_obj_id_123", Status: "rejected"}
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		app.Put("/achievements/:id", svc.UpdateAchievement)
+
+		body := validCompetition("Lomba")
+		body.Details.MedalType = "platinum"
+		status, resp := sendJSON(app, "PUT", "/achievements/"+achievementID.String(), body)
+
+		assert.Equal(t, 400, status)
+		assert.Equal(t, "must be one of gold, silver, bronze", resp["fields"].(map[string]interface{})["details.medalType"])
+		mockPg.AssertNotCalled(t, "TransitionStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockMongo.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success: List types", func(t *testing.T) {
+		svc, _, _, _ := setupAchievementServiceTest()
+		app := setupAchievementApp("mahasiswa", uuid.New())
+		app.Get("/achievements/types", svc.GetAchievementTypes)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/achievements/types", nil))
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var types []schema.TypeSchema
+		json.NewDecoder(resp.Body).Decode(&types)
+		assert.Equal(t, "academic", types[0].Type)
+		assert.True(t, types[2].Fields["competitionLevel"].Required)
+	})
+}
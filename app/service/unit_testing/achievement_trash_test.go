@@ -0,0 +1,305 @@
+package service_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	modelMongo "student-performance-report/app/models/mongodb"
+	modelPg "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	repoPg "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/trash"
+)
+
+// deletedRef adalah draft yang dihapus `ago` yang lalu.
+func deletedRef(studentID uuid.UUID, ago time.Duration) modelPg.AchievementReference {
+	deletedAt := time.Now().Add(-ago)
+	return modelPg.AchievementReference{
+		ID:                 uuid.New(),
+		StudentID:          studentID,
+		MongoAchievementID: primitive.NewObjectID().Hex(),
+		Status:             modelPg.StatusDeleted,
+		DeletedAt:          &deletedAt,
+	}
+}
+
+func TestRestoreAchievement(t *testing.T) {
+	userID, studentID := uuid.New(), uuid.New()
+
+	t.Run("Success: The owner restores a draft within the retention window", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		app := setupAchievementApp("mahasiswa", userID)
+		app.Post("/achievements/:id/restore", svc.RestoreAchievement)
+
+		ref := deletedRef(studentID, 24*time.Hour)
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetDeletedReference", mock.Anything, ref.ID).Return(ref, nil)
+		mockMongo.On("FindOne", mock.Anything, ref.MongoAchievementID).Return(&modelMongo.Achievement{}, nil)
+		mockPg.On("TransitionStatus", mock.Anything, ref.ID, "deleted", "draft", mock.MatchedBy(func(ch modelPg.StatusChange) bool {
+			return ch.ActorID == userID && len(ch.Outbox) == 1 && ch.Outbox[0].Kind == modelPg.OutboxRestoreDetails
+		})).Return(nil)
+		mockMongo.On("ClearDeleted", mock.Anything, ref.MongoAchievementID).Return(nil)
+
+		resp, _ := app.Test(httptest.NewRequest("POST", "/achievements/"+ref.ID.String()+"/restore", nil))
+
+		assert.Equal(t, 200, resp.StatusCode)
+		mockPg.AssertExpectations(t)
+		mockMongo.AssertExpectations(t)
+	})
+
+	t.Run("Success: An admin may restore any student's draft", func(t *testing.T) {
+		svc, mockMongo, mockPg, mockLecturer := setupAchievementServiceTest()
+		adminID := uuid.New()
+		app := setupApp("admin", adminID)
+		app.Post("/achievements/:id/restore", svc.RestoreAchievement)
+
+		ref := deletedRef(studentID, time.Hour)
+		mockPg.On("GetStudentByUserID", mock.Anything, adminID).Return(uuid.Nil, errors.New("not a student"))
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, adminID).Return(uuid.Nil, errors.New("not a lecturer")).Maybe()
+		mockPg.On("GetDeletedReference", mock.Anything, ref.ID).Return(ref, nil)
+		mockMongo.On("FindOne", mock.Anything, ref.MongoAchievementID).Return(&modelMongo.Achievement{}, nil)
+		mockPg.On("TransitionStatus", mock.Anything, ref.ID, "deleted", "draft", mock.Anything).Return(nil)
+		mockMongo.On("ClearDeleted", mock.Anything, ref.MongoAchievementID).Return(nil)
+
+		resp, _ := app.Test(httptest.NewRequest("POST", "/achievements/"+ref.ID.String()+"/restore", nil))
+
+		assert.Equal(t, 200, resp.StatusCode)
+	})
+
+	t.Run("Error: Another student cannot restore the draft", func(t *testing.T) {
+		svc, _, mockPg, _ := setupAchievementServiceTest()
+		app := setupAchievementApp("mahasiswa", userID)
+		app.Post("/achievements/:id/restore", svc.RestoreAchievement)
+
+		ref := deletedRef(uuid.New(), time.Hour)
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetDeletedReference", mock.Anything, ref.ID).Return(ref, nil)
+
+		resp, _ := app.Test(httptest.NewRequest("POST", "/achievements/"+ref.ID.String()+"/restore", nil))
+
+		assert.Equal(t, 403, resp.StatusCode)
+		mockPg.AssertNotCalled(t, "TransitionStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: The retention window has passed", func(t *testing.T) {
+		svc, _, mockPg, _ := setupAchievementServiceTest()
+		app := setupAchievementApp("mahasiswa", userID)
+		app.Post("/achievements/:id/restore", svc.RestoreAchievement)
+
+		ref := deletedRef(studentID, testTrashRetention+time.Hour)
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetDeletedReference", mock.Anything, ref.ID).Return(ref, nil)
+
+		resp, _ := app.Test(httptest.NewRequest("POST", "/achievements/"+ref.ID.String()+"/restore", nil))
+
+		assert.Equal(t, 410, resp.StatusCode)
+		mockPg.AssertNotCalled(t, "TransitionStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Details removed before the trash existed cannot be restored", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		app := setupAchievementApp("mahasiswa", userID)
+		app.Post("/achievements/:id/restore", svc.RestoreAchievement)
+
+		ref := deletedRef(studentID, time.Hour)
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetDeletedReference", mock.Anything, ref.ID).Return(ref, nil)
+		mockMongo.On("FindOne", mock.Anything, ref.MongoAchievementID).Return(nil, mongo.ErrNoDocuments)
+
+		resp, _ := app.Test(httptest.NewRequest("POST", "/achievements/"+ref.ID.String()+"/restore", nil))
+
+		assert.Equal(t, 410, resp.StatusCode)
+	})
+
+	t.Run("Error: Not in the trash", func(t *testing.T) {
+		svc, _, mockPg, _ := setupAchievementServiceTest()
+		app := setupAchievementApp("mahasiswa", userID)
+		app.Post("/achievements/:id/restore", svc.RestoreAchievement)
+
+		id := uuid.New()
+		mockPg.On("GetDeletedReference", mock.Anything, id).Return(modelPg.AchievementReference{}, errors.New("sql: no rows in result set"))
+
+		resp, _ := app.Test(httptest.NewRequest("POST", "/achievements/"+id.String()+"/restore", nil))
+
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+}
+
+func TestGetStudentTrash(t *testing.T) {
+	userID, studentID := uuid.New(), uuid.New()
+
+	t.Run("Success: The student sees the trash with purge dates", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		app := setupAchievementApp("mahasiswa", userID)
+		app.Get("/students/:id/trash", svc.GetStudentTrash)
+
+		recent, legacy := deletedRef(studentID, time.Hour), deletedRef(studentID, 2*time.Hour)
+		detail := validCompetition("Gemastik 2025")
+		detail.ID, _ = primitive.ObjectIDFromHex(recent.MongoAchievementID)
+
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetTrash", mock.Anything, studentID).Return([]modelPg.AchievementReference{recent, legacy}, nil)
+		mockMongo.On("FindAllDetails", mock.Anything, []string{recent.MongoAchievementID, legacy.MongoAchievementID}).Return([]modelMongo.Achievement{detail}, nil)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/students/"+studentID.String()+"/trash", nil))
+
+		assert.Equal(t, 200, resp.StatusCode)
+		var body struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		assert.Len(t, body.Data, 2)
+		assert.Equal(t, "Gemastik 2025", body.Data[0]["title"])
+		assert.Equal(t, true, body.Data[0]["restorable"])
+		assert.NotEmpty(t, body.Data[0]["purgeAt"])
+		// Detail yang sudah hilang di Mongo tidak bisa dipulihkan.
+		assert.Equal(t, true, body.Data[1]["detailsMissing"])
+		assert.Equal(t, false, body.Data[1]["restorable"])
+	})
+
+	t.Run("Error: The advisor does not see the trash", func(t *testing.T) {
+		svc, _, mockPg, mockLecturer := setupAchievementServiceTest()
+		lecturerUserID := uuid.New()
+		app := setupAchievementApp("dosen_wali", lecturerUserID)
+		app.Get("/students/:id/trash", svc.GetStudentTrash)
+
+		mockPg.On("GetStudentByUserID", mock.Anything, lecturerUserID).Return(uuid.Nil, errors.New("not a student"))
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, lecturerUserID).Return(uuid.New(), nil).Maybe()
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/students/"+studentID.String()+"/trash", nil))
+
+		assert.Equal(t, 403, resp.StatusCode)
+		mockPg.AssertNotCalled(t, "GetTrash", mock.Anything, mock.Anything)
+	})
+}
+
+func TestTrashPurge(t *testing.T) {
+	// writeUpload membuat file lampiran di dir dan mengembalikan URL-nya.
+	writeUpload := func(t *testing.T, dir, name string) (string, string) {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte("x"), 0644))
+		return "/uploads/" + name, path
+	}
+
+	t.Run("Success: Expired trash is removed with its attachments", func(t *testing.T) {
+		mockPg, mockMongo := new(mocks.MockAchievementPgRepo), new(mocks.MockAchievementMongoRepo)
+		dir := t.TempDir()
+		purger := trash.NewPurger(mockPg, mockMongo, testTrashRetention, dir)
+
+		currentURL, currentPath := writeUpload(t, dir, "current.pdf")
+		olderURL, olderPath := writeUpload(t, dir, "older.pdf")
+		_, keptPath := writeUpload(t, dir, "other.pdf")
+
+		ref := deletedRef(uuid.New(), testTrashRetention+time.Hour)
+		mockPg.On("ExpiredTrash", mock.Anything, mock.Anything, 50).Return([]modelPg.AchievementReference{ref}, nil)
+		mockMongo.On("FindOne", mock.Anything, ref.MongoAchievementID).Return(&modelMongo.Achievement{
+			Attachments: []modelMongo.Attachment{{FileURL: currentURL}},
+		}, nil)
+		mockMongo.On("ListVersions", mock.Anything, ref.MongoAchievementID).Return([]modelMongo.AchievementVersion{
+			{Snapshot: modelMongo.Achievement{Attachments: []modelMongo.Attachment{{FileURL: olderURL}}}},
+			{Snapshot: modelMongo.Achievement{Attachments: []modelMongo.Attachment{{FileURL: olderURL}, {FileURL: currentURL}}}},
+		}, nil)
+		mockPg.On("PurgeReference", mock.Anything, ref.ID, mock.Anything).Return(nil)
+		mockMongo.On("DeleteAchievement", mock.Anything, ref.MongoAchievementID).Return(nil)
+
+		purged, failed, err := purger.RunOnce(t.Context(), 50)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, purged)
+		assert.Equal(t, 0, failed)
+		assert.NoFileExists(t, currentPath)
+		assert.NoFileExists(t, olderPath)
+		assert.FileExists(t, keptPath)
+		mockMongo.AssertExpectations(t)
+	})
+
+	t.Run("Success: A draft restored meanwhile is left alone", func(t *testing.T) {
+		mockPg, mockMongo := new(mocks.MockAchievementPgRepo), new(mocks.MockAchievementMongoRepo)
+		dir := t.TempDir()
+		purger := trash.NewPurger(mockPg, mockMongo, testTrashRetention, dir)
+
+		url, path := writeUpload(t, dir, "proof.pdf")
+		ref := deletedRef(uuid.New(), testTrashRetention+time.Hour)
+		mockPg.On("ExpiredTrash", mock.Anything, mock.Anything, 50).Return([]modelPg.AchievementReference{ref}, nil)
+		mockMongo.On("FindOne", mock.Anything, ref.MongoAchievementID).Return(&modelMongo.Achievement{
+			Attachments: []modelMongo.Attachment{{FileURL: url}},
+		}, nil)
+		mockMongo.On("ListVersions", mock.Anything, ref.MongoAchievementID).Return(nil, nil)
+		mockPg.On("PurgeReference", mock.Anything, ref.ID, mock.Anything).Return(repoPg.ErrStatusConflict)
+
+		purged, failed, err := purger.RunOnce(t.Context(), 50)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, purged)
+		assert.Equal(t, 0, failed)
+		assert.FileExists(t, path)
+		mockMongo.AssertNotCalled(t, "DeleteAchievement", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success: Trash whose details are already gone is still purged", func(t *testing.T) {
+		mockPg, mockMongo := new(mocks.MockAchievementPgRepo), new(mocks.MockAchievementMongoRepo)
+		purger := trash.NewPurger(mockPg, mockMongo, testTrashRetention, t.TempDir())
+
+		ref := deletedRef(uuid.New(), testTrashRetention+time.Hour)
+		mockPg.On("ExpiredTrash", mock.Anything, mock.Anything, 50).Return([]modelPg.AchievementReference{ref}, nil)
+		mockMongo.On("FindOne", mock.Anything, ref.MongoAchievementID).Return(nil, mongo.ErrNoDocuments)
+		mockPg.On("PurgeReference", mock.Anything, ref.ID, mock.Anything).Return(nil)
+		mockMongo.On("DeleteAchievement", mock.Anything, ref.MongoAchievementID).Return(nil)
+
+		purged, _, err := purger.RunOnce(t.Context(), 50)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, purged)
+	})
+
+	t.Run("Error: Nothing is removed when the attachments cannot be read", func(t *testing.T) {
+		mockPg, mockMongo := new(mocks.MockAchievementPgRepo), new(mocks.MockAchievementMongoRepo)
+		purger := trash.NewPurger(mockPg, mockMongo, testTrashRetention, t.TempDir())
+
+		ref := deletedRef(uuid.New(), testTrashRetention+time.Hour)
+		mockPg.On("ExpiredTrash", mock.Anything, mock.Anything, 50).Return([]modelPg.AchievementReference{ref}, nil)
+		mockMongo.On("FindOne", mock.Anything, ref.MongoAchievementID).Return(nil, errors.New("mongo down"))
+		mockPg.On("PurgeReference", mock.Anything, ref.ID, mock.Anything).Return(nil)
+
+		purged, failed, err := purger.RunOnce(t.Context(), 50)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, purged)
+		assert.Equal(t, 1, failed)
+		mockMongo.AssertNotCalled(t, "DeleteAchievement", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: The reference is kept when the details cannot be removed", func(t *testing.T) {
+		mockPg, mockMongo := new(mocks.MockAchievementPgRepo), new(mocks.MockAchievementMongoRepo)
+		dir := t.TempDir()
+		purger := trash.NewPurger(mockPg, mockMongo, testTrashRetention, dir)
+
+		url, path := writeUpload(t, dir, "proof.pdf")
+		ref := deletedRef(uuid.New(), testTrashRetention+time.Hour)
+		mockPg.On("ExpiredTrash", mock.Anything, mock.Anything, 50).Return([]modelPg.AchievementReference{ref}, nil)
+		mockMongo.On("FindOne", mock.Anything, ref.MongoAchievementID).Return(&modelMongo.Achievement{
+			Attachments: []modelMongo.Attachment{{FileURL: url}},
+		}, nil)
+		mockMongo.On("ListVersions", mock.Anything, ref.MongoAchievementID).Return(nil, nil)
+		mockPg.On("PurgeReference", mock.Anything, ref.ID, mock.Anything).Return(nil)
+		mockMongo.On("DeleteAchievement", mock.Anything, ref.MongoAchievementID).Return(errors.New("mongo down"))
+
+		purged, failed, err := purger.RunOnce(t.Context(), 50)
+
+		// PurgeReference tidak commit, jadi run berikutnya mengulang seluruh purge.
+		assert.NoError(t, err)
+		assert.Equal(t, 0, purged)
+		assert.Equal(t, 1, failed)
+		assert.NoFileExists(t, path)
+	})
+}
@@ -0,0 +1,138 @@
+package service_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	modelMongo "student-performance-report/app/models/mongodb"
+	modelPg "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	repoMongo "student-performance-report/app/repository/mongodb"
+)
+
+// versionedCompetition adalah snapshot versi ke-v dari satu prestasi lomba.
+func versionedCompetition(v int, rank int) *modelMongo.AchievementVersion {
+	a := validCompetition("Gemastik 2025")
+	a.Version = v
+	a.Details.Rank = rank
+	a.UpdatedAt = time.Now().Add(time.Duration(v) * time.Hour)
+	return &modelMongo.AchievementVersion{Version: v, Snapshot: a, CreatedAt: a.UpdatedAt}
+}
+
+func TestAchievementVersions(t *testing.T) {
+	setup := func() (*fiber.App, *mocks.MockAchievementMongoRepo, uuid.UUID) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		userID, studentID, achievementID := uuid.New(), uuid.New(), uuid.New()
+		app := setupAchievementApp("mahasiswa", userID)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, MongoAchievementID: "mongo_obj_id_123", Status: "revision"}
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+
+		app.Get("/achievements/:id/versions", svc.GetAchievementVersions)
+		app.Get("/achievements/:id/versions/diff", svc.DiffAchievementVersions)
+		app.Get("/achievements/:id/versions/:v", svc.GetAchievementVersion)
+		return app, mockMongo, achievementID
+	}
+
+	t.Run("Success: List versions", func(t *testing.T) {
+		app, mockMongo, achievementID := setup()
+		mockMongo.On("ListVersions", mock.Anything, "mongo_obj_id_123").Return([]modelMongo.AchievementVersion{*versionedCompetition(1, 3), *versionedCompetition(2, 1)}, nil)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/achievements/"+achievementID.String()+"/versions", nil))
+
+		assert.Equal(t, 200, resp.StatusCode)
+		var body []modelMongo.AchievementVersion
+		json.NewDecoder(resp.Body).Decode(&body)
+		assert.Len(t, body, 2)
+		assert.Equal(t, 3, body[0].Snapshot.Details.Rank)
+	})
+
+	t.Run("Error: Unknown version", func(t *testing.T) {
+		app, mockMongo, achievementID := setup()
+		mockMongo.On("GetVersion", mock.Anything, "mongo_obj_id_123", 9).Return(nil, repoMongo.ErrVersionNotFound)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/achievements/"+achievementID.String()+"/versions/9", nil))
+		assert.Equal(t, 404, resp.StatusCode)
+
+		resp, _ = app.Test(httptest.NewRequest("GET", "/achievements/"+achievementID.String()+"/versions/abc", nil))
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("Success: Diff lists changed fields only", func(t *testing.T) {
+		app, mockMongo, achievementID := setup()
+		v1, v2 := versionedCompetition(1, 3), versionedCompetition(2, 1)
+		v2.Snapshot.Details.MedalType = "gold"
+		v2.Snapshot.Tags = []string{"nasional"}
+		mockMongo.On("GetVersion", mock.Anything, "mongo_obj_id_123", 1).Return(v1, nil)
+		mockMongo.On("GetVersion", mock.Anything, "mongo_obj_id_123", 2).Return(v2, nil)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/achievements/"+achievementID.String()+"/versions/diff?from=1&to=2", nil))
+
+		assert.Equal(t, 200, resp.StatusCode)
+		var body struct {
+			Changes []modelMongo.FieldChange `json:"changes"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		assert.Equal(t, []modelMongo.FieldChange{
+			{Field: "details.medalType", From: nil, To: "gold"},
+			{Field: "details.rank", From: float64(3), To: float64(1)},
+			{Field: "tags[0]", From: nil, To: "nasional"},
+		}, body.Changes)
+	})
+
+	t.Run("Error: Diff needs both versions", func(t *testing.T) {
+		app, _, achievementID := setup()
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/achievements/"+achievementID.String()+"/versions/diff?from=1", nil))
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+}
+
+func TestVerifyPinnedVersion(t *testing.T) {
+	setup := func() (*mocks.MockAchievementMongoRepo, *mocks.MockAchievementPgRepo, *fiber.App, uuid.UUID) {
+		svc, mockMongo, mockPg, mockLecturer, mockStudent := setupAchievementServiceWithStudents()
+		lecturerUserID, lecturerID := uuid.New(), uuid.New()
+		achievementID, studentID := uuid.New(), uuid.New()
+		app := setupAchievementApp("dosen_wali", lecturerUserID)
+		app.Post("/achievements/:id/verify", svc.VerifyAchievement)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, MongoAchievementID: "mongo_obj_id_123", Status: "submitted"}
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, lecturerUserID).Return(lecturerID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockStudent.On("GetStudentByID", mock.Anything, studentID).Return(&modelPg.Student{ID: studentID, AdvisorID: &lecturerID}, nil)
+		mockMongo.On("FindOne", mock.Anything, "mongo_obj_id_123").Return(&versionedCompetition(3, 1).Snapshot, nil)
+		return mockMongo, mockPg, app, achievementID
+	}
+
+	t.Run("Success: Verification records the reviewed version", func(t *testing.T) {
+		mockMongo, mockPg, app, achievementID := setup()
+		mockPg.On("TransitionStatus", mock.Anything, achievementID, "submitted", "verified", mock.MatchedBy(func(ch modelPg.StatusChange) bool {
+			return ch.Version != nil && *ch.Version == 3
+		})).Return(nil)
+		mockMongo.On("UpdatePoints", mock.Anything, "mongo_obj_id_123", 100).Return(nil)
+
+		status, body := sendJSON(app, "POST", "/achievements/"+achievementID.String()+"/verify", map[string]int{"points": 100, "version": 3})
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(3), body["version"])
+		mockPg.AssertExpectations(t)
+	})
+
+	t.Run("Error: Details changed after review", func(t *testing.T) {
+		mockMongo, mockPg, app, achievementID := setup()
+
+		status, body := sendJSON(app, "POST", "/achievements/"+achievementID.String()+"/verify", map[string]int{"points": 100, "version": 2})
+
+		assert.Equal(t, 409, status)
+		assert.Equal(t, float64(3), body["currentVersion"])
+		mockPg.AssertNotCalled(t, "TransitionStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockMongo.AssertNotCalled(t, "UpdatePoints", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,352 @@
+package service_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	modelMongo "student-performance-report/app/models/mongodb"
+	modelPg "student-performance-report/app/models/postgresql"
+	repoPg "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/workflow"
+)
+
+func TestAchievementWorkflowAllowed(t *testing.T) {
+	cases := []struct {
+		from, to string
+		role     workflow.Role
+		allowed  bool
+	}{
+		{"draft", "submitted", workflow.Owner, true},
+		{"submitted", "verified", workflow.Advisor, true},
+		{"submitted", "rejected", workflow.Advisor, true},
+		{"rejected", "revision", workflow.Owner, true},
+		{"rejected", "draft", workflow.Owner, true},
+		{"revision", "submitted", workflow.Owner, true},
+		{"verified", "revoked", workflow.Admin, true},
+		{"verified", "revoked", workflow.Advisor, true},
+
+		// Mahasiswa tidak bisa memverifikasi prestasinya sendiri.
+		{"submitted", "verified", workflow.Owner, false},
+		{"verified", "rejected", workflow.Advisor, false},
+		{"verified", "revoked", workflow.Owner, false},
+		{"rejected", "submitted", workflow.Owner, false},
+		{"submitted", "deleted", workflow.Owner, false},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.allowed, workflow.Allowed(tc.from, tc.to, tc.role), "%s -> %s by %s", tc.from, tc.to, tc.role)
+	}
+}
+
+func TestAchievementWorkflowHandlers(t *testing.T) {
+	t.Run("Error: Concurrent verify loses the race without writing points", func(t *testing.T) {
+		svc, mockMongo, mockPg, mockLecturer, mockStudent := setupAchievementServiceWithStudents()
+		lecturerUserID, lecturerID := uuid.New(), uuid.New()
+		achievementID, studentID := uuid.New(), uuid.New()
+		app := setupAchievementApp("dosen_wali", lecturerUserID)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, MongoAchievementID: "mongo_obj_id_123", Status: "submitted"}
+
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, lecturerUserID).Return(lecturerID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockStudent.On("GetStudentByID", mock.Anything, studentID).Return(&modelPg.Student{ID: studentID, AdvisorID: &lecturerID}, nil)
+		mockMongo.On("FindOne", mock.Anything, "mongo_obj_id_123").Return(&modelMongo.Achievement{}, nil)
+		// Permintaan lain sudah menolak prestasi ini lebih dulu.
+		mockPg.On("TransitionStatus", mock.Anything, achievementID, "submitted", "verified", mock.Anything).Return(repoPg.ErrStatusConflict)
+
+		app.Post("/achievements/:id/verify", svc.VerifyAchievement)
+
+		bodyBytes, _ := json.Marshal(map[string]int{"points": 100})
+		req := httptest.NewRequest("POST", "/achievements/"+achievementID.String()+"/verify", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 409, resp.StatusCode)
+		mockMongo.AssertNotCalled(t, "UpdatePoints", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success: Editing a rejected achievement reopens it as revision", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		userID, studentID, achievementID := uuid.New(), uuid.New(), uuid.New()
+		app := setupAchievementApp("mahasiswa", userID)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, MongoAchievementID: "mongo_obj_id_123", Status: "rejected"}
+
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockPg.On("TransitionStatus", mock.Anything, achievementID, "rejected", "revision", modelPg.StatusChange{ActorID: userID}).Return(nil)
+		mockMongo.On("UpdateOne", mock.Anything, "mongo_obj_id_123", mock.Anything).Return(nil)
+
+		app.Put("/achievements/:id", svc.UpdateAchievement)
+
+		bodyBytes, _ := json.Marshal(validCompetition("Lomba Coding (sertifikat diperbaiki)"))
+		req := httptest.NewRequest("PUT", "/achievements/"+achievementID.String(), bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 200, resp.StatusCode)
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		assert.Equal(t, "revision", body["status"])
+		mockPg.AssertExpectations(t)
+	})
+
+	t.Run("Error: Failed edit leaves a rejected achievement rejected", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		userID, studentID, achievementID := uuid.New(), uuid.New(), uuid.New()
+		app := setupAchievementApp("mahasiswa", userID)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, MongoAchievementID: "mongo_obj_id_123", Status: "rejected"}
+
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockMongo.On("UpdateOne", mock.Anything, "mongo_obj_id_123", mock.Anything).Return(errors.New("mongo down"))
+
+		app.Put("/achievements/:id", svc.UpdateAchievement)
+
+		bodyBytes, _ := json.Marshal(validCompetition("Lomba Coding (sertifikat diperbaiki)"))
+		req := httptest.NewRequest("PUT", "/achievements/"+achievementID.String(), bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		// Status dan riwayat baru berubah setelah perubahan tersimpan.
+		assert.Equal(t, 500, resp.StatusCode)
+		mockPg.AssertNotCalled(t, "TransitionStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success: Revision can be submitted again", func(t *testing.T) {
+		svc, _, mockPg, _ := setupAchievementServiceTest()
+		userID, studentID, achievementID := uuid.New(), uuid.New(), uuid.New()
+		app := setupAchievementApp("mahasiswa", userID)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, Status: "revision"}
+
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockPg.On("TransitionStatus", mock.Anything, achievementID, "revision", "submitted", modelPg.StatusChange{ActorID: userID}).Return(nil)
+
+		app.Post("/achievements/:id/submit", svc.SubmitAchievement)
+
+		req := httptest.NewRequest("POST", "/achievements/"+achievementID.String()+"/submit", nil)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 200, resp.StatusCode)
+		mockPg.AssertExpectations(t)
+	})
+
+	t.Run("Error: Verified achievement cannot be edited", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		userID, studentID, achievementID := uuid.New(), uuid.New(), uuid.New()
+		app := setupAchievementApp("mahasiswa", userID)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, Status: "verified"}
+
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+
+		app.Put("/achievements/:id", svc.UpdateAchievement)
+
+		bodyBytes, _ := json.Marshal(validCompetition("Ganti judul"))
+		req := httptest.NewRequest("PUT", "/achievements/"+achievementID.String(), bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 400, resp.StatusCode)
+		mockMongo.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success: Rejected achievement is reopened as draft", func(t *testing.T) {
+		svc, _, mockPg, _ := setupAchievementServiceTest()
+		userID, studentID, achievementID := uuid.New(), uuid.New(), uuid.New()
+		app := setupAchievementApp("mahasiswa", userID)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, Status: "rejected"}
+
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockPg.On("TransitionStatus", mock.Anything, achievementID, "rejected", "draft", modelPg.StatusChange{ActorID: userID}).Return(nil)
+
+		app.Post("/achievements/:id/reopen", svc.ReopenAchievement)
+
+		req := httptest.NewRequest("POST", "/achievements/"+achievementID.String()+"/reopen", nil)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 200, resp.StatusCode)
+		mockPg.AssertExpectations(t)
+	})
+}
+
+func TestRevokeAchievement(t *testing.T) {
+	t.Run("Success: Admin revokes, points drop to 0 in both stores", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		adminUserID, achievementID := uuid.New(), uuid.New()
+		app := setupApp("admin", adminUserID)
+
+		oldPoints, newPoints := 80, 0
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: uuid.New(), MongoAchievementID: "mongo_obj_id_123", Status: "verified", Points: oldPoints}
+
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockPg.On("TransitionStatus", mock.Anything, achievementID, "verified", "revoked", mock.MatchedBy(func(ch modelPg.StatusChange) bool {
+			return ch.ActorID == adminUserID && ch.Note == "Sertifikat palsu" && *ch.Points == newPoints && *ch.OldPoints == oldPoints &&
+				len(ch.Outbox) == 1 && ch.Outbox[0].Kind == modelPg.OutboxMarkRevoked
+		})).Return(nil)
+		mockMongo.On("MarkRevoked", mock.Anything, "mongo_obj_id_123", mock.Anything).Return(nil)
+
+		app.Post("/achievements/:id/revoke", svc.RevokeAchievement)
+
+		status, body := sendJSON(app, "POST", "/achievements/"+achievementID.String()+"/revoke", map[string]string{"reason": "Sertifikat palsu"})
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(80), body["oldPoints"])
+		mockPg.AssertExpectations(t)
+		mockMongo.AssertExpectations(t)
+		// Poin lama diambil dari Postgres, bukan dari dokumen Mongo.
+		mockMongo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success: Mongo failure leaves the revoke to the outbox", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		adminUserID, achievementID := uuid.New(), uuid.New()
+		app := setupApp("admin", adminUserID)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: uuid.New(), MongoAchievementID: "mongo_obj_id_123", Status: "verified", Points: 80}
+
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockPg.On("TransitionStatus", mock.Anything, achievementID, "verified", "revoked", mock.Anything).Return(nil)
+		mockMongo.On("MarkRevoked", mock.Anything, "mongo_obj_id_123", mock.Anything).Return(assert.AnError)
+
+		app.Post("/achievements/:id/revoke", svc.RevokeAchievement)
+
+		status, _ := sendJSON(app, "POST", "/achievements/"+achievementID.String()+"/revoke", map[string]string{"reason": "Sertifikat palsu"})
+
+		// Pencabutan sudah tercatat di Postgres; penandaan di Mongo diulang oleh relay.
+		assert.Equal(t, 200, status)
+		mockPg.AssertExpectations(t)
+		mockPg.AssertNotCalled(t, "TransitionStatus", mock.Anything, achievementID, "revoked", "verified", mock.Anything)
+	})
+
+	t.Run("Error: Reason is required", func(t *testing.T) {
+		svc, _, mockPg, _ := setupAchievementServiceTest()
+		app := setupApp("admin", uuid.New())
+		app.Post("/achievements/:id/revoke", svc.RevokeAchievement)
+
+		status, _ := sendJSON(app, "POST", "/achievements/"+uuid.NewString()+"/revoke", map[string]string{})
+
+		assert.Equal(t, 400, status)
+		mockPg.AssertNotCalled(t, "GetReferenceByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Student cannot revoke", func(t *testing.T) {
+		svc, mockMongo, mockPg, mockLecturer := setupAchievementServiceTest()
+		userID, achievementID := uuid.New(), uuid.New()
+		app := setupAchievementApp("mahasiswa", userID)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: uuid.New(), Status: "verified"}
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, userID).Return(uuid.Nil, errors.New("not found"))
+
+		app.Post("/achievements/:id/revoke", svc.RevokeAchievement)
+
+		status, _ := sendJSON(app, "POST", "/achievements/"+achievementID.String()+"/revoke", map[string]string{"reason": "iseng"})
+
+		assert.Equal(t, 403, status)
+		mockMongo.AssertNotCalled(t, "MarkRevoked", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAdjustAchievementPoints(t *testing.T) {
+	t.Run("Success: Advisor corrects points, old and new value are recorded", func(t *testing.T) {
+		svc, mockMongo, mockPg, mockLecturer, mockStudent := setupAchievementServiceWithStudents()
+		lecturerUserID, lecturerID := uuid.New(), uuid.New()
+		achievementID, studentID := uuid.New(), uuid.New()
+		app := setupAchievementApp("dosen_wali", lecturerUserID)
+
+		oldPoints, newPoints := 100, 60
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, MongoAchievementID: "mongo_obj_id_123", Status: "verified", Points: oldPoints}
+
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, lecturerUserID).Return(lecturerID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockStudent.On("GetStudentByID", mock.Anything, studentID).Return(&modelPg.Student{ID: studentID, AdvisorID: &lecturerID}, nil)
+		mockPg.On("AdjustPoints", mock.Anything, achievementID, "verified", mock.MatchedBy(func(ch modelPg.StatusChange) bool {
+			return ch.ActorID == lecturerUserID && ch.Note == "Tingkat lomba regional, bukan nasional" && *ch.Points == newPoints && *ch.OldPoints == oldPoints &&
+				len(ch.Outbox) == 1 && ch.Outbox[0].Kind == modelPg.OutboxSetPoints
+		})).Return(nil)
+		mockMongo.On("UpdatePoints", mock.Anything, "mongo_obj_id_123", newPoints).Return(nil)
+
+		app.Post("/achievements/:id/adjust-points", svc.AdjustAchievementPoints)
+
+		status, body := sendJSON(app, "POST", "/achievements/"+achievementID.String()+"/adjust-points", map[string]interface{}{
+			"points": 60, "reason": "Tingkat lomba regional, bukan nasional",
+		})
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(100), body["oldPoints"])
+		assert.Equal(t, float64(60), body["newPoints"])
+		mockPg.AssertExpectations(t)
+		mockMongo.AssertExpectations(t)
+		mockMongo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success: Mongo failure leaves the new points to the outbox", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		adminUserID, achievementID := uuid.New(), uuid.New()
+		app := setupApp("admin", adminUserID)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: uuid.New(), MongoAchievementID: "mongo_obj_id_123", Status: "verified", Points: 100}
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockPg.On("AdjustPoints", mock.Anything, achievementID, "verified", mock.Anything).Return(nil).Once()
+		mockMongo.On("UpdatePoints", mock.Anything, "mongo_obj_id_123", 60).Return(assert.AnError)
+
+		app.Post("/achievements/:id/adjust-points", svc.AdjustAchievementPoints)
+
+		status, _ := sendJSON(app, "POST", "/achievements/"+achievementID.String()+"/adjust-points", map[string]interface{}{
+			"points": 60, "reason": "koreksi",
+		})
+
+		// Tidak ada koreksi balik; relay yang mengulang penulisan poin.
+		assert.Equal(t, 200, status)
+		mockPg.AssertNumberOfCalls(t, "AdjustPoints", 1)
+	})
+
+	t.Run("Error: Points equal to the value in Postgres are unchanged", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		achievementID := uuid.New()
+		app := setupApp("admin", uuid.New())
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: uuid.New(), MongoAchievementID: "mongo_obj_id_123", Status: "verified", Points: 60}
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+
+		app.Post("/achievements/:id/adjust-points", svc.AdjustAchievementPoints)
+
+		status, _ := sendJSON(app, "POST", "/achievements/"+achievementID.String()+"/adjust-points", map[string]interface{}{
+			"points": 60, "reason": "koreksi",
+		})
+
+		assert.Equal(t, 400, status)
+		mockPg.AssertNotCalled(t, "AdjustPoints", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockMongo.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Only verified achievements can be adjusted", func(t *testing.T) {
+		svc, mockMongo, mockPg, _ := setupAchievementServiceTest()
+		achievementID := uuid.New()
+		app := setupApp("admin", uuid.New())
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: uuid.New(), MongoAchievementID: "mongo_obj_id_123", Status: "revoked", Points: 0}
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+
+		app.Post("/achievements/:id/adjust-points", svc.AdjustAchievementPoints)
+
+		status, _ := sendJSON(app, "POST", "/achievements/"+achievementID.String()+"/adjust-points", map[string]interface{}{
+			"points": 50, "reason": "koreksi",
+		})
+
+		assert.Equal(t, 400, status)
+		mockMongo.AssertNotCalled(t, "UpdatePoints", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -3,7 +3,10 @@ package service_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -11,15 +14,24 @@ import (
 	"github.com/stretchr/testify/mock"
 	models "student-performance-report/app/models/postgresql"
 	"student-performance-report/app/repository/mocks"
+	repo "student-performance-report/app/repository/postgresql"
 	"student-performance-report/app/service/postgresql"
+	"student-performance-report/middleware"
+	"student-performance-report/utils"
 )
 
 func setupAdminTest() (*service.AdminService, *mocks.MockAdminRepo, *mocks.MockUserRepo) {
+	svc, mockAdminRepo, mockUserRepo, _ := setupAdminTestWithRoles()
+	return svc, mockAdminRepo, mockUserRepo
+}
+
+func setupAdminTestWithRoles() (*service.AdminService, *mocks.MockAdminRepo, *mocks.MockUserRepo, *mocks.MockRoleRepo) {
 	mockAdminRepo := new(mocks.MockAdminRepo)
 	mockUserRepo := new(mocks.MockUserRepo)
-	svc := service.NewAdminService(mockAdminRepo, mockUserRepo)
+	mockRoleRepo := new(mocks.MockRoleRepo)
+	svc := service.NewAdminService(mockAdminRepo, mockUserRepo, mockRoleRepo)
 
-	return svc, mockAdminRepo, mockUserRepo
+	return svc, mockAdminRepo, mockUserRepo, mockRoleRepo
 }
 
 func setupApp(roleName string, userID uuid.UUID) *fiber.App {
@@ -29,6 +41,9 @@ func setupApp(roleName string, userID uuid.UUID) *fiber.App {
 	app.Use(func(c *fiber.Ctx) error {
 		c.Locals("role_name", roleName)
 		c.Locals("user_id", userID)
+		if roleName == "admin" {
+			c.Locals("permissions", []string{"manage:users"})
+		}
 		return c.Next()
 	})
 
@@ -59,7 +74,8 @@ func TestGetAllUsers(t *testing.T) {
 		svc, mockRepo, _ := setupAdminTest()
 		app := setupApp("student", uuid.New())
 
-		app.Get("/users", svc.GetAllUsers)
+		// Route-level policy, as declared in route.SetupPostgresRoutes
+		app.Get("/users", middleware.PermissionRequired("manage:users"), svc.GetAllUsers)
 
 		req := httptest.NewRequest("GET", "/users", nil)
 		resp, _ := app.Test(req)
@@ -70,31 +86,121 @@ func TestGetAllUsers(t *testing.T) {
 }
 
 func TestCreateUser(t *testing.T) {
-	t.Run("Success: Admin creates user", func(t *testing.T) {
-		svc, mockRepo, _ := setupAdminTest()
-		app := setupApp("admin", uuid.New())
+	studentRoleID := uuid.New()
+	studentPerms := []models.Permission{{Name: "achievement:create"}, {Name: "achievement:read"}}
 
-		inputPayload := models.User{
-			Username:     "new_admin",
-			PasswordHash: "raw_password",
-			Email:        "admin@test.com",
+	validPayload := func() models.CreateUserRequest {
+		return models.CreateUserRequest{
+			Username: "budi.s",
+			Email:    "budi@test.com",
+			Password: "Rahasia123",
+			FullName: "Budi Santoso",
+			RoleID:   studentRoleID.String(),
+			StudentProfile: &models.StudentProfileRequest{
+				StudentID:    "NIM001",
+				ProgramStudy: "Informatika",
+				AcademicYear: "2024",
+			},
 		}
+	}
 
-		mockRepo.On("CreateUser", mock.MatchedBy(func(u *models.User) bool {
-			return u.Username == "new_admin" && u.PasswordHash != "raw_password"
-		})).Return(nil)
-
+	postCreate := func(app *fiber.App, svc *service.AdminService, payload models.CreateUserRequest) (int, string) {
 		app.Post("/users", svc.CreateUser)
-
-		body, _ := json.Marshal(inputPayload)
+		body, _ := json.Marshal(payload)
 		req := httptest.NewRequest("POST", "/users", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
-
 		resp, _ := app.Test(req)
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(respBody)
+	}
 
-		assert.Equal(t, 200, resp.StatusCode)
+	t.Run("Success: Admin creates student with profile", func(t *testing.T) {
+		svc, mockRepo, _, mockRoleRepo := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+
+		mockRoleRepo.On("GetRoleByID", studentRoleID).Return(&models.Roles{ID: studentRoleID, Name: "mahasiswa"}, nil)
+		mockRoleRepo.On("GetPermissionsByRole", studentRoleID).Return(studentPerms, nil)
+		mockRepo.On("FindTakenCredentials", "budi.s", "budi@test.com").Return(false, false, nil)
+		mockRepo.On("CreateUserWithProfile",
+			mock.MatchedBy(func(u *models.User) bool {
+				return u.Username == "budi.s" && utils.CheckPasswordHash("Rahasia123", u.PasswordHash) && u.IsActive
+			}),
+			mock.MatchedBy(func(st *models.Student) bool {
+				return st != nil && st.StudentID == "NIM001"
+			}),
+			(*models.Lecturer)(nil),
+		).Return(nil)
+
+		status, body := postCreate(app, svc, validPayload())
+
+		assert.Equal(t, 201, status)
+		assert.NotContains(t, body, "Rahasia123")
+		assert.NotContains(t, strings.ToLower(body), "password")
+		assert.Contains(t, body, `"roleName":"mahasiswa"`)
+		assert.Contains(t, body, `"studentId":"NIM001"`)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("Error: Weak password and missing profile", func(t *testing.T) {
+		svc, mockRepo, _, mockRoleRepo := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+
+		mockRoleRepo.On("GetRoleByID", studentRoleID).Return(&models.Roles{ID: studentRoleID, Name: "mahasiswa"}, nil)
+		mockRoleRepo.On("GetPermissionsByRole", studentRoleID).Return(studentPerms, nil)
+
+		payload := validPayload()
+		payload.Password = "short"
+		payload.StudentProfile = nil
+
+		status, body := postCreate(app, svc, payload)
+
+		assert.Equal(t, 400, status)
+		assert.Contains(t, body, `"password"`)
+		assert.Contains(t, body, `"studentProfile"`)
+		mockRepo.AssertNotCalled(t, "CreateUserWithProfile", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Unknown role", func(t *testing.T) {
+		svc, mockRepo, _, mockRoleRepo := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+
+		mockRoleRepo.On("GetRoleByID", studentRoleID).Return(nil, repo.ErrRoleNotFound)
+
+		status, body := postCreate(app, svc, validPayload())
+
+		assert.Equal(t, 400, status)
+		assert.Contains(t, body, `"roleId"`)
+		mockRepo.AssertNotCalled(t, "CreateUserWithProfile", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Role lookup fails", func(t *testing.T) {
+		svc, mockRepo, _, mockRoleRepo := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+
+		mockRoleRepo.On("GetRoleByID", studentRoleID).Return(nil, errors.New("connection refused"))
+
+		status, body := postCreate(app, svc, validPayload())
+
+		// Gangguan database bukan kesalahan input.
+		assert.Equal(t, 500, status)
+		assert.NotContains(t, body, `"roleId"`)
+		mockRepo.AssertNotCalled(t, "CreateUserWithProfile", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Username already taken", func(t *testing.T) {
+		svc, mockRepo, _, mockRoleRepo := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+
+		mockRoleRepo.On("GetRoleByID", studentRoleID).Return(&models.Roles{ID: studentRoleID, Name: "mahasiswa"}, nil)
+		mockRoleRepo.On("GetPermissionsByRole", studentRoleID).Return(studentPerms, nil)
+		mockRepo.On("FindTakenCredentials", "budi.s", "budi@test.com").Return(true, false, nil)
+
+		status, body := postCreate(app, svc, validPayload())
+
+		assert.Equal(t, 409, status)
+		assert.Contains(t, body, `"username"`)
+		mockRepo.AssertNotCalled(t, "CreateUserWithProfile", mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
 func TestGetUserByID(t *testing.T) {
@@ -129,4 +235,131 @@ func TestGetUserByID(t *testing.T) {
 		assert.Equal(t, 403, resp.StatusCode)
 		mockRepo.AssertNotCalled(t, "GetUserByID")
 	})
-}
\ No newline at end of file
+}
+func TestUpdateUser(t *testing.T) {
+	t.Run("Success: Partial update keeps omitted fields", func(t *testing.T) {
+		svc, mockRepo, _, mockRoleRepo := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+		app.Put("/users/:id", svc.UpdateUser)
+
+		targetID := uuid.New()
+		roleID := uuid.New()
+		existing := &models.User{ID: targetID, Username: "budi", Email: "budi@test.com", FullName: "Budi", RoleID: roleID, IsActive: true}
+
+		mockRepo.On("GetUserByID", targetID).Return(existing, nil)
+		mockRepo.On("UpdateUser", mock.MatchedBy(func(u *models.User) bool {
+			return u.FullName == "Budi Santoso" && u.Username == "budi" && u.RoleID == roleID && u.IsActive
+		})).Return(nil)
+		mockRoleRepo.On("GetRoleByID", roleID).Return(&models.Roles{ID: roleID, Name: "mahasiswa"}, nil)
+
+		body, _ := json.Marshal(map[string]interface{}{"full_name": "Budi Santoso"})
+		req := httptest.NewRequest("PUT", "/users/"+targetID.String(), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 200, resp.StatusCode)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "FindTakenCredentials", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Password cannot be set through update", func(t *testing.T) {
+		svc, mockRepo, _ := setupAdminTest()
+		app := setupApp("admin", uuid.New())
+		app.Put("/users/:id", svc.UpdateUser)
+
+		targetID := uuid.New()
+		body, _ := json.Marshal(map[string]interface{}{"password_hash": "x"})
+		req := httptest.NewRequest("PUT", "/users/"+targetID.String(), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 400, resp.StatusCode)
+		mockRepo.AssertNotCalled(t, "UpdateUser", mock.Anything)
+	})
+
+	t.Run("Error: New email already taken", func(t *testing.T) {
+		svc, mockRepo, _ := setupAdminTest()
+		app := setupApp("admin", uuid.New())
+		app.Put("/users/:id", svc.UpdateUser)
+
+		targetID := uuid.New()
+		mockRepo.On("GetUserByID", targetID).Return(&models.User{ID: targetID, Username: "budi", Email: "budi@test.com", IsActive: true}, nil)
+		mockRepo.On("FindTakenCredentials", "", "sari@test.com").Return(false, true, nil)
+
+		body, _ := json.Marshal(map[string]interface{}{"email": "sari@test.com"})
+		req := httptest.NewRequest("PUT", "/users/"+targetID.String(), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 409, resp.StatusCode)
+		mockRepo.AssertNotCalled(t, "UpdateUser", mock.Anything)
+	})
+}
+
+func TestLastAdminGuard(t *testing.T) {
+	adminRoleID := uuid.New()
+	studentRoleID := uuid.New()
+	adminPerms := []models.Permission{{Name: "manage:users"}}
+	studentPerms := []models.Permission{{Name: "achievement:create"}}
+
+	// Admin terakhir: tidak ada role lain maupun user aktif lain yang memegang manage:users
+	setupLastAdmin := func() (*service.AdminService, *mocks.MockAdminRepo, *mocks.MockRoleRepo, uuid.UUID) {
+		svc, mockRepo, _, mockRoleRepo := setupAdminTestWithRoles()
+		targetID := uuid.New()
+		mockRepo.On("GetUserByID", targetID).Return(&models.User{ID: targetID, Username: "admin", RoleID: adminRoleID, IsActive: true}, nil)
+		mockRoleRepo.On("GetRoleByID", studentRoleID).Return(&models.Roles{ID: studentRoleID, Name: "mahasiswa"}, nil)
+		mockRoleRepo.On("GetPermissionsByRole", adminRoleID).Return(adminPerms, nil)
+		mockRoleRepo.On("GetPermissionsByRole", studentRoleID).Return(studentPerms, nil)
+		mockRoleRepo.On("CountAssignedRolesWithPermission", "manage:users", adminRoleID).Return(0, nil)
+		return svc, mockRepo, mockRoleRepo, targetID
+	}
+
+	t.Run("Error: Last admin cannot be moved to another role", func(t *testing.T) {
+		svc, mockRepo, mockRoleRepo, targetID := setupLastAdmin()
+		mockRoleRepo.On("CountActiveUsersByRole", adminRoleID).Return(1, nil)
+		app := setupApp("admin", uuid.New())
+		app.Put("/users/:id/role", svc.AssignRole)
+
+		status, _ := sendJSON(app, "PUT", "/users/"+targetID.String()+"/role", map[string]string{"roleId": studentRoleID.String()})
+
+		assert.Equal(t, 409, status)
+		mockRepo.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Last admin cannot be deactivated", func(t *testing.T) {
+		svc, mockRepo, mockRoleRepo, targetID := setupLastAdmin()
+		mockRoleRepo.On("CountActiveUsersByRole", adminRoleID).Return(1, nil)
+		app := setupApp("admin", uuid.New())
+		app.Put("/users/:id", svc.UpdateUser)
+
+		status, _ := sendJSON(app, "PUT", "/users/"+targetID.String(), map[string]interface{}{"is_active": false})
+
+		assert.Equal(t, 409, status)
+		mockRepo.AssertNotCalled(t, "UpdateUser", mock.Anything)
+	})
+
+	t.Run("Error: Last admin cannot be deleted", func(t *testing.T) {
+		svc, mockRepo, mockRoleRepo, targetID := setupLastAdmin()
+		mockRoleRepo.On("CountActiveUsersByRole", adminRoleID).Return(1, nil)
+		app := setupApp("admin", uuid.New())
+		app.Delete("/users/:id", svc.DeleteUser)
+
+		status, _ := sendJSON(app, "DELETE", "/users/"+targetID.String(), nil)
+
+		assert.Equal(t, 409, status)
+		mockRepo.AssertNotCalled(t, "DeleteUser", mock.Anything)
+	})
+
+	t.Run("Success: Admin deleted while another admin remains active", func(t *testing.T) {
+		svc, mockRepo, mockRoleRepo, targetID := setupLastAdmin()
+		mockRoleRepo.On("CountActiveUsersByRole", adminRoleID).Return(2, nil)
+		mockRepo.On("DeleteUser", targetID).Return(nil)
+		app := setupApp("admin", uuid.New())
+		app.Delete("/users/:id", svc.DeleteUser)
+
+		status, _ := sendJSON(app, "DELETE", "/users/"+targetID.String(), nil)
+
+		assert.Equal(t, 200, status)
+		mockRepo.AssertExpectations(t)
+	})
+}
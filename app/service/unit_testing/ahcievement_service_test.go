@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -15,18 +16,56 @@ import (
 	modelPg "student-performance-report/app/models/postgresql"
 	"student-performance-report/app/repository/mocks"
 	"student-performance-report/app/service/mongodb"
+	"student-performance-report/app/service/outbox"
+	"student-performance-report/app/service/policy"
+	"student-performance-report/app/service/trash"
 )
 
 // --- SETUP HELPERS ---
 
 func setupAchievementServiceTest() (*service.AchievementService, *mocks.MockAchievementMongoRepo, *mocks.MockAchievementPgRepo, *mocks.MockLecturerRepo) {
+	svc, mockMongo, mockPg, mockLecturer, _ := setupAchievementServiceWithStudents()
+	return svc, mockMongo, mockPg, mockLecturer
+}
+
+// setupAchievementServiceWithStudents juga mengembalikan student repo yang
+// dipakai access policy untuk mengecek relasi dosen wali.
+func setupAchievementServiceWithStudents() (*service.AchievementService, *mocks.MockAchievementMongoRepo, *mocks.MockAchievementPgRepo, *mocks.MockLecturerRepo, *mocks.MockStudentRepo) {
+	return setupAchievementServiceWithRubric(nil)
+}
+
+// setupAchievementServiceWithRubric memakai rubric sebagai versi aktif (nil:
+// tidak ada rubric aktif).
+func setupAchievementServiceWithRubric(rubric *modelPg.ScoringRubric) (*service.AchievementService, *mocks.MockAchievementMongoRepo, *mocks.MockAchievementPgRepo, *mocks.MockLecturerRepo, *mocks.MockStudentRepo) {
 	mockMongo := new(mocks.MockAchievementMongoRepo)
 	mockPg := new(mocks.MockAchievementPgRepo)
 	mockLecturer := new(mocks.MockLecturerRepo)
+	mockStudent := new(mocks.MockStudentRepo)
+	mockRubric := new(mocks.MockRubricRepo)
 
-	svc := service.NewAchievementService(mockMongo, mockPg, mockLecturer)
+	if rubric != nil {
+		mockRubric.On("GetActive", mock.Anything).Return(rubric, nil)
+	} else {
+		mockRubric.On("GetActive", mock.Anything).Return(nil, nil)
+	}
 
-	return svc, mockMongo, mockPg, mockLecturer
+	access := policy.NewAccessPolicy(mockPg, mockLecturer, mockStudent)
+	purger := trash.NewPurger(mockPg, mockMongo, testTrashRetention, "./uploads")
+	svc := service.NewAchievementService(mockMongo, mockPg, mockLecturer, access, mockRubric, newTestRelay(mockMongo, new(mocks.MockOutboxRepo)), purger)
+
+	return svc, mockMongo, mockPg, mockLecturer, mockStudent
+}
+
+// testTrashRetention adalah lama prestasi yang dihapus masih bisa dipulihkan.
+const testTrashRetention = 30 * 24 * time.Hour
+
+// newTestRelay mengirim event outbox ke mockMongo; tidak ada event lama yang
+// menghalangi dan status event di outbox selalu berhasil dicatat.
+func newTestRelay(mockMongo *mocks.MockAchievementMongoRepo, mockOutbox *mocks.MockOutboxRepo) *outbox.Relay {
+	mockOutbox.On("Blocked", mock.Anything, mock.Anything).Return(false, nil).Maybe()
+	mockOutbox.On("MarkDelivered", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockOutbox.On("MarkFailed", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	return outbox.NewRelay(mockOutbox, mockMongo, time.Second, time.Minute)
 }
 
 func setupAchievementApp(roleName string, userID uuid.UUID) *fiber.App {
@@ -39,6 +78,18 @@ func setupAchievementApp(roleName string, userID uuid.UUID) *fiber.App {
 	return app
 }
 
+// validCompetition adalah body lomba yang lolos registry tipe prestasi.
+func validCompetition(title string) modelMongo.Achievement {
+	return modelMongo.Achievement{
+		Title:           title,
+		AchievementType: "competition",
+		Details: modelMongo.AchievementDetails{
+			CompetitionName:  "Gemastik",
+			CompetitionLevel: "national",
+		},
+	}
+}
+
 // --- TEST CASES ---
 
 func TestCreateAchievement(t *testing.T) {
@@ -48,27 +99,30 @@ func TestCreateAchievement(t *testing.T) {
 		app := setupAchievementApp("mahasiswa", userID)
 
 		studentID := uuid.New()
-		mongoID := "mongo_obj_id_123"
 		newRefID := uuid.New()
 
-		reqBody := modelMongo.Achievement{
-			Title:       "Lomba Coding",
-			Description: "Juara 1",
-		}
+		reqBody := validCompetition("Lomba Coding")
+		reqBody.Description = "Juara 1"
 
 		// 1. Mock GetStudentByUserID (PG)
 		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
 
-		// 2. Mock InsertOne (Mongo) - Menyimpan detail
-		mockMongo.On("InsertOne", mock.Anything, mock.MatchedBy(func(a modelMongo.Achievement) bool {
-			return a.Title == "Lomba Coding" && a.StudentID == studentID.String()
-		})).Return(mongoID, nil)
-
-		// 3. Mock Create (PG) - Menyimpan referensi
+		// 2. Mock Create (PG) - Menyimpan referensi beserta event outbox
+		// yang menulis detail ke Mongo
+		var mongoID string
 		mockPg.On("Create", mock.Anything, mock.MatchedBy(func(r modelPg.AchievementReference) bool {
-			return r.StudentID == studentID && r.MongoAchievementID == mongoID && r.Status == "draft"
+			mongoID = r.MongoAchievementID
+			return r.StudentID == studentID && len(r.MongoAchievementID) == 24 && r.Status == "draft"
+		}), userID, mock.MatchedBy(func(events []modelPg.OutboxEvent) bool {
+			return len(events) == 1 && events[0].Kind == modelPg.OutboxCreateDetails
 		})).Return(newRefID, nil)
 
+		// 3. Mock InsertOne (Mongo) - Detail dikirim dengan ID yang sudah
+		// disimpan di referensi
+		mockMongo.On("InsertOne", mock.Anything, mock.MatchedBy(func(a modelMongo.Achievement) bool {
+			return a.Title == "Lomba Coding" && a.StudentID == studentID.String() && a.ID.Hex() == mongoID
+		})).Return(mongoID, nil)
+
 		app.Post("/achievements", svc.CreateAchievement)
 
 		bodyBytes, _ := json.Marshal(reqBody)
@@ -122,7 +176,7 @@ func TestSubmitAchievement(t *testing.T) {
 		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
 
 		// 3. Submit Action
-		mockPg.On("SubmitReference", mock.Anything, achievementID).Return(nil)
+		mockPg.On("TransitionStatus", mock.Anything, achievementID, "draft", "submitted", modelPg.StatusChange{ActorID: userID}).Return(nil)
 
 		app.Post("/achievements/:id/submit", svc.SubmitAchievement)
 
@@ -161,31 +215,140 @@ func TestSubmitAchievement(t *testing.T) {
 
 func TestVerifyAchievement(t *testing.T) {
 	t.Run("Success: Lecturer Verifies Achievement", func(t *testing.T) {
-		svc, _, mockPg, mockLecturer := setupAchievementServiceTest()
+		svc, mockMongo, mockPg, mockLecturer, mockStudent := setupAchievementServiceWithStudents()
 		lecturerUserID := uuid.New()
+		lecturerID := uuid.New()
 		achievementID := uuid.New()
+		studentID := uuid.New()
 		
 		app := setupAchievementApp("dosen_wali", lecturerUserID)
 
 		ref := modelPg.AchievementReference{
-			ID:     achievementID,
-			Status: "submitted",
+			ID:                 achievementID,
+			StudentID:          studentID,
+			MongoAchievementID: "mongo_obj_id_123",
+			Status:             "submitted",
 		}
 
 		// 1. Check is Lecturer
-		mockLecturer.On("GetLecturerByUserID", mock.Anything, lecturerUserID).Return(uuid.New(), nil)
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, lecturerUserID).Return(lecturerID, nil)
 
-		// 2. Get Reference
+		// 2. Get Reference & check advisor relationship
 		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockStudent.On("GetStudentByID", mock.Anything, studentID).Return(&modelPg.Student{ID: studentID, AdvisorID: &lecturerID}, nil)
 
-		// 3. Update Status
-		mockPg.On("UpdateStatus", mock.Anything, achievementID, "verified", &lecturerUserID, "").Return(nil)
+		// 3. Status (PG) bersama event outbox poin, lalu Update Points (Mongo)
+		mockMongo.On("FindOne", mock.Anything, "mongo_obj_id_123").Return(&modelMongo.Achievement{}, nil)
+		mockMongo.On("UpdatePoints", mock.Anything, "mongo_obj_id_123", 100).Return(nil)
+		mockPg.On("TransitionStatus", mock.Anything, achievementID, "submitted", "verified", mock.MatchedBy(func(ch modelPg.StatusChange) bool {
+			return ch.ActorID == lecturerUserID && *ch.VerifiedBy == lecturerID && *ch.Points == 100 &&
+				len(ch.Outbox) == 1 && ch.Outbox[0].Kind == modelPg.OutboxSetPoints
+		})).Return(nil)
 
 		app.Post("/achievements/:id/verify", svc.VerifyAchievement)
 
-		req := httptest.NewRequest("POST", "/achievements/"+achievementID.String()+"/verify", nil)
+		bodyBytes, _ := json.Marshal(map[string]int{"points": 100})
+		req := httptest.NewRequest("POST", "/achievements/"+achievementID.String()+"/verify", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
 		resp, _ := app.Test(req)
 
 		assert.Equal(t, 200, resp.StatusCode)
+		mockPg.AssertExpectations(t)
+		mockMongo.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+
+	t.Run("Error: Lecturer Is Not The Advisor", func(t *testing.T) {
+		svc, mockMongo, mockPg, mockLecturer, mockStudent := setupAchievementServiceWithStudents()
+		lecturerUserID := uuid.New()
+		lecturerID := uuid.New()
+		otherLecturerID := uuid.New()
+		achievementID := uuid.New()
+		studentID := uuid.New()
+
+		app := setupAchievementApp("dosen_wali", lecturerUserID)
+
+		ref := modelPg.AchievementReference{
+			ID:                 achievementID,
+			StudentID:          studentID,
+			MongoAchievementID: "mongo_obj_id_123",
+			Status:             "submitted",
+		}
+
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, lecturerUserID).Return(lecturerID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockStudent.On("GetStudentByID", mock.Anything, studentID).Return(&modelPg.Student{ID: studentID, AdvisorID: &otherLecturerID}, nil)
+
+		app.Post("/achievements/:id/verify", svc.VerifyAchievement)
+
+		bodyBytes, _ := json.Marshal(map[string]int{"points": 100})
+		req := httptest.NewRequest("POST", "/achievements/"+achievementID.String()+"/verify", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 403, resp.StatusCode)
+		mockMongo.AssertNotCalled(t, "UpdatePoints", mock.Anything, mock.Anything, mock.Anything)
+		mockPg.AssertNotCalled(t, "TransitionStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetAchievementHistory(t *testing.T) {
+	t.Run("Success: Every transition is served from the history table", func(t *testing.T) {
+		svc, _, mockPg, _ := setupAchievementServiceTest()
+		userID := uuid.New()
+		app := setupAchievementApp("mahasiswa", userID)
+
+		achievementID := uuid.New()
+		studentID := uuid.New()
+		lecturerUserID := uuid.New()
+
+		// Referensi sudah diverifikasi; kolom hanya menyimpan transisi terakhir.
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, Status: "verified"}
+
+		status := func(s string) *string { return &s }
+		note := "Sertifikat tidak terbaca"
+		history := []modelPg.AchievementStatusHistory{
+			{ToStatus: "draft", ActorID: &userID},
+			{FromStatus: status("draft"), ToStatus: "submitted", ActorID: &userID},
+			{FromStatus: status("submitted"), ToStatus: "rejected", ActorID: &lecturerUserID, Note: &note},
+			{FromStatus: status("rejected"), ToStatus: "submitted", ActorID: &userID},
+			{FromStatus: status("submitted"), ToStatus: "verified", ActorID: &lecturerUserID},
+		}
+
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockPg.On("GetStatusHistory", mock.Anything, achievementID).Return(history, nil)
+
+		app.Get("/achievements/:id/history", svc.GetAchievementHistory)
+
+		req := httptest.NewRequest("GET", "/achievements/"+achievementID.String()+"/history", nil)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 200, resp.StatusCode)
+		var body []modelPg.AchievementStatusHistory
+		json.NewDecoder(resp.Body).Decode(&body)
+		assert.Len(t, body, 5)
+		assert.Equal(t, "rejected", body[2].ToStatus)
+		assert.Equal(t, note, *body[2].Note)
+		assert.Nil(t, body[0].FromStatus)
+	})
+
+	t.Run("Error: Other student cannot read the history", func(t *testing.T) {
+		svc, _, mockPg, _ := setupAchievementServiceTest()
+		userID := uuid.New()
+		app := setupAchievementApp("mahasiswa", userID)
+
+		achievementID := uuid.New()
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: uuid.New(), Status: "draft"}
+
+		mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(uuid.New(), nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+
+		app.Get("/achievements/:id/history", svc.GetAchievementHistory)
+
+		req := httptest.NewRequest("GET", "/achievements/"+achievementID.String()+"/history", nil)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 403, resp.StatusCode)
+		mockPg.AssertNotCalled(t, "GetStatusHistory", mock.Anything, mock.Anything)
+	})
+}
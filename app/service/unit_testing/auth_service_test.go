@@ -6,23 +6,58 @@ import (
 	"errors"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"golang.org/x/crypto/bcrypt" 
 	models "student-performance-report/app/models/postgresql"
 	"student-performance-report/app/repository/mocks"
+	repo "student-performance-report/app/repository/postgresql"
 	"student-performance-report/app/service/postgresql"
+	"student-performance-report/utils"
 )
 
 // --- SETUP HELPERS ---
 
 func setupAuthServiceTest() (*service.AuthService, *mocks.MockUserRepo) {
-	mockUserRepo := new(mocks.MockUserRepo)
-	svc := service.NewAuthService(mockUserRepo)
+	svc, mockUserRepo, _ := setupAuthServiceWithSessions()
 	return svc, mockUserRepo
 }
 
+func setupAuthServiceWithSessions() (*service.AuthService, *mocks.MockUserRepo, *mocks.MockSessionRepo) {
+	mockUserRepo := new(mocks.MockUserRepo)
+	mockSessionRepo := new(mocks.MockSessionRepo)
+	svc := service.NewAuthService(mockUserRepo, mockSessionRepo, newTestAuthenticators(), newTestLoginThrottle(repo.NewMemoryLoginAttemptRepository()), newTestMFAService(nil, mockUserRepo, nil))
+	return svc, mockUserRepo, mockSessionRepo
+}
+
+// newTestAuthenticators: semua user memakai password lokal (bcrypt).
+func newTestAuthenticators() *service.Authenticators {
+	return service.NewAuthenticators(nil, nil, service.PasswordAuthenticator{})
+}
+
+// newTestMFAService: tanpa mfaRepo, semua user dianggap belum memakai 2FA.
+func newTestMFAService(mfaRepo *mocks.MockMFARepo, userRepo *mocks.MockUserRepo, passwordRepo *mocks.MockPasswordRepo, required ...string) *service.MFAService {
+	if mfaRepo == nil {
+		mfaRepo = new(mocks.MockMFARepo)
+		mfaRepo.On("Get", mock.Anything, mock.Anything).Return(nil, nil)
+	}
+	if passwordRepo == nil {
+		passwordRepo = new(mocks.MockPasswordRepo)
+	}
+	return service.NewMFAService(mfaRepo, userRepo, passwordRepo, "Test", 5*time.Minute, required)
+}
+
+// newTestLoginThrottle: 2 kegagalan gratis, kunci setelah 4 kegagalan per username.
+func newTestLoginThrottle(attempts repo.LoginAttemptRepository) *service.LoginThrottle {
+	return service.NewLoginThrottle(attempts,
+		service.LockoutPolicy{FreeFailures: 2, MaxFailures: 4, BackoffBase: time.Minute, BackoffMax: time.Minute, Lockout: time.Hour, Window: time.Hour},
+		service.LockoutPolicy{FreeFailures: 100, MaxFailures: 200, BackoffBase: time.Minute, BackoffMax: time.Minute, Lockout: time.Hour, Window: time.Hour},
+	)
+}
+
 func setupAuthApp() *fiber.App {
 	return fiber.New()
 }
@@ -31,7 +66,7 @@ func setupAuthApp() *fiber.App {
 
 func TestLogin(t *testing.T) {
 	t.Run("Success: Login with valid credentials", func(t *testing.T) {
-		svc, mockRepo := setupAuthServiceTest()
+		svc, mockRepo, mockSessions := setupAuthServiceWithSessions()
 		app := setupAuthApp()
 
 		// 1. Siapkan Password Hash yang VALID
@@ -54,6 +89,9 @@ func TestLogin(t *testing.T) {
 		// 2. Mock Expectations
 		mockRepo.On("GetByUsername", "admin").Return(mockUser, roleName, nil)
 		mockRepo.On("GetPermissionsByRoleID", roleID).Return(permissions, nil)
+		mockSessions.On("Create", mock.Anything, mock.MatchedBy(func(s *models.Session) bool {
+			return s.UserID == mockUser.ID && s.RefreshJTI != ""
+		})).Return(nil)
 
 		// 3. Execute
 		app.Post("/login", svc.Login)
@@ -78,6 +116,7 @@ func TestLogin(t *testing.T) {
 		assert.NotEmpty(t, response["refreshToken"])
 
 		mockRepo.AssertExpectations(t)
+		mockSessions.AssertExpectations(t)
 	})
 
 	t.Run("Error: Invalid Password", func(t *testing.T) {
@@ -218,10 +257,77 @@ func TestProfile(t *testing.T) {
 	})
 }
 
+func TestRefresh(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "student1", RoleID: uuid.New(), IsActive: true}
+	sessionID := uuid.New()
+
+	postRefresh := func(app *fiber.App, token string) int {
+		body, _ := json.Marshal(map[string]string{"refreshToken": token})
+		req := httptest.NewRequest("POST", "/refresh", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		return resp.StatusCode
+	}
+
+	t.Run("Success: Rotates refresh token", func(t *testing.T) {
+		svc, mockRepo, mockSessions := setupAuthServiceWithSessions()
+		app := setupAuthApp()
+		app.Post("/refresh", svc.Refresh)
+
+		token, _ := utils.GenerateRefreshToken(user, sessionID, "jti-1")
+		mockSessions.On("GetByID", mock.Anything, sessionID).Return(&models.Session{
+			ID: sessionID, UserID: user.ID, RefreshJTI: "jti-1", ExpiresAt: time.Now().Add(time.Hour),
+		}, nil)
+		mockSessions.On("Rotate", mock.Anything, sessionID, "jti-1", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockRepo.On("GetByID", user.ID).Return(user, nil)
+		mockRepo.On("GetByUsername", user.Username).Return(user, "student", nil)
+
+		assert.Equal(t, 200, postRefresh(app, token))
+		mockSessions.AssertExpectations(t)
+	})
+
+	t.Run("Error: Reused token revokes session", func(t *testing.T) {
+		svc, _, mockSessions := setupAuthServiceWithSessions()
+		app := setupAuthApp()
+		app.Post("/refresh", svc.Refresh)
+
+		oldToken, _ := utils.GenerateRefreshToken(user, sessionID, "jti-old")
+		mockSessions.On("GetByID", mock.Anything, sessionID).Return(&models.Session{
+			ID: sessionID, UserID: user.ID, RefreshJTI: "jti-new", ExpiresAt: time.Now().Add(time.Hour),
+		}, nil)
+		mockSessions.On("Revoke", mock.Anything, sessionID).Return(nil)
+
+		assert.Equal(t, 401, postRefresh(app, oldToken))
+		mockSessions.AssertCalled(t, "Revoke", mock.Anything, sessionID)
+		mockSessions.AssertNotCalled(t, "Rotate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Revoked session", func(t *testing.T) {
+		svc, _, mockSessions := setupAuthServiceWithSessions()
+		app := setupAuthApp()
+		app.Post("/refresh", svc.Refresh)
+
+		revokedAt := time.Now()
+		token, _ := utils.GenerateRefreshToken(user, sessionID, "jti-1")
+		mockSessions.On("GetByID", mock.Anything, sessionID).Return(&models.Session{
+			ID: sessionID, UserID: user.ID, RefreshJTI: "jti-1", ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt,
+		}, nil)
+
+		assert.Equal(t, 401, postRefresh(app, token))
+	})
+}
+
 func TestLogout(t *testing.T) {
-	t.Run("Success: Logout", func(t *testing.T) {
-		svc, _ := setupAuthServiceTest()
+	t.Run("Success: Logout revokes current session", func(t *testing.T) {
+		svc, _, mockSessions := setupAuthServiceWithSessions()
 		app := setupAuthApp()
+		sessionID := uuid.New()
+
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals("session_id", sessionID)
+			return c.Next()
+		})
+		mockSessions.On("Revoke", mock.Anything, sessionID).Return(nil)
 
 		app.Post("/logout", svc.Logout)
 
@@ -229,5 +335,6 @@ func TestLogout(t *testing.T) {
 		resp, _ := app.Test(req)
 
 		assert.Equal(t, 200, resp.StatusCode)
+		mockSessions.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,157 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	models "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/postgresql"
+	"student-performance-report/utils"
+)
+
+// --- SETUP HELPERS ---
+
+// fakeDirectory meniru server LDAP: satu password per username dan daftar grupnya.
+type fakeDirectory struct {
+	passwords map[string]string
+	groups    map[string][]string
+	err       error
+	calls     int
+}
+
+func (d *fakeDirectory) Authenticate(ctx context.Context, username, password string) ([]string, error) {
+	d.calls++
+	if d.err != nil {
+		return nil, d.err
+	}
+	if p, ok := d.passwords[username]; !ok || p != password {
+		return nil, utils.ErrLDAPInvalidCredentials
+	}
+	return d.groups[username], nil
+}
+
+func setupAuthenticators(dir *fakeDirectory) (*service.Authenticators, *mocks.MockRoleRepo, *mocks.MockAdminRepo) {
+	mockRoleRepo := new(mocks.MockRoleRepo)
+	mockAdminRepo := new(mocks.MockAdminRepo)
+	ldap := service.NewLDAPAuthenticator(dir, []service.GroupRole{
+		{Group: "tata-usaha", Role: "Admin"},
+		{Group: "dosen", Role: "Dosen Wali"},
+	})
+	authn := service.NewAuthenticators(mockRoleRepo, mockAdminRepo, service.PasswordAuthenticator{},
+		service.AuthenticatorRule{Roles: []string{"Dosen Wali"}, Authenticator: ldap},
+		service.AuthenticatorRule{UsernamePattern: regexp.MustCompile(`^ext\.`), Authenticator: ldap},
+	)
+	return authn, mockRoleRepo, mockAdminRepo
+}
+
+// --- TEST CASES ---
+
+func TestAuthenticators(t *testing.T) {
+	localHash, _ := utils.HashPassword("LocalPass1")
+
+	t.Run("Success: Student uses the local password", func(t *testing.T) {
+		dir := &fakeDirectory{}
+		authn, _, _ := setupAuthenticators(dir)
+		user := &models.User{ID: uuid.New(), Username: "budi", PasswordHash: localHash}
+
+		role, err := authn.Authenticate(context.Background(), user, "Mahasiswa", "LocalPass1")
+
+		require.NoError(t, err)
+		assert.Equal(t, "Mahasiswa", role)
+		assert.Zero(t, dir.calls)
+	})
+
+	t.Run("Success: Lecturer binds to LDAP, local password is ignored", func(t *testing.T) {
+		dir := &fakeDirectory{passwords: map[string]string{"siti": "LdapPass1"}, groups: map[string][]string{"siti": {"DOSEN"}}}
+		authn, _, mockAdminRepo := setupAuthenticators(dir)
+		user := &models.User{ID: uuid.New(), Username: "siti", PasswordHash: localHash}
+
+		role, err := authn.Authenticate(context.Background(), user, "Dosen Wali", "LdapPass1")
+		require.NoError(t, err)
+		assert.Equal(t, "Dosen Wali", role)
+		mockAdminRepo.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything)
+
+		_, err = authn.Authenticate(context.Background(), user, "Dosen Wali", "LocalPass1")
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	})
+
+	t.Run("Success: Group mapping syncs role_id", func(t *testing.T) {
+		dir := &fakeDirectory{passwords: map[string]string{"ext.andi": "LdapPass1"}, groups: map[string][]string{"ext.andi": {"dosen", "tata-usaha"}}}
+		authn, mockRoleRepo, mockAdminRepo := setupAuthenticators(dir)
+		adminRoleID := uuid.New()
+		user := &models.User{ID: uuid.New(), Username: "ext.andi", RoleID: uuid.New(), TokenVersion: 3}
+
+		mockRoleRepo.On("GetAllRoles").Return([]models.Roles{{ID: uuid.New(), Name: "Dosen Wali"}, {ID: adminRoleID, Name: "Admin"}}, nil)
+		mockAdminRepo.On("AssignRole", user.ID, adminRoleID).Return(nil)
+
+		// Grup pertama di konfigurasi (tata-usaha) yang menang.
+		role, err := authn.Authenticate(context.Background(), user, "Dosen Wali", "LdapPass1")
+
+		require.NoError(t, err)
+		assert.Equal(t, "Admin", role)
+		assert.Equal(t, adminRoleID, user.RoleID)
+		assert.Equal(t, 4, user.TokenVersion)
+		mockAdminRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error: Mapped role does not exist", func(t *testing.T) {
+		dir := &fakeDirectory{passwords: map[string]string{"siti": "LdapPass1"}, groups: map[string][]string{"siti": {"tata-usaha"}}}
+		authn, mockRoleRepo, mockAdminRepo := setupAuthenticators(dir)
+		user := &models.User{ID: uuid.New(), Username: "siti"}
+
+		mockRoleRepo.On("GetAllRoles").Return([]models.Roles{{ID: uuid.New(), Name: "Dosen Wali"}}, nil)
+
+		_, err := authn.Authenticate(context.Background(), user, "Dosen Wali", "LdapPass1")
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, service.ErrInvalidCredentials)
+		mockAdminRepo.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything)
+	})
+}
+
+func TestLoginWithLDAP(t *testing.T) {
+	t.Run("Error: Directory unreachable is not a failed attempt", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepo)
+		mockSessionRepo := new(mocks.MockSessionRepo)
+		authn, _, _ := setupAuthenticators(&fakeDirectory{err: errors.New("connection refused")})
+		attempts := repo.NewMemoryLoginAttemptRepository()
+		svc := service.NewAuthService(mockUserRepo, mockSessionRepo, authn, newTestLoginThrottle(attempts), newTestMFAService(nil, mockUserRepo, nil))
+		app := setupAuthApp()
+		app.Post("/login", svc.Login)
+
+		mockUserRepo.On("GetByUsername", "siti").Return(&models.User{ID: uuid.New(), Username: "siti", IsActive: true}, "Dosen Wali", nil)
+
+		status, _ := postLogin(app, "siti", "LdapPass1")
+
+		assert.Equal(t, 503, status)
+		attempt, err := attempts.Get(context.Background(), models.LoginScopeUsername, "siti")
+		require.NoError(t, err)
+		assert.Nil(t, attempt)
+	})
+
+	t.Run("Error: Wrong LDAP password counts as a failure", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepo)
+		mockSessionRepo := new(mocks.MockSessionRepo)
+		authn, _, _ := setupAuthenticators(&fakeDirectory{passwords: map[string]string{"siti": "LdapPass1"}})
+		attempts := repo.NewMemoryLoginAttemptRepository()
+		svc := service.NewAuthService(mockUserRepo, mockSessionRepo, authn, newTestLoginThrottle(attempts), newTestMFAService(nil, mockUserRepo, nil))
+		app := setupAuthApp()
+		app.Post("/login", svc.Login)
+
+		mockUserRepo.On("GetByUsername", "siti").Return(&models.User{ID: uuid.New(), Username: "siti", IsActive: true}, "Dosen Wali", nil)
+
+		status, _ := postLogin(app, "siti", "salah")
+
+		assert.Equal(t, 401, status)
+		attempt, err := attempts.Get(context.Background(), models.LoginScopeUsername, "siti")
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempt.Failures)
+	})
+}
@@ -0,0 +1,262 @@
+package service_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	modelMongo "student-performance-report/app/models/mongodb"
+	modelPg "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	"student-performance-report/app/service/mongodb"
+	"student-performance-report/app/service/policy"
+)
+
+type commentTest struct {
+	svc         *service.CommentService
+	comments    *mocks.MockCommentRepo
+	mongo       *mocks.MockAchievementMongoRepo
+	pg          *mocks.MockAchievementPgRepo
+	lecturer    *mocks.MockLecturerRepo
+	student     *mocks.MockStudentRepo
+	ref         modelPg.AchievementReference
+	studentUser uuid.UUID
+	advisorUser uuid.UUID
+}
+
+// setupCommentTest menyiapkan satu prestasi "submitted" milik mahasiswa
+// dengan dosen wali; jendela edit 15 menit, hapus 60 menit.
+func setupCommentTest() *commentTest {
+	ct := &commentTest{
+		comments:    new(mocks.MockCommentRepo),
+		mongo:       new(mocks.MockAchievementMongoRepo),
+		pg:          new(mocks.MockAchievementPgRepo),
+		lecturer:    new(mocks.MockLecturerRepo),
+		student:     new(mocks.MockStudentRepo),
+		studentUser: uuid.New(),
+		advisorUser: uuid.New(),
+	}
+	studentID, lecturerID := uuid.New(), uuid.New()
+	ct.ref = modelPg.AchievementReference{ID: uuid.New(), StudentID: studentID, MongoAchievementID: "mongo_obj_id_123", Status: "submitted"}
+
+	ct.pg.On("GetReferenceByID", mock.Anything, ct.ref.ID).Return(ct.ref, nil)
+	ct.pg.On("GetStudentByUserID", mock.Anything, ct.studentUser).Return(studentID, nil)
+	ct.pg.On("GetStudentByUserID", mock.Anything, ct.advisorUser).Return(uuid.Nil, errors.New("not a student"))
+	ct.lecturer.On("GetLecturerByUserID", mock.Anything, ct.advisorUser).Return(lecturerID, nil)
+	ct.student.On("GetStudentByID", mock.Anything, studentID).Return(&modelPg.Student{ID: studentID, AdvisorID: &lecturerID}, nil)
+
+	access := policy.NewAccessPolicy(ct.pg, ct.lecturer, ct.student)
+	ct.svc = service.NewCommentService(ct.comments, ct.pg, ct.mongo, access, 15*time.Minute, 60*time.Minute)
+	return ct
+}
+
+func (ct *commentTest) url() string {
+	return "/achievements/" + ct.ref.ID.String() + "/comments"
+}
+
+func TestCreateComment(t *testing.T) {
+	t.Run("Success: Owner comments on a details field", func(t *testing.T) {
+		ct := setupCommentTest()
+		app := setupApp("mahasiswa", ct.studentUser)
+		app.Post("/achievements/:id/comments", ct.svc.CreateComment)
+
+		ct.mongo.On("FindOne", mock.Anything, "mongo_obj_id_123").Return(&modelMongo.Achievement{AchievementType: "competition"}, nil)
+		ct.comments.On("Create", mock.Anything, mock.MatchedBy(func(c *modelPg.AchievementComment) bool {
+			return c.AuthorRole == "owner" && *c.Field == "details.rank" && c.Body == "Peringkat sudah diperbaiki"
+		})).Return(nil)
+
+		status, body := sendJSON(app, "POST", ct.url(), map[string]string{"body": "  Peringkat sudah diperbaiki ", "field": "details.rank"})
+
+		assert.Equal(t, 201, status)
+		assert.Equal(t, "owner", body["authorRole"])
+		ct.comments.AssertExpectations(t)
+	})
+
+	t.Run("Success: Advisor comments on an attachment", func(t *testing.T) {
+		ct := setupCommentTest()
+		app := setupApp("dosen_wali", ct.advisorUser)
+		app.Post("/achievements/:id/comments", ct.svc.CreateComment)
+
+		ct.mongo.On("FindOne", mock.Anything, "mongo_obj_id_123").Return(&modelMongo.Achievement{
+			Attachments: []modelMongo.Attachment{{FileName: "sertifikat.pdf", FileURL: "/uploads/sertifikat.pdf"}},
+		}, nil)
+		ct.comments.On("Create", mock.Anything, mock.MatchedBy(func(c *modelPg.AchievementComment) bool {
+			return c.AuthorRole == "advisor" && *c.Attachment == "/uploads/sertifikat.pdf"
+		})).Return(nil)
+
+		status, _ := sendJSON(app, "POST", ct.url(), map[string]string{"body": "Sertifikat tidak terbaca", "attachment": "/uploads/sertifikat.pdf"})
+
+		assert.Equal(t, 201, status)
+		ct.comments.AssertExpectations(t)
+	})
+
+	t.Run("Success: Advisor replies to the owner's comment", func(t *testing.T) {
+		ct := setupCommentTest()
+		app := setupApp("dosen_wali", ct.advisorUser)
+		app.Post("/achievements/:id/comments", ct.svc.CreateComment)
+
+		parent := &modelPg.AchievementComment{ID: uuid.New(), AchievementRefID: ct.ref.ID, AuthorID: &ct.studentUser, AuthorRole: "owner", Body: "Sudah saya unggah ulang"}
+		ct.comments.On("GetByID", mock.Anything, parent.ID).Return(parent, nil)
+		ct.comments.On("Create", mock.Anything, mock.MatchedBy(func(c *modelPg.AchievementComment) bool {
+			return c.ParentID != nil && *c.ParentID == parent.ID
+		})).Return(nil)
+
+		status, body := sendJSON(app, "POST", ct.url(), map[string]string{"body": "Terima kasih, sudah terbaca", "parentId": parent.ID.String()})
+
+		assert.Equal(t, 201, status)
+		assert.Equal(t, parent.ID.String(), body["parentId"])
+		ct.comments.AssertExpectations(t)
+	})
+
+	t.Run("Error: Reply to a comment of another achievement", func(t *testing.T) {
+		ct := setupCommentTest()
+		app := setupApp("mahasiswa", ct.studentUser)
+		app.Post("/achievements/:id/comments", ct.svc.CreateComment)
+
+		other := &modelPg.AchievementComment{ID: uuid.New(), AchievementRefID: uuid.New(), AuthorRole: "owner", Body: "lain"}
+		ct.comments.On("GetByID", mock.Anything, other.ID).Return(other, nil)
+
+		status, _ := sendJSON(app, "POST", ct.url(), map[string]string{"body": "balasan", "parentId": other.ID.String()})
+
+		assert.Equal(t, 400, status)
+		ct.comments.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success: Field of the achievement's own type", func(t *testing.T) {
+		ct := setupCommentTest()
+		app := setupApp("dosen_wali", ct.advisorUser)
+		app.Post("/achievements/:id/comments", ct.svc.CreateComment)
+
+		ct.mongo.On("FindOne", mock.Anything, "mongo_obj_id_123").Return(&modelMongo.Achievement{AchievementType: "publication"}, nil)
+		ct.comments.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		status, _ := sendJSON(app, "POST", ct.url(), map[string]string{"body": "ISSN belum sesuai", "field": "details.issn"})
+
+		assert.Equal(t, 201, status)
+	})
+
+	t.Run("Error: Unknown field, unknown attachment or empty body", func(t *testing.T) {
+		ct := setupCommentTest()
+		app := setupApp("mahasiswa", ct.studentUser)
+		app.Post("/achievements/:id/comments", ct.svc.CreateComment)
+		ct.mongo.On("FindOne", mock.Anything, "mongo_obj_id_123").Return(&modelMongo.Achievement{AchievementType: "competition"}, nil)
+
+		status, _ := sendJSON(app, "POST", ct.url(), map[string]string{"body": "x", "field": "details.level"})
+		assert.Equal(t, 400, status)
+
+		// issn ada di skema publikasi, bukan di skema lomba.
+		status, _ = sendJSON(app, "POST", ct.url(), map[string]string{"body": "x", "field": "details.issn"})
+		assert.Equal(t, 400, status)
+
+		status, _ = sendJSON(app, "POST", ct.url(), map[string]string{"body": "x", "attachment": "/uploads/lain.pdf"})
+		assert.Equal(t, 400, status)
+
+		status, _ = sendJSON(app, "POST", ct.url(), map[string]string{"body": "   "})
+		assert.Equal(t, 400, status)
+
+		ct.comments.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Lecturer who is not the advisor", func(t *testing.T) {
+		ct := setupCommentTest()
+		otherUser, otherLecturer := uuid.New(), uuid.New()
+		ct.pg.On("GetStudentByUserID", mock.Anything, otherUser).Return(uuid.Nil, errors.New("not a student"))
+		ct.lecturer.On("GetLecturerByUserID", mock.Anything, otherUser).Return(otherLecturer, nil)
+
+		app := setupApp("dosen_wali", otherUser)
+		app.Get("/achievements/:id/comments", ct.svc.GetComments)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", ct.url(), nil))
+		assert.Equal(t, 403, resp.StatusCode)
+	})
+}
+
+func TestGetComments(t *testing.T) {
+	ct := setupCommentTest()
+	app := setupApp("dosen_wali", ct.advisorUser)
+	app.Get("/achievements/:id/comments", ct.svc.GetComments)
+
+	ct.comments.On("ListByAchievement", mock.Anything, ct.ref.ID).Return(nil, nil)
+
+	resp, _ := app.Test(httptest.NewRequest("GET", ct.url(), nil))
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func TestUpdateAndDeleteComment(t *testing.T) {
+	comment := func(ct *commentTest, author uuid.UUID, age time.Duration) *modelPg.AchievementComment {
+		c := &modelPg.AchievementComment{ID: uuid.New(), AchievementRefID: ct.ref.ID, AuthorID: &author, AuthorRole: "owner", Body: "lama", CreatedAt: time.Now().Add(-age)}
+		ct.comments.On("GetByID", mock.Anything, c.ID).Return(c, nil)
+		return c
+	}
+
+	t.Run("Success: Author edits within the window", func(t *testing.T) {
+		ct := setupCommentTest()
+		c := comment(ct, ct.studentUser, 5*time.Minute)
+		app := setupApp("mahasiswa", ct.studentUser)
+		app.Put("/achievements/:id/comments/:commentId", ct.svc.UpdateComment)
+		ct.comments.On("Update", mock.Anything, c.ID, "baru").Return(nil)
+
+		status, _ := sendJSON(app, "PUT", ct.url()+"/"+c.ID.String(), map[string]string{"body": "baru"})
+
+		assert.Equal(t, 200, status)
+		ct.comments.AssertExpectations(t)
+	})
+
+	t.Run("Error: Edit window has passed", func(t *testing.T) {
+		ct := setupCommentTest()
+		c := comment(ct, ct.studentUser, 20*time.Minute)
+		app := setupApp("mahasiswa", ct.studentUser)
+		app.Put("/achievements/:id/comments/:commentId", ct.svc.UpdateComment)
+
+		status, _ := sendJSON(app, "PUT", ct.url()+"/"+c.ID.String(), map[string]string{"body": "baru"})
+
+		assert.Equal(t, 403, status)
+		ct.comments.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Someone else's comment", func(t *testing.T) {
+		ct := setupCommentTest()
+		c := comment(ct, ct.advisorUser, time.Minute)
+		app := setupApp("mahasiswa", ct.studentUser)
+		app.Delete("/achievements/:id/comments/:commentId", ct.svc.DeleteComment)
+
+		resp, _ := app.Test(httptest.NewRequest("DELETE", ct.url()+"/"+c.ID.String(), nil))
+
+		assert.Equal(t, 403, resp.StatusCode)
+		ct.comments.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success: Author deletes within the window, admin at any time", func(t *testing.T) {
+		ct := setupCommentTest()
+		recent := comment(ct, ct.studentUser, 30*time.Minute)
+		old := comment(ct, ct.studentUser, 48*time.Hour)
+		ct.comments.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+		app := setupApp("mahasiswa", ct.studentUser)
+		app.Delete("/achievements/:id/comments/:commentId", ct.svc.DeleteComment)
+		resp, _ := app.Test(httptest.NewRequest("DELETE", ct.url()+"/"+recent.ID.String(), nil))
+		assert.Equal(t, 200, resp.StatusCode)
+		resp, _ = app.Test(httptest.NewRequest("DELETE", ct.url()+"/"+old.ID.String(), nil))
+		assert.Equal(t, 403, resp.StatusCode)
+
+		admin := setupApp("admin", uuid.New())
+		admin.Delete("/achievements/:id/comments/:commentId", ct.svc.DeleteComment)
+		resp, _ = admin.Test(httptest.NewRequest("DELETE", ct.url()+"/"+old.ID.String(), nil))
+		assert.Equal(t, 200, resp.StatusCode)
+	})
+
+	t.Run("Error: Comment of another achievement", func(t *testing.T) {
+		ct := setupCommentTest()
+		c := comment(ct, ct.studentUser, time.Minute)
+		c.AchievementRefID = uuid.New()
+		app := setupApp("mahasiswa", ct.studentUser)
+		app.Put("/achievements/:id/comments/:commentId", ct.svc.UpdateComment)
+
+		status, _ := sendJSON(app, "PUT", ct.url()+"/"+c.ID.String(), map[string]string{"body": "baru"})
+		assert.Equal(t, 404, status)
+	})
+}
@@ -10,18 +10,27 @@ import (
 	models "student-performance-report/app/models/postgresql"
 	"student-performance-report/app/repository/mocks"
 	"student-performance-report/app/service/postgresql"
+	"student-performance-report/app/service/policy"
 )
 
 // --- SETUP HELPERS ---
 
 func setupLecturerServiceTest() (*service.LecturerService, *mocks.MockLecturerRepo) {
 	mockLecturerRepo := new(mocks.MockLecturerRepo)
-	svc := service.NewLecturerService(mockLecturerRepo)
+	access := policy.NewAccessPolicy(new(mocks.MockAchievementPgRepo), mockLecturerRepo, new(mocks.MockStudentRepo))
+	svc := service.NewLecturerService(mockLecturerRepo, access)
 	return svc, mockLecturerRepo
 }
 
+// setupSimpleApp menjalankan handler sebagai admin.
 func setupSimpleApp() *fiber.App {
-	return fiber.New()
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", uuid.New())
+		c.Locals("permissions", []string{policy.AdminPermission})
+		return c.Next()
+	})
+	return app
 }
 
 // --- TEST CASES ---
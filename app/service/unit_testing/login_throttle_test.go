@@ -0,0 +1,143 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	models "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/postgresql"
+	"student-performance-report/utils"
+)
+
+// --- SETUP HELPERS ---
+
+func setupLoginThrottleTest() (*fiber.App, *mocks.MockUserRepo, *mocks.MockSessionRepo, repo.LoginAttemptRepository) {
+	mockUserRepo := new(mocks.MockUserRepo)
+	mockSessionRepo := new(mocks.MockSessionRepo)
+	attempts := repo.NewMemoryLoginAttemptRepository()
+	throttle := newTestLoginThrottle(attempts)
+	svc := service.NewAuthService(mockUserRepo, mockSessionRepo, newTestAuthenticators(), throttle, newTestMFAService(nil, mockUserRepo, nil))
+
+	app := fiber.New()
+	app.Post("/login", svc.Login)
+	app.Get("/admin/login-locks", throttle.GetLoginLocks)
+	app.Delete("/admin/login-locks", throttle.ClearLoginLock)
+	return app, mockUserRepo, mockSessionRepo, attempts
+}
+
+func postLogin(app *fiber.App, username, password string) (int, string) {
+	body, _ := json.Marshal(map[string]string{"username": username, "password": password})
+	req := httptest.NewRequest("POST", "/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req, -1)
+	return resp.StatusCode, resp.Header.Get("Retry-After")
+}
+
+// --- TEST CASES ---
+
+func TestLockoutPolicyDelay(t *testing.T) {
+	p := service.LockoutPolicy{FreeFailures: 3, MaxFailures: 10, BackoffBase: time.Second, BackoffMax: 30 * time.Second, Lockout: 15 * time.Minute}
+
+	assert.Equal(t, time.Duration(0), p.Delay(3))
+	assert.Equal(t, time.Second, p.Delay(4))
+	assert.Equal(t, 2*time.Second, p.Delay(5))
+	assert.Equal(t, 16*time.Second, p.Delay(8))
+	assert.Equal(t, 30*time.Second, p.Delay(9))
+	assert.Equal(t, 15*time.Minute, p.Delay(10))
+}
+
+func TestLoginThrottle(t *testing.T) {
+	hash, _ := utils.HashPassword("Rahasia123")
+	user := &models.User{ID: uuid.New(), Username: "budi", PasswordHash: hash, RoleID: uuid.New(), IsActive: true}
+
+	t.Run("Backoff: attempts after the free failures are blocked, even with the right password", func(t *testing.T) {
+		app, mockRepo, _, _ := setupLoginThrottleTest()
+		mockRepo.On("GetByUsername", "budi").Return(user, "mahasiswa", nil)
+
+		for i := 0; i < 3; i++ {
+			status, _ := postLogin(app, "budi", "salah")
+			assert.Equal(t, 401, status)
+		}
+
+		status, retryAfter := postLogin(app, "budi", "Rahasia123")
+		assert.Equal(t, 429, status)
+		assert.Equal(t, "60", retryAfter)
+		mockRepo.AssertNumberOfCalls(t, "GetByUsername", 3)
+	})
+
+	t.Run("Unknown username is counted like a wrong password", func(t *testing.T) {
+		app, mockRepo, _, attempts := setupLoginThrottleTest()
+		mockRepo.On("GetByUsername", "hantu").Return(nil, "", errors.New("user not found"))
+
+		status, _ := postLogin(app, "hantu", "apa saja")
+		assert.Equal(t, 401, status)
+
+		a, _ := attempts.Get(context.Background(), models.LoginScopeUsername, "hantu")
+		assert.Equal(t, 1, a.Failures)
+	})
+
+	t.Run("Success resets the username counter", func(t *testing.T) {
+		app, mockRepo, mockSessions, attempts := setupLoginThrottleTest()
+		mockRepo.On("GetByUsername", "budi").Return(user, "mahasiswa", nil)
+		mockRepo.On("GetPermissionsByRoleID", user.RoleID).Return([]string{"achievement:create"}, nil)
+		mockRepo.On("GetByUsername", "Budi").Return(nil, "", errors.New("user not found"))
+		mockSessions.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		// "Budi" dan "budi" berbagi satu counter
+		postLogin(app, "Budi", "salah")
+		postLogin(app, "budi", "salah")
+		a, _ := attempts.Get(context.Background(), models.LoginScopeUsername, "budi")
+		assert.Equal(t, 2, a.Failures)
+
+		status, _ := postLogin(app, "budi", "Rahasia123")
+		assert.Equal(t, 200, status)
+		a, _ = attempts.Get(context.Background(), models.LoginScopeUsername, "budi")
+		assert.Nil(t, a)
+	})
+
+	t.Run("Admin: list locks and clear one", func(t *testing.T) {
+		app, mockRepo, _, attempts := setupLoginThrottleTest()
+		mockRepo.On("GetByUsername", "budi").Return(user, "mahasiswa", nil)
+
+		// Lewat HTTP percobaan ke-4 sudah diblok backoff, jadi catat langsung
+		throttle := newTestLoginThrottle(attempts)
+		for i := 0; i < 4; i++ {
+			throttle.RecordFailure(context.Background(), "budi", "0.0.0.0")
+		}
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/admin/login-locks", nil))
+		var locks []models.LoginLockResp
+		json.NewDecoder(resp.Body).Decode(&locks)
+		assert.Equal(t, 200, resp.StatusCode)
+		if assert.Len(t, locks, 1) {
+			assert.Equal(t, "budi", locks[0].Subject)
+			assert.True(t, locks[0].LockedOut)
+		}
+
+		resp, _ = app.Test(httptest.NewRequest("DELETE", "/admin/login-locks?scope=username&subject=BUDI", nil))
+		assert.Equal(t, 200, resp.StatusCode)
+
+		status, _ := postLogin(app, "budi", "salah")
+		assert.Equal(t, 401, status)
+	})
+
+	t.Run("Admin: clear validates scope and reports unknown subjects", func(t *testing.T) {
+		app, _, _, _ := setupLoginThrottleTest()
+
+		resp, _ := app.Test(httptest.NewRequest("DELETE", "/admin/login-locks?scope=email&subject=x", nil))
+		assert.Equal(t, 400, resp.StatusCode)
+
+		resp, _ = app.Test(httptest.NewRequest("DELETE", "/admin/login-locks?scope=ip&subject=10.0.0.1", nil))
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+}
@@ -0,0 +1,280 @@
+package service_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	models "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/postgresql"
+	"student-performance-report/utils"
+)
+
+// --- SETUP HELPERS ---
+
+type mfaTestEnv struct {
+	app          *fiber.App
+	userRepo     *mocks.MockUserRepo
+	sessionRepo  *mocks.MockSessionRepo
+	mfaRepo      *mocks.MockMFARepo
+	passwordRepo *mocks.MockPasswordRepo
+}
+
+func setupMFATest(userID uuid.UUID, required ...string) mfaTestEnv {
+	env := mfaTestEnv{
+		userRepo:     new(mocks.MockUserRepo),
+		sessionRepo:  new(mocks.MockSessionRepo),
+		mfaRepo:      new(mocks.MockMFARepo),
+		passwordRepo: new(mocks.MockPasswordRepo),
+	}
+	mfaSvc := newTestMFAService(env.mfaRepo, env.userRepo, env.passwordRepo, required...)
+	authSvc := service.NewAuthService(env.userRepo, env.sessionRepo, newTestAuthenticators(), newTestLoginThrottle(repo.NewMemoryLoginAttemptRepository()), mfaSvc)
+
+	env.app = fiber.New()
+	env.app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return c.Next()
+	})
+	env.app.Post("/login", authSvc.Login)
+	env.app.Post("/2fa/login", authSvc.LoginMFA)
+	env.app.Post("/2fa/login/setup", mfaSvc.LoginSetup)
+	env.app.Post("/2fa/setup", mfaSvc.Setup)
+	env.app.Post("/2fa/confirm", mfaSvc.Confirm)
+	env.app.Post("/2fa/disable", mfaSvc.Disable)
+	return env
+}
+
+func postMFA(app *fiber.App, url string, payload interface{}, out interface{}) int {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest("POST", url, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req, -1)
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+func currentTOTP(secret string) string {
+	code, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	return code
+}
+
+// --- TEST CASES ---
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 lampiran B (SHA1, T=59), dipotong ke 6 digit
+	code, err := utils.TOTPCode("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", utils.TOTPStep(time.Unix(59, 0)))
+	assert.NoError(t, err)
+	assert.Equal(t, "287082", code)
+
+	_, ok := utils.ValidateTOTP("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "287082", time.Unix(89, 0))
+	assert.True(t, ok, "one step of clock drift is accepted")
+	_, ok = utils.ValidateTOTP("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "287082", time.Unix(200, 0))
+	assert.False(t, ok)
+}
+
+func TestLoginWithMFA(t *testing.T) {
+	secret, _ := utils.GenerateTOTPSecret()
+	hash, _ := utils.HashPassword("Rahasia123")
+	enabledAt := time.Now()
+	user := &models.User{ID: uuid.New(), Username: "pak.dedi", PasswordHash: hash, RoleID: uuid.New(), IsActive: true}
+	challenge := &models.MFAChallenge{ID: uuid.New(), UserID: user.ID, ExpiresAt: time.Now().Add(time.Minute)}
+
+	t.Run("Login returns a challenge instead of tokens when 2FA is enabled", func(t *testing.T) {
+		env := setupMFATest(uuid.Nil)
+		env.userRepo.On("GetByUsername", "pak.dedi").Return(user, "dosen_wali", nil)
+		env.userRepo.On("GetPermissionsByRoleID", user.RoleID).Return([]string{"achievement:verify"}, nil)
+		env.mfaRepo.On("Get", mock.Anything, user.ID).Return(&models.UserMFA{UserID: user.ID, Secret: secret, EnabledAt: &enabledAt}, nil)
+		env.mfaRepo.On("CreateChallenge", mock.Anything, mock.Anything).Return(nil)
+
+		var resp models.MFAChallengeResp
+		status := postMFA(env.app, "/login", map[string]string{"username": "pak.dedi", "password": "Rahasia123"}, &resp)
+
+		assert.Equal(t, 200, status)
+		assert.True(t, resp.MFARequired)
+		assert.False(t, resp.SetupRequired)
+		assert.NotEmpty(t, resp.ChallengeToken)
+		env.sessionRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Role that requires 2FA gets a setup challenge", func(t *testing.T) {
+		env := setupMFATest(uuid.Nil, "achievement:verify")
+		env.userRepo.On("GetByUsername", "pak.dedi").Return(user, "dosen_wali", nil)
+		env.userRepo.On("GetPermissionsByRoleID", user.RoleID).Return([]string{"achievement:verify"}, nil)
+		env.mfaRepo.On("Get", mock.Anything, user.ID).Return(nil, nil)
+		env.mfaRepo.On("CreateChallenge", mock.Anything, mock.Anything).Return(nil)
+
+		var resp models.MFAChallengeResp
+		status := postMFA(env.app, "/login", map[string]string{"username": "pak.dedi", "password": "Rahasia123"}, &resp)
+
+		assert.Equal(t, 200, status)
+		assert.True(t, resp.SetupRequired)
+	})
+
+	t.Run("Valid TOTP code completes the login", func(t *testing.T) {
+		env := setupMFATest(uuid.Nil)
+		env.mfaRepo.On("GetChallenge", mock.Anything, utils.HashOpaqueToken("mfa_abc")).Return(challenge, nil)
+		env.userRepo.On("GetByID", user.ID).Return(user, nil)
+		env.userRepo.On("GetByUsername", "pak.dedi").Return(user, "dosen_wali", nil)
+		env.userRepo.On("GetPermissionsByRoleID", user.RoleID).Return([]string{"achievement:verify"}, nil)
+		env.mfaRepo.On("Get", mock.Anything, user.ID).Return(&models.UserMFA{UserID: user.ID, Secret: secret, EnabledAt: &enabledAt}, nil)
+		env.mfaRepo.On("UseStep", mock.Anything, user.ID, mock.Anything).Return(true, nil)
+		env.mfaRepo.On("ConsumeChallenge", mock.Anything, challenge.ID, mock.Anything).Return(nil)
+		env.sessionRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		var resp models.MFALoginResponse
+		status := postMFA(env.app, "/2fa/login", map[string]string{"challengeToken": "mfa_abc", "code": currentTOTP(secret)}, &resp)
+
+		assert.Equal(t, 200, status)
+		assert.NotEmpty(t, resp.Token)
+		assert.Empty(t, resp.RecoveryCodes)
+	})
+
+	t.Run("Wrong code counts against the challenge", func(t *testing.T) {
+		env := setupMFATest(uuid.Nil)
+		env.mfaRepo.On("GetChallenge", mock.Anything, mock.Anything).Return(challenge, nil)
+		env.userRepo.On("GetByID", user.ID).Return(user, nil)
+		env.mfaRepo.On("Get", mock.Anything, user.ID).Return(&models.UserMFA{UserID: user.ID, Secret: secret, EnabledAt: &enabledAt}, nil)
+		env.mfaRepo.On("FailChallenge", mock.Anything, challenge.ID).Return(nil)
+
+		status := postMFA(env.app, "/2fa/login", map[string]string{"challengeToken": "mfa_abc", "code": "000000x"}, nil)
+
+		assert.Equal(t, 401, status)
+		env.mfaRepo.AssertCalled(t, "FailChallenge", mock.Anything, challenge.ID)
+		env.sessionRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Recovery code is accepted once", func(t *testing.T) {
+		env := setupMFATest(uuid.Nil)
+		env.mfaRepo.On("GetChallenge", mock.Anything, mock.Anything).Return(challenge, nil)
+		env.userRepo.On("GetByID", user.ID).Return(user, nil)
+		env.userRepo.On("GetByUsername", "pak.dedi").Return(user, "dosen_wali", nil)
+		env.userRepo.On("GetPermissionsByRoleID", user.RoleID).Return([]string{}, nil)
+		env.mfaRepo.On("Get", mock.Anything, user.ID).Return(&models.UserMFA{UserID: user.ID, Secret: secret, EnabledAt: &enabledAt}, nil)
+		env.mfaRepo.On("UseRecoveryCode", mock.Anything, user.ID, utils.HashOpaqueToken("abcde-fghjk")).Return(true, nil)
+		env.mfaRepo.On("ConsumeChallenge", mock.Anything, challenge.ID, mock.Anything).Return(nil)
+		env.sessionRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		status := postMFA(env.app, "/2fa/login", map[string]string{"challengeToken": "mfa_abc", "recoveryCode": "ABCDEFGHJK"}, nil)
+
+		assert.Equal(t, 200, status)
+	})
+
+	t.Run("Expired challenge is rejected", func(t *testing.T) {
+		env := setupMFATest(uuid.Nil)
+		expired := &models.MFAChallenge{ID: uuid.New(), UserID: user.ID, ExpiresAt: time.Now().Add(-time.Second)}
+		env.mfaRepo.On("GetChallenge", mock.Anything, mock.Anything).Return(expired, nil)
+
+		status := postMFA(env.app, "/2fa/login", map[string]string{"challengeToken": "mfa_old", "code": currentTOTP(secret)}, nil)
+
+		assert.Equal(t, 401, status)
+	})
+
+	t.Run("Forced enrollment during login returns recovery codes", func(t *testing.T) {
+		env := setupMFATest(uuid.Nil, "achievement:verify")
+		env.mfaRepo.On("GetChallenge", mock.Anything, mock.Anything).Return(challenge, nil)
+		env.userRepo.On("GetByID", user.ID).Return(user, nil)
+		env.userRepo.On("GetByUsername", "pak.dedi").Return(user, "dosen_wali", nil)
+		env.userRepo.On("GetPermissionsByRoleID", user.RoleID).Return([]string{"achievement:verify"}, nil)
+		env.mfaRepo.On("SavePendingSecret", mock.Anything, user.ID, mock.Anything).Return(nil)
+
+		var setup models.MFASetupResp
+		status := postMFA(env.app, "/2fa/login/setup", map[string]string{"challengeToken": "mfa_abc"}, &setup)
+		assert.Equal(t, 200, status)
+		assert.Contains(t, setup.OtpauthURL, "otpauth://totp/Test:pak.dedi")
+
+		env.mfaRepo.On("Get", mock.Anything, user.ID).Return(&models.UserMFA{UserID: user.ID, Secret: setup.Secret}, nil)
+		env.mfaRepo.On("Enable", mock.Anything, user.ID, mock.Anything, mock.Anything).Return(nil)
+		env.mfaRepo.On("ConsumeChallenge", mock.Anything, challenge.ID, mock.Anything).Return(nil)
+		env.sessionRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		var resp models.MFALoginResponse
+		status = postMFA(env.app, "/2fa/login", map[string]string{"challengeToken": "mfa_abc", "code": currentTOTP(setup.Secret)}, &resp)
+
+		assert.Equal(t, 200, status)
+		assert.NotEmpty(t, resp.Token)
+		assert.Len(t, resp.RecoveryCodes, 10)
+	})
+}
+
+func TestMFAEnrollment(t *testing.T) {
+	userID := uuid.New()
+	roleID := uuid.New()
+	user := &models.User{ID: userID, Username: "admin", RoleID: roleID, IsActive: true}
+
+	t.Run("Setup then confirm enables 2FA with hashed recovery codes", func(t *testing.T) {
+		env := setupMFATest(userID)
+		env.userRepo.On("GetByID", userID).Return(user, nil)
+		env.mfaRepo.On("SavePendingSecret", mock.Anything, userID, mock.Anything).Return(nil)
+
+		var setup models.MFASetupResp
+		assert.Equal(t, 200, postMFA(env.app, "/2fa/setup", nil, &setup))
+		assert.NotEmpty(t, setup.Secret)
+
+		var hashes []string
+		env.mfaRepo.On("Get", mock.Anything, userID).Return(&models.UserMFA{UserID: userID, Secret: setup.Secret}, nil)
+		env.mfaRepo.On("Enable", mock.Anything, userID, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			hashes = args.Get(3).([]string)
+		}).Return(nil)
+
+		var resp models.MFARecoveryCodesResp
+		status := postMFA(env.app, "/2fa/confirm", map[string]string{"code": currentTOTP(setup.Secret)}, &resp)
+
+		assert.Equal(t, 200, status)
+		assert.Len(t, resp.RecoveryCodes, 10)
+		assert.Equal(t, utils.HashOpaqueToken(resp.RecoveryCodes[0]), hashes[0])
+	})
+
+	t.Run("Error: Setup when already enabled", func(t *testing.T) {
+		env := setupMFATest(userID)
+		env.userRepo.On("GetByID", userID).Return(user, nil)
+		env.mfaRepo.On("SavePendingSecret", mock.Anything, userID, mock.Anything).Return(repo.ErrMFAAlreadyEnabled)
+
+		assert.Equal(t, 409, postMFA(env.app, "/2fa/setup", nil, nil))
+	})
+
+	t.Run("Error: Confirm with wrong code", func(t *testing.T) {
+		env := setupMFATest(userID)
+		secret, _ := utils.GenerateTOTPSecret()
+		env.mfaRepo.On("Get", mock.Anything, userID).Return(&models.UserMFA{UserID: userID, Secret: secret}, nil)
+
+		assert.Equal(t, 400, postMFA(env.app, "/2fa/confirm", map[string]string{"code": "12345"}, nil))
+		env.mfaRepo.AssertNotCalled(t, "Enable", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Disable requires password and code", func(t *testing.T) {
+		env := setupMFATest(userID)
+		secret, _ := utils.GenerateTOTPSecret()
+		enabledAt := time.Now()
+		hash, _ := utils.HashPassword("Rahasia123")
+		env.mfaRepo.On("Get", mock.Anything, userID).Return(&models.UserMFA{UserID: userID, Secret: secret, EnabledAt: &enabledAt}, nil)
+		env.userRepo.On("GetByID", userID).Return(user, nil)
+		env.userRepo.On("GetPermissionsByRoleID", roleID).Return([]string{"achievement:create"}, nil)
+		env.passwordRepo.On("GetPasswordHash", mock.Anything, userID).Return(hash, nil)
+		env.mfaRepo.On("UseStep", mock.Anything, userID, mock.Anything).Return(true, nil)
+		env.mfaRepo.On("Disable", mock.Anything, userID).Return(nil)
+
+		assert.Equal(t, 400, postMFA(env.app, "/2fa/disable", map[string]string{"password": "salah", "code": currentTOTP(secret)}, nil))
+		assert.Equal(t, 200, postMFA(env.app, "/2fa/disable", map[string]string{"password": "Rahasia123", "code": currentTOTP(secret)}, nil))
+		env.mfaRepo.AssertCalled(t, "Disable", mock.Anything, userID)
+	})
+
+	t.Run("Error: Disable refused when the role requires 2FA", func(t *testing.T) {
+		env := setupMFATest(userID, "manage:users")
+		enabledAt := time.Now()
+		env.mfaRepo.On("Get", mock.Anything, userID).Return(&models.UserMFA{UserID: userID, EnabledAt: &enabledAt}, nil)
+		env.userRepo.On("GetByID", userID).Return(user, nil)
+		env.userRepo.On("GetPermissionsByRoleID", roleID).Return([]string{"manage:users"}, nil)
+
+		assert.Equal(t, 403, postMFA(env.app, "/2fa/disable", map[string]string{"password": "Rahasia123", "code": "123456"}, nil))
+		env.mfaRepo.AssertNotCalled(t, "Disable", mock.Anything, mock.Anything)
+	})
+}
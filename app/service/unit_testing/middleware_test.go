@@ -0,0 +1,170 @@
+package service_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	models "student-performance-report/app/models/postgresql"
+	"student-performance-report/middleware"
+	"student-performance-report/route"
+	"student-performance-report/utils"
+)
+
+// --- SETUP HELPERS ---
+
+func setupAuthRequiredApp() *fiber.App {
+	app := fiber.New()
+	app.Get("/protected", middleware.AuthRequired(), func(c *fiber.Ctx) error {
+		return c.SendStatus(200)
+	})
+	return app
+}
+
+func callProtected(app *fiber.App, token string) int {
+	req := httptest.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, _ := app.Test(req)
+	return resp.StatusCode
+}
+
+// --- TEST CASES ---
+
+func TestAuthRequiredTokenVersion(t *testing.T) {
+	defer middleware.UseTokenVersionCache(nil)
+
+	user := &models.User{ID: uuid.New(), RoleID: uuid.New(), TokenVersion: 3}
+	token, _ := utils.GenerateToken(user, "student", uuid.New())
+
+	t.Run("Success: Current version accepted", func(t *testing.T) {
+		middleware.UseTokenVersionCache(middleware.NewTokenVersionCache(func(id uuid.UUID) (int, bool, error) {
+			return 3, true, nil
+		}, time.Minute))
+
+		assert.Equal(t, 200, callProtected(setupAuthRequiredApp(), token))
+	})
+
+	t.Run("Error: Bumped version rejected after invalidation", func(t *testing.T) {
+		current := 3
+		middleware.UseTokenVersionCache(middleware.NewTokenVersionCache(func(id uuid.UUID) (int, bool, error) {
+			return current, true, nil
+		}, time.Minute))
+		app := setupAuthRequiredApp()
+
+		assert.Equal(t, 200, callProtected(app, token))
+
+		current = 4
+		assert.Equal(t, 200, callProtected(app, token), "cached version is still used")
+
+		middleware.InvalidateTokenVersion(user.ID)
+		assert.Equal(t, 401, callProtected(app, token))
+	})
+
+	t.Run("Error: Deactivated user rejected", func(t *testing.T) {
+		middleware.UseTokenVersionCache(middleware.NewTokenVersionCache(func(id uuid.UUID) (int, bool, error) {
+			return 3, false, nil
+		}, time.Minute))
+
+		assert.Equal(t, 401, callProtected(setupAuthRequiredApp(), token))
+	})
+
+	t.Run("Error: Version lookup failure is not a 401", func(t *testing.T) {
+		middleware.UseTokenVersionCache(middleware.NewTokenVersionCache(func(id uuid.UUID) (int, bool, error) {
+			return 0, false, errors.New("db down")
+		}, time.Minute))
+
+		assert.Equal(t, 500, callProtected(setupAuthRequiredApp(), token))
+	})
+}
+
+func TestAuthRequiredSession(t *testing.T) {
+	defer middleware.UseSessionCache(nil)
+
+	user := &models.User{ID: uuid.New(), RoleID: uuid.New()}
+	sessionID := uuid.New()
+	token, _ := utils.GenerateToken(user, "student", sessionID)
+
+	t.Run("Error: Revoked session rejected after invalidation", func(t *testing.T) {
+		active := true
+		middleware.UseSessionCache(middleware.NewSessionCache(func(id uuid.UUID) (bool, error) {
+			return active, nil
+		}, time.Minute))
+		app := setupAuthRequiredApp()
+
+		assert.Equal(t, 200, callProtected(app, token))
+
+		// Logout mencabut sesi lalu membuang cache-nya
+		active = false
+		middleware.InvalidateSession(sessionID)
+		assert.Equal(t, 401, callProtected(app, token))
+	})
+
+	t.Run("Error: Session lookup failure", func(t *testing.T) {
+		middleware.UseSessionCache(middleware.NewSessionCache(func(id uuid.UUID) (bool, error) {
+			return false, errors.New("db down")
+		}, time.Minute))
+
+		assert.Equal(t, 500, callProtected(setupAuthRequiredApp(), token))
+	})
+}
+
+func TestPermissionRequiredResolver(t *testing.T) {
+	defer middleware.UsePermissionResolver(nil)
+
+	roleID := uuid.New()
+	mapping := map[uuid.UUID][]string{roleID: {"achievement:read"}}
+	loads := 0
+	middleware.UsePermissionResolver(middleware.NewPermissionResolver(func() (map[uuid.UUID][]string, error) {
+		loads++
+		return mapping, nil
+	}, time.Minute))
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("role_id", roleID)
+		return c.Next()
+	})
+	app.Get("/verify", middleware.PermissionRequired("achievement:verify"), func(c *fiber.Ctx) error {
+		return c.SendStatus(200)
+	})
+
+	call := func() int {
+		resp, _ := app.Test(httptest.NewRequest("GET", "/verify", nil))
+		return resp.StatusCode
+	}
+
+	t.Run("Forbidden: Role lacks permission", func(t *testing.T) {
+		assert.Equal(t, 403, call())
+	})
+
+	t.Run("Success: Granted permission applies after invalidation", func(t *testing.T) {
+		mapping = map[uuid.UUID][]string{roleID: {"achievement:read", "achievement:verify"}}
+		assert.Equal(t, 403, call(), "cached mapping is still used")
+
+		middleware.InvalidatePermissions()
+		assert.Equal(t, 200, call())
+		assert.Equal(t, 2, loads)
+	})
+}
+
+func TestVerifyRoutePolicies(t *testing.T) {
+	t.Run("Error: Undeclared API route fails the check", func(t *testing.T) {
+		app := fiber.New()
+		app.Get("/api/v1/undeclared", func(c *fiber.Ctx) error { return nil })
+
+		err := route.VerifyRoutePolicies(app)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "GET /api/v1/undeclared")
+	})
+
+	t.Run("Success: Routes outside /api/v1 are ignored", func(t *testing.T) {
+		app := fiber.New()
+		app.Static("/uploads", "./uploads")
+
+		assert.NoError(t, route.VerifyRoutePolicies(app))
+	})
+}
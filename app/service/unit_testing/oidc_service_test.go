@@ -0,0 +1,303 @@
+package service_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	models "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/postgresql"
+	"student-performance-report/utils"
+)
+
+// --- SETUP HELPERS ---
+
+// stubIdP adalah identity provider lokal: discovery, JWKS dan token endpoint.
+// Token endpoint hanya menerima kode "good-code" dengan verifier yang benar.
+type stubIdP struct {
+	server   *httptest.Server
+	key      *utils.SigningKey
+	verifier string
+	claims   jwt.MapClaims
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := utils.GenerateEd25519Key("idp-1")
+	require.NoError(t, err)
+	idp := &stubIdP{key: key, verifier: "test-verifier"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(utils.NewKeySet(idp.key).JWKS())
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || r.Form.Get("code_verifier") != idp.verifier {
+			w.WriteHeader(400)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss": idp.server.URL,
+			"aud": "spr-client",
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}
+		for k, v := range idp.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+		token.Header["kid"] = idp.key.ID
+		signed, _ := token.SignedString(idp.key.Private)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+type oidcTestDeps struct {
+	svc       *service.OIDCService
+	oidcRepo  *mocks.MockOIDCRepo
+	userRepo  *mocks.MockUserRepo
+	sessions  *mocks.MockSessionRepo
+	adminRepo *mocks.MockAdminRepo
+	roleRepo  *mocks.MockRoleRepo
+}
+
+func setupOIDCServiceTest(idp *stubIdP, provisioning bool) oidcTestDeps {
+	d := oidcTestDeps{
+		oidcRepo:  new(mocks.MockOIDCRepo),
+		userRepo:  new(mocks.MockUserRepo),
+		sessions:  new(mocks.MockSessionRepo),
+		adminRepo: new(mocks.MockAdminRepo),
+		roleRepo:  new(mocks.MockRoleRepo),
+	}
+	auth := service.NewAuthService(d.userRepo, d.sessions, newTestAuthenticators(), newTestLoginThrottle(repo.NewMemoryLoginAttemptRepository()), newTestMFAService(nil, d.userRepo, nil))
+	admin := service.NewAdminService(d.adminRepo, d.userRepo, d.roleRepo)
+	provider := utils.NewOIDCProvider(idp.server.URL, "spr-client", "secret", "http://app.test/api/v1/auth/oidc/callback", []string{"openid", "email"}, nil)
+	d.svc = service.NewOIDCService(provider, d.oidcRepo, admin, auth, 10*time.Minute, service.OIDCProvisioning{
+		Enabled:           provisioning,
+		RoleName:          "Mahasiswa",
+		StudentIDClaim:    "student_id",
+		ProgramStudyClaim: "program_study",
+		AcademicYearClaim: "academic_year",
+	})
+	return d
+}
+
+// expectLoginState: state "st-1" masih berlaku dengan nonce "n-1".
+func (d oidcTestDeps) expectLoginState(idp *stubIdP) {
+	d.oidcRepo.On("ConsumeLoginState", mock.Anything, utils.HashOpaqueToken("st-1")).Return(&models.OIDCLoginState{
+		CodeVerifier: idp.verifier,
+		Nonce:        "n-1",
+	}, nil)
+}
+
+// expectSession: user berhasil login dan sesi dibuat.
+func (d oidcTestDeps) expectSession(user *models.User) {
+	d.userRepo.On("GetByID", user.ID).Return(user, nil)
+	d.userRepo.On("GetByUsername", user.Username).Return(user, "Mahasiswa", nil)
+	d.userRepo.On("GetPermissionsByRoleID", user.RoleID).Return([]string{"achievement:create"}, nil)
+	d.sessions.On("Create", mock.Anything, mock.Anything).Return(nil)
+}
+
+func oidcCallback(svc *service.OIDCService, query string) (int, map[string]interface{}) {
+	app := fiber.New()
+	app.Get("/auth/oidc/callback", svc.OIDCCallback)
+	resp, _ := app.Test(httptest.NewRequest("GET", "/auth/oidc/callback?"+query, nil), -1)
+
+	var out map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+// --- TEST CASES ---
+
+func TestOIDCLogin(t *testing.T) {
+	t.Run("Success: Redirect carries PKCE challenge and hashed state is stored", func(t *testing.T) {
+		idp := newStubIdP(t)
+		d := setupOIDCServiceTest(idp, false)
+		app := fiber.New()
+		app.Get("/auth/oidc/login", d.svc.OIDCLogin)
+
+		var stored *models.OIDCLoginState
+		d.oidcRepo.On("SaveLoginState", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.OIDCLoginState)
+		}).Return(nil)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/auth/oidc/login", nil), -1)
+
+		assert.Equal(t, 302, resp.StatusCode)
+		location, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "/authorize", location.Path)
+
+		q := location.Query()
+		sum := sha256.Sum256([]byte(stored.CodeVerifier))
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(sum[:]), q.Get("code_challenge"))
+		assert.Equal(t, "S256", q.Get("code_challenge_method"))
+		assert.Equal(t, stored.Nonce, q.Get("nonce"))
+		assert.Equal(t, utils.HashOpaqueToken(q.Get("state")), stored.StateHash)
+		assert.True(t, stored.ExpiresAt.After(time.Now()))
+	})
+
+	t.Run("Error: SSO not configured", func(t *testing.T) {
+		svc := service.NewOIDCService(nil, new(mocks.MockOIDCRepo), nil, nil, time.Minute, service.OIDCProvisioning{})
+		app := fiber.New()
+		app.Get("/auth/oidc/login", svc.OIDCLogin)
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/auth/oidc/login", nil), -1)
+
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+}
+
+func TestOIDCCallback(t *testing.T) {
+	t.Run("Success: Linked identity logs in", func(t *testing.T) {
+		idp := newStubIdP(t)
+		idp.claims = jwt.MapClaims{"sub": "idp-user-1", "nonce": "n-1"}
+		d := setupOIDCServiceTest(idp, false)
+		user := &models.User{ID: uuid.New(), Username: "budi", RoleID: uuid.New(), IsActive: true}
+
+		d.expectLoginState(idp)
+		d.oidcRepo.On("FindUserByIdentity", mock.Anything, idp.server.URL, "idp-user-1").Return(user.ID, nil)
+		d.oidcRepo.On("TouchIdentity", mock.Anything, idp.server.URL, "idp-user-1").Return(nil)
+		d.expectSession(user)
+
+		status, body := oidcCallback(d.svc, "code=good-code&state=st-1")
+
+		assert.Equal(t, 200, status)
+		assert.NotEmpty(t, body["token"])
+		assert.NotEmpty(t, body["refreshToken"])
+		d.sessions.AssertExpectations(t)
+	})
+
+	t.Run("Success: Verified email links existing account", func(t *testing.T) {
+		idp := newStubIdP(t)
+		idp.claims = jwt.MapClaims{"sub": "idp-user-2", "nonce": "n-1", "email": "budi@univ.ac.id", "email_verified": true}
+		d := setupOIDCServiceTest(idp, false)
+		user := &models.User{ID: uuid.New(), Username: "budi", RoleID: uuid.New(), IsActive: true}
+
+		d.expectLoginState(idp)
+		d.oidcRepo.On("FindUserByIdentity", mock.Anything, mock.Anything, mock.Anything).Return(uuid.Nil, nil)
+		d.oidcRepo.On("FindUserByEmail", mock.Anything, "budi@univ.ac.id").Return(user.ID, nil)
+		d.oidcRepo.On("LinkIdentity", mock.Anything, mock.MatchedBy(func(i *models.UserIdentity) bool {
+			return i.UserID == user.ID && i.Subject == "idp-user-2" && i.Issuer == idp.server.URL
+		})).Return(nil)
+		d.expectSession(user)
+
+		status, _ := oidcCallback(d.svc, "code=good-code&state=st-1")
+
+		assert.Equal(t, 200, status)
+		d.oidcRepo.AssertExpectations(t)
+	})
+
+	t.Run("Success: Unknown student is provisioned from claims", func(t *testing.T) {
+		idp := newStubIdP(t)
+		idp.claims = jwt.MapClaims{
+			"sub": "idp-user-3", "nonce": "n-1", "email": "siti@univ.ac.id", "email_verified": "true",
+			"name": "Siti Aminah", "preferred_username": "siti",
+			"student_id": "2201001", "program_study": "Informatika", "academic_year": "2022",
+		}
+		d := setupOIDCServiceTest(idp, true)
+		roleID := uuid.New()
+
+		d.expectLoginState(idp)
+		d.oidcRepo.On("FindUserByIdentity", mock.Anything, mock.Anything, mock.Anything).Return(uuid.Nil, nil)
+		d.oidcRepo.On("FindUserByEmail", mock.Anything, "siti@univ.ac.id").Return(uuid.Nil, nil)
+		d.roleRepo.On("GetAllRoles").Return([]models.Roles{{ID: uuid.New(), Name: "Admin"}, {ID: roleID, Name: "Mahasiswa"}}, nil)
+		d.roleRepo.On("GetRoleByID", roleID).Return(&models.Roles{ID: roleID, Name: "Mahasiswa"}, nil)
+		d.roleRepo.On("GetPermissionsByRole", roleID).Return([]models.Permission{{Name: "achievement:create"}}, nil)
+		d.adminRepo.On("FindTakenCredentials", "siti", "siti@univ.ac.id").Return(true, false, nil)
+
+		var created *models.User
+		d.adminRepo.On("CreateUserWithProfile", mock.Anything, mock.MatchedBy(func(s *models.Student) bool {
+			return s != nil && s.StudentID == "2201001" && s.ProgramStudy == "Informatika" && s.AcademicYear == "2022"
+		}), (*models.Lecturer)(nil)).Run(func(args mock.Arguments) {
+			created = args.Get(0).(*models.User)
+			d.expectSession(created)
+		}).Return(nil)
+		d.oidcRepo.On("LinkIdentity", mock.Anything, mock.Anything).Return(nil)
+
+		status, body := oidcCallback(d.svc, "code=good-code&state=st-1")
+
+		require.Equal(t, 200, status, body)
+		assert.Equal(t, roleID, created.RoleID)
+		assert.Equal(t, "Siti Aminah", created.FullName)
+		// Username "siti" sudah dipakai, jadi diberi akhiran.
+		assert.Contains(t, created.Username, "siti.")
+		assert.NotEmpty(t, created.PasswordHash)
+	})
+
+	t.Run("Error: Unknown user without provisioning", func(t *testing.T) {
+		idp := newStubIdP(t)
+		idp.claims = jwt.MapClaims{"sub": "idp-user-4", "nonce": "n-1", "email": "tamu@univ.ac.id", "email_verified": true}
+		d := setupOIDCServiceTest(idp, false)
+
+		d.expectLoginState(idp)
+		d.oidcRepo.On("FindUserByIdentity", mock.Anything, mock.Anything, mock.Anything).Return(uuid.Nil, nil)
+		d.oidcRepo.On("FindUserByEmail", mock.Anything, "tamu@univ.ac.id").Return(uuid.Nil, nil)
+
+		status, _ := oidcCallback(d.svc, "code=good-code&state=st-1")
+
+		assert.Equal(t, 403, status)
+		d.sessions.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Unverified email is not matched", func(t *testing.T) {
+		idp := newStubIdP(t)
+		idp.claims = jwt.MapClaims{"sub": "idp-user-5", "nonce": "n-1", "email": "budi@univ.ac.id", "email_verified": false}
+		d := setupOIDCServiceTest(idp, true)
+
+		d.expectLoginState(idp)
+		d.oidcRepo.On("FindUserByIdentity", mock.Anything, mock.Anything, mock.Anything).Return(uuid.Nil, nil)
+
+		status, _ := oidcCallback(d.svc, "code=good-code&state=st-1")
+
+		assert.Equal(t, 403, status)
+		d.oidcRepo.AssertNotCalled(t, "FindUserByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Nonce mismatch", func(t *testing.T) {
+		idp := newStubIdP(t)
+		idp.claims = jwt.MapClaims{"sub": "idp-user-1", "nonce": "other"}
+		d := setupOIDCServiceTest(idp, false)
+
+		d.expectLoginState(idp)
+
+		status, _ := oidcCallback(d.svc, "code=good-code&state=st-1")
+
+		assert.Equal(t, 401, status)
+	})
+
+	t.Run("Error: Used or unknown state", func(t *testing.T) {
+		idp := newStubIdP(t)
+		d := setupOIDCServiceTest(idp, false)
+
+		d.oidcRepo.On("ConsumeLoginState", mock.Anything, mock.Anything).Return(nil, repo.ErrOIDCStateInvalid)
+
+		status, _ := oidcCallback(d.svc, "code=good-code&state=forged")
+
+		assert.Equal(t, 401, status)
+	})
+}
@@ -0,0 +1,169 @@
+package service_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	models "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	repo "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/postgresql"
+	"student-performance-report/utils"
+)
+
+// --- SETUP HELPERS ---
+
+func setupPasswordServiceTest() (*service.PasswordService, *mocks.MockPasswordRepo, *mocks.MockAdminRepo) {
+	mockPasswordRepo := new(mocks.MockPasswordRepo)
+	mockAdminRepo := new(mocks.MockAdminRepo)
+	svc := service.NewPasswordService(mockPasswordRepo, mockAdminRepo, time.Hour)
+	return svc, mockPasswordRepo, mockAdminRepo
+}
+
+func sendJSON(app *fiber.App, method, url string, payload interface{}) (int, map[string]interface{}) {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req, -1)
+
+	var out map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+// --- TEST CASES ---
+
+func TestChangePassword(t *testing.T) {
+	currentHash, _ := utils.HashPassword("OldPass123")
+
+	t.Run("Success: Change password revokes sessions", func(t *testing.T) {
+		svc, mockPasswordRepo, _ := setupPasswordServiceTest()
+		userID := uuid.New()
+		app := setupApp("mahasiswa", userID)
+		app.Put("/auth/password", svc.ChangePassword)
+
+		mockPasswordRepo.On("GetPasswordHash", mock.Anything, userID).Return(currentHash, nil)
+		mockPasswordRepo.On("UpdatePassword", mock.Anything, userID, mock.MatchedBy(func(h string) bool {
+			return utils.CheckPasswordHash("NewPass456", h)
+		})).Return(nil)
+
+		status, _ := sendJSON(app, "PUT", "/auth/password", models.ChangePasswordRequest{
+			CurrentPassword: "OldPass123",
+			NewPassword:     "NewPass456",
+		})
+
+		assert.Equal(t, 200, status)
+		mockPasswordRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error: Wrong current password", func(t *testing.T) {
+		svc, mockPasswordRepo, _ := setupPasswordServiceTest()
+		userID := uuid.New()
+		app := setupApp("mahasiswa", userID)
+		app.Put("/auth/password", svc.ChangePassword)
+
+		mockPasswordRepo.On("GetPasswordHash", mock.Anything, userID).Return(currentHash, nil)
+
+		status, _ := sendJSON(app, "PUT", "/auth/password", models.ChangePasswordRequest{
+			CurrentPassword: "Salah123",
+			NewPassword:     "NewPass456",
+		})
+
+		assert.Equal(t, 400, status)
+		mockPasswordRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: New password violates policy", func(t *testing.T) {
+		svc, mockPasswordRepo, _ := setupPasswordServiceTest()
+		userID := uuid.New()
+		app := setupApp("mahasiswa", userID)
+		app.Put("/auth/password", svc.ChangePassword)
+
+		mockPasswordRepo.On("GetPasswordHash", mock.Anything, userID).Return(currentHash, nil)
+
+		status, _ := sendJSON(app, "PUT", "/auth/password", models.ChangePasswordRequest{
+			CurrentPassword: "OldPass123",
+			NewPassword:     "lemah",
+		})
+
+		assert.Equal(t, 400, status)
+		mockPasswordRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestIssueResetToken(t *testing.T) {
+	t.Run("Success: Admin issues token, only hash is stored", func(t *testing.T) {
+		svc, mockPasswordRepo, mockAdminRepo := setupPasswordServiceTest()
+		adminID := uuid.New()
+		targetID := uuid.New()
+		app := setupApp("admin", adminID)
+		app.Post("/users/:id/reset-password", svc.IssueResetToken)
+
+		mockAdminRepo.On("GetUserByID", targetID).Return(&models.User{ID: targetID, IsActive: true}, nil)
+
+		var stored *models.PasswordResetToken
+		mockPasswordRepo.On("CreateResetToken", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.PasswordResetToken)
+		}).Return(nil)
+
+		status, body := sendJSON(app, "POST", "/users/"+targetID.String()+"/reset-password", nil)
+
+		assert.Equal(t, 201, status)
+		token, _ := body["token"].(string)
+		assert.NotEmpty(t, token)
+		assert.Equal(t, utils.HashOpaqueToken(token), stored.TokenHash)
+		assert.NotEqual(t, token, stored.TokenHash)
+		assert.Equal(t, adminID, *stored.CreatedBy)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), stored.ExpiresAt, time.Minute)
+	})
+
+	t.Run("Error: User not found", func(t *testing.T) {
+		svc, _, mockAdminRepo := setupPasswordServiceTest()
+		targetID := uuid.New()
+		app := setupApp("admin", uuid.New())
+		app.Post("/users/:id/reset-password", svc.IssueResetToken)
+
+		mockAdminRepo.On("GetUserByID", targetID).Return(nil, assert.AnError)
+
+		status, _ := sendJSON(app, "POST", "/users/"+targetID.String()+"/reset-password", nil)
+
+		assert.Equal(t, 404, status)
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	t.Run("Success: Valid token resets password", func(t *testing.T) {
+		svc, mockPasswordRepo, _ := setupPasswordServiceTest()
+		app := fiber.New()
+		app.Post("/auth/reset-password", svc.ResetPassword)
+
+		token := "prt_example-token"
+		mockPasswordRepo.On("ResetPassword", mock.Anything, utils.HashOpaqueToken(token), mock.MatchedBy(func(h string) bool {
+			return utils.CheckPasswordHash("NewPass456", h)
+		})).Return(uuid.New(), nil)
+
+		status, _ := sendJSON(app, "POST", "/auth/reset-password", models.ResetPasswordRequest{Token: token, NewPassword: "NewPass456"})
+
+		assert.Equal(t, 200, status)
+		mockPasswordRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error: Used or expired token", func(t *testing.T) {
+		svc, mockPasswordRepo, _ := setupPasswordServiceTest()
+		app := fiber.New()
+		app.Post("/auth/reset-password", svc.ResetPassword)
+
+		mockPasswordRepo.On("ResetPassword", mock.Anything, mock.Anything, mock.Anything).Return(uuid.Nil, repo.ErrResetTokenInvalid)
+
+		status, body := sendJSON(app, "POST", "/auth/reset-password", models.ResetPasswordRequest{Token: "prt_used", NewPassword: "NewPass456"})
+
+		assert.Equal(t, 400, status)
+		assert.Equal(t, repo.ErrResetTokenInvalid.Error(), body["error"])
+	})
+}
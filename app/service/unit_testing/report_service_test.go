@@ -12,7 +12,9 @@ import (
 	modelMongo "student-performance-report/app/models/mongodb"
 	models "student-performance-report/app/models/postgresql"
 	"student-performance-report/app/repository/mocks"
+	repo "student-performance-report/app/repository/postgresql"
 	"student-performance-report/app/service/mongodb"
+	"student-performance-report/app/service/policy"
 )
 
 // --- SETUP HELPERS ---
@@ -22,13 +24,21 @@ func setupReportServiceTest() (*service.ReportService, *mocks.MockAchievementRep
 	mockMongo := new(mocks.MockAchievementRepo)
 	mockPg := new(mocks.MockStudentRepo)
 
-	svc := service.NewReportService(mockMongo, mockPg)
+	access := policy.NewAccessPolicy(new(mocks.MockAchievementPgRepo), new(mocks.MockLecturerRepo), mockPg)
+	svc := service.NewReportService(mockMongo, mockPg, access)
 
 	return svc, mockMongo, mockPg
 }
 
+// setupReportApp menjalankan handler sebagai admin.
 func setupReportApp() *fiber.App {
-	return fiber.New()
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", uuid.New())
+		c.Locals("permissions", []string{policy.AdminPermission})
+		return c.Next()
+	})
+	return app
 }
 
 // --- TEST CASES ---
@@ -152,15 +162,13 @@ func TestGetStudentReport(t *testing.T) {
 		app := setupReportApp()
 		invalidID := "bukan-uuid"
 
-		// Mock Mongo tetap dipanggil karena urutan kode di service: Mongo dulu -> baru Parse UUID
-		mockStats := &modelMongo.StudentStatistics{}
-		mockMongo.On("GetStudentStats", mock.Anything, invalidID).Return(mockStats, nil)
-
 		app.Get("/report/:id", svc.GetStudentReport)
 		req := httptest.NewRequest("GET", "/report/"+invalidID, nil)
 		resp, _ := app.Test(req)
 
+		// UUID divalidasi sebelum cek akses, jadi Mongo tidak pernah dipanggil
 		assert.Equal(t, 400, resp.StatusCode)
+		mockMongo.AssertNotCalled(t, "GetStudentStats", mock.Anything, mock.Anything)
 	})
 
 	t.Run("Success: Student Not Found in Postgres (Return Stats without Name)", func(t *testing.T) {
@@ -172,7 +180,7 @@ func TestGetStudentReport(t *testing.T) {
 
 		mockMongo.On("GetStudentStats", mock.Anything, targetID.String()).Return(mockStats, nil)
 		// Mock Postgres return error/not found
-		mockPg.On("GetStudentByID", mock.Anything, targetID).Return(nil, errors.New("student not found"))
+		mockPg.On("GetStudentByID", mock.Anything, targetID).Return(nil, repo.ErrStudentNotFound)
 
 		app.Get("/report/:id", svc.GetStudentReport)
 		req := httptest.NewRequest("GET", "/report/"+targetID.String(), nil)
@@ -0,0 +1,196 @@
+package service_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	models "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	"student-performance-report/app/service/postgresql"
+)
+
+// --- SETUP HELPERS ---
+
+func setupRoleServiceTest() (*service.RoleService, *mocks.MockRoleRepo, *mocks.MockPermissionRepo) {
+	mockRoleRepo := new(mocks.MockRoleRepo)
+	mockPermRepo := new(mocks.MockPermissionRepo)
+	svc := service.NewRoleService(mockRoleRepo, mockPermRepo)
+	return svc, mockRoleRepo, mockPermRepo
+}
+
+func setupAdminPermApp() *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("permissions", []string{"manage:users"})
+		return c.Next()
+	})
+	return app
+}
+
+// --- TEST CASES ---
+
+func TestDeleteRole(t *testing.T) {
+	t.Run("Conflict: Role still has users", func(t *testing.T) {
+		svc, mockRoleRepo, _ := setupRoleServiceTest()
+		app := setupAdminPermApp()
+		roleID := uuid.New()
+
+		mockRoleRepo.On("GetRoleByID", roleID).Return(&models.Roles{ID: roleID, Name: "mahasiswa"}, nil)
+		mockRoleRepo.On("CountUsersByRole", roleID).Return(12, nil)
+
+		app.Delete("/roles/:id", svc.DeleteRole)
+
+		req := httptest.NewRequest("DELETE", "/roles/"+roleID.String(), nil)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 409, resp.StatusCode)
+		mockRoleRepo.AssertNotCalled(t, "DeleteRole", roleID)
+	})
+
+	t.Run("Success: Unused role deleted", func(t *testing.T) {
+		svc, mockRoleRepo, _ := setupRoleServiceTest()
+		app := setupAdminPermApp()
+		roleID := uuid.New()
+
+		mockRoleRepo.On("GetRoleByID", roleID).Return(&models.Roles{ID: roleID, Name: "tamu"}, nil)
+		mockRoleRepo.On("CountUsersByRole", roleID).Return(0, nil)
+		mockRoleRepo.On("GetPermissionsByRole", roleID).Return([]models.Permission{{Name: "achievement:read"}}, nil)
+		mockRoleRepo.On("DeleteRole", roleID).Return(nil)
+
+		app.Delete("/roles/:id", svc.DeleteRole)
+
+		req := httptest.NewRequest("DELETE", "/roles/"+roleID.String(), nil)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 200, resp.StatusCode)
+		mockRoleRepo.AssertExpectations(t)
+	})
+}
+
+func TestDetachPermission(t *testing.T) {
+	t.Run("Conflict: Removing manage:users from last admin role", func(t *testing.T) {
+		svc, mockRoleRepo, mockPermRepo := setupRoleServiceTest()
+		app := setupAdminPermApp()
+		roleID := uuid.New()
+		permID := uuid.New()
+
+		mockPermRepo.On("GetPermissionByID", permID).Return(&models.Permission{ID: permID, Name: "manage:users"}, nil)
+		mockRoleRepo.On("GetPermissionsByRole", roleID).Return([]models.Permission{{ID: permID, Name: "manage:users"}}, nil)
+		mockRoleRepo.On("CountAssignedRolesWithPermission", "manage:users", roleID).Return(0, nil)
+
+		app.Delete("/roles/:id/permissions/:permissionId", svc.DetachPermission)
+
+		req := httptest.NewRequest("DELETE", "/roles/"+roleID.String()+"/permissions/"+permID.String(), nil)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 409, resp.StatusCode)
+		mockRoleRepo.AssertNotCalled(t, "DetachPermission", roleID, permID)
+	})
+
+	t.Run("Conflict: Another admin role without users does not count", func(t *testing.T) {
+		svc, mockRoleRepo, mockPermRepo := setupRoleServiceTest()
+		app := setupAdminPermApp()
+		roleID := uuid.New()
+		permID := uuid.New()
+
+		// "Backup Admin" juga memegang manage:users tetapi tidak punya user
+		// aktif, sehingga tidak ikut dihitung oleh repository.
+		mockPermRepo.On("GetPermissionByID", permID).Return(&models.Permission{ID: permID, Name: "manage:users"}, nil)
+		mockRoleRepo.On("GetPermissionsByRole", roleID).Return([]models.Permission{{ID: permID, Name: "manage:users"}}, nil)
+		mockRoleRepo.On("CountAssignedRolesWithPermission", "manage:users", roleID).Return(0, nil)
+
+		app.Delete("/roles/:id/permissions/:permissionId", svc.DetachPermission)
+
+		req := httptest.NewRequest("DELETE", "/roles/"+roleID.String()+"/permissions/"+permID.String(), nil)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 409, resp.StatusCode)
+		mockRoleRepo.AssertNotCalled(t, "DetachPermission", roleID, permID)
+	})
+
+	t.Run("Success: Another admin role with users remains", func(t *testing.T) {
+		svc, mockRoleRepo, mockPermRepo := setupRoleServiceTest()
+		app := setupAdminPermApp()
+		roleID := uuid.New()
+		permID := uuid.New()
+
+		mockPermRepo.On("GetPermissionByID", permID).Return(&models.Permission{ID: permID, Name: "manage:users"}, nil)
+		mockRoleRepo.On("GetPermissionsByRole", roleID).Return([]models.Permission{{ID: permID, Name: "manage:users"}}, nil)
+		mockRoleRepo.On("CountAssignedRolesWithPermission", "manage:users", roleID).Return(1, nil)
+		mockRoleRepo.On("DetachPermission", roleID, permID).Return(nil)
+
+		app.Delete("/roles/:id/permissions/:permissionId", svc.DetachPermission)
+
+		req := httptest.NewRequest("DELETE", "/roles/"+roleID.String()+"/permissions/"+permID.String(), nil)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 200, resp.StatusCode)
+		mockRoleRepo.AssertExpectations(t)
+	})
+
+	t.Run("Success: Detach regular permission", func(t *testing.T) {
+		svc, mockRoleRepo, mockPermRepo := setupRoleServiceTest()
+		app := setupAdminPermApp()
+		roleID := uuid.New()
+		permID := uuid.New()
+
+		mockPermRepo.On("GetPermissionByID", permID).Return(&models.Permission{ID: permID, Name: "achievement:verify"}, nil)
+		mockRoleRepo.On("DetachPermission", roleID, permID).Return(nil)
+
+		app.Delete("/roles/:id/permissions/:permissionId", svc.DetachPermission)
+
+		req := httptest.NewRequest("DELETE", "/roles/"+roleID.String()+"/permissions/"+permID.String(), nil)
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 200, resp.StatusCode)
+		mockRoleRepo.AssertExpectations(t)
+	})
+}
+
+func TestCreatePermission(t *testing.T) {
+	t.Run("Success: Resource and action derived from name", func(t *testing.T) {
+		svc, _, mockPermRepo := setupRoleServiceTest()
+		app := setupAdminPermApp()
+
+		mockPermRepo.On("CreatePermission", mock.MatchedBy(func(p *models.Permission) bool {
+			return p.Name == "report:export" && p.Resource == "report" && p.Action == "export"
+		})).Return(nil)
+
+		app.Post("/permissions", svc.CreatePermission)
+
+		body, _ := json.Marshal(map[string]string{"name": "report:export"})
+		req := httptest.NewRequest("POST", "/permissions", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 201, resp.StatusCode)
+		mockPermRepo.AssertExpectations(t)
+	})
+}
+
+func TestAssignRole(t *testing.T) {
+	t.Run("Error: Unknown role", func(t *testing.T) {
+		svc, mockAdminRepo, _, mockRoleRepo := setupAdminTestWithRoles()
+		app := setupAdminPermApp()
+		userID := uuid.New()
+		roleID := uuid.New()
+
+		mockRoleRepo.On("GetRoleByID", roleID).Return(nil, errors.New("role not found"))
+
+		app.Put("/users/:id/role", svc.AssignRole)
+
+		body, _ := json.Marshal(map[string]string{"roleId": roleID.String()})
+		req := httptest.NewRequest("PUT", "/users/"+userID.String()+"/role", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+
+		assert.Equal(t, 404, resp.StatusCode)
+		mockAdminRepo.AssertNotCalled(t, "AssignRole", userID, roleID)
+	})
+}
@@ -0,0 +1,196 @@
+package service_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	modelMongo "student-performance-report/app/models/mongodb"
+	modelPg "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+	repoPg "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/postgresql"
+	"student-performance-report/app/service/scoring"
+)
+
+// --- SETUP HELPERS ---
+
+// testRubric: juara 1 nasional lebih spesifik daripada lomba nasional biasa.
+func testRubric() *modelPg.ScoringRubric {
+	return &modelPg.ScoringRubric{
+		ID:       uuid.New(),
+		Version:  3,
+		IsActive: true,
+		Rules: []modelPg.RubricRule{
+			{AchievementType: "competition", Points: 20},
+			{AchievementType: "competition", When: map[string]string{"competitionLevel": "national"}, Points: 100},
+			{AchievementType: "competition", When: map[string]string{"competitionLevel": "national", "rank": "1"}, Points: 150},
+			{AchievementType: "publication", When: map[string]string{"publicationType": "journal"}, Points: 120},
+		},
+	}
+}
+
+func nationalWinner() *modelMongo.Achievement {
+	return &modelMongo.Achievement{
+		AchievementType: "competition",
+		Details:         modelMongo.AchievementDetails{CompetitionLevel: "National", Rank: 1},
+	}
+}
+
+// --- TEST CASES ---
+
+func TestScoringSuggest(t *testing.T) {
+	t.Run("Success: Most specific matching rule wins", func(t *testing.T) {
+		sg := scoring.Suggest(testRubric(), nationalWinner())
+
+		require.NotNil(t, sg)
+		assert.Equal(t, 150, sg.Points)
+		assert.Equal(t, 3, sg.RubricVersion)
+	})
+
+	t.Run("Success: Falls back to the type-wide rule", func(t *testing.T) {
+		a := &modelMongo.Achievement{AchievementType: "competition", Details: modelMongo.AchievementDetails{CompetitionLevel: "local"}}
+
+		sg := scoring.Suggest(testRubric(), a)
+
+		require.NotNil(t, sg)
+		assert.Equal(t, 20, sg.Points)
+	})
+
+	t.Run("Success: No rule for the type gives no suggestion", func(t *testing.T) {
+		assert.Nil(t, scoring.Suggest(testRubric(), &modelMongo.Achievement{AchievementType: "organization"}))
+		assert.Nil(t, scoring.Suggest(nil, nationalWinner()))
+	})
+
+	t.Run("Error: Unknown detail field is rejected", func(t *testing.T) {
+		err := scoring.Validate([]modelPg.RubricRule{{AchievementType: "competition", When: map[string]string{"level": "national"}, Points: 10}})
+
+		assert.ErrorContains(t, err, `"level"`)
+	})
+}
+
+func TestVerifyWithRubric(t *testing.T) {
+	setup := func() (*mocks.MockAchievementMongoRepo, *mocks.MockAchievementPgRepo, func(points interface{}) (int, map[string]interface{}), uuid.UUID, uuid.UUID) {
+		svc, mockMongo, mockPg, mockLecturer, mockStudent := setupAchievementServiceWithRubric(testRubric())
+		lecturerUserID, lecturerID := uuid.New(), uuid.New()
+		achievementID, studentID := uuid.New(), uuid.New()
+		app := setupAchievementApp("dosen_wali", lecturerUserID)
+		app.Post("/achievements/:id/verify", svc.VerifyAchievement)
+
+		ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, MongoAchievementID: "mongo_obj_id_123", Status: "submitted"}
+		mockLecturer.On("GetLecturerByUserID", mock.Anything, lecturerUserID).Return(lecturerID, nil)
+		mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+		mockStudent.On("GetStudentByID", mock.Anything, studentID).Return(&modelPg.Student{ID: studentID, AdvisorID: &lecturerID}, nil)
+		mockMongo.On("FindOne", mock.Anything, "mongo_obj_id_123").Return(nationalWinner(), nil)
+
+		verify := func(points interface{}) (int, map[string]interface{}) {
+			body := map[string]interface{}{}
+			if points != nil {
+				body["points"] = points
+			}
+			return sendJSON(app, "POST", "/achievements/"+achievementID.String()+"/verify", body)
+		}
+		return mockMongo, mockPg, verify, achievementID, lecturerUserID
+	}
+
+	t.Run("Success: Without points the suggestion is awarded", func(t *testing.T) {
+		mockMongo, mockPg, verify, achievementID, _ := setup()
+
+		mockPg.On("TransitionStatus", mock.Anything, achievementID, "submitted", "verified", mock.MatchedBy(func(ch modelPg.StatusChange) bool {
+			return *ch.Points == 150 && ch.Suggestion != nil && ch.Suggestion.RubricVersion == 3
+		})).Return(nil)
+		mockMongo.On("UpdatePoints", mock.Anything, "mongo_obj_id_123", 150).Return(nil)
+
+		status, body := verify(nil)
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(150), body["points"])
+		assert.Equal(t, false, body["overridden"])
+		mockPg.AssertExpectations(t)
+	})
+
+	t.Run("Success: Different points are recorded as an override", func(t *testing.T) {
+		mockMongo, mockPg, verify, achievementID, _ := setup()
+
+		mockPg.On("TransitionStatus", mock.Anything, achievementID, "submitted", "verified", mock.MatchedBy(func(ch modelPg.StatusChange) bool {
+			return *ch.Points == 120 && ch.Suggestion.Points == 150
+		})).Return(nil)
+		mockMongo.On("UpdatePoints", mock.Anything, "mongo_obj_id_123", 120).Return(nil)
+
+		status, body := verify(120)
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, true, body["overridden"])
+		assert.Equal(t, float64(150), body["suggestedPoints"])
+	})
+}
+
+func TestGetAchievementDetailSuggestedScore(t *testing.T) {
+	svc, mockMongo, mockPg, _, _ := setupAchievementServiceWithRubric(testRubric())
+	userID, studentID, achievementID := uuid.New(), uuid.New(), uuid.New()
+	app := setupAchievementApp("mahasiswa", userID)
+	app.Get("/achievements/:id", svc.GetAchievementDetail)
+
+	ref := modelPg.AchievementReference{ID: achievementID, StudentID: studentID, MongoAchievementID: "mongo_obj_id_123", Status: "submitted"}
+	mockPg.On("GetStudentByUserID", mock.Anything, userID).Return(studentID, nil)
+	mockPg.On("GetReferenceByID", mock.Anything, achievementID).Return(ref, nil)
+	mockMongo.On("FindOne", mock.Anything, "mongo_obj_id_123").Return(nationalWinner(), nil)
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/achievements/"+achievementID.String(), nil))
+
+	require.Equal(t, 200, resp.StatusCode)
+	var body struct {
+		SuggestedScore *modelPg.SuggestedScore `json:"suggestedScore"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	require.NotNil(t, body.SuggestedScore)
+	assert.Equal(t, 150, body.SuggestedScore.Points)
+}
+
+func TestRubricService(t *testing.T) {
+	t.Run("Success: Create assigns the next version", func(t *testing.T) {
+		mockRubric := new(mocks.MockRubricRepo)
+		svc := service.NewRubricService(mockRubric)
+		app := setupApp("admin", uuid.New())
+		app.Post("/rubrics", svc.CreateRubric)
+
+		mockRubric.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*modelPg.ScoringRubric).Version = 4
+		}).Return(nil)
+
+		status, body := sendJSON(app, "POST", "/rubrics", modelPg.RubricRequest{Name: "2026/2027", Rules: testRubric().Rules})
+
+		assert.Equal(t, 201, status)
+		assert.Equal(t, float64(4), body["version"])
+		assert.Equal(t, false, body["isActive"])
+	})
+
+	t.Run("Error: Rule without points", func(t *testing.T) {
+		mockRubric := new(mocks.MockRubricRepo)
+		svc := service.NewRubricService(mockRubric)
+		app := setupApp("admin", uuid.New())
+		app.Post("/rubrics", svc.CreateRubric)
+
+		status, _ := sendJSON(app, "POST", "/rubrics", modelPg.RubricRequest{Name: "salah", Rules: []modelPg.RubricRule{{AchievementType: "competition"}}})
+
+		assert.Equal(t, 400, status)
+		mockRubric.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error: Version that has been active is frozen", func(t *testing.T) {
+		mockRubric := new(mocks.MockRubricRepo)
+		svc := service.NewRubricService(mockRubric)
+		app := setupApp("admin", uuid.New())
+		app.Put("/rubrics/:id", svc.UpdateRubric)
+
+		id := uuid.New()
+		mockRubric.On("Update", mock.Anything, id, "2025/2026", mock.Anything).Return(repoPg.ErrRubricFrozen)
+
+		status, _ := sendJSON(app, "PUT", "/rubrics/"+id.String(), modelPg.RubricRequest{Name: "2025/2026", Rules: testRubric().Rules})
+
+		assert.Equal(t, 409, status)
+	})
+}
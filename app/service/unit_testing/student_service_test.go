@@ -13,7 +13,9 @@ import (
 	"github.com/stretchr/testify/mock"
 	models "student-performance-report/app/models/postgresql"
 	"student-performance-report/app/service/postgresql"
+	"student-performance-report/app/service/policy"
 	"student-performance-report/app/repository/mocks"
+	repo "student-performance-report/app/repository/postgresql"
 	modelMongo "student-performance-report/app/models/mongodb"
 )
 
@@ -22,13 +24,21 @@ import (
 func setupStudentServiceTest() (*service.StudentService, *mocks.MockStudentRepo, *mocks.MockAchievementRepo) {
 	mockStudentRepo := new(mocks.MockStudentRepo)
 	mockAchievementRepo := new(mocks.MockAchievementRepo)
-	svc := service.NewStudentService(mockStudentRepo, mockAchievementRepo)
+	access := policy.NewAccessPolicy(new(mocks.MockAchievementPgRepo), new(mocks.MockLecturerRepo), mockStudentRepo)
+	svc := service.NewStudentService(mockStudentRepo, mockAchievementRepo, access)
 
 	return svc, mockStudentRepo, mockAchievementRepo
 }
 
+// setupStudentApp menjalankan handler sebagai admin; aturan per-role
+// untuk akses data mahasiswa diuji di access_policy_test.go.
 func setupStudentApp() *fiber.App {
 	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", uuid.New())
+		c.Locals("permissions", []string{policy.AdminPermission})
+		return c.Next()
+	})
 	return app
 }
 
@@ -105,7 +115,7 @@ func TestGetStudentByID(t *testing.T) {
 		app := setupStudentApp()
 
 		targetID := uuid.New()
-		mockStudentRepo.On("GetStudentByID", mock.Anything, targetID).Return(nil, errors.New("student not found"))
+		mockStudentRepo.On("GetStudentByID", mock.Anything, targetID).Return(nil, repo.ErrStudentNotFound)
 
 		app.Get("/students/:id", svc.GetStudentByID)
 
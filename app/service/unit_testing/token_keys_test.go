@@ -0,0 +1,186 @@
+package service_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	models "student-performance-report/app/models/postgresql"
+	"student-performance-report/config"
+	"student-performance-report/route"
+	"student-performance-report/utils"
+)
+
+// --- SETUP HELPERS ---
+
+var testJWTConfig = config.JWTConfig{Secret: []byte("test-refresh-secret"), TTLHours: 1, Issuer: "student-performance-app", Audience: "student-performance-api"}
+
+// TestMain memasang kunci sementara dan secret refresh token untuk semua test.
+func TestMain(m *testing.M) {
+	key, err := utils.GenerateEd25519Key("test")
+	if err != nil {
+		panic(err)
+	}
+	utils.UseTokenKeys(utils.NewKeySet(key), testJWTConfig)
+	os.Exit(m.Run())
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600))
+}
+
+// restoreEphemeralKeys mengembalikan kunci global agar test lain tidak terpengaruh.
+func restoreEphemeralKeys(t *testing.T) {
+	key, err := utils.GenerateEd25519Key("test")
+	require.NoError(t, err)
+	utils.UseTokenKeys(utils.NewKeySet(key), testJWTConfig)
+}
+
+// --- TEST CASES ---
+
+func TestTokenKeys(t *testing.T) {
+	defer restoreEphemeralKeys(t)
+	user := &models.User{ID: uuid.New(), RoleID: uuid.New()}
+
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writePEM(t, filepath.Join(dir, "2025-01.pem"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	writePEM(t, filepath.Join(dir, "2026-01.pem"), "PRIVATE KEY", edDER)
+
+	t.Run("Newest private key signs, with kid header", func(t *testing.T) {
+		ks, err := utils.LoadKeySet(dir, "")
+		require.NoError(t, err)
+		utils.UseTokenKeys(ks, testJWTConfig)
+
+		token, err := utils.GenerateToken(user, "student", uuid.New())
+		require.NoError(t, err)
+
+		parsed, _, _ := jwt.NewParser().ParseUnverified(token, &models.JWTClaims{})
+		assert.Equal(t, "2026-01", parsed.Header["kid"])
+		assert.Equal(t, "EdDSA", parsed.Header["alg"])
+
+		claims, err := utils.ValidateToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, claims.UserID)
+	})
+
+	t.Run("Rotation keeps tokens of retired public keys valid", func(t *testing.T) {
+		ks, err := utils.LoadKeySet(dir, "2025-01")
+		require.NoError(t, err)
+		utils.UseTokenKeys(ks, testJWTConfig)
+		oldToken, _ := utils.GenerateToken(user, "student", uuid.New())
+
+		// Kunci lama tinggal public key saja, kunci baru aktif
+		rotated := t.TempDir()
+		pubDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+		writePEM(t, filepath.Join(rotated, "2025-01.pub.pem"), "PUBLIC KEY", pubDER)
+		writePEM(t, filepath.Join(rotated, "2026-01.pem"), "PRIVATE KEY", edDER)
+		ks, err = utils.LoadKeySet(rotated, "")
+		require.NoError(t, err)
+		utils.UseTokenKeys(ks, testJWTConfig)
+
+		_, err = utils.ValidateToken(oldToken)
+		assert.NoError(t, err)
+
+		// Setelah kunci lama dibuang, tokennya ditolak
+		key, _ := utils.ParseSigningKey("2026-01", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER}))
+		utils.UseTokenKeys(utils.NewKeySet(key), testJWTConfig)
+		_, err = utils.ValidateToken(oldToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("Error: HS256, none and foreign audience are rejected", func(t *testing.T) {
+		ks, _ := utils.LoadKeySet(dir, "")
+		utils.UseTokenKeys(ks, testJWTConfig)
+
+		claims := jwt.RegisteredClaims{
+			Issuer:    "student-performance-app",
+			Audience:  jwt.ClaimStrings{"student-performance-api"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}
+
+		hs := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		hs.Header["kid"] = "2026-01"
+		hsToken, _ := hs.SignedString([]byte("secret"))
+		_, err := utils.ValidateToken(hsToken)
+		assert.Error(t, err)
+
+		none := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+		none.Header["kid"] = "2026-01"
+		noneToken, _ := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		_, err = utils.ValidateToken(noneToken)
+		assert.Error(t, err)
+
+		claims.Audience = jwt.ClaimStrings{"other-service"}
+		ed := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+		ed.Header["kid"] = "2026-01"
+		edToken, _ := ed.SignedString(edKey)
+		_, err = utils.ValidateToken(edToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("JWKS publishes every verification key", func(t *testing.T) {
+		ks, _ := utils.LoadKeySet(dir, "")
+		utils.UseTokenKeys(ks, testJWTConfig)
+
+		app := fiber.New()
+		app.Get("/.well-known/jwks.json", route.GetJWKS)
+		resp, _ := app.Test(httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+
+		var set utils.JWKSet
+		json.NewDecoder(resp.Body).Decode(&set)
+		assert.Equal(t, 200, resp.StatusCode)
+		require.Len(t, set.Keys, 2)
+		assert.Equal(t, "2026-01", set.Keys[0].Kid)
+		assert.Equal(t, "OKP", set.Keys[0].Kty)
+		assert.Equal(t, "RS256", set.Keys[1].Alg)
+		assert.NotEmpty(t, set.Keys[1].N)
+		assert.Equal(t, "AQAB", set.Keys[1].E)
+	})
+}
+
+func TestRefreshTokenSecret(t *testing.T) {
+	defer restoreEphemeralKeys(t)
+
+	t.Run("Error: Startup fails without a refresh secret", func(t *testing.T) {
+		cfg := testJWTConfig
+		cfg.Secret = nil
+		assert.Error(t, utils.InitTokenKeys(cfg))
+	})
+
+	t.Run("Error: Refresh tokens with another algorithm or secret are rejected", func(t *testing.T) {
+		restoreEphemeralKeys(t)
+		user := &models.User{ID: uuid.New()}
+
+		token, err := utils.GenerateRefreshToken(user, uuid.New(), "jti-1")
+		require.NoError(t, err)
+		_, err = utils.ValidateRefreshToken(token)
+		assert.NoError(t, err)
+
+		claims := models.RefreshClaims{UserID: user.ID.String(), RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "student-performance-app",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}}
+		hs512, _ := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString(testJWTConfig.Secret)
+		_, err = utils.ValidateRefreshToken(hs512)
+		assert.Error(t, err)
+
+		other, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret lain"))
+		_, err = utils.ValidateRefreshToken(other)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,246 @@
+package service_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	models "student-performance-report/app/models/postgresql"
+	"student-performance-report/app/repository/mocks"
+)
+
+// --- SETUP HELPERS ---
+
+type importRoles struct {
+	student  uuid.UUID
+	lecturer uuid.UUID
+}
+
+// mockImportRoles mendaftarkan role "mahasiswa" dan "dosen_wali" beserta
+// permission yang menentukan jenis profilnya.
+func mockImportRoles(mockRoleRepo *mocks.MockRoleRepo) importRoles {
+	r := importRoles{student: uuid.New(), lecturer: uuid.New()}
+
+	mockRoleRepo.On("GetAllRoles").Return([]models.Roles{
+		{ID: r.student, Name: "mahasiswa"},
+		{ID: r.lecturer, Name: "dosen_wali"},
+	}, nil)
+	mockRoleRepo.On("GetRoleByID", r.student).Return(&models.Roles{ID: r.student, Name: "mahasiswa"}, nil)
+	mockRoleRepo.On("GetRoleByID", r.lecturer).Return(&models.Roles{ID: r.lecturer, Name: "dosen_wali"}, nil)
+	mockRoleRepo.On("GetPermissionsByRole", r.student).Return([]models.Permission{{Name: "achievement:create"}}, nil)
+	mockRoleRepo.On("GetPermissionsByRole", r.lecturer).Return([]models.Permission{{Name: "achievement:verify"}}, nil)
+	return r
+}
+
+func postImport(app *fiber.App, filename string, content []byte, dryRun bool) (int, models.UserImportResult) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write(content)
+	writer.Close()
+
+	url := "/users/import"
+	if dryRun {
+		url += "?dryRun=true"
+	}
+	req := httptest.NewRequest("POST", url, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, _ := app.Test(req, -1)
+	var result models.UserImportResult
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+// buildXLSX membuat file .xlsx minimal (inline string) berisi rows.
+func buildXLSX(rows [][]string) []byte {
+	sheet := `<?xml version="1.0" encoding="UTF-8"?><worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	for i, row := range rows {
+		sheet += `<row>`
+		for j, v := range row {
+			ref := string(rune('A'+j)) + string(rune('1'+i))
+			sheet += `<c r="` + ref + `" t="inlineStr"><is><t>` + v + `</t></is></c>`
+		}
+		sheet += `</row>`
+	}
+	sheet += `</sheetData></worksheet>`
+	return buildXLSXSheet(sheet)
+}
+
+// buildXLSXSheet membungkus XML worksheet apa adanya menjadi file .xlsx.
+func buildXLSXSheet(sheet string) []byte {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	write := func(name, content string) {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+
+	write("xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`)
+	write("xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`)
+	write("xl/worksheets/sheet1.xml", sheet)
+
+	zw.Close()
+	return buf.Bytes()
+}
+
+// --- TEST CASES ---
+
+func TestImportUsers(t *testing.T) {
+	header := "username,email,full_name,role,password,student_id,program_study,academic_year,advisor_id,lecturer_id,department\n"
+
+	t.Run("Dry-run: returns per-row errors and writes nothing", func(t *testing.T) {
+		svc, mockRepo, _, mockRoleRepo := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+		app.Post("/users/import", svc.ImportUsers)
+		mockImportRoles(mockRoleRepo)
+
+		csv := header +
+			"budi,budi@test.com,Budi,mahasiswa,Rahasia123,NIM001,Informatika,2024,,,\n" +
+			"budi,budi2@test.com,Budi Dua,mahasiswa,Rahasia123,NIM002,Informatika,2024,,,\n" +
+			"sari,sari@test.com,Sari,satpam,Rahasia123,,,,,,\n" +
+			"andi,andi@test.com,Andi,mahasiswa,Rahasia123,,,,,,\n"
+
+		mockRepo.On("ListTakenCredentials", mock.Anything, mock.Anything).Return(map[string]bool{}, map[string]bool{}, nil)
+
+		status, result := postImport(app, "users.csv", []byte(csv), true)
+
+		assert.Equal(t, 200, status)
+		assert.True(t, result.DryRun)
+		assert.False(t, result.Committed)
+		assert.Equal(t, 4, result.Total)
+		assert.Equal(t, 1, result.Valid)
+		assert.Equal(t, 3, result.Invalid)
+		assert.Equal(t, 3, result.Rows[1].Row)
+		assert.Contains(t, result.Rows[1].Errors["username"], "duplicate of row 2")
+		assert.Equal(t, "unknown role", result.Rows[2].Errors["role"])
+		assert.Contains(t, result.Rows[3].Errors, "student_id")
+		assert.Empty(t, result.Rows[0].TemporaryPassword)
+		mockRepo.AssertNotCalled(t, "ImportUsers", mock.Anything)
+	})
+
+	t.Run("Commit: invalid row rejects the whole file", func(t *testing.T) {
+		svc, mockRepo, _, mockRoleRepo := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+		app.Post("/users/import", svc.ImportUsers)
+		mockImportRoles(mockRoleRepo)
+
+		csv := header +
+			"budi,budi@test.com,Budi,mahasiswa,Rahasia123,NIM001,Informatika,2024,,,\n" +
+			"sari,sari@test.com,Sari,mahasiswa,Rahasia123,NIM002,Informatika,2024,,,\n"
+
+		mockRepo.On("ListTakenCredentials", mock.Anything, mock.Anything).
+			Return(map[string]bool{}, map[string]bool{"sari@test.com": true}, nil)
+
+		status, result := postImport(app, "users.csv", []byte(csv), false)
+
+		assert.Equal(t, 422, status)
+		assert.False(t, result.Committed)
+		assert.Equal(t, "already taken", result.Rows[1].Errors["email"])
+		mockRepo.AssertNotCalled(t, "ImportUsers", mock.Anything)
+	})
+
+	t.Run("Commit: students linked to advisors from file and database", func(t *testing.T) {
+		svc, mockRepo, _, mockRoleRepo := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+		app.Post("/users/import", svc.ImportUsers)
+		mockImportRoles(mockRoleRepo)
+
+		existingLecturerID := uuid.New()
+		csv := header +
+			"pak.dedi,dedi@test.com,Dedi,dosen_wali,,,,,,D001,Informatika\n" +
+			"budi,budi@test.com,Budi,mahasiswa,,NIM001,Informatika,2024,D001,,\n" +
+			"sari,sari@test.com,Sari,mahasiswa,Rahasia123,NIM002,Informatika,2024,D999,,\n"
+
+		mockRepo.On("ListTakenCredentials", mock.Anything, mock.Anything).Return(map[string]bool{}, map[string]bool{}, nil)
+		mockRepo.On("ResolveLecturers", []string{"D999"}).Return(map[string]uuid.UUID{"D999": existingLecturerID}, nil)
+
+		var imported []models.NewUserAccount
+		mockRepo.On("ImportUsers", mock.Anything).Run(func(args mock.Arguments) {
+			imported = args.Get(0).([]models.NewUserAccount)
+		}).Return(nil)
+
+		status, result := postImport(app, "users.csv", []byte(csv), false)
+
+		assert.Equal(t, 201, status)
+		assert.True(t, result.Committed)
+		assert.Len(t, imported, 3)
+		assert.Equal(t, imported[0].Lecturer.ID, *imported[1].Student.AdvisorID)
+		assert.Equal(t, existingLecturerID, *imported[2].Student.AdvisorID)
+		for _, acc := range imported {
+			assert.NotEmpty(t, acc.User.PasswordHash)
+		}
+		assert.NotEmpty(t, result.Rows[0].TemporaryPassword)
+		assert.Empty(t, result.Rows[2].TemporaryPassword)
+		assert.NotNil(t, result.Rows[1].UserID)
+	})
+
+	t.Run("Dry-run: XLSX file", func(t *testing.T) {
+		svc, mockRepo, _, mockRoleRepo := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+		app.Post("/users/import", svc.ImportUsers)
+		mockImportRoles(mockRoleRepo)
+
+		xlsx := buildXLSX([][]string{
+			{"Username", "Email", "Full Name", "Role", "NIM", "Program Study", "Academic Year"},
+			{"budi", "budi@test.com", "Budi", "mahasiswa", "NIM001", "Informatika", "2024"},
+		})
+
+		mockRepo.On("ListTakenCredentials", []string{"budi"}, []string{"budi@test.com"}).Return(map[string]bool{}, map[string]bool{}, nil)
+
+		status, result := postImport(app, "users.xlsx", xlsx, true)
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, 1, result.Valid)
+		assert.Equal(t, "budi", result.Rows[0].Username)
+	})
+
+	t.Run("Error: XLSX cell past the column limit", func(t *testing.T) {
+		svc, _, _, _ := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+		app.Post("/users/import", svc.ImportUsers)
+
+		// Kolom ZZZZZZZ (sekitar 8 miliar) tidak boleh dialokasikan.
+		xlsx := buildXLSXSheet(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row><c r="ZZZZZZZ1" t="inlineStr"><is><t>x</t></is></c></row></sheetData></worksheet>`)
+
+		status, _ := postImport(app, "users.xlsx", xlsx, true)
+
+		assert.Equal(t, 400, status)
+	})
+
+	t.Run("Error: XLSX sheet that inflates past the size limit", func(t *testing.T) {
+		svc, _, _, _ := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+		app.Post("/users/import", svc.ImportUsers)
+
+		padding := strings.Repeat(" ", 33<<20)
+		xlsx := buildXLSXSheet(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + padding + `</sheetData></worksheet>`)
+
+		status, _ := postImport(app, "users.xlsx", xlsx, true)
+
+		assert.Equal(t, 400, status)
+	})
+
+	t.Run("Error: Missing required column", func(t *testing.T) {
+		svc, _, _, _ := setupAdminTestWithRoles()
+		app := setupApp("admin", uuid.New())
+		app.Post("/users/import", svc.ImportUsers)
+
+		status, _ := postImport(app, "users.csv", []byte("username,email\nbudi,budi@test.com\n"), true)
+
+		assert.Equal(t, 400, status)
+	})
+}
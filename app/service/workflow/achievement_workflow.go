@@ -0,0 +1,142 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	modelPg "student-performance-report/app/models/postgresql"
+	repoPg "student-performance-report/app/repository/postgresql"
+	"github.com/google/uuid"
+)
+
+// ErrTransitionNotAllowed is returned for a status change the workflow does
+// not define, or one the actor's role may not make.
+var ErrTransitionNotAllowed = errors.New("status transition not allowed")
+
+// Role is the part an actor plays for one achievement. The access policy
+// decides which role a caller has; the workflow only decides what that role
+// may do in each status.
+type Role int
+
+const (
+	Owner   Role = iota // the student who owns the achievement
+	Advisor             // the student's academic advisor
+	Admin
+)
+
+func (r Role) String() string {
+	switch r {
+	case Owner:
+		return "owner"
+	case Advisor:
+		return "advisor"
+	case Admin:
+		return "admin"
+	}
+	return "unknown"
+}
+
+type transition struct {
+	from, to string
+	roles    []Role
+}
+
+// transitions is the complete achievement life cycle. The student submits a
+// draft, the advisor verifies or rejects it, and the advisor or an admin may
+// revoke a verified achievement. A rejected achievement is not final: editing it
+// moves it to revision (which can be submitted again), and the student may
+// also reopen it as a plain draft. A deleted draft sits in the trash, from
+// which the student or an admin may restore it until it is purged.
+var transitions = []transition{
+	{modelPg.StatusDraft, modelPg.StatusSubmitted, []Role{Owner}},
+	{modelPg.StatusDraft, modelPg.StatusDeleted, []Role{Owner}},
+	{modelPg.StatusDeleted, modelPg.StatusDraft, []Role{Owner, Admin}},
+	{modelPg.StatusSubmitted, modelPg.StatusVerified, []Role{Advisor}},
+	{modelPg.StatusSubmitted, modelPg.StatusRejected, []Role{Advisor}},
+	{modelPg.StatusRejected, modelPg.StatusDraft, []Role{Owner}},
+	{modelPg.StatusRejected, modelPg.StatusRevision, []Role{Owner}},
+	{modelPg.StatusRevision, modelPg.StatusSubmitted, []Role{Owner}},
+	{modelPg.StatusVerified, modelPg.StatusRevoked, []Role{Advisor, Admin}},
+}
+
+// Allowed reports whether role may move an achievement from one status to
+// another.
+func Allowed(from, to string, role Role) bool {
+	for _, t := range transitions {
+		if t.from != from || t.to != to {
+			continue
+		}
+		for _, r := range t.roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Editable reports whether the owner may change the details and attachments
+// of an achievement in this status. Editing a rejected achievement moves it
+// to revision (see FinishEdit).
+func Editable(status string) bool {
+	switch status {
+	case modelPg.StatusDraft, modelPg.StatusRevision, modelPg.StatusRejected:
+		return true
+	}
+	return false
+}
+
+// AchievementWorkflow checks transitions against the table above and applies
+// them with a conditional update, so the status read by the handler must
+// still be current when the change is written.
+type AchievementWorkflow struct {
+	repo repoPg.AchievementRepoPostgres
+}
+
+func NewAchievementWorkflow(repo repoPg.AchievementRepoPostgres) *AchievementWorkflow {
+	return &AchievementWorkflow{repo: repo}
+}
+
+// Transition moves ref to status `to`. It returns ErrTransitionNotAllowed
+// (wrapped) when the workflow forbids it and repoPg.ErrStatusConflict when
+// another request changed the status in the meantime.
+func (w *AchievementWorkflow) Transition(ctx context.Context, ref modelPg.AchievementReference, to string, role Role, change modelPg.StatusChange) error {
+	if !Allowed(ref.Status, to, role) {
+		return fmt.Errorf("%w: %s cannot move an achievement from %s to %s", ErrTransitionNotAllowed, role, ref.Status, to)
+	}
+	return w.repo.TransitionStatus(ctx, ref.ID, ref.Status, to, change)
+}
+
+// EditStatus makes sure the owner may edit ref and returns the status it is
+// edited in: a rejected achievement moves to revision.
+func EditStatus(ref modelPg.AchievementReference) (string, error) {
+	if !Editable(ref.Status) {
+		return "", fmt.Errorf("%w: %s achievements cannot be edited", ErrTransitionNotAllowed, ref.Status)
+	}
+	if ref.Status == modelPg.StatusRejected {
+		return modelPg.StatusRevision, nil
+	}
+	return ref.Status, nil
+}
+
+// FinishEdit moves a rejected ref to revision once the owner's edit is
+// stored, so a failed write leaves the status and history untouched. Other
+// editable statuses are left as they are.
+func (w *AchievementWorkflow) FinishEdit(ctx context.Context, ref modelPg.AchievementReference, actorID uuid.UUID) error {
+	if ref.Status != modelPg.StatusRejected {
+		return nil
+	}
+	return w.Transition(ctx, ref, modelPg.StatusRevision, Owner, modelPg.StatusChange{ActorID: actorID})
+}
+
+// AdjustPoints corrects the points of a verified achievement; only the
+// advisor or an admin may. change.Points is the new value.
+func (w *AchievementWorkflow) AdjustPoints(ctx context.Context, ref modelPg.AchievementReference, role Role, change modelPg.StatusChange) error {
+	if ref.Status != modelPg.StatusVerified {
+		return fmt.Errorf("%w: points of %s achievements cannot be adjusted", ErrTransitionNotAllowed, ref.Status)
+	}
+	if role != Advisor && role != Admin {
+		return fmt.Errorf("%w: %s cannot adjust points", ErrTransitionNotAllowed, role)
+	}
+	return w.repo.AdjustPoints(ctx, ref.ID, ref.Status, change)
+}
@@ -0,0 +1,72 @@
+// Command reconcile checks the achievement references in PostgreSQL against
+// the achievement documents in MongoDB and reports orphans, missing details,
+// point mismatches and stuck outbox events. With -repair it also fixes them.
+//
+//	go run ./cmd/reconcile [-repair] [-json]
+//
+// The exit status is 1 when issues remain unresolved.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+	repoMongo "student-performance-report/app/repository/mongodb"
+	repoPg "student-performance-report/app/repository/postgresql"
+	"student-performance-report/app/service/reconcile"
+	"student-performance-report/config"
+	"student-performance-report/database"
+)
+
+func main() {
+	repair := flag.Bool("repair", false, "fix the issues found instead of only reporting them")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	config.LoadEnv()
+	database.ConnectPostgres()
+	defer database.PostgresDB.Close()
+	database.ConnectMongo()
+
+	cfg := config.LoadAchievement()
+	reconciler := reconcile.New(
+		repoPg.NewAchievementRepoPostgres(database.PostgresDB),
+		repoMongo.NewAchievementRepository(database.MongoDB),
+		repoPg.NewOutboxRepository(database.PostgresDB),
+		time.Duration(cfg.ReconcileGraceMinutes)*time.Minute,
+	)
+
+	report, err := reconciler.Run(context.Background(), *repair)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		fmt.Printf("Checked %d references and %d documents, %d issues\n", report.References, report.Documents, len(report.Issues))
+		for _, i := range report.Issues {
+			id := "-"
+			if i.AchievementID != nil {
+				id = i.AchievementID.String()
+			}
+			state := "open"
+			switch {
+			case i.Repaired:
+				state = "repaired"
+			case i.RepairError != "":
+				state = "repair failed: " + i.RepairError
+			}
+			fmt.Printf("%-16s %-36s %-24s %s [%s]\n", i.Kind, id, i.MongoID, i.Detail, state)
+		}
+	}
+
+	if report.Unresolved() > 0 {
+		os.Exit(1)
+	}
+}
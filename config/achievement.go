@@ -0,0 +1,42 @@
+package config
+
+type AchievementConfig struct {
+	// Authors may edit or delete their own comments for this long after
+	// posting; admins may delete any comment at any time.
+	CommentEditWindowMinutes   int
+	CommentDeleteWindowMinutes int
+
+	// Undelivered outbox events are retried every OutboxIntervalSeconds, at
+	// most OutboxBatchSize per run. A failed event waits OutboxRetrySeconds,
+	// doubling after every attempt up to OutboxRetryMaxMinutes.
+	OutboxIntervalSeconds int
+	OutboxBatchSize       int
+	OutboxRetrySeconds    int
+	OutboxRetryMaxMinutes int
+
+	// The reconcile command leaves records younger than this alone, since
+	// their writes may still be in progress.
+	ReconcileGraceMinutes int
+
+	// Deleted achievements can be restored for TrashRetentionDays. Every
+	// TrashPurgeIntervalMinutes up to TrashPurgeBatchSize expired ones are
+	// removed for good, attachments included.
+	TrashRetentionDays        int
+	TrashPurgeIntervalMinutes int
+	TrashPurgeBatchSize       int
+}
+
+func LoadAchievement() AchievementConfig {
+	return AchievementConfig{
+		CommentEditWindowMinutes:   positiveEnv("COMMENT_EDIT_WINDOW_MINUTES", 15),
+		CommentDeleteWindowMinutes: positiveEnv("COMMENT_DELETE_WINDOW_MINUTES", 60),
+		OutboxIntervalSeconds:      positiveEnv("OUTBOX_INTERVAL_SECONDS", 30),
+		OutboxBatchSize:            positiveEnv("OUTBOX_BATCH_SIZE", 100),
+		OutboxRetrySeconds:         positiveEnv("OUTBOX_RETRY_SECONDS", 30),
+		OutboxRetryMaxMinutes:      positiveEnv("OUTBOX_RETRY_MAX_MINUTES", 60),
+		ReconcileGraceMinutes:      positiveEnv("RECONCILE_GRACE_MINUTES", 60),
+		TrashRetentionDays:         positiveEnv("TRASH_RETENTION_DAYS", 30),
+		TrashPurgeIntervalMinutes:  positiveEnv("TRASH_PURGE_INTERVAL_MINUTES", 60),
+		TrashPurgeBatchSize:        positiveEnv("TRASH_PURGE_BATCH_SIZE", 100),
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+type AuthConfig struct {
+	PasswordResetTTLMinutes int
+
+	// LoginAttemptStore is "postgres" (shared by all replicas) or "memory".
+	LoginAttemptStore         string
+	LoginFreeFailures         int
+	LoginMaxFailures          int
+	LoginFreeFailuresPerIP    int
+	LoginMaxFailuresPerIP     int
+	LoginBackoffMaxSeconds    int
+	LoginLockoutMinutes       int
+	LoginFailureWindowMinutes int
+
+	MFAIssuer                 string
+	MFAChallengeTTLMinutes    int
+	// MFARequiredPermissions makes 2FA mandatory for every role holding one
+	// of these permissions, e.g. "achievement:verify,manage:users".
+	MFARequiredPermissions    []string
+
+	AccessTokenDefaultDays int
+	AccessTokenMaxDays     int
+}
+
+func LoadAuth() AuthConfig {
+	issuer := os.Getenv("MFA_ISSUER")
+	if issuer == "" {
+		issuer = "Student Performance Report"
+	}
+	store := os.Getenv("LOGIN_ATTEMPT_STORE")
+	if store != "memory" {
+		store = "postgres"
+	}
+	return AuthConfig{
+		PasswordResetTTLMinutes:   positiveEnv("PASSWORD_RESET_TTL_MINUTES", 60),
+		LoginAttemptStore:         store,
+		LoginFreeFailures:         positiveEnv("LOGIN_FREE_FAILURES", 3),
+		LoginMaxFailures:          positiveEnv("LOGIN_MAX_FAILURES", 10),
+		LoginFreeFailuresPerIP:    positiveEnv("LOGIN_FREE_FAILURES_PER_IP", 10),
+		LoginMaxFailuresPerIP:     positiveEnv("LOGIN_MAX_FAILURES_PER_IP", 50),
+		LoginBackoffMaxSeconds:    positiveEnv("LOGIN_BACKOFF_MAX_SECONDS", 60),
+		LoginLockoutMinutes:       positiveEnv("LOGIN_LOCKOUT_MINUTES", 15),
+		LoginFailureWindowMinutes: positiveEnv("LOGIN_FAILURE_WINDOW_MINUTES", 15),
+		MFAIssuer:                 issuer,
+		MFAChallengeTTLMinutes:    positiveEnv("MFA_CHALLENGE_TTL_MINUTES", 5),
+		MFARequiredPermissions:    listEnv("MFA_REQUIRED_PERMISSIONS"),
+		AccessTokenDefaultDays:    positiveEnv("ACCESS_TOKEN_DEFAULT_DAYS", 90),
+		AccessTokenMaxDays:        positiveEnv("ACCESS_TOKEN_MAX_DAYS", 365),
+	}
+}
+
+func positiveEnv(name string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
@@ -6,16 +6,56 @@ import (
 )
 
 type JWTConfig struct {
+	// Secret signs refresh tokens: JWT_REFRESH_SECRET, or JWT_SECRET when
+	// that is not set.
 	Secret []byte
 	TTLHours int
+	TokenVersionCacheSeconds int
+	PermissionCacheSeconds int
+	// KeyDir holds the PEM keys for access tokens, one file per kid. Private
+	// keys can sign; public-only files keep retired keys verifiable.
+	KeyDir string
+	ActiveKeyID string
+	Issuer string
+	Audience string
 }
 
 func LoadJWT() JWTConfig {
-	secret := os.Getenv("JWT_SECRET")
+	secret := os.Getenv("JWT_REFRESH_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
 	ttlStr := os.Getenv("JWT_TTL_HOURS")
 	ttl, err := strconv.Atoi(ttlStr)
 	if err != nil || ttl <= 0 {
 		ttl = 24
 	}
-	return JWTConfig{Secret: []byte(secret), TTLHours: ttl}
+	cacheStr := os.Getenv("TOKEN_VERSION_CACHE_SECONDS")
+	cacheTTL, err := strconv.Atoi(cacheStr)
+	if err != nil || cacheTTL <= 0 {
+		cacheTTL = 30
+	}
+	permStr := os.Getenv("PERMISSION_CACHE_SECONDS")
+	permTTL, err := strconv.Atoi(permStr)
+	if err != nil || permTTL <= 0 {
+		permTTL = 60
+	}
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" {
+		issuer = "student-performance-app"
+	}
+	audience := os.Getenv("JWT_AUDIENCE")
+	if audience == "" {
+		audience = "student-performance-api"
+	}
+	return JWTConfig{
+		Secret: []byte(secret),
+		TTLHours: ttl,
+		TokenVersionCacheSeconds: cacheTTL,
+		PermissionCacheSeconds: permTTL,
+		KeyDir: os.Getenv("JWT_KEY_DIR"),
+		ActiveKeyID: os.Getenv("JWT_ACTIVE_KEY_ID"),
+		Issuer: issuer,
+		Audience: audience,
+	}
 }
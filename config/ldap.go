@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// LDAPConfig configures password checks against the faculty directory.
+// LDAP login is off while LDAP_URL is empty.
+type LDAPConfig struct {
+	URL            string
+	UserDN         string
+	GroupBaseDN    string
+	GroupFilter    string
+	TimeoutSeconds int
+
+	// Roles and UsernamePattern select the users that log in through LDAP;
+	// everyone else keeps the local password.
+	Roles           []string
+	UsernamePattern string
+
+	// GroupRoles maps directory groups to local roles, in priority order,
+	// from LDAP_GROUP_ROLES="dosen=Dosen Wali,tata-usaha=Admin".
+	GroupRoles []LDAPGroupRole
+}
+
+type LDAPGroupRole struct {
+	Group string
+	Role  string
+}
+
+func (c LDAPConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+func LoadLDAP() LDAPConfig {
+	var groupRoles []LDAPGroupRole
+	for _, pair := range listEnv("LDAP_GROUP_ROLES") {
+		if group, role, ok := strings.Cut(pair, "="); ok {
+			groupRoles = append(groupRoles, LDAPGroupRole{Group: strings.TrimSpace(group), Role: strings.TrimSpace(role)})
+		}
+	}
+	return LDAPConfig{
+		URL:             os.Getenv("LDAP_URL"),
+		UserDN:          envOr("LDAP_USER_DN", "uid=%s,ou=people,dc=example,dc=ac,dc=id"),
+		GroupBaseDN:     os.Getenv("LDAP_GROUP_BASE_DN"),
+		GroupFilter:     envOr("LDAP_GROUP_FILTER", "(member=%s)"),
+		TimeoutSeconds:  positiveEnv("LDAP_TIMEOUT_SECONDS", 5),
+		Roles:           listEnv("LDAP_ROLES"),
+		UsernamePattern: os.Getenv("LDAP_USERNAME_PATTERN"),
+		GroupRoles:      groupRoles,
+	}
+}
+
+// listEnv splits a comma-separated variable, dropping empty items.
+func listEnv(name string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// OIDCConfig configures single sign-on with the university identity
+// provider. SSO is off while OIDC_ISSUER is empty.
+type OIDCConfig struct {
+	Issuer          string
+	ClientID        string
+	ClientSecret    string
+	RedirectURL     string
+	Scopes          []string
+	StateTTLMinutes int
+
+	// AutoProvision creates a student account for unknown users whose ID
+	// token carries a verified email and the student profile claims.
+	AutoProvision     bool
+	ProvisionRole     string
+	StudentIDClaim    string
+	ProgramStudyClaim string
+	AcademicYearClaim string
+}
+
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != ""
+}
+
+func LoadOIDC() OIDCConfig {
+	scopes := strings.Fields(os.Getenv("OIDC_SCOPES"))
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	return OIDCConfig{
+		Issuer:            os.Getenv("OIDC_ISSUER"),
+		ClientID:          os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:      os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:       os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:            scopes,
+		StateTTLMinutes:   positiveEnv("OIDC_STATE_TTL_MINUTES", 10),
+		AutoProvision:     os.Getenv("OIDC_AUTO_PROVISION") == "true",
+		ProvisionRole:     envOr("OIDC_PROVISION_ROLE", "mahasiswa"),
+		StudentIDClaim:    envOr("OIDC_CLAIM_STUDENT_ID", "student_id"),
+		ProgramStudyClaim: envOr("OIDC_CLAIM_PROGRAM_STUDY", "program_study"),
+		AcademicYearClaim: envOr("OIDC_CLAIM_ACADEMIC_YEAR", "academic_year"),
+	}
+}
+
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
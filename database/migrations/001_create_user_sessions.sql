@@ -0,0 +1,15 @@
+-- Refresh-token sessions. One row per logged-in device; refresh_jti holds the
+-- only refresh token of the session that is still accepted.
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id           UUID PRIMARY KEY,
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_jti  VARCHAR(64) NOT NULL,
+    user_agent   TEXT NOT NULL DEFAULT '',
+    ip_address   VARCHAR(64) NOT NULL DEFAULT '',
+    issued_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    revoked_at   TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id);
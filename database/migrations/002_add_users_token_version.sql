@@ -0,0 +1,3 @@
+-- Bumped whenever a user's existing access tokens must stop working
+-- (deactivation, role change, password change).
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INT NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+-- Failed-login counters per username and per client IP, shared by all
+-- replicas. A row is removed once its lock is cleared or the login succeeds.
+CREATE TABLE IF NOT EXISTS login_attempts (
+    scope           VARCHAR(16)  NOT NULL,
+    subject         VARCHAR(255) NOT NULL,
+    failures        INT          NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP    NOT NULL,
+    locked_until    TIMESTAMP,
+    PRIMARY KEY (scope, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_locked_until ON login_attempts(locked_until);
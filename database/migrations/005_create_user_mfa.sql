@@ -0,0 +1,35 @@
+-- TOTP two-factor authentication. A row with enabled_at NULL is an enrollment
+-- that was started but not confirmed yet. last_used_step stops a code from
+-- being accepted twice.
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id        UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret         VARCHAR(64) NOT NULL,
+    created_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+    enabled_at     TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0
+);
+
+-- Single-use recovery codes, stored as SHA-256 hashes.
+CREATE TABLE IF NOT EXISTS user_mfa_recovery_codes (
+    id         UUID PRIMARY KEY,
+    user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash  CHAR(64) NOT NULL,
+    used_at    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_mfa_recovery_codes_user_id ON user_mfa_recovery_codes(user_id);
+
+-- Second step of a login: issued after the password check, spent once the
+-- TOTP or recovery code is accepted.
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id          UUID PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash  CHAR(64) NOT NULL UNIQUE,
+    ip_address  VARCHAR(64),
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at  TIMESTAMP NOT NULL,
+    attempts    INT NOT NULL DEFAULT 0,
+    used_at     TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_user_id ON mfa_challenges(user_id);
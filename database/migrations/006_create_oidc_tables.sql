@@ -0,0 +1,24 @@
+-- Pending single sign-on logins. The state parameter is stored hashed; the
+-- PKCE verifier and nonce are needed once, in the callback.
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    id             UUID PRIMARY KEY,
+    state_hash     CHAR(64) NOT NULL UNIQUE,
+    code_verifier  VARCHAR(128) NOT NULL,
+    nonce          VARCHAR(128) NOT NULL,
+    created_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at     TIMESTAMP NOT NULL,
+    used_at        TIMESTAMP
+);
+
+-- Links an identity provider subject to a local user.
+CREATE TABLE IF NOT EXISTS user_identities (
+    issuer      VARCHAR(255) NOT NULL,
+    subject     VARCHAR(255) NOT NULL,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email       VARCHAR(255),
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMP,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
@@ -0,0 +1,18 @@
+-- Long-lived API tokens for scripts. Only the SHA-256 hash is stored; the
+-- first characters are kept in token_prefix so users can tell tokens apart.
+-- scopes is a subset of the owner's permissions at creation time.
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+    id            UUID PRIMARY KEY,
+    user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name          VARCHAR(100) NOT NULL,
+    token_prefix  VARCHAR(16) NOT NULL,
+    token_hash    CHAR(64) NOT NULL UNIQUE,
+    scopes        TEXT[] NOT NULL,
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at    TIMESTAMP NOT NULL,
+    last_used_at  TIMESTAMP,
+    last_used_ip  VARCHAR(64),
+    revoked_at    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user_id ON personal_access_tokens(user_id);
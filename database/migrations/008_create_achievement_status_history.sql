@@ -0,0 +1,14 @@
+-- Append-only log of achievement status changes. Rows are written in the same
+-- transaction as the change to achievement_references and never updated, so
+-- a reject -> edit -> resubmit -> verify cycle keeps every step and note.
+CREATE TABLE IF NOT EXISTS achievement_status_history (
+    id                  UUID PRIMARY KEY,
+    achievement_ref_id  UUID NOT NULL REFERENCES achievement_references(id) ON DELETE CASCADE,
+    from_status         VARCHAR(20),
+    to_status           VARCHAR(20) NOT NULL,
+    actor_id            UUID REFERENCES users(id) ON DELETE SET NULL,
+    note                TEXT,
+    created_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_achievement_status_history_ref_id ON achievement_status_history(achievement_ref_id, created_at);
@@ -0,0 +1,9 @@
+-- Postgres keeps a copy of the points awarded on verification so that the
+-- reference and the Mongo document can be checked against each other.
+ALTER TABLE achievement_references ADD COLUMN IF NOT EXISTS points INT NOT NULL DEFAULT 0;
+
+-- Point corrections and revocations are recorded in the status history with
+-- the value before and after the change. A correction keeps the status, so
+-- from_status = to_status = 'verified'.
+ALTER TABLE achievement_status_history ADD COLUMN IF NOT EXISTS old_points INT;
+ALTER TABLE achievement_status_history ADD COLUMN IF NOT EXISTS new_points INT;
@@ -0,0 +1,22 @@
+-- Versioned scoring rubrics. Rules are a JSON array of
+-- {"achievementType", "when": {detailField: value}, "points"}. At most one
+-- version is active; a version that has ever been active is frozen, so a
+-- verification can always be traced back to the rules it was scored with.
+CREATE TABLE IF NOT EXISTS scoring_rubrics (
+    id            UUID PRIMARY KEY,
+    version       INT NOT NULL UNIQUE,
+    name          VARCHAR(100) NOT NULL,
+    rules         JSONB NOT NULL,
+    is_active     BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by    UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    activated_at  TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scoring_rubrics_active ON scoring_rubrics(is_active) WHERE is_active;
+
+-- What the rubric suggested when the achievement was verified, and whether
+-- the lecturer awarded something else.
+ALTER TABLE achievement_references ADD COLUMN IF NOT EXISTS rubric_version INT;
+ALTER TABLE achievement_references ADD COLUMN IF NOT EXISTS suggested_points INT;
+ALTER TABLE achievement_references ADD COLUMN IF NOT EXISTS points_overridden BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+-- The achievement_versions snapshot (MongoDB) a lecturer verified. Later
+-- edits create new versions, so this pins the verification to what was
+-- actually reviewed.
+ALTER TABLE achievement_references ADD COLUMN IF NOT EXISTS verified_version INT;
@@ -0,0 +1,17 @@
+-- Discussion between a student and the verifier on one achievement. A
+-- comment may point at a details field (e.g. "details.rank") or at one
+-- attachment by its file URL. author_role is the author's relation to the
+-- achievement when writing: owner, advisor or admin.
+CREATE TABLE IF NOT EXISTS achievement_comments (
+    id                  UUID PRIMARY KEY,
+    achievement_ref_id  UUID NOT NULL REFERENCES achievement_references(id) ON DELETE CASCADE,
+    author_id           UUID REFERENCES users(id) ON DELETE SET NULL,
+    author_role         VARCHAR(20) NOT NULL,
+    body                TEXT NOT NULL,
+    field               VARCHAR(100),
+    attachment_url      TEXT,
+    created_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+    edited_at           TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_achievement_comments_ref ON achievement_comments(achievement_ref_id, created_at);
@@ -0,0 +1,21 @@
+-- Mongo writes that follow a change in Postgres are stored here in the same
+-- transaction and delivered afterwards, so a failed Mongo write is retried
+-- instead of lost. kind is create_details, set_points or delete_details;
+-- payload holds what the write needs (the details document or the points).
+-- delivered_at is set once the write succeeded or a later event of the same
+-- achievement made it obsolete.
+CREATE TABLE IF NOT EXISTS achievement_outbox (
+    id                    UUID PRIMARY KEY,
+    achievement_ref_id    UUID NOT NULL REFERENCES achievement_references(id) ON DELETE CASCADE,
+    mongo_achievement_id  VARCHAR(24) NOT NULL,
+    kind                  VARCHAR(20) NOT NULL,
+    payload               JSONB,
+    attempts              INT NOT NULL DEFAULT 0,
+    last_error            TEXT,
+    next_attempt_at       TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at            TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at          TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_achievement_outbox_due ON achievement_outbox(next_attempt_at) WHERE delivered_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_achievement_outbox_ref ON achievement_outbox(achievement_ref_id) WHERE delivered_at IS NULL;
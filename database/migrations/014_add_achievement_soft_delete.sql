@@ -0,0 +1,13 @@
+-- Deleted achievements stay in the trash, restorable by their owner, until
+-- the purge job removes them for good once the retention window has passed.
+-- Rows deleted before this migration have no deleted_at; the time of their
+-- last update is the closest we have.
+ALTER TABLE achievement_references ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE achievement_references ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+UPDATE achievement_references SET deleted_at = updated_at WHERE status = 'deleted' AND deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_achievement_references_trash ON achievement_references(student_id, deleted_at) WHERE status = 'deleted';
+
+-- Deleting no longer removes the Mongo document but marks it trashed.
+UPDATE achievement_outbox SET kind = 'trash_details' WHERE kind = 'delete_details' AND delivered_at IS NULL;
@@ -0,0 +1,7 @@
+-- Events of one achievement are delivered strictly in the order they were
+-- stored: a later write must not overtake an earlier one that waits for a
+-- retry. created_at is the start of the storing transaction and ties within
+-- it, so the order is kept by a sequence taken at insert time instead.
+ALTER TABLE achievement_outbox ADD COLUMN IF NOT EXISTS seq BIGSERIAL;
+
+CREATE INDEX IF NOT EXISTS idx_achievement_outbox_seq ON achievement_outbox(achievement_ref_id, seq) WHERE delivered_at IS NULL;
@@ -0,0 +1,6 @@
+-- Comments form threads: a reply points at the comment it answers, which
+-- always belongs to the same achievement. Replies to a deleted comment are
+-- kept and become top-level comments.
+ALTER TABLE achievement_comments ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES achievement_comments(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_achievement_comments_parent ON achievement_comments(parent_id);
@@ -6,6 +6,7 @@ import (
 	"os"
 	"time"
 	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -30,5 +31,24 @@ func ConnectMongo() {
 	MongoDB = client.Database(os.Getenv("MONGO_DB_NAME"))
 	fmt.Println("Connected to MongoDB")
 	fmt.Println("DB MongoDB :", os.Getenv("MONGO_DB_NAME"))
-	
+
+	ensureMongoIndexes(ctx)
+}
+
+func DisconnectMongo(ctx context.Context) {
+	if err := MongoDB.Client().Disconnect(ctx); err != nil {
+		log.Println("Failed to disconnect from MongoDB:", err)
+	}
+}
+
+// ensureMongoIndexes creates the indexes the repositories rely on; creating
+// an existing index is a no-op.
+func ensureMongoIndexes(ctx context.Context) {
+	_, err := MongoDB.Collection("achievement_versions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "achievementId", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Println("Failed to create achievement_versions index:", err)
+	}
 }
@@ -12,23 +12,32 @@ package main
 // @tag.description Endpoint for Admin to manage users (Admin Only)
 // @tag.order 2
 
+// @tag.name Roles & Permissions
+// @tag.description Endpoint for Admin to manage roles and their permissions (Admin Only)
+// @tag.order 3
+
 // @tag.name Achievements
 // @tag.description Endpoint for achievement data
-// @tag.order 3
+// @tag.order 4
 
 // @tag.name Students & Lecturers
 // @tag.description Endpoint for student and lecturer data 
-// @tag.order 4
+// @tag.order 5
 
 // @tag.name Reports
 // @tag.description Endpoint for generating reports and statistics
-// @tag.order 5
+// @tag.order 6
+
+// @tag.name Comments
+// @tag.description Endpoint for the discussion on an achievement
+// @tag.order 7
 
 import (
 	"fmt"
 	"os"
 	"log"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 	"context"
@@ -37,6 +46,7 @@ import (
 	"student-performance-report/database"
 	FiberApp "student-performance-report/fiber"
 	route "student-performance-report/route"
+	"student-performance-report/utils"
 	"github.com/gofiber/swagger"
 	docs "student-performance-report/docs"
 )
@@ -73,7 +83,16 @@ func main() {
 	log.Println("➡️  Swagger UI available at: http://localhost:" + os.Getenv("PORT") + "/swagger/index.html")
 
 	// 5. Setup Route
-	route.SetupPostgresRoutes(app, database.PostgresDB)
+	if err := utils.InitTokenKeys(config.LoadJWT()); err != nil {
+		log.Fatal(err)
+	}
+	// Background jobs stop before the database clients are closed
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	route.SetupPostgresRoutes(workerCtx, app, database.PostgresDB, &workers)
+	if err := route.VerifyRoutePolicies(app); err != nil {
+		log.Fatal(err)
+	}
 
 	fmt.Println("Setup route berhasil")
 
@@ -100,4 +119,8 @@ func main() {
 	if err := app.ShutdownWithContext(ctx); err != nil {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
+
+	stopWorkers()
+	workers.Wait()
+	database.DisconnectMongo(ctx)
 }
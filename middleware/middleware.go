@@ -4,6 +4,7 @@ import (
     "strings"
     "student-performance-report/utils"
     "github.com/gofiber/fiber/v2"
+    "github.com/google/uuid"
 )
 
 func AuthRequired() fiber.Handler {
@@ -18,15 +19,42 @@ func AuthRequired() fiber.Handler {
             return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token format"})
         }
 
+        if strings.HasPrefix(parts[1], AccessTokenPrefix) {
+            return authenticateAccessToken(c, parts[1])
+        }
+
         claims, err := utils.ValidateToken(parts[1])
         if err != nil {
             return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or expired token"})
         }
 
+        if tokenVersions != nil {
+            version, active, err := tokenVersions.Get(claims.UserID)
+            if err != nil {
+                return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to check token version"})
+            }
+            if !active {
+                return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "account is inactive"})
+            }
+            if version != claims.TokenVersion {
+                return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "token has been revoked"})
+            }
+        }
+
+        if sessions != nil && claims.SessionID != uuid.Nil {
+            active, err := sessions.Get(claims.SessionID)
+            if err != nil {
+                return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to check session"})
+            }
+            if !active {
+                return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "session has been revoked"})
+            }
+        }
+
         c.Locals("user_id", claims.UserID)
         c.Locals("role_id", claims.RoleID)
         c.Locals("role_name", claims.RoleName) 
-        c.Locals("permissions", claims.Permissions) 
+        c.Locals("session_id", claims.SessionID)
 
         return c.Next()
     }
@@ -56,42 +84,51 @@ func RoleAllowed(allowedRoles ...string) fiber.Handler {
 
 func PermissionRequired(needed string) fiber.Handler {
     return func(c *fiber.Ctx) error {
-        raw := c.Locals("permissions")
-        if raw == nil {
-            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "no permissions found"})
+        ok, err := checkPermission(c, needed)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to resolve permissions"})
         }
-
-        perms, ok := raw.([]string)
         if !ok {
-
-            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "invalid permissions format"})
+            return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+                "error": "permission denied: needed '" + needed + "'",
+            })
         }
-
-        for _, p := range perms {
-            if p == needed {
-                return c.Next()
-            }
-        }
-
-        return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-            "error": "permission denied: needed '" + needed + "'",
-        })
+        return c.Next()
     }
 }
 
 func HasPermission(c *fiber.Ctx, needed string) bool {
-    raw := c.Locals("permissions")
-    if raw == nil {
-        return false
+    ok, _ := checkPermission(c, needed)
+    return ok
+}
+
+// checkPermission resolves the permission from the role ID in the token when a
+// PermissionResolver is configured, otherwise from a "permissions" slice placed
+// in the context (used by handler tests). Personal access tokens are further
+// limited to their scopes.
+func checkPermission(c *fiber.Ctx, needed string) (bool, error) {
+    if scopes, ok := c.Locals("token_scopes").([]string); ok && !containsString(scopes, needed) {
+        return false, nil
+    }
+
+    if permissionResolver != nil {
+        roleID, ok := c.Locals("role_id").(uuid.UUID)
+        if !ok {
+            return false, nil
+        }
+        return permissionResolver.Has(roleID, needed)
     }
 
-    perms, ok := raw.([]string)
+    perms, ok := c.Locals("permissions").([]string)
     if !ok {
-        return false
+        return false, nil
     }
+    return containsString(perms, needed), nil
+}
 
-    for _, p := range perms {
-        if p == needed {
+func containsString(list []string, s string) bool {
+    for _, v := range list {
+        if v == s {
             return true
         }
     }
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+	"github.com/google/uuid"
+)
+
+// RolePermissionLoader returns every role's permission names keyed by role ID.
+type RolePermissionLoader func() (map[uuid.UUID][]string, error)
+
+// PermissionResolver answers "does this role hold this permission" from the
+// roles/permissions/role_permissions tables. The whole mapping is small, so it
+// is loaded at once and kept until ttl passes or Invalidate is called.
+type PermissionResolver struct {
+	load     RolePermissionLoader
+	ttl      time.Duration
+	mu       sync.RWMutex
+	perms    map[uuid.UUID]map[string]struct{}
+	loadedAt time.Time
+}
+
+func NewPermissionResolver(load RolePermissionLoader, ttl time.Duration) *PermissionResolver {
+	return &PermissionResolver{load: load, ttl: ttl}
+}
+
+func (r *PermissionResolver) snapshot() (map[uuid.UUID]map[string]struct{}, error) {
+	r.mu.RLock()
+	perms, loadedAt := r.perms, r.loadedAt
+	r.mu.RUnlock()
+
+	if perms != nil && time.Since(loadedAt) < r.ttl {
+		return perms, nil
+	}
+
+	raw, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	perms = make(map[uuid.UUID]map[string]struct{}, len(raw))
+	for roleID, names := range raw {
+		set := make(map[string]struct{}, len(names))
+		for _, n := range names {
+			set[n] = struct{}{}
+		}
+		perms[roleID] = set
+	}
+
+	r.mu.Lock()
+	r.perms, r.loadedAt = perms, time.Now()
+	r.mu.Unlock()
+
+	return perms, nil
+}
+
+func (r *PermissionResolver) Has(roleID uuid.UUID, needed string) (bool, error) {
+	perms, err := r.snapshot()
+	if err != nil {
+		return false, err
+	}
+	_, ok := perms[roleID][needed]
+	return ok, nil
+}
+
+func (r *PermissionResolver) Permissions(roleID uuid.UUID) ([]string, error) {
+	perms, err := r.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]string, 0, len(perms[roleID]))
+	for p := range perms[roleID] {
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+func (r *PermissionResolver) Invalidate() {
+	r.mu.Lock()
+	r.perms = nil
+	r.mu.Unlock()
+}
+
+var permissionResolver *PermissionResolver
+
+// UsePermissionResolver makes PermissionRequired and HasPermission resolve
+// permissions live from the role ID in the token.
+func UsePermissionResolver(resolver *PermissionResolver) {
+	permissionResolver = resolver
+}
+
+// InvalidatePermissions forces the next permission check to reload the
+// role→permission mapping. Call it after changing roles or role_permissions.
+func InvalidatePermissions() {
+	if permissionResolver != nil {
+		permissionResolver.Invalidate()
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+	"github.com/google/uuid"
+)
+
+// SessionLoader reports whether a login session is still active, i.e. it exists,
+// is not revoked and has not expired.
+type SessionLoader func(sessionID uuid.UUID) (bool, error)
+
+type sessionEntry struct {
+	active    bool
+	expiresAt time.Time
+}
+
+// SessionCache keeps the active flag of login sessions in memory so AuthRequired
+// can reject access tokens of a revoked session without a query per request.
+// Like TokenVersionCache, entries expire after ttl.
+type SessionCache struct {
+	load    SessionLoader
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[uuid.UUID]sessionEntry
+}
+
+func NewSessionCache(load SessionLoader, ttl time.Duration) *SessionCache {
+	return &SessionCache{
+		load:    load,
+		ttl:     ttl,
+		entries: make(map[uuid.UUID]sessionEntry),
+	}
+}
+
+func (c *SessionCache) Get(sessionID uuid.UUID) (bool, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[sessionID]
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.active, nil
+	}
+
+	active, err := c.load(sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.entries[sessionID] = sessionEntry{active: active, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return active, nil
+}
+
+func (c *SessionCache) Invalidate(sessionID uuid.UUID) {
+	c.mu.Lock()
+	delete(c.entries, sessionID)
+	c.mu.Unlock()
+}
+
+var sessions *SessionCache
+
+// UseSessionCache enables the session check in AuthRequired.
+func UseSessionCache(cache *SessionCache) {
+	sessions = cache
+}
+
+// InvalidateSession drops the cached state of a session after it was revoked,
+// so the next request on this instance sees the revocation.
+func InvalidateSession(sessionID uuid.UUID) {
+	if sessions != nil {
+		sessions.Invalidate(sessionID)
+	}
+}
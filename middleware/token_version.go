@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+	"github.com/google/uuid"
+)
+
+// TokenVersionLoader returns the current token_version and is_active flag of a user.
+// A user that does not exist is reported as inactive rather than as an error.
+type TokenVersionLoader func(userID uuid.UUID) (int, bool, error)
+
+type tokenVersionEntry struct {
+	version   int
+	active    bool
+	expiresAt time.Time
+}
+
+// TokenVersionCache keeps users' token versions in memory so AuthRequired does not
+// hit Postgres on every request. Entries expire after ttl, which bounds how long
+// another replica can keep accepting a revoked token.
+type TokenVersionCache struct {
+	load    TokenVersionLoader
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[uuid.UUID]tokenVersionEntry
+}
+
+func NewTokenVersionCache(load TokenVersionLoader, ttl time.Duration) *TokenVersionCache {
+	return &TokenVersionCache{
+		load:    load,
+		ttl:     ttl,
+		entries: make(map[uuid.UUID]tokenVersionEntry),
+	}
+}
+
+func (c *TokenVersionCache) Get(userID uuid.UUID) (int, bool, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[userID]
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.version, entry.active, nil
+	}
+
+	version, active, err := c.load(userID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	c.mu.Lock()
+	c.entries[userID] = tokenVersionEntry{version: version, active: active, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return version, active, nil
+}
+
+func (c *TokenVersionCache) Invalidate(userID uuid.UUID) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.mu.Unlock()
+}
+
+var tokenVersions *TokenVersionCache
+
+// UseTokenVersionCache enables the token_version check in AuthRequired.
+func UseTokenVersionCache(cache *TokenVersionCache) {
+	tokenVersions = cache
+}
+
+// InvalidateTokenVersion drops the cached version of a user after it was bumped,
+// so the change is seen by the next request on this instance.
+func InvalidateTokenVersion(userID uuid.UUID) {
+	if tokenVersions != nil {
+		tokenVersions.Invalidate(userID)
+	}
+}
@@ -1,78 +1,309 @@
 package route
 
 import (
+    "context"
     "database/sql"
+    "errors"
+    "regexp"
+    "sync"
+    "time"
     "github.com/gofiber/fiber/v2"
+    "github.com/google/uuid"
     repoMongo "student-performance-report/app/repository/mongodb"
     repoPostgre "student-performance-report/app/repository/postgresql"
     mongoService "student-performance-report/app/service/mongodb"
     postgreService "student-performance-report/app/service/postgresql"
+    "student-performance-report/app/service/outbox"
+    "student-performance-report/app/service/policy"
+    "student-performance-report/app/service/trash"
+    "student-performance-report/config"
     "student-performance-report/database"
     "student-performance-report/middleware"
+    "student-performance-report/utils"
 )
 
-func SetupPostgresRoutes(app *fiber.App, db *sql.DB) {
+// SetupPostgresRoutes registers the API and starts the background jobs on
+// workers; they run until ctx is cancelled.
+func SetupPostgresRoutes(ctx context.Context, app *fiber.App, db *sql.DB, workers *sync.WaitGroup) {
     // Repositories
     userRepo := repoPostgre.NewUserRepository(db)
+    sessionRepo := repoPostgre.NewSessionRepository(db)
     adminRepo := repoPostgre.NewAdminRepository(db)
     studentRepo := repoPostgre.NewStudentRepository(db)
     lecturerRepo := repoPostgre.NewLecturerRepository(db)
     achRepoPg := repoPostgre.NewAchievementRepoPostgres(db)
     achRepoMongo := repoMongo.NewAchievementRepository(database.MongoDB)
+    roleRepo := repoPostgre.NewRoleRepository(db)
+    permRepo := repoPostgre.NewPermissionRepository(db)
+    passwordRepo := repoPostgre.NewPasswordRepository(db)
+    mfaRepo := repoPostgre.NewMFARepository(db)
+    oidcRepo := repoPostgre.NewOIDCRepository(db)
+    accessTokenRepo := repoPostgre.NewAccessTokenRepository(db)
+    rubricRepo := repoPostgre.NewRubricRepository(db)
+    commentRepo := repoPostgre.NewCommentRepository(db)
+    outboxRepo := repoPostgre.NewOutboxRepository(db)
+    authCfg := config.LoadAuth()
+    loginAttemptRepo := repoPostgre.NewLoginAttemptRepository(db)
+    if authCfg.LoginAttemptStore == "memory" {
+        loginAttemptRepo = repoPostgre.NewMemoryLoginAttemptRepository()
+    }
+
+    // Access-token revocation (token_version check in AuthRequired)
+    jwtCfg := config.LoadJWT()
+    middleware.UseTokenVersionCache(middleware.NewTokenVersionCache(
+        func(id uuid.UUID) (int, bool, error) {
+            version, active, err := userRepo.GetTokenVersion(id)
+            if errors.Is(err, repoPostgre.ErrUserNotFound) {
+                return 0, false, nil
+            }
+            return version, active, err
+        },
+        time.Duration(jwtCfg.TokenVersionCacheSeconds)*time.Second,
+    ))
+
+    // Logout / session revocation (session check in AuthRequired)
+    middleware.UseSessionCache(middleware.NewSessionCache(
+        func(id uuid.UUID) (bool, error) {
+            session, err := sessionRepo.GetByID(ctx, id)
+            if err != nil {
+                if errors.Is(err, repoPostgre.ErrSessionNotFound) {
+                    return false, nil
+                }
+                return false, err
+            }
+            return session.RevokedAt == nil && time.Now().Before(session.ExpiresAt), nil
+        },
+        time.Duration(jwtCfg.TokenVersionCacheSeconds)*time.Second,
+    ))
+
+    // Live role→permission resolution for PermissionRequired/HasPermission
+    middleware.UsePermissionResolver(middleware.NewPermissionResolver(
+        roleRepo.GetRolePermissionMap,
+        time.Duration(jwtCfg.PermissionCacheSeconds)*time.Second,
+    ))
+
+    // Ownership / advisor rules shared by achievement, student, lecturer and report handlers
+    accessPolicy := policy.NewAccessPolicy(achRepoPg, lecturerRepo, studentRepo)
+
+    // Failed-login backoff and lockout, per username and per client IP
+    lockout := func(free, max int) postgreService.LockoutPolicy {
+        return postgreService.LockoutPolicy{
+            FreeFailures: free,
+            MaxFailures:  max,
+            BackoffBase:  time.Second,
+            BackoffMax:   time.Duration(authCfg.LoginBackoffMaxSeconds) * time.Second,
+            Lockout:      time.Duration(authCfg.LoginLockoutMinutes) * time.Minute,
+            Window:       time.Duration(authCfg.LoginFailureWindowMinutes) * time.Minute,
+        }
+    }
+    loginThrottle := postgreService.NewLoginThrottle(loginAttemptRepo,
+        lockout(authCfg.LoginFreeFailures, authCfg.LoginMaxFailures),
+        lockout(authCfg.LoginFreeFailuresPerIP, authCfg.LoginMaxFailuresPerIP),
+    )
 
     // Services
-    authService := postgreService.NewAuthService(userRepo)
-    adminService := postgreService.NewAdminService(adminRepo, userRepo)
-    lecturerService := postgreService.NewLecturerService(lecturerRepo)
-    studentService := postgreService.NewStudentService(studentRepo, achRepoMongo)
-    achievementService := mongoService.NewAchievementService(achRepoMongo, achRepoPg, lecturerRepo)
-	reportService := mongoService.NewReportService(achRepoMongo, studentRepo)
+    mfaService := postgreService.NewMFAService(mfaRepo, userRepo, passwordRepo,
+        authCfg.MFAIssuer,
+        time.Duration(authCfg.MFAChallengeTTLMinutes)*time.Minute,
+        authCfg.MFARequiredPermissions,
+    )
+    authenticators := newAuthenticators(config.LoadLDAP(), roleRepo, adminRepo)
+    authService := postgreService.NewAuthService(userRepo, sessionRepo, authenticators, loginThrottle, mfaService)
+    adminService := postgreService.NewAdminService(adminRepo, userRepo, roleRepo)
+    roleService := postgreService.NewRoleService(roleRepo, permRepo)
+    oidcService := newOIDCService(config.LoadOIDC(), oidcRepo, adminService, authService)
+    accessTokenService := postgreService.NewAccessTokenService(accessTokenRepo, userRepo,
+        time.Duration(authCfg.AccessTokenDefaultDays)*24*time.Hour,
+        time.Duration(authCfg.AccessTokenMaxDays)*24*time.Hour,
+    )
+    // Personal access tokens ("Bearer spr_pat_...") next to JWTs in AuthRequired
+    middleware.UseAccessTokens(accessTokenService.Authenticate)
+    passwordService := postgreService.NewPasswordService(passwordRepo, adminRepo, time.Duration(authCfg.PasswordResetTTLMinutes)*time.Minute)
+    lecturerService := postgreService.NewLecturerService(lecturerRepo, accessPolicy)
+    studentService := postgreService.NewStudentService(studentRepo, achRepoMongo, accessPolicy)
+    achievementCfg := config.LoadAchievement()
+    // Mongo writes owed after Postgres changes; delivered by the request and
+    // retried here in the background until they succeed
+    outboxRelay := outbox.NewRelay(outboxRepo, achRepoMongo,
+        time.Duration(achievementCfg.OutboxRetrySeconds)*time.Second,
+        time.Duration(achievementCfg.OutboxRetryMaxMinutes)*time.Minute,
+    )
+    workers.Go(func() {
+        outboxRelay.Run(ctx, time.Duration(achievementCfg.OutboxIntervalSeconds)*time.Second, achievementCfg.OutboxBatchSize)
+    })
+    // Deleted achievements stay restorable for the retention window; the
+    // purge job then removes them together with their uploaded files
+    trashPurger := trash.NewPurger(achRepoPg, achRepoMongo, time.Duration(achievementCfg.TrashRetentionDays)*24*time.Hour, "./uploads")
+    workers.Go(func() {
+        trashPurger.Run(ctx, time.Duration(achievementCfg.TrashPurgeIntervalMinutes)*time.Minute, achievementCfg.TrashPurgeBatchSize)
+    })
+    achievementService := mongoService.NewAchievementService(achRepoMongo, achRepoPg, lecturerRepo, accessPolicy, rubricRepo, outboxRelay, trashPurger)
+    rubricService := postgreService.NewRubricService(rubricRepo)
+    commentService := mongoService.NewCommentService(commentRepo, achRepoPg, achRepoMongo, accessPolicy,
+        time.Duration(achievementCfg.CommentEditWindowMinutes)*time.Minute,
+        time.Duration(achievementCfg.CommentDeleteWindowMinutes)*time.Minute,
+    )
+	reportService := mongoService.NewReportService(achRepoMongo, studentRepo, accessPolicy)
 
     // Static Files Config
     app.Static("/uploads", "./uploads")   
+
+    // Public verification keys for other campus services
+    app.Get("/.well-known/jwks.json", GetJWKS)
     api := app.Group("/api/v1")
 
+    // Every /api/v1 route declares its policy here; VerifyRoutePolicies
+    // rejects the startup if one is missing.
+
     // 5.1 Authentication
     auth := api.Group("/auth")
-    auth.Post("/login", authService.Login)
-    auth.Post("/refresh", authService.Refresh)
-    auth.Post("/logout", middleware.AuthRequired(), authService.Logout)
-    auth.Get("/profile", middleware.AuthRequired(), authService.Profile)
+    secure(auth, fiber.MethodPost, "/login", PolicyPublic, authService.Login)
+    secure(auth, fiber.MethodPost, "/refresh", PolicyPublic, authService.Refresh)
+    secure(auth, fiber.MethodPost, "/logout", PolicyAuthenticated, middleware.AuthRequired(), authService.Logout)
+    secure(auth, fiber.MethodGet, "/profile", PolicyAuthenticated, middleware.AuthRequired(), authService.Profile)
+    secure(auth, fiber.MethodGet, "/sessions", PolicyAuthenticated, middleware.AuthRequired(), middleware.SessionRequired(), authService.GetSessions)
+    secure(auth, fiber.MethodDelete, "/sessions/:id", PolicyAuthenticated, middleware.AuthRequired(), middleware.SessionRequired(), authService.RevokeSession)
+    secure(auth, fiber.MethodPut, "/password", PolicyAuthenticated, middleware.AuthRequired(), middleware.SessionRequired(), passwordService.ChangePassword)
+    secure(auth, fiber.MethodPost, "/reset-password", PolicyPublic, passwordService.ResetPassword)
+    secure(auth, fiber.MethodPost, "/2fa/login", PolicyPublic, authService.LoginMFA)
+    secure(auth, fiber.MethodPost, "/2fa/login/setup", PolicyPublic, mfaService.LoginSetup)
+    secure(auth, fiber.MethodPost, "/2fa/setup", PolicyAuthenticated, middleware.AuthRequired(), middleware.SessionRequired(), mfaService.Setup)
+    secure(auth, fiber.MethodPost, "/2fa/confirm", PolicyAuthenticated, middleware.AuthRequired(), middleware.SessionRequired(), mfaService.Confirm)
+    secure(auth, fiber.MethodPost, "/2fa/disable", PolicyAuthenticated, middleware.AuthRequired(), middleware.SessionRequired(), mfaService.Disable)
+    secure(auth, fiber.MethodPost, "/tokens", PolicyAuthenticated, middleware.AuthRequired(), middleware.SessionRequired(), accessTokenService.CreateAccessToken)
+    secure(auth, fiber.MethodGet, "/tokens", PolicyAuthenticated, middleware.AuthRequired(), middleware.SessionRequired(), accessTokenService.GetAccessTokens)
+    secure(auth, fiber.MethodDelete, "/tokens/:id", PolicyAuthenticated, middleware.AuthRequired(), middleware.SessionRequired(), accessTokenService.RevokeAccessToken)
+    secure(auth, fiber.MethodGet, "/oidc/login", PolicyPublic, oidcService.OIDCLogin)
+    secure(auth, fiber.MethodGet, "/oidc/callback", PolicyPublic, oidcService.OIDCCallback)
 
     // 5.2 Users 
     users := api.Group("/users", middleware.AuthRequired())
-    users.Get("/", adminService.GetAllUsers)
-    users.Get("/:id", adminService.GetUserByID)
-    users.Post("/", adminService.CreateUser)
-    users.Put("/:id", adminService.UpdateUser)
-    users.Delete("/:id", adminService.DeleteUser)
-    users.Put("/:id/role", adminService.AssignRole)
+    secure(users, fiber.MethodGet, "/", "manage:users", adminService.GetAllUsers)
+    secure(users, fiber.MethodGet, "/:id", PolicyAuthenticated, adminService.GetUserByID)
+    secure(users, fiber.MethodPost, "/", "manage:users", adminService.CreateUser)
+    secure(users, fiber.MethodPost, "/import", "manage:users", adminService.ImportUsers)
+    secure(users, fiber.MethodPut, "/:id", "manage:users", adminService.UpdateUser)
+    secure(users, fiber.MethodDelete, "/:id", "manage:users", adminService.DeleteUser)
+    secure(users, fiber.MethodPut, "/:id/role", "manage:users", adminService.AssignRole)
+    secure(users, fiber.MethodPost, "/:id/reset-password", "manage:users", passwordService.IssueResetToken)
+    secure(users, fiber.MethodDelete, "/:id/2fa", "manage:users", mfaService.ResetUserMFA)
+
+    // 5.3 Roles & Permissions
+    roles := api.Group("/roles", middleware.AuthRequired())
+    secure(roles, fiber.MethodGet, "/", "manage:users", roleService.GetAllRoles)
+    secure(roles, fiber.MethodGet, "/:id", "manage:users", roleService.GetRoleByID)
+    secure(roles, fiber.MethodPost, "/", "manage:users", roleService.CreateRole)
+    secure(roles, fiber.MethodPut, "/:id", "manage:users", roleService.UpdateRole)
+    secure(roles, fiber.MethodDelete, "/:id", "manage:users", roleService.DeleteRole)
+    secure(roles, fiber.MethodPost, "/:id/permissions", "manage:users", roleService.AttachPermission)
+    secure(roles, fiber.MethodDelete, "/:id/permissions/:permissionId", "manage:users", roleService.DetachPermission)
+
+    permissions := api.Group("/permissions", middleware.AuthRequired())
+    secure(permissions, fiber.MethodGet, "/", "manage:users", roleService.GetAllPermissions)
+    secure(permissions, fiber.MethodGet, "/:id", "manage:users", roleService.GetPermissionByID)
+    secure(permissions, fiber.MethodPost, "/", "manage:users", roleService.CreatePermission)
+    secure(permissions, fiber.MethodPut, "/:id", "manage:users", roleService.UpdatePermission)
+    secure(permissions, fiber.MethodDelete, "/:id", "manage:users", roleService.DeletePermission)
+
+    admin := api.Group("/admin", middleware.AuthRequired())
+    secure(admin, fiber.MethodGet, "/routes", "manage:users", GetRoutePolicies)
+    secure(admin, fiber.MethodGet, "/login-locks", "manage:users", loginThrottle.GetLoginLocks)
+    secure(admin, fiber.MethodDelete, "/login-locks", "manage:users", loginThrottle.ClearLoginLock)
+
+    rubrics := api.Group("/rubrics", middleware.AuthRequired())
+    secure(rubrics, fiber.MethodGet, "/", "manage:users", rubricService.GetRubrics)
+    secure(rubrics, fiber.MethodGet, "/:id", "manage:users", rubricService.GetRubric)
+    secure(rubrics, fiber.MethodPost, "/", "manage:users", rubricService.CreateRubric)
+    secure(rubrics, fiber.MethodPut, "/:id", "manage:users", rubricService.UpdateRubric)
+    secure(rubrics, fiber.MethodDelete, "/:id", "manage:users", rubricService.DeleteRubric)
+    secure(rubrics, fiber.MethodPost, "/:id/activate", "manage:users", rubricService.ActivateRubric)
 
     // 5.4 Achievements
     ach := api.Group("/achievements", middleware.AuthRequired())
-    ach.Get("/", achievementService.GetAllAchievements)
-    ach.Get("/:id", achievementService.GetAchievementDetail)
-    ach.Get("/:id/history", achievementService.GetAchievementHistory)
-    ach.Post("/", achievementService.CreateAchievement) 
-    ach.Put("/:id", achievementService.UpdateAchievement)
-    ach.Delete("/:id",  achievementService.DeleteAchievement)
-    ach.Post("/:id/submit", achievementService.SubmitAchievement)
-    ach.Post("/:id/attachments", achievementService.UploadAttachments)
-    ach.Post("/:id/verify", achievementService.VerifyAchievement)
-    ach.Post("/:id/reject", achievementService.RejectAchievement)
+    secure(ach, fiber.MethodGet, "/", "achievement:read", achievementService.GetAllAchievements)
+    secure(ach, fiber.MethodGet, "/types", "achievement:read", achievementService.GetAchievementTypes)
+    secure(ach, fiber.MethodPost, "/bulk/verify", "achievement:verify", achievementService.BulkVerifyAchievements)
+    secure(ach, fiber.MethodPost, "/bulk/reject", "achievement:verify", achievementService.BulkRejectAchievements)
+    secure(ach, fiber.MethodGet, "/:id", "achievement:read", achievementService.GetAchievementDetail)
+    secure(ach, fiber.MethodGet, "/:id/history", "achievement:read", achievementService.GetAchievementHistory)
+    secure(ach, fiber.MethodGet, "/:id/versions", "achievement:read", achievementService.GetAchievementVersions)
+    secure(ach, fiber.MethodGet, "/:id/versions/diff", "achievement:read", achievementService.DiffAchievementVersions)
+    secure(ach, fiber.MethodGet, "/:id/versions/:v", "achievement:read", achievementService.GetAchievementVersion)
+    secure(ach, fiber.MethodGet, "/:id/comments", "achievement:read", commentService.GetComments)
+    secure(ach, fiber.MethodPost, "/:id/comments", "achievement:read", commentService.CreateComment)
+    secure(ach, fiber.MethodPut, "/:id/comments/:commentId", "achievement:read", commentService.UpdateComment)
+    secure(ach, fiber.MethodDelete, "/:id/comments/:commentId", "achievement:read", commentService.DeleteComment)
+    secure(ach, fiber.MethodPost, "/", "achievement:create", achievementService.CreateAchievement)
+    secure(ach, fiber.MethodPut, "/:id", "achievement:update", achievementService.UpdateAchievement)
+    secure(ach, fiber.MethodDelete, "/:id", "achievement:delete", achievementService.DeleteAchievement)
+    secure(ach, fiber.MethodPost, "/:id/restore", "achievement:delete", achievementService.RestoreAchievement)
+    secure(ach, fiber.MethodPost, "/:id/submit", "achievement:create", achievementService.SubmitAchievement)
+    secure(ach, fiber.MethodPost, "/:id/attachments", "achievement:update", achievementService.UploadAttachments)
+    secure(ach, fiber.MethodPost, "/:id/verify", "achievement:verify", achievementService.VerifyAchievement)
+    secure(ach, fiber.MethodPost, "/:id/reject", "achievement:verify", achievementService.RejectAchievement)
+    secure(ach, fiber.MethodPost, "/:id/reopen", "achievement:update", achievementService.ReopenAchievement)
+    secure(ach, fiber.MethodPost, "/:id/revoke", "achievement:verify", achievementService.RevokeAchievement)
+    secure(ach, fiber.MethodPost, "/:id/adjust-points", "achievement:verify", achievementService.AdjustAchievementPoints)
 
     // 5.5 Students & Lecturers
     student := api.Group("/students", middleware.AuthRequired())
     lecturer := api.Group("/lecturers", middleware.AuthRequired())
-    student.Get("/",  studentService.GetAllStudents)
-    student.Get("/:id",   studentService.GetStudentByID)
-    student.Get("/:id/achievements", studentService.GetStudentAchievements)
-    student.Put("/:id/advisor",   studentService.UpdateAdvisor)
-    lecturer.Get("/",   lecturerService.GetAllLecturers)
-    lecturer.Get("/:id/advisees", lecturerService.GetAdvisees)
+    secure(student, fiber.MethodGet, "/", "manage:students", studentService.GetAllStudents)
+    secure(student, fiber.MethodGet, "/:id", "achievement:read", studentService.GetStudentByID)
+    secure(student, fiber.MethodGet, "/:id/achievements", "achievement:read", studentService.GetStudentAchievements)
+    secure(student, fiber.MethodGet, "/:id/trash", "achievement:read", achievementService.GetStudentTrash)
+    secure(student, fiber.MethodPut, "/:id/advisor", "manage:students", studentService.UpdateAdvisor)
+    secure(lecturer, fiber.MethodGet, "/", "manage:lecturers", lecturerService.GetAllLecturers)
+    secure(lecturer, fiber.MethodGet, "/:id", "manage:lecturers", lecturerService.GetLecturerByID)
+    secure(lecturer, fiber.MethodGet, "/:id/advisees", "achievement:verify", lecturerService.GetAdvisees)
 
 	// 5.8 Reports & Analytics (NEW)
 	reports := api.Group("/reports", middleware.AuthRequired())    
-	reports.Get("/statistics", reportService.GetStatistics)
-	reports.Get("/student/:id", reportService.GetStudentReport)
-}
\ No newline at end of file
+	secure(reports, fiber.MethodGet, "/statistics", "report:students", reportService.GetStatistics)
+	secure(reports, fiber.MethodGet, "/student/:id", "report:students", reportService.GetStudentReport)
+}
+
+// newOIDCService wires single sign-on; without OIDC_ISSUER the endpoints
+// exist but answer 404.
+func newOIDCService(cfg config.OIDCConfig, oidcRepo repoPostgre.OIDCRepository, admin *postgreService.AdminService, auth *postgreService.AuthService) *postgreService.OIDCService {
+    var provider *utils.OIDCProvider
+    if cfg.Enabled() {
+        provider = utils.NewOIDCProvider(cfg.Issuer, cfg.ClientID, cfg.ClientSecret, cfg.RedirectURL, cfg.Scopes, nil)
+    }
+    return postgreService.NewOIDCService(provider, oidcRepo, admin, auth,
+        time.Duration(cfg.StateTTLMinutes)*time.Minute,
+        postgreService.OIDCProvisioning{
+            Enabled:           cfg.AutoProvision,
+            RoleName:          cfg.ProvisionRole,
+            StudentIDClaim:    cfg.StudentIDClaim,
+            ProgramStudyClaim: cfg.ProgramStudyClaim,
+            AcademicYearClaim: cfg.AcademicYearClaim,
+        },
+    )
+}
+
+// newAuthenticators checks local bcrypt passwords, except for the roles and
+// usernames configured to log in through the faculty LDAP directory.
+func newAuthenticators(cfg config.LDAPConfig, roleRepo repoPostgre.RoleRepository, adminRepo repoPostgre.AdminRepository) *postgreService.Authenticators {
+    if !cfg.Enabled() {
+        return postgreService.NewAuthenticators(roleRepo, adminRepo, postgreService.PasswordAuthenticator{})
+    }
+
+    groupRoles := make([]postgreService.GroupRole, 0, len(cfg.GroupRoles))
+    for _, gr := range cfg.GroupRoles {
+        groupRoles = append(groupRoles, postgreService.GroupRole{Group: gr.Group, Role: gr.Role})
+    }
+    ldap := postgreService.NewLDAPAuthenticator(&utils.LDAPDirectory{
+        URL:         cfg.URL,
+        UserDN:      cfg.UserDN,
+        GroupBaseDN: cfg.GroupBaseDN,
+        GroupFilter: cfg.GroupFilter,
+        Timeout:     time.Duration(cfg.TimeoutSeconds) * time.Second,
+    }, groupRoles)
+
+    rule := postgreService.AuthenticatorRule{Roles: cfg.Roles, Authenticator: ldap}
+    if cfg.UsernamePattern != "" {
+        rule.UsernamePattern = regexp.MustCompile(cfg.UsernamePattern)
+    }
+    return postgreService.NewAuthenticators(roleRepo, adminRepo, postgreService.PasswordAuthenticator{}, rule)
+}
@@ -0,0 +1,24 @@
+package route
+
+import (
+    "github.com/gofiber/fiber/v2"
+    "student-performance-report/utils"
+)
+
+// GetJWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys that verify our access tokens, selected by the token's kid header. Served outside /api/v1 at /.well-known/jwks.json.
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} utils.JWKSet
+// @Failure 500 {object} map[string]interface{}
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(c *fiber.Ctx) error {
+    set, err := utils.PublicJWKS()
+    if err != nil {
+        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+    }
+
+    c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+    return c.JSON(set)
+}
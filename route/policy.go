@@ -0,0 +1,104 @@
+package route
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "github.com/gofiber/fiber/v2"
+    "student-performance-report/middleware"
+)
+
+// Policies that do not map to a permission. Every /api/v1 route must be
+// registered through secure with either one of these or a permission name.
+const (
+    PolicyPublic        = "public"
+    PolicyAuthenticated = "authenticated"
+)
+
+type RoutePolicy struct {
+    Method     string `json:"method"`
+    Path       string `json:"path"`
+    Permission string `json:"permission"`
+}
+
+var (
+    policyMu sync.RWMutex
+    policies = make(map[string]RoutePolicy)
+)
+
+func policyKey(method, path string) string {
+    if len(path) > 1 {
+        path = strings.TrimRight(path, "/")
+    }
+    return method + " " + path
+}
+
+// secure registers handlers on the router behind PermissionRequired(permission)
+// and records the route in the policy table checked by VerifyRoutePolicies.
+func secure(r fiber.Router, method, path, permission string, handlers ...fiber.Handler) {
+    prefix := ""
+    if g, ok := r.(*fiber.Group); ok {
+        prefix = g.Prefix
+    }
+    fullPath := strings.TrimRight(prefix, "/") + path
+
+    policyMu.Lock()
+    policies[policyKey(method, fullPath)] = RoutePolicy{Method: method, Path: fullPath, Permission: permission}
+    policyMu.Unlock()
+
+    if permission != PolicyPublic && permission != PolicyAuthenticated {
+        handlers = append([]fiber.Handler{middleware.PermissionRequired(permission)}, handlers...)
+    }
+    r.Add(method, path, handlers...)
+}
+
+// VerifyRoutePolicies fails if any /api/v1 route was registered without a
+// declared policy, so a new endpoint cannot silently ship unprotected.
+func VerifyRoutePolicies(app *fiber.App) error {
+    policyMu.RLock()
+    defer policyMu.RUnlock()
+
+    var missing []string
+    for _, r := range app.GetRoutes(true) {
+        if r.Method == fiber.MethodHead || !strings.HasPrefix(r.Path, "/api/v1") {
+            continue
+        }
+        if _, ok := policies[policyKey(r.Method, r.Path)]; !ok {
+            missing = append(missing, r.Method+" "+r.Path)
+        }
+    }
+
+    if len(missing) > 0 {
+        sort.Strings(missing)
+        return fmt.Errorf("routes without a declared authorization policy: %s", strings.Join(missing, ", "))
+    }
+    return nil
+}
+
+// GetRoutePolicies godoc
+// @Summary Get Route Permission Matrix
+// @Description List every API route with the permission it requires (Admin only)
+// @Tags Users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} route.RoutePolicy
+// @Failure 403 {object} map[string]interface{}
+// @Router /admin/routes [get]
+func GetRoutePolicies(c *fiber.Ctx) error {
+    policyMu.RLock()
+    list := make([]RoutePolicy, 0, len(policies))
+    for _, p := range policies {
+        list = append(list, p)
+    }
+    policyMu.RUnlock()
+
+    sort.Slice(list, func(i, j int) bool {
+        if list[i].Path != list[j].Path {
+            return list[i].Path < list[j].Path
+        }
+        return list[i].Method < list[j].Method
+    })
+
+    return c.JSON(list)
+}
@@ -0,0 +1,492 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLDAPInvalidCredentials is returned when the directory rejects a bind
+// (result code 49) or the password is empty.
+var ErrLDAPInvalidCredentials = errors.New("ldap: invalid credentials")
+
+// LDAPDirectory authenticates users with a simple bind against an LDAP v3
+// server and looks up the groups they belong to. It speaks just enough of
+// the protocol for that (bind and search) so no client library is needed.
+type LDAPDirectory struct {
+	// URL is ldap://host[:389] or ldaps://host[:636].
+	URL string
+	// UserDN is the bind DN template, e.g. "uid=%s,ou=people,dc=univ,dc=ac,dc=id".
+	UserDN string
+	// GroupBaseDN and GroupFilter find the user's groups; %s in the filter
+	// is the user's DN. Groups are skipped when GroupBaseDN is empty.
+	GroupBaseDN string
+	GroupFilter string
+	Timeout     time.Duration
+	TLSConfig   *tls.Config
+}
+
+// Authenticate binds as the user and returns the cn of every group found.
+func (d *LDAPDirectory) Authenticate(ctx context.Context, username, password string) ([]string, error) {
+	// An empty password would be an "unauthenticated bind", which most
+	// servers accept without checking anything.
+	if password == "" {
+		return nil, ErrLDAPInvalidCredentials
+	}
+
+	conn, err := d.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	userDN := fmt.Sprintf(d.UserDN, EscapeLDAPDN(username))
+	if err := conn.bind(userDN, password); err != nil {
+		return nil, err
+	}
+	if d.GroupBaseDN == "" {
+		return nil, nil
+	}
+
+	filter := d.GroupFilter
+	if filter == "" {
+		filter = "(member=%s)"
+	}
+	entries, err := conn.search(d.GroupBaseDN, fmt.Sprintf(filter, EscapeLDAPFilter(userDN)), []string{"cn"})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if cn := e.attrs["cn"]; len(cn) > 0 {
+			groups = append(groups, cn[0])
+		} else {
+			groups = append(groups, e.dn)
+		}
+	}
+	return groups, nil
+}
+
+// EscapeLDAPDN escapes a value for use inside an RDN (RFC 4514).
+func EscapeLDAPDN(v string) string {
+	var b strings.Builder
+	for i, r := range v {
+		switch {
+		case strings.ContainsRune(`,+"\<>;=`, r),
+			i == 0 && (r == ' ' || r == '#'),
+			i == len(v)-1 && r == ' ':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == 0:
+			b.WriteString(`\00`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// EscapeLDAPFilter escapes a value for use inside a search filter (RFC 4515).
+func EscapeLDAPFilter(v string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, `\%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// --- wire protocol ---
+
+const (
+	ldapBindRequest    = 0x60
+	ldapBindResponse   = 0x61
+	ldapUnbindRequest  = 0x42
+	ldapSearchRequest  = 0x63
+	ldapSearchEntry    = 0x64
+	ldapSearchDone     = 0x65
+	ldapSearchRef      = 0x73
+	ldapResultSuccess  = 0
+	ldapResultBadCreds = 49
+)
+
+type ldapConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	nextID int
+}
+
+type ldapEntry struct {
+	dn    string
+	attrs map[string][]string
+}
+
+func (d *LDAPDirectory) dial(ctx context.Context) (*ldapConn, error) {
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return nil, err
+	}
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		conn, err = dialer.DialContext(ctx, "tcp", hostWithPort(u, "389"))
+	case "ldaps":
+		cfg := d.TLSConfig
+		if cfg == nil {
+			cfg = &tls.Config{ServerName: u.Hostname()}
+		}
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: cfg}).DialContext(ctx, "tcp", hostWithPort(u, "636"))
+	default:
+		return nil, fmt.Errorf("ldap: unsupported scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	return &ldapConn{conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+func hostWithPort(u *url.URL, port string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func (c *ldapConn) close() {
+	c.send(berPrimitive(ldapUnbindRequest, nil))
+	c.conn.Close()
+}
+
+func (c *ldapConn) send(op []byte) (int, error) {
+	c.nextID++
+	msg := berConstructed(0x30, berInt(0x02, c.nextID), op)
+	_, err := c.conn.Write(msg)
+	return c.nextID, err
+}
+
+// receive reads the next message for id and returns its protocol op.
+func (c *ldapConn) receive(id int) (berElement, error) {
+	for {
+		msg, err := readBER(c.r)
+		if err != nil {
+			return berElement{}, err
+		}
+		parts, err := msg.children()
+		if err != nil || len(parts) < 2 {
+			return berElement{}, errors.New("ldap: malformed message")
+		}
+		if parts[0].int() == id {
+			return parts[1], nil
+		}
+	}
+}
+
+func (c *ldapConn) bind(dn, password string) error {
+	id, err := c.send(berConstructed(ldapBindRequest,
+		berInt(0x02, 3),
+		berPrimitive(0x04, []byte(dn)),
+		berPrimitive(0x80, []byte(password)),
+	))
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.tag != ldapBindResponse {
+		return fmt.Errorf("ldap: unexpected response 0x%02x to bind", op.tag)
+	}
+	code, msg, err := op.result()
+	if err != nil {
+		return err
+	}
+	switch code {
+	case ldapResultSuccess:
+		return nil
+	case ldapResultBadCreds:
+		return ErrLDAPInvalidCredentials
+	}
+	return fmt.Errorf("ldap: bind failed with result %d: %s", code, msg)
+}
+
+func (c *ldapConn) search(baseDN, filter string, attrs []string) ([]ldapEntry, error) {
+	encodedFilter, rest, err := encodeLDAPFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("ldap: trailing data in filter %q", filter)
+	}
+
+	attrList := make([][]byte, 0, len(attrs))
+	for _, a := range attrs {
+		attrList = append(attrList, berPrimitive(0x04, []byte(a)))
+	}
+	id, err := c.send(berConstructed(ldapSearchRequest,
+		berPrimitive(0x04, []byte(baseDN)),
+		berInt(0x0a, 2), // scope: wholeSubtree
+		berInt(0x0a, 0), // derefAliases: never
+		berInt(0x02, 0), // no size limit
+		berInt(0x02, 0), // no time limit
+		berPrimitive(0x01, []byte{0}),
+		encodedFilter,
+		berConstructed(0x30, attrList...),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ldapEntry
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch op.tag {
+		case ldapSearchEntry:
+			entry, err := op.entry()
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case ldapSearchRef:
+			// Referrals to other servers are not followed.
+		case ldapSearchDone:
+			code, msg, err := op.result()
+			if err != nil {
+				return nil, err
+			}
+			if code != ldapResultSuccess {
+				return nil, fmt.Errorf("ldap: search failed with result %d: %s", code, msg)
+			}
+			return entries, nil
+		default:
+			return nil, fmt.Errorf("ldap: unexpected response 0x%02x to search", op.tag)
+		}
+	}
+}
+
+// encodeLDAPFilter encodes the filter at the start of s (RFC 4515 subset:
+// &, |, !, equality and presence) and returns what follows it.
+func encodeLDAPFilter(s string) ([]byte, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("ldap: filter must start with '(': %q", s)
+	}
+	s = s[1:]
+
+	if s != "" && strings.ContainsRune("&|!", rune(s[0])) {
+		tag := map[byte]byte{'&': 0xa0, '|': 0xa1, '!': 0xa2}[s[0]]
+		s = s[1:]
+		var parts [][]byte
+		for strings.HasPrefix(s, "(") {
+			part, rest, err := encodeLDAPFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			parts = append(parts, part)
+			s = rest
+		}
+		if !strings.HasPrefix(s, ")") || len(parts) == 0 || (tag == 0xa2 && len(parts) != 1) {
+			return nil, "", errors.New("ldap: malformed filter")
+		}
+		return berConstructed(tag, parts...), s[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	eq := strings.IndexByte(s, '=')
+	if end < 0 || eq <= 0 || eq > end {
+		return nil, "", errors.New("ldap: malformed filter")
+	}
+	attr, value := s[:eq], s[eq+1:end]
+	if value == "*" {
+		return berPrimitive(0x87, []byte(attr)), s[end+1:], nil
+	}
+	raw, err := unescapeLDAPFilter(value)
+	if err != nil {
+		return nil, "", err
+	}
+	return berConstructed(0xa3, berPrimitive(0x04, []byte(attr)), berPrimitive(0x04, raw)), s[end+1:], nil
+}
+
+func unescapeLDAPFilter(v string) ([]byte, error) {
+	out := make([]byte, 0, len(v))
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			if i+3 > len(v) {
+				return nil, errors.New("ldap: bad escape in filter")
+			}
+			b, err := strconv.ParseUint(v[i+1:i+3], 16, 8)
+			if err != nil {
+				return nil, errors.New("ldap: bad escape in filter")
+			}
+			out = append(out, byte(b))
+			i += 2
+		case '*', '(':
+			return nil, errors.New("ldap: substring filters are not supported")
+		default:
+			out = append(out, v[i])
+		}
+	}
+	return out, nil
+}
+
+// --- BER ---
+
+type berElement struct {
+	tag     byte
+	content []byte
+}
+
+func berLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func berPrimitive(tag byte, content []byte) []byte {
+	out := append([]byte{tag}, berLength(len(content))...)
+	return append(out, content...)
+}
+
+func berConstructed(tag byte, children ...[]byte) []byte {
+	var content []byte
+	for _, c := range children {
+		content = append(content, c...)
+	}
+	return berPrimitive(tag, content)
+}
+
+func berInt(tag byte, v int) []byte {
+	b := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return berPrimitive(tag, b)
+}
+
+// maxLDAPMessage bounds what a misbehaving server can make us allocate.
+const maxLDAPMessage = 1 << 20
+
+func readBER(r *bufio.Reader) (berElement, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return berElement{}, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return berElement{}, err
+	}
+	length := int(first)
+	if first&0x80 != 0 {
+		n := int(first & 0x7f)
+		if n == 0 || n > 4 {
+			return berElement{}, errors.New("ldap: unsupported BER length")
+		}
+		length = 0
+		for i := 0; i < n; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return berElement{}, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxLDAPMessage {
+		return berElement{}, errors.New("ldap: message too large")
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return berElement{}, err
+	}
+	return berElement{tag: tag, content: content}, nil
+}
+
+func (e berElement) children() ([]berElement, error) {
+	r := bufio.NewReader(bytes.NewReader(e.content))
+	var out []berElement
+	for {
+		child, err := readBER(r)
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, child)
+	}
+}
+
+func (e berElement) int() int {
+	v := 0
+	for _, b := range e.content {
+		v = v<<8 | int(b)
+	}
+	return v
+}
+
+// result decodes an LDAPResult (resultCode, matchedDN, diagnosticMessage).
+func (e berElement) result() (int, string, error) {
+	parts, err := e.children()
+	if err != nil || len(parts) < 3 {
+		return 0, "", errors.New("ldap: malformed result")
+	}
+	return parts[0].int(), string(parts[2].content), nil
+}
+
+func (e berElement) entry() (ldapEntry, error) {
+	parts, err := e.children()
+	if err != nil || len(parts) < 2 {
+		return ldapEntry{}, errors.New("ldap: malformed search entry")
+	}
+	entry := ldapEntry{dn: string(parts[0].content), attrs: map[string][]string{}}
+	attrs, err := parts[1].children()
+	if err != nil {
+		return ldapEntry{}, err
+	}
+	for _, a := range attrs {
+		kv, err := a.children()
+		if err != nil || len(kv) < 2 {
+			return ldapEntry{}, errors.New("ldap: malformed attribute")
+		}
+		vals, err := kv[1].children()
+		if err != nil {
+			return ldapEntry{}, err
+		}
+		name := strings.ToLower(string(kv[0].content))
+		for _, v := range vals {
+			entry.attrs[name] = append(entry.attrs[name], string(v.content))
+		}
+	}
+	return entry, nil
+}
@@ -1,6 +1,16 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"unicode"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const MinPasswordLength = 8
+
+var ErrWeakPassword = errors.New("password must be 8-72 characters and contain upper-case, lower-case and digit characters")
 
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -10,4 +20,47 @@ func HashPassword(password string) (string, error) {
 func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
-}
\ No newline at end of file
+}
+
+// ValidatePassword enforces the password policy for new and changed passwords.
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength || len(password) > 72 {
+		return ErrWeakPassword
+	}
+
+	var upper, lower, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	if !upper || !lower || !digit {
+		return ErrWeakPassword
+	}
+	return nil
+}
+
+const passwordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+
+// GenerateTemporaryPassword returns a random password that satisfies
+// ValidatePassword, for accounts created without one (e.g. bulk import).
+func GenerateTemporaryPassword() (string, error) {
+	for {
+		buf := make([]byte, 16)
+		for i := range buf {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordAlphabet))))
+			if err != nil {
+				return "", err
+			}
+			buf[i] = passwordAlphabet[n.Int64()]
+		}
+		if ValidatePassword(string(buf)) == nil {
+			return string(buf), nil
+		}
+	}
+}
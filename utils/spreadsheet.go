@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ReadCSVRows returns every record of a CSV file. A UTF-8 BOM (as written by
+// Excel) is stripped and ragged rows are allowed.
+func ReadCSVRows(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader.ReadAll()
+}
+
+const (
+	// xlsxMaxColumns is the XLSX column limit (XFD); a cell reference beyond
+	// it can only come from a malformed or hostile file.
+	xlsxMaxColumns = 16384
+	// xlsxMaxPartSize caps the decompressed size of each XML part read, so a
+	// small zip cannot expand into gigabytes before the row limit applies.
+	xlsxMaxPartSize = 32 << 20
+)
+
+// ReadXLSXRows returns the cell text of the first worksheet of an .xlsx file.
+// Only what an import needs is supported: shared and inline strings, numbers
+// and booleans. Empty cells are kept so columns stay aligned.
+func ReadXLSXRows(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.New("invalid xlsx file")
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxRichText `xml:"si"`
+		}
+		if err := decodeZipXML(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, si := range sst.Items {
+			shared = append(shared, si.String())
+		}
+	}
+
+	sheetFile, ok := files[sheetPath]
+	if !ok {
+		return nil, errors.New("invalid xlsx file: worksheet not found")
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string       `xml:"r,attr"`
+				Type   string       `xml:"t,attr"`
+				Value  string       `xml:"v"`
+				Inline xlsxRichText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodeZipXML(sheetFile, &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		var values []string
+		for i, cell := range row.Cells {
+			col := i
+			if idx := xlsxColumnIndex(cell.Ref); idx >= 0 {
+				col = idx
+			}
+			if col >= xlsxMaxColumns {
+				return nil, errors.New("invalid xlsx file")
+			}
+
+			var text string
+			switch cell.Type {
+			case "s":
+				idx, err := strconv.Atoi(cell.Value)
+				if err != nil || idx < 0 || idx >= len(shared) {
+					return nil, errors.New("invalid xlsx file: bad shared string index")
+				}
+				text = shared[idx]
+			case "inlineStr":
+				text = cell.Inline.String()
+			case "b":
+				text = map[string]string{"1": "TRUE", "0": "FALSE"}[cell.Value]
+			default:
+				text = cell.Value
+			}
+
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			values[col] = text
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+// firstSheetPath follows workbook.xml and its relationships to the first sheet.
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	wbFile, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", errors.New("invalid xlsx file: workbook not found")
+	}
+	var wb struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(wbFile, &wb); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", errors.New("invalid xlsx file: workbook has no sheets")
+	}
+
+	relsFile, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(relsFile, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Items {
+		if rel.ID == wb.Sheets[0].RelID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+	}
+	return "", errors.New("invalid xlsx file: first sheet not found")
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	if f.UncompressedSize64 > xlsxMaxPartSize {
+		return errors.New("invalid xlsx file: " + f.Name + " is too large")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	// The size in the header is not trusted: a part that inflates past the
+	// limit is cut off and fails to decode.
+	if err := xml.NewDecoder(io.LimitReader(rc, xlsxMaxPartSize)).Decode(v); err != nil {
+		return errors.New("invalid xlsx file: " + err.Error())
+	}
+	return nil
+}
+
+// xlsxColumnIndex turns a cell reference such as "C7" into the 0-based column
+// 2. Any column past the XLSX limit is returned as xlsxMaxColumns.
+func xlsxColumnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		if col > xlsxMaxColumns {
+			return xlsxMaxColumns
+		}
+	}
+	return col - 1
+}
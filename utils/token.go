@@ -2,36 +2,156 @@ package utils
 
 import (
 	"errors"
-	"os"
+	"fmt"
+	"log"
+	"sync"
 	"time"
 	"student-performance-report/config"
 	"student-performance-report/app/models/postgresql"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-func GenerateToken(user *models.User, roleName string, permissions []string) (string, error) {
-	jwtCfg := config.LoadJWT()
+const RefreshTokenTTL = 7 * 24 * time.Hour
 
+// accessTokenAlgs are the only algorithms ValidateToken accepts; HS256 and
+// "none" are refused even if a key would match.
+var accessTokenAlgs = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+
+type tokenSettings struct {
+	keys          *KeySet
+	refreshSecret []byte
+	ttl           time.Duration
+	issuer        string
+	audience      string
+}
+
+var (
+	tokensMu   sync.RWMutex
+	tokens     *tokenSettings
+	tokensOnce sync.Once
+)
+
+// InitTokenKeys loads the access-token keys, the refresh-token secret and
+// the claims settings once at startup; a missing secret is an error. Without
+// JWT_KEY_DIR an ephemeral Ed25519 key is generated, which only suits a
+// single instance: tokens die with the process.
+func InitTokenKeys(cfg config.JWTConfig) error {
+	if len(cfg.Secret) == 0 {
+		return errors.New("JWT_REFRESH_SECRET or JWT_SECRET must be set to sign refresh tokens")
+	}
+
+	var ks *KeySet
+	if cfg.KeyDir != "" {
+		loaded, err := LoadKeySet(cfg.KeyDir, cfg.ActiveKeyID)
+		if err != nil {
+			return fmt.Errorf("load JWT keys: %w", err)
+		}
+		ks = loaded
+	} else {
+		key, err := GenerateEd25519Key("ephemeral-" + uuid.NewString()[:8])
+		if err != nil {
+			return err
+		}
+		log.Println("JWT_KEY_DIR is not set, signing access tokens with an ephemeral key")
+		ks = NewKeySet(key)
+	}
+
+	UseTokenKeys(ks, cfg)
+	return nil
+}
+
+// UseTokenKeys replaces the key set, e.g. to rotate keys in tests.
+func UseTokenKeys(ks *KeySet, cfg config.JWTConfig) {
+	tokensMu.Lock()
+	tokens = &tokenSettings{
+		keys:          ks,
+		refreshSecret: cfg.Secret,
+		ttl:           time.Duration(cfg.TTLHours) * time.Hour,
+		issuer:        cfg.Issuer,
+		audience:      cfg.Audience,
+	}
+	tokensMu.Unlock()
+}
+
+func currentTokens() (*tokenSettings, error) {
+	tokensOnce.Do(func() {
+		tokensMu.RLock()
+		ready := tokens != nil
+		tokensMu.RUnlock()
+		if !ready {
+			if err := InitTokenKeys(config.LoadJWT()); err != nil {
+				log.Println(err)
+			}
+		}
+	})
+
+	tokensMu.RLock()
+	defer tokensMu.RUnlock()
+	if tokens == nil {
+		return nil, errors.New("JWT keys are not configured")
+	}
+	return tokens, nil
+}
+
+// PublicJWKS returns the verification keys for /.well-known/jwks.json.
+func PublicJWKS() (JWKSet, error) {
+	t, err := currentTokens()
+	if err != nil {
+		return JWKSet{}, err
+	}
+	return t.keys.JWKS(), nil
+}
+
+func GenerateToken(user *models.User, roleName string, sessionID uuid.UUID) (string, error) {
+	t, err := currentTokens()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := &models.JWTClaims{
 		UserID:      user.ID,
 		RoleID:      user.RoleID,
 		RoleName:    roleName,
-		Permissions: permissions,
+		SessionID:   sessionID,
+		TokenVersion: user.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(jwtCfg.TTLHours) * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "student-performance-app",
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    t.issuer,
+			Audience:  jwt.ClaimStrings{t.audience},
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtCfg.Secret)
+	key := t.keys.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
 }
 
 func ValidateToken(tokenString string) (*models.JWTClaims, error) {
-	jwtCfg := config.LoadJWT()
-	token, err := jwt.ParseWithClaims(tokenString, &models.JWTClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return jwtCfg.Secret, nil
+	t, err := currentTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(accessTokenAlgs),
+		jwt.WithIssuer(t.issuer),
+		jwt.WithAudience(t.audience),
+		jwt.WithExpirationRequired(),
+	)
+	token, err := parser.ParseWithClaims(tokenString, &models.JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := t.keys.Key(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("key %q does not sign with %s", kid, token.Method.Alg())
+		}
+		return key.Public, nil
 	})
 
 	if err != nil {
@@ -46,38 +166,57 @@ func ValidateToken(tokenString string) (*models.JWTClaims, error) {
 
 
 
-func GenerateRefreshToken(user *models.User) (string, error) {
-	secret := os.Getenv("JWT_REFRESH_SECRET")
-	if secret == "" {
-		secret = os.Getenv("JWT_SECRET")
+// GenerateRefreshToken signs a refresh token for the given session. jti must match
+// the session's current refresh_jti for the token to be accepted by /auth/refresh.
+func GenerateRefreshToken(user *models.User, sessionID uuid.UUID, jti string) (string, error) {
+	t, err := currentTokens()
+	if err != nil {
+		return "", err
+	}
+	if len(t.refreshSecret) == 0 {
+		return "", errors.New("refresh token secret is not configured")
 	}
 
-	expiration := time.Now().Add(7 * 24 * time.Hour)
+	expiration := time.Now().Add(RefreshTokenTTL)
 
 	claims := &models.RefreshClaims{
-		UserID: user.ID.String(),
+		UserID:    user.ID.String(),
+		SessionID: sessionID.String(),
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(expiration),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "student-performance-app",
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return token.SignedString(t.refreshSecret)
 }
 
-func ValidateRefreshToken(t string) (*models.RefreshClaims, error) {
-	secret := os.Getenv("JWT_REFRESH_SECRET")
-	if secret == "" {
-		secret = os.Getenv("JWT_SECRET")
+// ValidateRefreshToken only accepts HS256. Refresh tokens never leave this
+// service, so they keep the shared secret rather than the published keys.
+func ValidateRefreshToken(tokenString string) (*models.RefreshClaims, error) {
+	t, err := currentTokens()
+	if err != nil {
+		return nil, err
+	}
+	if len(t.refreshSecret) == 0 {
+		return nil, errors.New("refresh token secret is not configured")
 	}
 
 	token, err := jwt.ParseWithClaims(
-		t,
+		tokenString,
 		&models.RefreshClaims{},
 		func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
+			if token.Method != jwt.SigningMethodHS256 {
+				return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+			}
+			return t.refreshSecret, nil
 		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer("student-performance-app"),
+		jwt.WithExpirationRequired(),
 	)
 
 	if err != nil {